@@ -1,21 +1,30 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"support-chat/config"
+	"support-chat/internal/canned"
 	"support-chat/internal/chat"
 	"support-chat/internal/chat/room"
 	"support-chat/internal/health"
 	"support-chat/internal/user"
+	"support-chat/internal/user/admin"
 	"support-chat/internal/user/auth"
 	"support-chat/pkg/jwt"
+	"support-chat/pkg/keyPair"
 	"support-chat/pkg/logger"
+	"support-chat/pkg/mailer"
 	"support-chat/pkg/mongodb"
+	"support-chat/pkg/oidc"
+	"support-chat/pkg/rbac"
 	"support-chat/pkg/redis"
 	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -93,11 +102,33 @@ func main() {
 		zapLogger.Fatalf("failed to set up room repository %v", err)
 	}
 
+	auditRepository, err := admin.NewRepository(db, cfg.MongoDbName, zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up audit repository %v", err)
+	}
+
+	cannedRepository, err := canned.NewRepository(db, cfg.MongoDbName, zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up canned repository %v", err)
+	}
+
 	// Services
+	keyPairService, err := keyPair.NewKeyPairService(zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up key pair service %v", err)
+	}
+
+	jwtKeys, err := jwt.NewKeySet(cfg.JwtKeysDir, &cfg.JwtKeyRotation, &cfg.JwtExpiryRefresh, keyPairService, zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("failed to load jwt keys %v", err)
+	}
+	go jwtKeys.RunRotation(context.Background())
+
 	jwtService, err := jwt.NewJwtService(
-		cfg.JwtSecretAccess,
+		jwtKeys,
+		cfg.JwtIssuer,
+		cfg.JwtAudience,
 		&cfg.JwtExpiryAccess,
-		cfg.JwtSecretRefresh,
 		&cfg.JwtExpiryRefresh,
 		&cfg.AutoLogout,
 		redisAuthClient)
@@ -105,40 +136,133 @@ func main() {
 		zapLogger.Fatalf("failde to jwt service: %v", err)
 	}
 
-	userService, err := user.NewService(userRepository, zapLogger, &cfg.Salt)
+	userService, err := user.NewService(userRepository, zapLogger, &cfg.Salt, &cfg.SupportDefaultCapacity)
 	if err != nil {
 		zapLogger.Fatalf("failde to create user service: %v", err)
 	}
 
-	userAuthService, err := auth.NewService(userService, jwtService, zapLogger)
+	if err = userService.BackfillVerified(context.Background()); err != nil {
+		zapLogger.Fatalf("failed to backfill verified users: %v", err)
+	}
+
+	ticketService, err := auth.NewTicketService(redisAuthClient)
 	if err != nil {
-		zapLogger.Fatalf("failde to create user service: %v", err)
+		zapLogger.Fatalf("failed to set up ticket service %v", err)
 	}
 
-	roomService, err := room.NewService(roomRepository, userService, zapLogger)
+	mailTokenService, err := auth.NewMailTokenService(redisAuthClient)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up mail token service %v", err)
+	}
+
+	mfaTokenService, err := auth.NewMfaTokenService(redisAuthClient)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up mfa token service %v", err)
+	}
+
+	var mailService mailer.Mailer
+	switch cfg.MailSender {
+	case mailer.SenderSMTP:
+		mailService, err = mailer.NewSMTPMailer(cfg.SmtpHost, cfg.SmtpPort, cfg.SmtpUsername, cfg.SmtpPassword, cfg.MailFrom)
+	case mailer.SenderOutbox:
+		mailService, err = mailer.NewOutboxMailer(cfg.MailOutboxDir, cfg.MailFrom)
+	default:
+		err = fmt.Errorf("unknown mail sender %q", cfg.MailSender)
+	}
+	if err != nil {
+		zapLogger.Fatalf("failed to set up mailer %v", err)
+	}
+
+	policy, err := rbac.LoadPolicy(cfg.RbacPolicyFile)
+	if err != nil {
+		zapLogger.Fatalf("failed to load rbac policy %v", err)
+	}
+
+	roomService, err := room.NewService(roomRepository, userService, policy, zapLogger, &cfg.QueueDefaultWait, &cfg.QueueClaimLease, &cfg.MessageEditWindow, &cfg.ArchiveRetention)
 	if err != nil {
 		zapLogger.Fatalf("failed to set up room service %v", err)
 	}
 
-	chatService, err := chat.NewService(redisChatClient, roomService, jwtService, userService, zapLogger)
+	cannedService, err := canned.NewService(cannedRepository, roomService, userService, zapLogger)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up canned service %v", err)
+	}
+
+	chatBroker, err := room.NewRedisBroker(redisChatClient)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up chat broker %v", err)
+	}
+
+	chatService, err := chat.NewService(chatBroker, roomService, userService, zapLogger)
 	if err != nil {
 		zapLogger.Fatalf("failed to set up chat service %v", err)
 	}
+	go chatService.RunLeaseWatcher(context.Background())
+	go chatService.RunSessionWatcher(context.Background())
+	go roomService.RunArchivePurger(context.Background())
 
-	//Middleware
-	supportMiddleware, err := user.NewMiddleware(jwtService, userService, zapLogger)
+	adminService, err := admin.NewService(auditRepository, userService, jwtService, chatService, zapLogger)
 	if err != nil {
-		zapLogger.Fatalf("failed to set up user middleware %v", err)
+		zapLogger.Fatalf("failed to set up admin service %v", err)
 	}
 
-	chatMiddleware, err := chat.NewMiddleware(jwtService, userService, zapLogger)
+	userAuthService, err := auth.NewService(
+		userService,
+		jwtService,
+		ticketService,
+		mailTokenService,
+		mfaTokenService,
+		mailService,
+		chatService,
+		adminService,
+		policy,
+		cfg.AppUrl,
+		cfg.TotpIssuer,
+		&cfg.PasswordResetTTL,
+		&cfg.EmailVerificationTTL,
+		&cfg.EmailVerificationCooldown,
+		zapLogger)
 	if err != nil {
-		zapLogger.Fatalf("failed to set up chat middleware %v", err)
+		zapLogger.Fatalf("failde to create user service: %v", err)
 	}
 
-	roomMiddleware, err := room.NewMiddleware(jwtService, userService, zapLogger)
+	//Middleware
+	permissionsMiddleware, err := rbac.NewMiddleware(policy, zapLogger)
 	if err != nil {
-		zapLogger.Fatalf("failed to set up room middleware %v", err)
+		zapLogger.Fatalf("failed to set up permissions middleware %v", err)
+	}
+
+	authMiddleware, err := auth.NewMiddleware(jwtService, userService, ticketService, zapLogger,
+		auth.HeaderSource(),
+		auth.CookieSource(auth.AccessCookie))
+	if err != nil {
+		zapLogger.Fatalf("failed to set up auth middleware %v", err)
+	}
+
+	// Single sign-on is optional, it needs a provider
+	var ssoHandler *auth.SSOHandler
+	if cfg.OidcIssuer != "" {
+		oidcClient, err := oidc.NewClient(context.Background(), cfg.OidcIssuer, cfg.OidcClientId, cfg.OidcClientSecret,
+			cfg.OidcRedirectUrl, cfg.OidcScopes, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			zapLogger.Fatalf("failed to set up oidc client %v", err)
+		}
+
+		ssoStateService, err := auth.NewSSOStateService(redisAuthClient)
+		if err != nil {
+			zapLogger.Fatalf("failed to set up sso state service %v", err)
+		}
+
+		ssoService, err := auth.NewSSOService(userAuthService, userService, oidcClient, ssoStateService, adminService,
+			cfg.OidcSupportClaim, cfg.OidcSupportValue, zapLogger)
+		if err != nil {
+			zapLogger.Fatalf("failed to set up sso service %v", err)
+		}
+
+		if ssoHandler, err = auth.NewSSOHandler(ssoService, authMiddleware); err != nil {
+			zapLogger.Fatalf("failed to set up sso handler %v", err)
+		}
+		zapLogger.Infof("Single sign-on with %v enabled", cfg.OidcIssuer)
 	}
 
 	// Set-up Route
@@ -156,43 +280,59 @@ func main() {
 	// Handlers
 	healthHandler := health.NewHandler()
 
-	userHandler, err := user.NewHandler(userService)
+	userHandler, err := user.NewHandler(userService, permissionsMiddleware)
 	if err != nil {
 		zapLogger.Fatalf("failde to create user handler: %v", err)
 	}
 
-	userAuthHandler, err := auth.NewHandler(userAuthService)
+	userAuthHandler, err := auth.NewHandler(userAuthService, authMiddleware, permissionsMiddleware)
 	if err != nil {
 		zapLogger.Fatalf("failde to create user auth handler: %v", err)
 	}
 
+	adminHandler, err := admin.NewHandler(adminService, permissionsMiddleware)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up admin handler %v", err)
+	}
+
 	chatHandler, err := chat.NewHandler(chatService)
 	if err != nil {
 		zapLogger.Fatalf("failed to set up chat handler %v", err)
 	}
 
-	roomHandler, err := room.NewHandler(roomService)
+	roomHandler, err := room.NewHandler(roomService, chatService, permissionsMiddleware)
 	if err != nil {
 		zapLogger.Fatalf("failed to set up room handler %v", err)
 	}
 
+	cannedHandler, err := canned.NewHandler(cannedService, permissionsMiddleware)
+	if err != nil {
+		zapLogger.Fatalf("failed to set up canned handler %v", err)
+	}
+
 	// Routes
+	userAuthHandler.SetupWellKnownRoutes(router)
+
 	router.Route("/api/v1/auth", func(r chi.Router) {
 		userAuthHandler.SetupRoutes(r)
+		if ssoHandler != nil {
+			ssoHandler.SetupRoutes(r)
+		}
 	})
 
 	router.Route("/api/v1", func(r chi.Router) {
-		supportRoute := r.With(supportMiddleware.JwtMiddleware)
-		roomRoute := r.With(roomMiddleware.JwtMiddleware)
+		authRoute := r.With(authMiddleware.JwtMiddleware)
 
 		healthHandler.SetupRoutes(r)
-		userHandler.SetupRoutes(supportRoute)
-		roomHandler.SetupRoutes(roomRoute)
+		userHandler.SetupRoutes(authRoute)
+		cannedHandler.SetupRoutes(authRoute)
+		adminHandler.SetupRoutes(authRoute)
+		roomHandler.SetupRoutes(authRoute)
 		//chatHandler.SetupRoutes(r)
 	})
 
 	router.Route("/", func(r chi.Router) {
-		chatRoute := r.With(chatMiddleware.JwtMiddleware)
+		chatRoute := r.With(authMiddleware.TicketMiddleware, permissionsMiddleware.Require(rbac.ChatConnect))
 		chatHandler.SetupRoutes(chatRoute)
 	})
 
@@ -11,9 +11,20 @@ type Config struct {
 	PORT        string `required:"true" default:"5000" envconfig:"APP_PORT"`
 	Environment string `required:"true" envconfig:"APP_ENV"`
 	Salt        int    `required:"true" envconfig:"SALT"`
+	AppUrl      string `required:"true" default:"http://localhost:5000" envconfig:"APP_URL"`
 	MongoDb
 	Jwt
 	Redis
+	Queue
+	Support
+	Message
+	Archive
+	Rbac
+	Mail
+	PasswordReset
+	EmailVerification
+	Mfa
+	Oidc
 }
 
 type MongoDb struct {
@@ -22,9 +33,11 @@ type MongoDb struct {
 }
 
 type Jwt struct {
-	JwtSecretAccess  string `required:"true" envconfig:"JWT_SECRET_ACCESS"`
+	JwtKeysDir       string `required:"true" default:"keys/jwt" envconfig:"JWT_KEYS_DIR"`
+	JwtKeyRotation   int    `required:"true" default:"720" envconfig:"JWT_KEY_ROTATION"`
+	JwtIssuer        string `required:"true" default:"support-chat" envconfig:"JWT_ISSUER"`
+	JwtAudience      string `required:"true" default:"support-chat" envconfig:"JWT_AUDIENCE"`
 	JwtExpiryAccess  int    `required:"true" envconfig:"JWT_EXPIRY_ACCESS"`
-	JwtSecretRefresh string `required:"true" envconfig:"JWT_SECRET_REFRESH"`
 	JwtExpiryRefresh int    `required:"true" envconfig:"JWT_EXPIRY_REFRESH"`
 	AutoLogout       int    `required:"true" envconfig:"AUTO_LOGOUT"`
 }
@@ -36,6 +49,62 @@ type Redis struct {
 	RedisPortChat string `required:"true" envconfig:"REDIS_PORT_CHAT"`
 }
 
+type Queue struct {
+	QueueDefaultWait int `required:"true" default:"120" envconfig:"QUEUE_DEFAULT_WAIT"`
+	QueueClaimLease  int `required:"true" default:"60" envconfig:"QUEUE_CLAIM_LEASE"`
+}
+
+type Support struct {
+	SupportDefaultCapacity int `required:"true" default:"3" envconfig:"SUPPORT_DEFAULT_CAPACITY"`
+}
+
+type Message struct {
+	MessageEditWindow int `required:"true" default:"900" envconfig:"MESSAGE_EDIT_WINDOW"`
+}
+
+type Archive struct {
+	ArchiveRetention int `required:"true" default:"90" envconfig:"ARCHIVE_RETENTION"`
+}
+
+type Rbac struct {
+	RbacPolicyFile string `envconfig:"RBAC_POLICY_FILE"`
+}
+
+type Mail struct {
+	MailSender    string `required:"true" default:"outbox" envconfig:"MAIL_SENDER"`
+	MailFrom      string `required:"true" default:"support-chat@localhost" envconfig:"MAIL_FROM"`
+	MailOutboxDir string `required:"true" default:"outbox" envconfig:"MAIL_OUTBOX_DIR"`
+	SmtpHost      string `envconfig:"SMTP_HOST"`
+	SmtpPort      string `default:"587" envconfig:"SMTP_PORT"`
+	SmtpUsername  string `envconfig:"SMTP_USERNAME"`
+	SmtpPassword  string `envconfig:"SMTP_PASSWORD"`
+}
+
+type PasswordReset struct {
+	PasswordResetTTL int `required:"true" default:"30" envconfig:"PASSWORD_RESET_TTL"`
+}
+
+type Mfa struct {
+	TotpIssuer string `required:"true" default:"Support Chat" envconfig:"TOTP_ISSUER"`
+}
+
+// Oidc configures the login with an OpenID Connect provider, it is off while
+// OidcIssuer is empty.
+type Oidc struct {
+	OidcIssuer       string   `envconfig:"OIDC_ISSUER"`
+	OidcClientId     string   `envconfig:"OIDC_CLIENT_ID"`
+	OidcClientSecret string   `envconfig:"OIDC_CLIENT_SECRET"`
+	OidcRedirectUrl  string   `default:"http://localhost:3000/sso/callback" envconfig:"OIDC_REDIRECT_URL"`
+	OidcScopes       []string `default:"openid,email,profile" envconfig:"OIDC_SCOPES"`
+	OidcSupportClaim string   `default:"groups" envconfig:"OIDC_SUPPORT_CLAIM"`
+	OidcSupportValue string   `default:"support" envconfig:"OIDC_SUPPORT_VALUE"`
+}
+
+type EmailVerification struct {
+	EmailVerificationTTL      int `required:"true" default:"1440" envconfig:"EMAIL_VERIFICATION_TTL"`
+	EmailVerificationCooldown int `required:"true" default:"60" envconfig:"EMAIL_VERIFICATION_COOLDOWN"`
+}
+
 var (
 	once   sync.Once
 	config *Config
@@ -14,9 +14,7 @@ func TestInit(t *testing.T) {
 		mongoDbName      string
 		mongoDbUrl       string
 		salt             string
-		jwtSecretAccess  string
 		jwtExpiryAccess  string
-		jwtSecretRefresh string
 		jwtExpiryRefresh string
 		autoLogout       string
 		redisHostAuth    string
@@ -35,9 +33,7 @@ func TestInit(t *testing.T) {
 		os.Setenv("MONGO_DB_NAME", env.mongoDbName)
 		os.Setenv("MONGO_DB_URL", env.mongoDbUrl)
 		os.Setenv("SALT", env.salt)
-		os.Setenv("JWT_SECRET_ACCESS", env.jwtSecretAccess)
 		os.Setenv("JWT_EXPIRY_ACCESS", env.jwtExpiryAccess)
-		os.Setenv("JWT_SECRET_REFRESH", env.jwtSecretRefresh)
 		os.Setenv("JWT_EXPIRY_REFRESH", env.jwtExpiryRefresh)
 		os.Setenv("AUTO_LOGOUT", env.autoLogout)
 		os.Setenv("REDIS_HOST_AUTH", env.redisHostAuth)
@@ -61,9 +57,7 @@ func TestInit(t *testing.T) {
 					mongoDbName:      "example",
 					mongoDbUrl:       "http://127.0.0.1",
 					salt:             "11",
-					jwtSecretAccess:  "jwt",
 					jwtExpiryAccess:  "100",
-					jwtSecretRefresh: "asd",
 					jwtExpiryRefresh: "300",
 					autoLogout:       "3",
 					redisHostAuth:    "localhost",
@@ -76,14 +70,17 @@ func TestInit(t *testing.T) {
 				PORT:        "5000",
 				Environment: "development",
 				Salt:        11,
+				AppUrl:      "http://localhost:5000",
 				MongoDb: config.MongoDb{
 					MongoDbName: "example",
 					MongoDbUrl:  "http://127.0.0.1",
 				},
 				Jwt: config.Jwt{
-					JwtSecretAccess:  "jwt",
+					JwtKeysDir:       "keys/jwt",
+					JwtKeyRotation:   720,
+					JwtIssuer:        "support-chat",
+					JwtAudience:      "support-chat",
 					JwtExpiryAccess:  100,
-					JwtSecretRefresh: "asd",
 					JwtExpiryRefresh: 300,
 					AutoLogout:       3,
 				},
@@ -93,6 +90,41 @@ func TestInit(t *testing.T) {
 					RedisHostChat: "localhost",
 					RedisPortChat: "4321",
 				},
+				Queue: config.Queue{
+					QueueDefaultWait: 120,
+					QueueClaimLease:  60,
+				},
+				Support: config.Support{
+					SupportDefaultCapacity: 3,
+				},
+				Message: config.Message{
+					MessageEditWindow: 900,
+				},
+				Archive: config.Archive{
+					ArchiveRetention: 90,
+				},
+				Mail: config.Mail{
+					MailSender:    "outbox",
+					MailFrom:      "support-chat@localhost",
+					MailOutboxDir: "outbox",
+					SmtpPort:      "587",
+				},
+				PasswordReset: config.PasswordReset{
+					PasswordResetTTL: 30,
+				},
+				EmailVerification: config.EmailVerification{
+					EmailVerificationTTL:      1440,
+					EmailVerificationCooldown: 60,
+				},
+				Mfa: config.Mfa{
+					TotpIssuer: "Support Chat",
+				},
+				Oidc: config.Oidc{
+					OidcRedirectUrl:  "http://localhost:3000/sso/callback",
+					OidcScopes:       []string{"openid", "email", "profile"},
+					OidcSupportClaim: "groups",
+					OidcSupportValue: "support",
+				},
 			},
 		},
 	}
@@ -0,0 +1,29 @@
+package canned
+
+import "time"
+
+type DTO struct {
+	ID        string    `json:"id"`
+	OwnerId   string    `json:"owner_id"`
+	Scope     Scope     `json:"scope"`
+	Shortcut  string    `json:"shortcut"`
+	Title     string    `json:"title,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ResponseDTO struct {
+	Scope    Scope  `json:"scope"`
+	Shortcut string `json:"shortcut"`
+	Title    string `json:"title"`
+	Body     string `json:"body"`
+}
+
+// RenderedDTO is the response with its placeholders filled in. The agent's
+// client encrypts the text before sending it to the room.
+type RenderedDTO struct {
+	ID       string `json:"id"`
+	Shortcut string `json:"shortcut"`
+	Text     string `json:"text"`
+}
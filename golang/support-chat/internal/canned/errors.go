@@ -0,0 +1,38 @@
+package canned
+
+import (
+	"support-chat/pkg/codes"
+	"support-chat/pkg/errors"
+)
+
+const (
+	StatusNotFound              errors.Status = "canned_response_not_found"
+	StatusInvalidId             errors.Status = "invalid_id"
+	StatusInvalidScope          errors.Status = "invalid_scope"
+	StatusInvalidShortcut       errors.Status = "invalid_shortcut"
+	StatusInvalidBody           errors.Status = "invalid_body"
+	StatusShortcutAlreadyExists errors.Status = "shortcut_already_exists"
+	StatusNotSupport            errors.Status = "user_is_not_support"
+	StatusNotOwner              errors.Status = "user_is_not_owner"
+	StatusNotParticipant        errors.Status = "user_is_not_participant"
+	StatusFailedSaveResponse    errors.Status = "failed_save_canned_response"
+	StatusFailedFindResponses   errors.Status = "failed_find_canned_responses"
+	StatusFailedUpdateResponse  errors.Status = "failed_update_canned_response"
+	StatusFailedDeleteResponse  errors.Status = "failed_delete_canned_response"
+)
+
+var (
+	ErrNotFound              = errors.New(codes.NotFound, StatusNotFound)
+	ErrInvalidId             = errors.New(codes.BadRequest, StatusInvalidId)
+	ErrInvalidScope          = errors.New(codes.BadRequest, StatusInvalidScope)
+	ErrInvalidShortcut       = errors.New(codes.BadRequest, StatusInvalidShortcut)
+	ErrInvalidBody           = errors.New(codes.BadRequest, StatusInvalidBody)
+	ErrShortcutAlreadyExists = errors.New(codes.DuplicateError, StatusShortcutAlreadyExists)
+	ErrNotSupport            = errors.New(codes.Forbidden, StatusNotSupport)
+	ErrNotOwner              = errors.New(codes.Forbidden, StatusNotOwner)
+	ErrNotParticipant        = errors.New(codes.Forbidden, StatusNotParticipant)
+	ErrFailedSaveResponse    = errors.New(codes.BadRequest, StatusFailedSaveResponse)
+	ErrFailedFindResponses   = errors.New(codes.BadRequest, StatusFailedFindResponses)
+	ErrFailedUpdateResponse  = errors.New(codes.BadRequest, StatusFailedUpdateResponse)
+	ErrFailedDeleteResponse  = errors.New(codes.BadRequest, StatusFailedDeleteResponse)
+)
@@ -0,0 +1,181 @@
+package canned
+
+import (
+	"encoding/json"
+	gerrors "errors"
+	"net/http"
+	"support-chat/internal/user"
+	"support-chat/pkg/errors"
+	"support-chat/pkg/rbac"
+	"support-chat/pkg/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	cannedSvc   Service
+	permissions rbac.Middleware
+}
+
+func NewHandler(cannedSvc Service, permissions rbac.Middleware) (*Handler, error) {
+	if cannedSvc == nil {
+		return nil, gerrors.New("[canned_handler] invalid canned service")
+	}
+	if permissions == nil {
+		return nil, gerrors.New("[canned_handler] invalid permissions middleware")
+	}
+
+	return &Handler{cannedSvc: cannedSvc, permissions: permissions}, nil
+}
+
+func (h *Handler) SetupRoutes(router chi.Router) {
+	read := router.With(h.permissions.Require(rbac.CannedRead))
+	write := router.With(h.permissions.Require(rbac.CannedWrite))
+
+	read.Get("/canned", h.GetResponses)
+	write.Post("/canned", h.CreateResponse)
+	read.Get("/canned/{id}", h.GetResponse)
+	write.Patch("/canned/{id}", h.UpdateResponse)
+	write.Delete("/canned/{id}", h.DeleteResponse)
+	read.Get("/canned/{id}/render", h.RenderResponse)
+	read.Get("/canned/shortcuts/{shortcut}/render", h.RenderShortcut)
+}
+
+func (h *Handler) GetResponses(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	responses, err := h.cannedSvc.GetResponses(r.Context(), &u, Scope(r.URL.Query().Get("scope")))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, responses)
+}
+
+func (h *Handler) CreateResponse(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	var dto ResponseDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	response, err := h.cannedSvc.CreateResponse(r.Context(), &u, &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusCreated, response)
+}
+
+func (h *Handler) GetResponse(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	response, err := h.cannedSvc.GetResponse(r.Context(), chi.URLParam(r, "id"), &u)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, response)
+}
+
+func (h *Handler) UpdateResponse(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	var dto ResponseDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	response, err := h.cannedSvc.UpdateResponse(r.Context(), chi.URLParam(r, "id"), &u, &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, response)
+}
+
+func (h *Handler) DeleteResponse(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	if err := h.cannedSvc.DeleteResponse(r.Context(), chi.URLParam(r, "id"), &u); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, "OK")
+}
+
+// RenderResponse returns the response filled in for the customer of the room
+// given in the query.
+func (h *Handler) RenderResponse(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	rendered, err := h.cannedSvc.RenderResponse(r.Context(), chi.URLParam(r, "id"), &u, r.URL.Query().Get("room"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, rendered)
+}
+
+func (h *Handler) RenderShortcut(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	rendered, err := h.cannedSvc.RenderShortcut(r.Context(), chi.URLParam(r, "shortcut"), &u, r.URL.Query().Get("room"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, rendered)
+}
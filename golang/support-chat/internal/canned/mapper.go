@@ -0,0 +1,14 @@
+package canned
+
+func MapToDTO(m *Model) *DTO {
+	return &DTO{
+		ID:        m.ID.Hex(),
+		OwnerId:   m.OwnerId,
+		Scope:     m.Scope,
+		Shortcut:  m.Shortcut,
+		Title:     m.Title,
+		Body:      m.Body,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: repository.go
+
+// Package mock_canned is a generated GoMock package.
+package mock_canned
+
+import (
+	context "context"
+	reflect "reflect"
+	canned "support-chat/internal/canned"
+
+	gomock "github.com/golang/mock/gomock"
+	bson "go.mongodb.org/mongo-driver/bson"
+	options "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MockRepository is a mock of Repository interface.
+type MockRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRepositoryMockRecorder
+}
+
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
+type MockRepositoryMockRecorder struct {
+	mock *MockRepository
+}
+
+// NewMockRepository creates a new mock instance.
+func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
+	mock := &MockRepository{ctrl: ctrl}
+	mock.recorder = &MockRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateResponse mocks base method.
+func (m *MockRepository) CreateResponse(ctx context.Context, response *canned.Model) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateResponse", ctx, response)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateResponse indicates an expected call of CreateResponse.
+func (mr *MockRepositoryMockRecorder) CreateResponse(ctx, response interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateResponse", reflect.TypeOf((*MockRepository)(nil).CreateResponse), ctx, response)
+}
+
+// DeleteResponse mocks base method.
+func (m *MockRepository) DeleteResponse(ctx context.Context, filters bson.M) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteResponse", ctx, filters)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteResponse indicates an expected call of DeleteResponse.
+func (mr *MockRepositoryMockRecorder) DeleteResponse(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResponse", reflect.TypeOf((*MockRepository)(nil).DeleteResponse), ctx, filters)
+}
+
+// FindAndUpdateResponse mocks base method.
+func (m *MockRepository) FindAndUpdateResponse(ctx context.Context, filters, update bson.M) (*canned.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAndUpdateResponse", ctx, filters, update)
+	ret0, _ := ret[0].(*canned.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAndUpdateResponse indicates an expected call of FindAndUpdateResponse.
+func (mr *MockRepositoryMockRecorder) FindAndUpdateResponse(ctx, filters, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAndUpdateResponse", reflect.TypeOf((*MockRepository)(nil).FindAndUpdateResponse), ctx, filters, update)
+}
+
+// GetResponse mocks base method.
+func (m *MockRepository) GetResponse(ctx context.Context, filters bson.M) (*canned.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResponse", ctx, filters)
+	ret0, _ := ret[0].(*canned.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResponse indicates an expected call of GetResponse.
+func (mr *MockRepositoryMockRecorder) GetResponse(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponse", reflect.TypeOf((*MockRepository)(nil).GetResponse), ctx, filters)
+}
+
+// GetResponses mocks base method.
+func (m *MockRepository) GetResponses(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*canned.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResponses", ctx, filters, opts)
+	ret0, _ := ret[0].([]*canned.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResponses indicates an expected call of GetResponses.
+func (mr *MockRepositoryMockRecorder) GetResponses(ctx, filters, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponses", reflect.TypeOf((*MockRepository)(nil).GetResponses), ctx, filters, opts)
+}
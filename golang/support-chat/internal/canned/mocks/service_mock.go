@@ -0,0 +1,141 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mock_canned is a generated GoMock package.
+package mock_canned
+
+import (
+	context "context"
+	reflect "reflect"
+	canned "support-chat/internal/canned"
+	user "support-chat/internal/user"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateResponse mocks base method.
+func (m *MockService) CreateResponse(ctx context.Context, agent *user.DTO, dto *canned.ResponseDTO) (*canned.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateResponse", ctx, agent, dto)
+	ret0, _ := ret[0].(*canned.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateResponse indicates an expected call of CreateResponse.
+func (mr *MockServiceMockRecorder) CreateResponse(ctx, agent, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateResponse", reflect.TypeOf((*MockService)(nil).CreateResponse), ctx, agent, dto)
+}
+
+// DeleteResponse mocks base method.
+func (m *MockService) DeleteResponse(ctx context.Context, id string, agent *user.DTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteResponse", ctx, id, agent)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteResponse indicates an expected call of DeleteResponse.
+func (mr *MockServiceMockRecorder) DeleteResponse(ctx, id, agent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResponse", reflect.TypeOf((*MockService)(nil).DeleteResponse), ctx, id, agent)
+}
+
+// GetResponse mocks base method.
+func (m *MockService) GetResponse(ctx context.Context, id string, agent *user.DTO) (*canned.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResponse", ctx, id, agent)
+	ret0, _ := ret[0].(*canned.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResponse indicates an expected call of GetResponse.
+func (mr *MockServiceMockRecorder) GetResponse(ctx, id, agent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponse", reflect.TypeOf((*MockService)(nil).GetResponse), ctx, id, agent)
+}
+
+// GetResponses mocks base method.
+func (m *MockService) GetResponses(ctx context.Context, agent *user.DTO, scope canned.Scope) ([]*canned.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetResponses", ctx, agent, scope)
+	ret0, _ := ret[0].([]*canned.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetResponses indicates an expected call of GetResponses.
+func (mr *MockServiceMockRecorder) GetResponses(ctx, agent, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetResponses", reflect.TypeOf((*MockService)(nil).GetResponses), ctx, agent, scope)
+}
+
+// RenderResponse mocks base method.
+func (m *MockService) RenderResponse(ctx context.Context, id string, agent *user.DTO, roomName string) (*canned.RenderedDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderResponse", ctx, id, agent, roomName)
+	ret0, _ := ret[0].(*canned.RenderedDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenderResponse indicates an expected call of RenderResponse.
+func (mr *MockServiceMockRecorder) RenderResponse(ctx, id, agent, roomName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderResponse", reflect.TypeOf((*MockService)(nil).RenderResponse), ctx, id, agent, roomName)
+}
+
+// RenderShortcut mocks base method.
+func (m *MockService) RenderShortcut(ctx context.Context, shortcut string, agent *user.DTO, roomName string) (*canned.RenderedDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenderShortcut", ctx, shortcut, agent, roomName)
+	ret0, _ := ret[0].(*canned.RenderedDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenderShortcut indicates an expected call of RenderShortcut.
+func (mr *MockServiceMockRecorder) RenderShortcut(ctx, shortcut, agent, roomName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderShortcut", reflect.TypeOf((*MockService)(nil).RenderShortcut), ctx, shortcut, agent, roomName)
+}
+
+// UpdateResponse mocks base method.
+func (m *MockService) UpdateResponse(ctx context.Context, id string, agent *user.DTO, dto *canned.ResponseDTO) (*canned.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateResponse", ctx, id, agent, dto)
+	ret0, _ := ret[0].(*canned.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateResponse indicates an expected call of UpdateResponse.
+func (mr *MockServiceMockRecorder) UpdateResponse(ctx, id, agent, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateResponse", reflect.TypeOf((*MockService)(nil).UpdateResponse), ctx, id, agent, dto)
+}
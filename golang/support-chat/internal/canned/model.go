@@ -0,0 +1,27 @@
+package canned
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Scope string
+
+const (
+	// ScopePersonal responses are only visible to the agent who wrote them
+	ScopePersonal Scope = "personal"
+	// ScopeTeam responses are shared with every support agent
+	ScopeTeam Scope = "team"
+)
+
+type Model struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	OwnerId   string             `bson:"ownerId"`
+	Scope     Scope              `bson:"scope"`
+	Shortcut  string             `bson:"shortcut"`
+	Title     string             `bson:"title"`
+	Body      string             `bson:"body"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt"`
+}
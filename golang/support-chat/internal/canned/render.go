@@ -0,0 +1,31 @@
+package canned
+
+import (
+	"regexp"
+	"support-chat/internal/user"
+)
+
+// placeholder matches {{customer.name}} style placeholders, spaces inside the
+// braces are allowed.
+var placeholder = regexp.MustCompile(`\{\{\s*([a-z]+\.[a-z_]+)\s*\}\}`)
+
+// render fills in the placeholders of body from the agent and customer
+// records. Unknown placeholders, and customer ones when there is no customer,
+// are left as they are so the agent notices them before sending.
+func render(body string, agent, customer *user.DTO) string {
+	values := map[string]string{
+		"agent.name":  agent.Name,
+		"agent.email": agent.Email,
+	}
+	if customer != nil {
+		values["customer.name"] = customer.Name
+		values["customer.email"] = customer.Email
+	}
+
+	return placeholder.ReplaceAllStringFunc(body, func(match string) string {
+		if value, ok := values[placeholder.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
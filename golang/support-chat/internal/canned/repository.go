@@ -0,0 +1,116 @@
+package canned
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=repository.go -destination=mocks/repository_mock.go
+type Repository interface {
+	GetResponse(ctx context.Context, filters bson.M) (*Model, error)
+	GetResponses(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*Model, error)
+	CreateResponse(ctx context.Context, response *Model) error
+	FindAndUpdateResponse(ctx context.Context, filters, update bson.M) (*Model, error)
+	DeleteResponse(ctx context.Context, filters bson.M) error
+}
+
+type repository struct {
+	db     *mongo.Client
+	dbName string
+	logger *zap.SugaredLogger
+}
+
+func NewRepository(db *mongo.Client, dbName string, logger *zap.SugaredLogger) (Repository, error) {
+	if db == nil {
+		return nil, errors.New("[canned_repository] invalid database")
+	}
+	if dbName == "" {
+		return nil, errors.New("[canned_repository] invalid database name")
+	}
+	if logger == nil {
+		return nil, errors.New("[canned_repository] invalid logger")
+	}
+
+	return &repository{db: db, dbName: dbName, logger: logger}, nil
+}
+
+func (r *repository) GetResponse(ctx context.Context, filters bson.M) (*Model, error) {
+	var response Model
+
+	if err := r.db.Database(r.dbName).Collection("canned_responses").FindOne(ctx, filters).Decode(&response); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+
+		r.logger.Errorf("unable to find canned response due to internal error: %v", err)
+		return nil, ErrFailedFindResponses
+	}
+
+	return &response, nil
+}
+
+func (r *repository) GetResponses(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*Model, error) {
+	var responses []*Model
+
+	cursor, err := r.db.Database(r.dbName).Collection("canned_responses").Find(ctx, filters, opts)
+	if err != nil {
+		r.logger.Errorf("failed to get canned responses: %v", err)
+		return nil, ErrFailedFindResponses
+	}
+
+	if err = cursor.All(ctx, &responses); err != nil {
+		r.logger.Errorf("failed to get canned responses: %v", err)
+		return nil, ErrFailedFindResponses
+	}
+
+	return responses, nil
+}
+
+func (r *repository) CreateResponse(ctx context.Context, response *Model) error {
+	_, err := r.db.Database(r.dbName).Collection("canned_responses").InsertOne(ctx, response)
+	if err != nil {
+		r.logger.Errorf("failed to insert canned response to db: %v", err)
+		return ErrFailedSaveResponse
+	}
+
+	return nil
+}
+
+// FindAndUpdateResponse atomically applies update to the response matching
+// filters and returns the document as it is after the update.
+func (r *repository) FindAndUpdateResponse(ctx context.Context, filters, update bson.M) (*Model, error) {
+	var response Model
+
+	err := r.db.Database(r.dbName).Collection("canned_responses").
+		FindOneAndUpdate(ctx, filters, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&response)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+
+		r.logger.Errorf("failed to find and update canned response %v", err)
+		return nil, ErrFailedUpdateResponse
+	}
+
+	return &response, nil
+}
+
+func (r *repository) DeleteResponse(ctx context.Context, filters bson.M) error {
+	result, err := r.db.Database(r.dbName).Collection("canned_responses").DeleteOne(ctx, filters)
+	if err != nil {
+		r.logger.Errorf("failed to delete canned response %v", err)
+		return ErrFailedDeleteResponse
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package canned
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"support-chat/internal/chat/room"
+	"support-chat/internal/user"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=service.go -destination=mocks/service_mock.go
+type Service interface {
+	GetResponses(ctx context.Context, agent *user.DTO, scope Scope) ([]*DTO, error)
+	GetResponse(ctx context.Context, id string, agent *user.DTO) (*DTO, error)
+	CreateResponse(ctx context.Context, agent *user.DTO, dto *ResponseDTO) (*DTO, error)
+	UpdateResponse(ctx context.Context, id string, agent *user.DTO, dto *ResponseDTO) (*DTO, error)
+	DeleteResponse(ctx context.Context, id string, agent *user.DTO) error
+	RenderResponse(ctx context.Context, id string, agent *user.DTO, roomName string) (*RenderedDTO, error)
+	RenderShortcut(ctx context.Context, shortcut string, agent *user.DTO, roomName string) (*RenderedDTO, error)
+}
+
+const maxBodyLength = 4000
+
+var shortcutPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+type service struct {
+	repository Repository
+	roomSvc    room.Service
+	userSvc    user.Service
+	logger     *zap.SugaredLogger
+}
+
+func NewService(repository Repository, roomSvc room.Service, userSvc user.Service, logger *zap.SugaredLogger) (Service, error) {
+	if repository == nil {
+		return nil, errors.New("[canned_service] invalid repository")
+	}
+	if roomSvc == nil {
+		return nil, errors.New("[canned_service] invalid room service")
+	}
+	if userSvc == nil {
+		return nil, errors.New("[canned_service] invalid user service")
+	}
+	if logger == nil {
+		return nil, errors.New("[canned_service] invalid logger")
+	}
+
+	return &service{repository: repository, roomSvc: roomSvc, userSvc: userSvc, logger: logger}, nil
+}
+
+// GetResponses lists the personal responses of the agent and the team ones,
+// optionally narrowed to one scope.
+func (s *service) GetResponses(ctx context.Context, agent *user.DTO, scope Scope) ([]*DTO, error) {
+	if !agent.Support {
+		return nil, ErrNotSupport
+	}
+
+	filters := visibleResponses(agent)
+	switch scope {
+	case "":
+	case ScopePersonal, ScopeTeam:
+		filters["scope"] = scope
+	default:
+		return nil, ErrInvalidScope
+	}
+
+	responses, err := s.repository.GetResponses(ctx, filters, options.Find().SetSort(bson.D{{Key: "shortcut", Value: 1}}))
+	if err != nil {
+		s.logger.Errorf("failed to get canned responses: %v", err)
+		return nil, err
+	}
+
+	dtos := make([]*DTO, 0, len(responses))
+	for _, response := range responses {
+		dtos = append(dtos, MapToDTO(response))
+	}
+
+	return dtos, nil
+}
+
+func (s *service) GetResponse(ctx context.Context, id string, agent *user.DTO) (*DTO, error) {
+	response, err := s.getResponse(ctx, id, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	return MapToDTO(response), nil
+}
+
+func (s *service) CreateResponse(ctx context.Context, agent *user.DTO, dto *ResponseDTO) (*DTO, error) {
+	if !agent.Support {
+		return nil, ErrNotSupport
+	}
+
+	now := time.Now()
+	response := &Model{
+		ID:        primitive.NewObjectID(),
+		OwnerId:   agent.ID,
+		Scope:     dto.Scope,
+		Shortcut:  dto.Shortcut,
+		Title:     dto.Title,
+		Body:      dto.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if response.Scope == "" {
+		response.Scope = ScopePersonal
+	}
+
+	if err := s.validate(ctx, response); err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.CreateResponse(ctx, response); err != nil {
+		s.logger.Errorf("failed to save canned response: %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(response), nil
+}
+
+// UpdateResponse changes the fields set in dto. Only the owner can change a
+// response, team ones included.
+func (s *service) UpdateResponse(ctx context.Context, id string, agent *user.DTO, dto *ResponseDTO) (*DTO, error) {
+	response, err := s.getResponse(ctx, id, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.OwnerId != agent.ID {
+		return nil, ErrNotOwner
+	}
+
+	if dto.Scope != "" {
+		response.Scope = dto.Scope
+	}
+	if dto.Shortcut != "" {
+		response.Shortcut = dto.Shortcut
+	}
+	if dto.Title != "" {
+		response.Title = dto.Title
+	}
+	if dto.Body != "" {
+		response.Body = dto.Body
+	}
+
+	if err = s.validate(ctx, response); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repository.FindAndUpdateResponse(ctx,
+		bson.M{"_id": response.ID, "ownerId": agent.ID},
+		bson.M{"$set": bson.M{
+			"scope":     response.Scope,
+			"shortcut":  response.Shortcut,
+			"title":     response.Title,
+			"body":      response.Body,
+			"updatedAt": time.Now(),
+		}})
+	if err != nil {
+		s.logger.Errorf("failed to update canned response: %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(updated), nil
+}
+
+func (s *service) DeleteResponse(ctx context.Context, id string, agent *user.DTO) error {
+	response, err := s.getResponse(ctx, id, agent)
+	if err != nil {
+		return err
+	}
+
+	if response.OwnerId != agent.ID {
+		return ErrNotOwner
+	}
+
+	if err = s.repository.DeleteResponse(ctx, bson.M{"_id": response.ID, "ownerId": agent.ID}); err != nil {
+		s.logger.Errorf("failed to delete canned response: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) RenderResponse(ctx context.Context, id string, agent *user.DTO, roomName string) (*RenderedDTO, error) {
+	response, err := s.getResponse(ctx, id, agent)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(ctx, response, agent, roomName)
+}
+
+// RenderShortcut renders the response behind a shortcut. A personal shortcut
+// takes precedence over a team one with the same code.
+func (s *service) RenderShortcut(ctx context.Context, shortcut string, agent *user.DTO, roomName string) (*RenderedDTO, error) {
+	if !agent.Support {
+		return nil, ErrNotSupport
+	}
+
+	response, err := s.repository.GetResponse(ctx, bson.M{"shortcut": shortcut, "scope": ScopePersonal, "ownerId": agent.ID})
+	if err == ErrNotFound {
+		response, err = s.repository.GetResponse(ctx, bson.M{"shortcut": shortcut, "scope": ScopeTeam})
+	}
+	if err != nil {
+		if err != ErrNotFound {
+			s.logger.Errorf("failed to get canned response: %v", err)
+		}
+		return nil, err
+	}
+
+	return s.render(ctx, response, agent, roomName)
+}
+
+// render fills in the response for the customer of the room. Without a room
+// only the agent placeholders are filled in.
+func (s *service) render(ctx context.Context, response *Model, agent *user.DTO, roomName string) (*RenderedDTO, error) {
+	var customer *user.DTO
+	if roomName != "" {
+		agentEntity, err := user.MapToEntity(agent)
+		if err != nil {
+			return nil, err
+		}
+		if !agentEntity.HasRoom(roomName) {
+			return nil, ErrNotParticipant
+		}
+
+		r, err := s.roomSvc.GetRoomByName(ctx, roomName)
+		if err != nil {
+			s.logger.Errorf("failed to get room: %v", err)
+			return nil, err
+		}
+
+		customer, err = s.userSvc.GetUserById(ctx, r.CustomerId, false)
+		if err != nil {
+			s.logger.Errorf("failed to get customer: %v", err)
+			return nil, err
+		}
+	}
+
+	return &RenderedDTO{
+		ID:       response.ID.Hex(),
+		Shortcut: response.Shortcut,
+		Text:     render(response.Body, agent, customer),
+	}, nil
+}
+
+// getResponse returns the response if the agent can see it.
+func (s *service) getResponse(ctx context.Context, id string, agent *user.DTO) (*Model, error) {
+	if !agent.Support {
+		return nil, ErrNotSupport
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidId
+	}
+
+	filters := visibleResponses(agent)
+	filters["_id"] = objectId
+
+	response, err := s.repository.GetResponse(ctx, filters)
+	if err != nil {
+		if err != ErrNotFound {
+			s.logger.Errorf("failed to get canned response: %v", err)
+		}
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// validate checks the fields of the response and that its shortcut is free in
+// its scope.
+func (s *service) validate(ctx context.Context, response *Model) error {
+	if response.Scope != ScopePersonal && response.Scope != ScopeTeam {
+		return ErrInvalidScope
+	}
+	if !shortcutPattern.MatchString(response.Shortcut) {
+		return ErrInvalidShortcut
+	}
+	if response.Body == "" || len(response.Body) > maxBodyLength {
+		return ErrInvalidBody
+	}
+
+	filters := bson.M{"_id": bson.M{"$ne": response.ID}, "scope": response.Scope, "shortcut": response.Shortcut}
+	if response.Scope == ScopePersonal {
+		filters["ownerId"] = response.OwnerId
+	}
+
+	_, err := s.repository.GetResponse(ctx, filters)
+	switch err {
+	case nil:
+		return ErrShortcutAlreadyExists
+	case ErrNotFound:
+		return nil
+	}
+
+	s.logger.Errorf("failed to check shortcut: %v", err)
+	return err
+}
+
+// visibleResponses filters the responses the agent can see.
+func visibleResponses(agent *user.DTO) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"scope": ScopePersonal, "ownerId": agent.ID},
+		bson.M{"scope": ScopeTeam},
+	}}
+}
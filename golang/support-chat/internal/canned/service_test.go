@@ -0,0 +1,333 @@
+package canned_test
+
+import (
+	"context"
+	"support-chat/internal/canned"
+	mock_canned "support-chat/internal/canned/mocks"
+	"support-chat/internal/chat/room"
+	mock_room "support-chat/internal/chat/room/mocks"
+	"support-chat/internal/user"
+	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func TestNewService(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	tests := []struct {
+		name       string
+		repository canned.Repository
+		roomSvc    room.Service
+		userSvc    user.Service
+		logger     *zap.SugaredLogger
+		expect     func(*testing.T, canned.Service, error)
+	}{
+		{
+			name:       "should return service",
+			repository: mock_canned.NewMockRepository(controller),
+			roomSvc:    mock_room.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s canned.Service, err error) {
+				assert.NotNil(t, s)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:       "should return invalid repository",
+			repository: nil,
+			roomSvc:    mock_room.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s canned.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[canned_service] invalid repository")
+			},
+		},
+		{
+			name:       "should return invalid room service",
+			repository: mock_canned.NewMockRepository(controller),
+			roomSvc:    nil,
+			userSvc:    mock_user.NewMockService(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s canned.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[canned_service] invalid room service")
+			},
+		},
+		{
+			name:       "should return invalid user service",
+			repository: mock_canned.NewMockRepository(controller),
+			roomSvc:    mock_room.NewMockService(controller),
+			userSvc:    nil,
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s canned.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[canned_service] invalid user service")
+			},
+		},
+		{
+			name:       "should return invalid logger",
+			repository: mock_canned.NewMockRepository(controller),
+			roomSvc:    mock_room.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			logger:     nil,
+			expect: func(t *testing.T, s canned.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[canned_service] invalid logger")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := canned.NewService(tc.repository, tc.roomSvc, tc.userSvc, tc.logger)
+			tc.expect(t, s, err)
+		})
+	}
+}
+
+func TestService_CreateResponse(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_canned.NewMockRepository(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := canned.NewService(mockRepo, mock_room.NewMockService(controller), mock_user.NewMockService(controller), zapLogger)
+
+	agent := &user.DTO{ID: "agent", Support: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		agent  *user.DTO
+		dto    *canned.ResponseDTO
+		setup  func(context.Context)
+		expect func(*testing.T, *canned.DTO, error)
+	}{
+		{
+			name:  "should create personal response",
+			ctx:   context.Background(),
+			agent: agent,
+			dto:   &canned.ResponseDTO{Shortcut: "hello", Body: "Hi {{customer.name}}"},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetResponse(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, filters bson.M) (*canned.Model, error) {
+					assert.Equal(t, "agent", filters["ownerId"])
+					return nil, canned.ErrNotFound
+				})
+				mockRepo.EXPECT().CreateResponse(ctx, gomock.Any()).Return(nil)
+			},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, canned.ScopePersonal, dto.Scope)
+				assert.Equal(t, "agent", dto.OwnerId)
+			},
+		},
+		{
+			name:  "should return shortcut already exists",
+			ctx:   context.Background(),
+			agent: agent,
+			dto:   &canned.ResponseDTO{Scope: canned.ScopeTeam, Shortcut: "hello", Body: "Hi"},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetResponse(ctx, gomock.Any()).Return(&canned.Model{}, nil)
+			},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, canned.ErrShortcutAlreadyExists, err)
+			},
+		},
+		{
+			name:  "should return invalid shortcut",
+			ctx:   context.Background(),
+			agent: agent,
+			dto:   &canned.ResponseDTO{Shortcut: "Hello there", Body: "Hi"},
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, canned.ErrInvalidShortcut, err)
+			},
+		},
+		{
+			name:  "should return invalid scope",
+			ctx:   context.Background(),
+			agent: agent,
+			dto:   &canned.ResponseDTO{Scope: "global", Shortcut: "hello", Body: "Hi"},
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, canned.ErrInvalidScope, err)
+			},
+		},
+		{
+			name:  "should return not support",
+			ctx:   context.Background(),
+			agent: &user.DTO{ID: "customer"},
+			dto:   &canned.ResponseDTO{Shortcut: "hello", Body: "Hi"},
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, canned.ErrNotSupport, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.CreateResponse(tc.ctx, tc.agent, tc.dto)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_UpdateResponse(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_canned.NewMockRepository(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := canned.NewService(mockRepo, mock_room.NewMockService(controller), mock_user.NewMockService(controller), zapLogger)
+
+	id := primitive.NewObjectID()
+	agent := &user.DTO{ID: "agent", Support: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		agent  *user.DTO
+		setup  func(context.Context)
+		expect func(*testing.T, *canned.DTO, error)
+	}{
+		{
+			name:  "should update body",
+			ctx:   context.Background(),
+			agent: agent,
+			setup: func(ctx context.Context) {
+				current := &canned.Model{ID: id, OwnerId: "agent", Scope: canned.ScopeTeam, Shortcut: "hello", Body: "Hi"}
+				mockRepo.EXPECT().GetResponse(ctx, gomock.Any()).Return(current, nil)
+				mockRepo.EXPECT().GetResponse(ctx, gomock.Any()).Return(nil, canned.ErrNotFound)
+				mockRepo.EXPECT().FindAndUpdateResponse(ctx, bson.M{"_id": id, "ownerId": "agent"}, gomock.Any()).
+					Return(&canned.Model{ID: id, OwnerId: "agent", Scope: canned.ScopeTeam, Shortcut: "hello", Body: "Hello"}, nil)
+			},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "Hello", dto.Body)
+			},
+		},
+		{
+			name:  "should return not owner",
+			ctx:   context.Background(),
+			agent: &user.DTO{ID: "other", Support: true},
+			setup: func(ctx context.Context) {
+				current := &canned.Model{ID: id, OwnerId: "agent", Scope: canned.ScopeTeam, Shortcut: "hello", Body: "Hi"}
+				mockRepo.EXPECT().GetResponse(ctx, gomock.Any()).Return(current, nil)
+			},
+			expect: func(t *testing.T, dto *canned.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, canned.ErrNotOwner, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.UpdateResponse(tc.ctx, id.Hex(), tc.agent, &canned.ResponseDTO{Body: "Hello"})
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_RenderShortcut(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_canned.NewMockRepository(controller)
+	mockRoomSvc := mock_room.NewMockService(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := canned.NewService(mockRepo, mockRoomSvc, mockUserSvc, zapLogger)
+
+	agent := &user.DTO{ID: primitive.NewObjectID().Hex(), Name: "Ann", Support: true, Rooms: []string{"room"}}
+	team := &canned.Model{
+		ID:       primitive.NewObjectID(),
+		Scope:    canned.ScopeTeam,
+		Shortcut: "hello",
+		Body:     "Hi {{customer.name}}, {{ agent.name }} here. {{customer.phone}}",
+	}
+	personalFilters := bson.M{"shortcut": "hello", "scope": canned.ScopePersonal, "ownerId": agent.ID}
+	teamFilters := bson.M{"shortcut": "hello", "scope": canned.ScopeTeam}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		roomName string
+		setup    func(context.Context)
+		expect   func(*testing.T, *canned.RenderedDTO, error)
+	}{
+		{
+			name:     "should fill in placeholders for the customer of the room",
+			ctx:      context.Background(),
+			roomName: "room",
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetResponse(ctx, personalFilters).Return(nil, canned.ErrNotFound)
+				mockRepo.EXPECT().GetResponse(ctx, teamFilters).Return(team, nil)
+				mockRoomSvc.EXPECT().GetRoomByName(ctx, "room").Return(&room.DTO{Name: "room", CustomerId: "customer"}, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, "customer", false).Return(&user.DTO{ID: "customer", Name: "Bob"}, nil)
+			},
+			expect: func(t *testing.T, rendered *canned.RenderedDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "Hi Bob, Ann here. {{customer.phone}}", rendered.Text)
+			},
+		},
+		{
+			name:     "should leave customer placeholders without room",
+			ctx:      context.Background(),
+			roomName: "",
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetResponse(ctx, personalFilters).Return(team, nil)
+			},
+			expect: func(t *testing.T, rendered *canned.RenderedDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "Hi {{customer.name}}, Ann here. {{customer.phone}}", rendered.Text)
+			},
+		},
+		{
+			name:     "should return not participant",
+			ctx:      context.Background(),
+			roomName: "other",
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetResponse(ctx, personalFilters).Return(team, nil)
+			},
+			expect: func(t *testing.T, rendered *canned.RenderedDTO, err error) {
+				assert.Nil(t, rendered)
+				assert.Equal(t, canned.ErrNotParticipant, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			rendered, err := service.RenderShortcut(tc.ctx, "hello", agent, tc.roomName)
+			tc.expect(t, rendered, err)
+		})
+	}
+}
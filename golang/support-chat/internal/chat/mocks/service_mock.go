@@ -7,6 +7,8 @@ package mock_chat
 import (
 	context "context"
 	reflect "reflect"
+	room "support-chat/internal/chat/room"
+	user "support-chat/internal/user"
 
 	gomock "github.com/golang/mock/gomock"
 	websocket "github.com/gorilla/websocket"
@@ -48,3 +50,74 @@ func (mr *MockServiceMockRecorder) Chat(ctx, ws interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Chat", reflect.TypeOf((*MockService)(nil).Chat), ctx, ws)
 }
+
+// CloseSessions mocks base method.
+func (m *MockService) CloseSessions(userId string, sids ...string) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{userId}
+	for _, a := range sids {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "CloseSessions", varargs...)
+}
+
+// CloseSessions indicates an expected call of CloseSessions.
+func (mr *MockServiceMockRecorder) CloseSessions(userId interface{}, sids ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{userId}, sids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSessions", reflect.TypeOf((*MockService)(nil).CloseSessions), varargs...)
+}
+
+// InviteToRoom mocks base method.
+func (m *MockService) InviteToRoom(ctx context.Context, by *user.DTO, name, agentId, note string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InviteToRoom", ctx, by, name, agentId, note)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InviteToRoom indicates an expected call of InviteToRoom.
+func (mr *MockServiceMockRecorder) InviteToRoom(ctx, by, name, agentId, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InviteToRoom", reflect.TypeOf((*MockService)(nil).InviteToRoom), ctx, by, name, agentId, note)
+}
+
+// RunLeaseWatcher mocks base method.
+func (m *MockService) RunLeaseWatcher(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunLeaseWatcher", ctx)
+}
+
+// RunLeaseWatcher indicates an expected call of RunLeaseWatcher.
+func (mr *MockServiceMockRecorder) RunLeaseWatcher(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunLeaseWatcher", reflect.TypeOf((*MockService)(nil).RunLeaseWatcher), ctx)
+}
+
+// RunSessionWatcher mocks base method.
+func (m *MockService) RunSessionWatcher(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunSessionWatcher", ctx)
+}
+
+// RunSessionWatcher indicates an expected call of RunSessionWatcher.
+func (mr *MockServiceMockRecorder) RunSessionWatcher(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunSessionWatcher", reflect.TypeOf((*MockService)(nil).RunSessionWatcher), ctx)
+}
+
+// TransferRoom mocks base method.
+func (m *MockService) TransferRoom(ctx context.Context, from *user.DTO, name, agentId, note string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferRoom", ctx, from, name, agentId, note)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferRoom indicates an expected call of TransferRoom.
+func (mr *MockServiceMockRecorder) TransferRoom(ctx, from, name, agentId, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferRoom", reflect.TypeOf((*MockService)(nil).TransferRoom), ctx, from, name, agentId, note)
+}
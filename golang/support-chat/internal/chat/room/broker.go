@@ -0,0 +1,74 @@
+package room
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// BrokerMessage is a message received on one of the subscribed channels.
+type BrokerMessage struct {
+	Channel string
+	Payload []byte
+}
+
+// Broker carries the messages between the instances of the app, every
+// instance gets what any of them publishes.
+//
+//go:generate mockgen -source=broker.go -destination=mocks/broker_mock.go
+type Broker interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe delivers the messages of the channels until ctx is done.
+	Subscribe(ctx context.Context, channels ...string) (<-chan *BrokerMessage, error)
+}
+
+type redisBroker struct {
+	client *redis.Client
+}
+
+func NewRedisBroker(client *redis.Client) (Broker, error) {
+	if client == nil {
+		return nil, errors.New("[chat_room_broker] invalid redis client")
+	}
+
+	return &redisBroker{client: client}, nil
+}
+
+func (b *redisBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	return b.client.Publish(ctx, channel, payload).Err()
+}
+
+func (b *redisBroker) Subscribe(ctx context.Context, channels ...string) (<-chan *BrokerMessage, error) {
+	pubsub := b.client.Subscribe(ctx, channels...)
+	// wait for the subscription, so nothing published from now on is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	messages := make(chan *BrokerMessage)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- &BrokerMessage{Channel: msg.Channel, Payload: []byte(msg.Payload)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
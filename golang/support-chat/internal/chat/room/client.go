@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/gorilla/websocket"
 	"log"
+	"sync"
 
 	"time"
 )
@@ -20,13 +21,41 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 10000
+
+	// Idle time after which a live client is reported away
+	awayAfter = 5 * time.Minute
+)
+
+type Presence string
+
+const (
+	PresenceOnline  Presence = "online"
+	PresenceAway    Presence = "away"
+	PresenceOffline Presence = "offline"
 )
 
+type PresenceFunc func(*Client, Presence)
+
+// DeliveredFunc is called with every message once it was written to the socket.
+type DeliveredFunc func(*Client, []byte)
+
 type Client struct {
 	Id         string          `json:"id"`
 	Room       *Room           `json:"room"`
 	Connection *websocket.Conn `json:"connection"`
 	Send       chan []byte     `json:"send"`
+	// Role is RoleAgent for support users and RoleCustomer for everyone else
+	Role Role `json:"role"`
+	// SessionId is the login session the connection was opened with
+	SessionId string `json:"-"`
+	// OnPresence is called whenever the presence of the client changes
+	OnPresence PresenceFunc `json:"-"`
+	// OnDelivered is called for every message written to the connection
+	OnDelivered DeliveredFunc `json:"-"`
+
+	mu           sync.Mutex
+	presence     Presence
+	lastActivity time.Time
 }
 
 func NewClient(id string, conn *websocket.Conn) (*Client, error) {
@@ -38,14 +67,72 @@ func NewClient(id string, conn *websocket.Conn) (*Client, error) {
 	}
 
 	return &Client{
-		Id:         id,
-		Room:       nil,
-		Connection: conn,
-		Send:       make(chan []byte, 256),
+		Id:           id,
+		Room:         nil,
+		Connection:   conn,
+		Send:         make(chan []byte, 256),
+		Role:         RoleCustomer,
+		presence:     PresenceOnline,
+		lastActivity: time.Now(),
 	}, nil
 }
 
-type HandlerFunc func([]byte)
+func (c *Client) Presence() Presence {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.presence
+}
+
+// setPresence stores the presence and reports it to OnPresence when it changed.
+func (c *Client) setPresence(presence Presence) {
+	c.mu.Lock()
+	if c.presence == presence {
+		c.mu.Unlock()
+		return
+	}
+	c.presence = presence
+	onPresence := c.OnPresence
+	c.mu.Unlock()
+
+	if onPresence != nil {
+		onPresence(c, presence)
+	}
+}
+
+func (c *Client) markActive() {
+	c.mu.Lock()
+	c.lastActivity = time.Now()
+	c.mu.Unlock()
+
+	c.setPresence(PresenceOnline)
+}
+
+func (c *Client) idleFor() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Since(c.lastActivity)
+}
+
+// Enqueue hands the message to the write pump without blocking. A client
+// whose buffer is full can't keep up, its connection is closed and the read
+// pump then lets the chat service forget it.
+func (c *Client) Enqueue(message []byte) bool {
+	select {
+	case c.Send <- message:
+		return true
+	default:
+		log.Printf("send buffer of client %v is full, closing connection", c.Id)
+		if err := c.Connection.Close(); err != nil {
+			log.Printf("failed to close connection %v", err)
+		}
+		return false
+	}
+}
+
+// HandlerFunc handles a message the client sent, on behalf of its user.
+type HandlerFunc func(*Client, []byte)
 
 func (c *Client) ReadPump(msgHandleFunc HandlerFunc) {
 	c.Connection.SetReadLimit(maxMessageSize)
@@ -62,6 +149,9 @@ func (c *Client) ReadPump(msgHandleFunc HandlerFunc) {
 		return nil
 	})
 
+	// a client that stops answering pings runs into the read deadline
+	defer c.setPresence(PresenceOffline)
+
 	// Start endless read loop, waiting for messages from client
 	for {
 		_, jsonMessage, err := c.Connection.ReadMessage()
@@ -72,7 +162,8 @@ func (c *Client) ReadPump(msgHandleFunc HandlerFunc) {
 			break
 		}
 
-		msgHandleFunc(jsonMessage)
+		c.markActive()
+		msgHandleFunc(c, jsonMessage)
 	}
 }
 
@@ -109,6 +200,7 @@ func (c *Client) WritePump() {
 			if err != nil {
 				return
 			}
+			written := [][]byte{message}
 
 			//Attach queued chat messages to the current websocket message.
 			n := len(c.Send)
@@ -117,16 +209,30 @@ func (c *Client) WritePump() {
 				if err != nil {
 					log.Printf("failed to write message %v", err)
 				}
-				_, err = w.Write(<-c.Send)
+				queued := <-c.Send
+				_, err = w.Write(queued)
 				if err != nil {
 					log.Printf("failed to write message %v", err)
+					continue
 				}
+				written = append(written, queued)
 			}
 
 			if err := w.Close(); err != nil {
 				return
 			}
+
+			if c.OnDelivered != nil {
+				for _, message := range written {
+					c.OnDelivered(c, message)
+				}
+			}
 		case <-ticker.C:
+			// the connection is alive, but nobody has sent anything for a while
+			if c.idleFor() > awayAfter {
+				c.setPresence(PresenceAway)
+			}
+
 			err := c.Connection.SetWriteDeadline(time.Now().Add(writeWait))
 			if err != nil {
 				log.Printf("failed to set write deadline %v", err)
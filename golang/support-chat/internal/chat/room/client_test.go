@@ -28,6 +28,8 @@ func TestNewClient(t *testing.T) {
 			expect: func(t *testing.T, s *room.Client, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
+				assert.Equal(t, room.RoleCustomer, s.Role)
+				assert.Equal(t, room.PresenceOnline, s.Presence())
 			},
 		},
 		{
@@ -1,7 +1,32 @@
 package room
 
+import "time"
+
 type DTO struct {
-	ID       string          `json:"id"`
-	Name     string          `json:"name"`
-	Messages *[]*RoomMessage `json:"messages"`
+	ID             string         `json:"id"`
+	Name           string         `json:"name"`
+	CustomerId     string         `json:"customer_id"`
+	AgentId        string         `json:"agent_id,omitempty"`
+	State          State          `json:"state"`
+	QueuedAt       *time.Time     `json:"queued_at,omitempty"`
+	AssignedAt     *time.Time     `json:"assigned_at,omitempty"`
+	LeaseExpiresAt *time.Time     `json:"lease_expires_at,omitempty"`
+	ClosedAt       *time.Time     `json:"closed_at,omitempty"`
+	ClosedBy       string         `json:"closed_by,omitempty"`
+	CloseReason    string         `json:"close_reason,omitempty"`
+	Transfers      []*Transfer    `json:"transfers,omitempty"`
+	Participants   []*Participant `json:"participants"`
+	// DeliveredCursors and ReadCursors map user ids to message ids
+	DeliveredCursors map[string]string `json:"delivered_cursors,omitempty"`
+	ReadCursors      map[string]string `json:"read_cursors,omitempty"`
+}
+
+type InviteDTO struct {
+	AgentId string `json:"agent_id"`
+	Note    string `json:"note"`
+}
+
+type TransferDTO struct {
+	AgentId string `json:"agent_id"`
+	Note    string `json:"note"`
 }
@@ -6,31 +6,77 @@ import (
 )
 
 const (
-	StatusToken             errors.Status = "invalid_token"
-	StatusRequiredToken     errors.Status = "token_required"
-	StatusInvalidId         errors.Status = "invalid_id"
-	StatusInvalidConnection errors.Status = "invalid_connection"
-	StatusInvalidName       errors.Status = "invalid_name"
-	StatusInvalidUserId     errors.Status = "invalid_userId"
-	StatusRoomAlreadyExists errors.Status = "room_already_exists"
-	StatusUserNotFound      errors.Status = "user_not_found"
-	StatusFailedCreateRoom  errors.Status = "failed_create_room"
-	StatusFailedSaveRoom    errors.Status = "failed_save_room"
-	StatusFailedUpdateRoom  errors.Status = "failed_update_room"
-	StatusFailedDeleteRoom  errors.Status = "failed_delete_room"
+	StatusInvalidId           errors.Status = "invalid_id"
+	StatusInvalidConnection   errors.Status = "invalid_connection"
+	StatusInvalidName         errors.Status = "invalid_name"
+	StatusInvalidUserId       errors.Status = "invalid_userId"
+	StatusRoomAlreadyExists   errors.Status = "room_already_exists"
+	StatusUserNotFound        errors.Status = "user_not_found"
+	StatusFailedCreateRoom    errors.Status = "failed_create_room"
+	StatusFailedSaveRoom      errors.Status = "failed_save_room"
+	StatusFailedUpdateRoom    errors.Status = "failed_update_room"
+	StatusFailedDeleteRoom    errors.Status = "failed_delete_room"
+	StatusFailedFindRooms     errors.Status = "failed_find_rooms"
+	StatusQueueEmpty          errors.Status = "queue_empty"
+	StatusNotInQueue          errors.Status = "room_not_in_queue"
+	StatusAlreadyClaimed      errors.Status = "room_already_claimed"
+	StatusNotSupport          errors.Status = "user_is_not_support"
+	StatusNotSupervisor       errors.Status = "user_is_not_supervisor"
+	StatusNotParticipant      errors.Status = "user_is_not_participant"
+	StatusAlreadyInRoom       errors.Status = "user_already_in_room"
+	StatusFailedSaveMessage   errors.Status = "failed_save_message"
+	StatusFailedFindMessages  errors.Status = "failed_find_messages"
+	StatusInvalidCursor       errors.Status = "invalid_cursor"
+	StatusMessageNotFound     errors.Status = "message_not_found"
+	StatusFailedUpdateMessage errors.Status = "failed_update_message"
+	StatusNotAuthor           errors.Status = "user_is_not_author"
+	StatusEditWindowClosed    errors.Status = "edit_window_closed"
+	StatusMessageDeleted      errors.Status = "message_deleted"
+	StatusRoomClosed          errors.Status = "room_closed"
+	StatusRoomNotClosed       errors.Status = "room_not_closed"
+	StatusInvalidFormat       errors.Status = "invalid_format"
+	StatusInvalidScore        errors.Status = "invalid_score"
+	StatusInvalidComment      errors.Status = "invalid_comment"
+	StatusInvalidGroup        errors.Status = "invalid_group"
+	StatusAlreadyRated        errors.Status = "room_already_rated"
+	StatusFailedSaveRating    errors.Status = "failed_save_rating"
+	StatusFailedFindRatings   errors.Status = "failed_find_ratings"
 )
 
 var (
-	ErrToken             = errors.New(codes.Unauthorized, StatusToken)
-	ErrRequiredToken     = errors.New(codes.Unauthorized, StatusRequiredToken)
-	ErrInvalidId         = errors.New(codes.BadRequest, StatusInvalidId)
-	ErrInvalidConnection = errors.New(codes.BadRequest, StatusInvalidConnection)
-	ErrInvalidName       = errors.New(codes.BadRequest, StatusInvalidName)
-	ErrInvalidUserId     = errors.New(codes.BadRequest, StatusInvalidUserId)
-	ErrAlreadyExists     = errors.New(codes.DuplicateError, StatusRoomAlreadyExists)
-	ErrNotFound          = errors.New(codes.NotFound, StatusUserNotFound)
-	ErrFailedCreateRoom  = errors.New(codes.BadRequest, StatusFailedCreateRoom)
-	ErrFailedSaveRoom    = errors.New(codes.BadRequest, StatusFailedSaveRoom)
-	ErrFailedUpdateRoom  = errors.New(codes.BadRequest, StatusFailedUpdateRoom)
-	ErrFailedDeleteRoom  = errors.New(codes.BadRequest, StatusFailedDeleteRoom)
+	ErrInvalidId           = errors.New(codes.BadRequest, StatusInvalidId)
+	ErrInvalidConnection   = errors.New(codes.BadRequest, StatusInvalidConnection)
+	ErrInvalidName         = errors.New(codes.BadRequest, StatusInvalidName)
+	ErrInvalidUserId       = errors.New(codes.BadRequest, StatusInvalidUserId)
+	ErrAlreadyExists       = errors.New(codes.DuplicateError, StatusRoomAlreadyExists)
+	ErrNotFound            = errors.New(codes.NotFound, StatusUserNotFound)
+	ErrFailedCreateRoom    = errors.New(codes.BadRequest, StatusFailedCreateRoom)
+	ErrFailedSaveRoom      = errors.New(codes.BadRequest, StatusFailedSaveRoom)
+	ErrFailedUpdateRoom    = errors.New(codes.BadRequest, StatusFailedUpdateRoom)
+	ErrFailedDeleteRoom    = errors.New(codes.BadRequest, StatusFailedDeleteRoom)
+	ErrFailedFindRooms     = errors.New(codes.BadRequest, StatusFailedFindRooms)
+	ErrQueueEmpty          = errors.New(codes.NotFound, StatusQueueEmpty)
+	ErrNotInQueue          = errors.New(codes.BadRequest, StatusNotInQueue)
+	ErrAlreadyClaimed      = errors.New(codes.DuplicateError, StatusAlreadyClaimed)
+	ErrNotSupport          = errors.New(codes.Forbidden, StatusNotSupport)
+	ErrNotSupervisor       = errors.New(codes.Forbidden, StatusNotSupervisor)
+	ErrNotParticipant      = errors.New(codes.Forbidden, StatusNotParticipant)
+	ErrAlreadyInRoom       = errors.New(codes.DuplicateError, StatusAlreadyInRoom)
+	ErrFailedSaveMessage   = errors.New(codes.BadRequest, StatusFailedSaveMessage)
+	ErrFailedFindMessages  = errors.New(codes.BadRequest, StatusFailedFindMessages)
+	ErrInvalidCursor       = errors.New(codes.BadRequest, StatusInvalidCursor)
+	ErrMessageNotFound     = errors.New(codes.NotFound, StatusMessageNotFound)
+	ErrFailedUpdateMessage = errors.New(codes.BadRequest, StatusFailedUpdateMessage)
+	ErrNotAuthor           = errors.New(codes.Forbidden, StatusNotAuthor)
+	ErrEditWindowClosed    = errors.New(codes.Forbidden, StatusEditWindowClosed)
+	ErrMessageDeleted      = errors.New(codes.BadRequest, StatusMessageDeleted)
+	ErrRoomClosed          = errors.New(codes.BadRequest, StatusRoomClosed)
+	ErrRoomNotClosed       = errors.New(codes.NotFound, StatusRoomNotClosed)
+	ErrInvalidFormat       = errors.New(codes.BadRequest, StatusInvalidFormat)
+	ErrInvalidScore        = errors.New(codes.BadRequest, StatusInvalidScore)
+	ErrInvalidComment      = errors.New(codes.BadRequest, StatusInvalidComment)
+	ErrInvalidGroup        = errors.New(codes.BadRequest, StatusInvalidGroup)
+	ErrAlreadyRated        = errors.New(codes.DuplicateError, StatusAlreadyRated)
+	ErrFailedSaveRating    = errors.New(codes.BadRequest, StatusFailedSaveRating)
+	ErrFailedFindRatings   = errors.New(codes.BadRequest, StatusFailedFindRatings)
 )
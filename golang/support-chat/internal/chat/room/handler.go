@@ -1,44 +1,448 @@
 package room
 
 import (
+	"encoding/json"
 	gerrors "errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"support-chat/internal/user"
 	"support-chat/pkg/errors"
+	"support-chat/pkg/rbac"
 	"support-chat/pkg/respond"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	roomSvc Service
+	roomSvc     Service
+	liveSvc     LiveService
+	permissions rbac.Middleware
 }
 
-func NewHandler(roomSvc Service) (*Handler, error) {
+func NewHandler(roomSvc Service, liveSvc LiveService, permissions rbac.Middleware) (*Handler, error) {
 	if roomSvc == nil {
 		return nil, gerrors.New("[chat_room_handler] invalid room service")
 	}
+	if liveSvc == nil {
+		return nil, gerrors.New("[chat_room_handler] invalid live service")
+	}
+	if permissions == nil {
+		return nil, gerrors.New("[chat_room_handler] invalid permissions middleware")
+	}
 
-	return &Handler{roomSvc: roomSvc}, nil
+	return &Handler{roomSvc: roomSvc, liveSvc: liveSvc, permissions: permissions}, nil
 }
 
 func (h *Handler) SetupRoutes(router chi.Router) {
-	router.HandleFunc("/get-room-messages", h.GetRoomMessages)
+	read := router.With(h.permissions.Require(rbac.RoomsRead))
+	queue := router.With(h.permissions.Require(rbac.RoomsQueue))
+	transfer := router.With(h.permissions.Require(rbac.RoomsTransfer))
+	archive := router.With(h.permissions.Require(rbac.RoomsArchive))
+	supervise := router.With(h.permissions.Require(rbac.RoomsSupervise))
+
+	read.HandleFunc("/get-room-messages", h.GetRoomMessages)
+	read.Get("/unread", h.GetUnreadCounts)
+	queue.Get("/waiting", h.GetWaitingRooms)
+	archive.Get("/archive", h.GetArchivedRooms)
+	archive.Get("/archive/{name}", h.GetArchivedRoom)
+	archive.Get("/archive/{name}/messages", h.GetArchivedRoomMessages)
+	queue.Post("/rooms/{name}/claim", h.ClaimRoom)
+	transfer.Post("/rooms/{name}/transfer", h.TransferRoom)
+	transfer.Post("/rooms/{name}/invite", h.InviteToRoom)
+	read.Get("/rooms/{name}/participants", h.GetParticipants)
+	read.Get("/rooms/{name}/transcript", h.GetTranscript)
+	supervise.Get("/archive/{name}/transcript", h.GetSupervisedTranscript)
+	router.With(h.permissions.Require(rbac.RoomsRate)).Post("/rooms/{name}/rating", h.RateRoom)
+	router.With(h.permissions.Require(rbac.RatingsRead)).Get("/ratings", h.GetRatingStats)
 }
 
 func (h *Handler) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
-	userCtxValue := r.Context().Value(contextKey("user"))
-	if userCtxValue == nil {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+	roomName, err := participantRoom(&u, r.URL.Query().Get("room"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	page, err := messagesPage(r)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	room, err := h.roomSvc.GetRoomWithFormatMessages(r.Context(), roomName, u.ID, page)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, room)
+}
+
+// GetUnreadCounts returns the number of unread messages in each room of the user.
+func (h *Handler) GetUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+	rooms := u.Rooms
+	if !u.Support && u.RoomName != nil {
+		rooms = []string{*u.RoomName}
+	}
+
+	unread := make(map[string]int64, len(rooms))
+	for _, roomName := range rooms {
+		count, err := h.roomSvc.GetUnreadCount(r.Context(), roomName, u.ID)
+		if err != nil {
+			respond.Respond(w, errors.HTTPCode(err), err)
+			return
+		}
+		unread[roomName] = count
+	}
+
+	respond.Respond(w, http.StatusOK, unread)
+}
+
+func (h *Handler) GetWaitingRooms(w http.ResponseWriter, r *http.Request) {
+	rooms, err := h.roomSvc.GetWaitingRooms(r.Context())
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, rooms)
+}
+
+func (h *Handler) ClaimRoom(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
 		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
 		return
 	}
 
-	u := userCtxValue.(user.DTO)
-	room, err := h.roomSvc.GetRoomWithFormatMessages(r.Context(), *u.RoomName, u.ID)
+	u := principal.User
+	room, err := h.roomSvc.ClaimRoom(r.Context(), chi.URLParam(r, "name"), &u)
 	if err != nil {
-		respond.Respond(w, http.StatusInternalServerError, errors.NewInternal(err.Error()))
+		respond.Respond(w, errors.HTTPCode(err), err)
 		return
 	}
 
 	respond.Respond(w, http.StatusOK, room)
 }
+
+func (h *Handler) TransferRoom(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	var dto TransferDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	u := principal.User
+	room, err := h.liveSvc.TransferRoom(r.Context(), &u, chi.URLParam(r, "name"), dto.AgentId, dto.Note)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, room)
+}
+
+func (h *Handler) InviteToRoom(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	var dto InviteDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	u := principal.User
+	room, err := h.liveSvc.InviteToRoom(r.Context(), &u, chi.URLParam(r, "name"), dto.AgentId, dto.Note)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, room)
+}
+
+func (h *Handler) GetParticipants(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+	roomName, err := participantRoom(&u, chi.URLParam(r, "name"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	room, err := h.roomSvc.GetRoomByName(r.Context(), roomName)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	participants := room.Participants
+	if !u.Support {
+		participants = CustomerRoster(participants)
+	}
+
+	respond.Respond(w, http.StatusOK, participants)
+}
+
+// GetTranscript streams the history of a room the user took part in as json,
+// text or html. Messages keep their ciphertext, clients decrypt them locally.
+func (h *Handler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	h.exportTranscript(w, r, false)
+}
+
+// GetSupervisedTranscript streams the history of any room, the route requires
+// rooms:supervise.
+func (h *Handler) GetSupervisedTranscript(w http.ResponseWriter, r *http.Request) {
+	h.exportTranscript(w, r, true)
+}
+
+func (h *Handler) exportTranscript(w http.ResponseWriter, r *http.Request, supervised bool) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	format, err := ParseTranscriptFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	u := principal.User
+	name := chi.URLParam(r, "name")
+	tw := &transcriptResponse{ResponseWriter: w, format: format, name: name}
+	if err = h.roomSvc.ExportTranscript(r.Context(), tw, name, &u, format, supervised); err != nil && !tw.started {
+		respond.Respond(w, errors.HTTPCode(err), err)
+	}
+}
+
+// transcriptResponse sets the transcript headers on the first write, so errors
+// found before anything is written can still be answered as json.
+type transcriptResponse struct {
+	http.ResponseWriter
+	format  TranscriptFormat
+	name    string
+	started bool
+}
+
+func (t *transcriptResponse) Write(b []byte) (int, error) {
+	if !t.started {
+		t.started = true
+		t.Header().Set("Content-Type", t.format.ContentType())
+		t.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="transcript-%s.%s"`, t.name, t.format.Extension()))
+		t.WriteHeader(http.StatusOK)
+	}
+
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *transcriptResponse) Flush() {
+	if flusher, ok := t.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (h *Handler) RateRoom(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	var dto RateDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	u := principal.User
+	rating, err := h.roomSvc.RateRoom(r.Context(), chi.URLParam(r, "name"), &u, dto.Score, dto.Comment)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusCreated, rating)
+}
+
+// GetRatingStats returns the satisfaction stats grouped by the group query,
+// optionally narrowed to an agent and a time range given as RFC3339.
+func (h *Handler) GetRatingStats(w http.ResponseWriter, r *http.Request) {
+	from, err := timeParam(r, "from")
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+	to, err := timeParam(r, "to")
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	query := &RatingQuery{
+		GroupBy: r.URL.Query().Get("group"),
+		AgentId: r.URL.Query().Get("agent"),
+		From:    from,
+		To:      to,
+	}
+
+	stats, err := h.roomSvc.GetRatingStats(r.Context(), query)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, stats)
+}
+
+func (h *Handler) GetArchivedRooms(w http.ResponseWriter, r *http.Request) {
+	page := &ArchivePage{CustomerId: r.URL.Query().Get("customer")}
+	if before := r.URL.Query().Get("before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest("invalid before"))
+			return
+		}
+		page.Before = &t
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		page.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest("invalid limit"))
+			return
+		}
+	}
+
+	rooms, err := h.roomSvc.GetArchivedRooms(r.Context(), page)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, rooms)
+}
+
+func (h *Handler) GetArchivedRoom(w http.ResponseWriter, r *http.Request) {
+	room, err := h.roomSvc.GetArchivedRoom(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, room)
+}
+
+func (h *Handler) GetArchivedRoomMessages(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	room, err := h.roomSvc.GetArchivedRoom(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	page, err := messagesPage(r)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	messages, err := h.roomSvc.GetRoomWithFormatMessages(r.Context(), room.Name, u.ID, page)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, messages)
+}
+
+// messagesPage reads the history cursor from the query.
+func messagesPage(r *http.Request) (*Page, error) {
+	page := &Page{Before: r.URL.Query().Get("before"), After: r.URL.Query().Get("after")}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		page.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return nil, errors.NewBadRequest("invalid limit")
+		}
+	}
+
+	return page, nil
+}
+
+// timeParam reads an optional RFC3339 time from the query.
+func timeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, errors.NewBadRequest("invalid " + name)
+	}
+
+	return &t, nil
+}
+
+// participantRoom checks that the user takes part in the requested room.
+// Customers may omit the room and get their own one.
+func participantRoom(u *user.DTO, roomName string) (string, error) {
+	if roomName == "" {
+		if u.Support || u.RoomName == nil {
+			return "", ErrInvalidName
+		}
+		return *u.RoomName, nil
+	}
+
+	uEntity, err := user.MapToEntity(u)
+	if err != nil {
+		return "", err
+	}
+
+	if !uEntity.HasRoom(roomName) {
+		return "", ErrNotParticipant
+	}
+
+	return roomName, nil
+}
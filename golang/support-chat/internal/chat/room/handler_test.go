@@ -3,6 +3,8 @@ package room_test
 import (
 	"support-chat/internal/chat/room"
 	mock_room "support-chat/internal/chat/room/mocks"
+	"support-chat/pkg/rbac"
+	mock_rbac "support-chat/pkg/rbac/mocks"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -14,32 +16,60 @@ func TestNewHandler(t *testing.T) {
 	defer controller.Finish()
 
 	tests := []struct {
-		name    string
-		roomSvc room.Service
-		expect  func(*testing.T, *room.Handler, error)
+		name        string
+		roomSvc     room.Service
+		liveSvc     room.LiveService
+		permissions rbac.Middleware
+		expect      func(*testing.T, *room.Handler, error)
 	}{
 		{
-			name:    "should return service",
-			roomSvc: mock_room.NewMockService(controller),
+			name:        "should return service",
+			roomSvc:     mock_room.NewMockService(controller),
+			liveSvc:     mock_room.NewMockLiveService(controller),
+			permissions: mock_rbac.NewMockMiddleware(controller),
 			expect: func(t *testing.T, s *room.Handler, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:    "should return invalid room service",
-			roomSvc: nil,
+			name:        "should return invalid room service",
+			roomSvc:     nil,
+			liveSvc:     mock_room.NewMockLiveService(controller),
+			permissions: mock_rbac.NewMockMiddleware(controller),
 			expect: func(t *testing.T, s *room.Handler, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, "[chat_room_handler] invalid room service")
 			},
 		},
+		{
+			name:        "should return invalid live service",
+			roomSvc:     mock_room.NewMockService(controller),
+			liveSvc:     nil,
+			permissions: mock_rbac.NewMockMiddleware(controller),
+			expect: func(t *testing.T, s *room.Handler, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_handler] invalid live service")
+			},
+		},
+		{
+			name:        "should return invalid permissions middleware",
+			roomSvc:     mock_room.NewMockService(controller),
+			liveSvc:     mock_room.NewMockLiveService(controller),
+			permissions: nil,
+			expect: func(t *testing.T, s *room.Handler, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_handler] invalid permissions middleware")
+			},
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := room.NewHandler(tc.roomSvc)
+			svc, err := room.NewHandler(tc.roomSvc, tc.liveSvc, tc.permissions)
 			tc.expect(t, svc, err)
 		})
 	}
@@ -0,0 +1,15 @@
+package room
+
+import (
+	"context"
+	"support-chat/internal/user"
+)
+
+//go:generate mockgen -source=live.go -destination=mocks/live_mock.go
+
+// LiveService changes rooms that may have connected websocket clients and
+// sends those clients the matching events. It's implemented by the chat service.
+type LiveService interface {
+	TransferRoom(ctx context.Context, from *user.DTO, name, agentId, note string) (*DTO, error)
+	InviteToRoom(ctx context.Context, by *user.DTO, name, agentId, note string) (*DTO, error)
+}
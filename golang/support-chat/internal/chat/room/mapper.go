@@ -1,22 +1,37 @@
 package room
 
 import (
+	"sort"
 	"support-chat/pkg/errors"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func MapToDTO(r *Model) *DTO {
-
-	var messages []*RoomMessage
-	if r.Messages != nil {
-		messages = *r.Messages
+	participants := make([]*Participant, 0, len(r.Participants))
+	for _, p := range r.Participants {
+		participants = append(participants, p)
 	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].JoinedAt.Before(participants[j].JoinedAt)
+	})
 
 	return &DTO{
-		ID:       r.ID.Hex(),
-		Name:     r.Name,
-		Messages: &messages,
+		ID:               r.ID.Hex(),
+		Name:             r.Name,
+		CustomerId:       r.CustomerId,
+		AgentId:          r.AgentId,
+		State:            r.State,
+		QueuedAt:         r.QueuedAt,
+		AssignedAt:       r.AssignedAt,
+		LeaseExpiresAt:   r.LeaseExpiresAt,
+		ClosedAt:         r.ClosedAt,
+		ClosedBy:         r.ClosedBy,
+		CloseReason:      r.CloseReason,
+		Transfers:        r.Transfers,
+		Participants:     participants,
+		DeliveredCursors: mapCursorsToDTO(r.DeliveredCursors),
+		ReadCursors:      mapCursorsToDTO(r.ReadCursors),
 	}
 }
 
@@ -26,9 +41,58 @@ func MapToEntity(dto *DTO) (*Model, error) {
 		return nil, errors.NewInternal(err.Error())
 	}
 
+	participants := make(map[string]*Participant, len(dto.Participants))
+	for _, p := range dto.Participants {
+		participants[p.UserId] = p
+	}
+
+	deliveredCursors, err := mapCursorsToEntity(dto.DeliveredCursors)
+	if err != nil {
+		return nil, err
+	}
+
+	readCursors, err := mapCursorsToEntity(dto.ReadCursors)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Model{
-		ID:       id,
-		Name:     dto.Name,
-		Messages: dto.Messages,
+		ID:               id,
+		Name:             dto.Name,
+		CustomerId:       dto.CustomerId,
+		AgentId:          dto.AgentId,
+		State:            dto.State,
+		QueuedAt:         dto.QueuedAt,
+		AssignedAt:       dto.AssignedAt,
+		LeaseExpiresAt:   dto.LeaseExpiresAt,
+		ClosedAt:         dto.ClosedAt,
+		ClosedBy:         dto.ClosedBy,
+		CloseReason:      dto.CloseReason,
+		Transfers:        dto.Transfers,
+		Participants:     participants,
+		DeliveredCursors: deliveredCursors,
+		ReadCursors:      readCursors,
 	}, nil
 }
+
+func mapCursorsToDTO(cursors map[string]primitive.ObjectID) map[string]string {
+	mapped := make(map[string]string, len(cursors))
+	for userId, messageId := range cursors {
+		mapped[userId] = messageId.Hex()
+	}
+
+	return mapped
+}
+
+func mapCursorsToEntity(cursors map[string]string) (map[string]primitive.ObjectID, error) {
+	mapped := make(map[string]primitive.ObjectID, len(cursors))
+	for userId, messageId := range cursors {
+		id, err := primitive.ObjectIDFromHex(messageId)
+		if err != nil {
+			return nil, errors.NewInternal(err.Error())
+		}
+		mapped[userId] = id
+	}
+
+	return mapped, nil
+}
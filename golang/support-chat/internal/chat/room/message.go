@@ -1,11 +1,21 @@
 package room
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Message struct {
-	Action  string           `json:"action"`
-	Message EncryptedMessage `json:"message,omitempty"`
-	Token   string           `json:"token"`
+	Action    string           `json:"action"`
+	Message   EncryptedMessage `json:"message,omitempty"`
+	RoomName  string           `json:"roomName,omitempty"`
+	AgentId   string           `json:"agentId,omitempty"`
+	MessageId string           `json:"messageId,omitempty"`
+	Note      string           `json:"note,omitempty"`
+	Reason    string           `json:"reason,omitempty"`
+	Score     int              `json:"score,omitempty"`
+	Comment   string           `json:"comment,omitempty"`
 }
 
 type EncryptedMessage struct {
@@ -15,27 +25,134 @@ type EncryptedMessage struct {
 }
 
 type MessageResponse struct {
-	Action  string            `json:"action"`
-	Message *EncryptedMessage `json:"message,omitempty"`
-	From    string            `json:"from"`
-	Error   interface{}       `json:"error"`
+	Id       string            `json:"id,omitempty"`
+	Action   string            `json:"action"`
+	Message  *EncryptedMessage `json:"message,omitempty"`
+	From     string            `json:"from"`
+	RoomName string            `json:"roomName,omitempty"`
+	Data     interface{}       `json:"data,omitempty"`
+	Error    interface{}       `json:"error"`
+}
+
+type QueuePosition struct {
+	RoomName      string `json:"room_name"`
+	Position      int    `json:"position"`
+	EstimatedWait int64  `json:"estimated_wait"`
+}
+
+type TransferEvent struct {
+	RoomName   string `json:"room_name"`
+	CustomerId string `json:"customer_id"`
+	From       string `json:"from"`
+	To         string `json:"to,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+type InviteEvent struct {
+	RoomName   string `json:"room_name"`
+	CustomerId string `json:"customer_id"`
+	InvitedBy  string `json:"invited_by"`
+	Note       string `json:"note,omitempty"`
+}
+
+type Roster struct {
+	RoomName     string         `json:"room_name"`
+	Participants []*Participant `json:"participants"`
+}
+
+type PresenceEvent struct {
+	UserId string   `json:"user_id"`
+	Status Presence `json:"status"`
+}
+
+type ReceiptEvent struct {
+	UserId    string  `json:"user_id"`
+	MessageId string  `json:"message_id"`
+	Status    Receipt `json:"status"`
+}
+
+type RoomClosed struct {
+	RoomName    string `json:"room_name"`
+	ClosedBy    string `json:"closed_by"`
+	CloseReason string `json:"close_reason"`
+}
+
+// RatingPrompt asks the customer to rate the conversation with the agent.
+type RatingPrompt struct {
+	RoomName string `json:"room_name"`
+	AgentId  string `json:"agent_id,omitempty"`
+}
+
+type SystemMessage struct {
+	Text string `json:"text"`
+}
+
+type RoomAssigned struct {
+	RoomName   string `json:"room_name"`
+	CustomerId string `json:"customer_id"`
 }
 
 type BroadcastMessage struct {
 	Action   string          `json:"action"`
 	Message  MessageResponse `json:"message"`
 	RoomName string          `json:"roomName"`
+	// AgentsOnly keeps the message from the customer of the room
+	AgentsOnly bool `json:"agentsOnly,omitempty"`
 }
 
 type FormatMessages struct {
-	To      string           `json:"to,omitempty"`
-	From    string           `json:"from,omitempty"`
-	Message EncryptedMessage `json:"message"`
-	Time    time.Time        `json:"time"`
+	Id         string           `json:"id"`
+	To         string           `json:"to,omitempty"`
+	From       string           `json:"from,omitempty"`
+	Message    EncryptedMessage `json:"message"`
+	Time       time.Time        `json:"time"`
+	AgentsOnly bool             `json:"agents_only,omitempty"`
+	EditedAt   *time.Time       `json:"edited_at,omitempty"`
+	Deleted    bool             `json:"deleted,omitempty"`
+	Status     Receipt          `json:"status"`
+}
+
+type MessagesPage struct {
+	Messages []*FormatMessages `json:"messages"`
+	Unread   int64             `json:"unread"`
 }
 
+// RoomMessage is a document of the messages collection. Id is the author, the
+// message itself is identified by ID.
 type RoomMessage struct {
-	Id      string           `bson:"id"`
-	Time    time.Time        `bson:"time"`
-	Message EncryptedMessage `bson:"message,omitempty"`
+	ID       primitive.ObjectID `bson:"_id"`
+	RoomName string             `bson:"roomName"`
+	Id       string             `bson:"id"`
+	Time     time.Time          `bson:"time"`
+	Message  EncryptedMessage   `bson:"message,omitempty"`
+	// AgentsOnly marks whispers, they are never shown to the customer
+	AgentsOnly bool       `bson:"agentsOnly,omitempty"`
+	EditedAt   *time.Time `bson:"editedAt,omitempty"`
+	DeletedAt  *time.Time `bson:"deletedAt,omitempty"`
+	DeletedBy  string     `bson:"deletedBy,omitempty"`
+	// Revisions keeps every earlier version of the message, oldest first
+	Revisions []*Revision `bson:"revisions,omitempty"`
+}
+
+// Revision is a version of a message that was replaced by an edit or cleared
+// by a delete.
+type Revision struct {
+	Message    EncryptedMessage `bson:"message"`
+	ReplacedAt time.Time        `bson:"replacedAt"`
+	ReplacedBy string           `bson:"replacedBy"`
+}
+
+// Page selects a part of the room history. Before and After are message ids,
+// only one of them may be set. Without either the latest messages are returned.
+type Page struct {
+	Before string
+	After  string
+	Limit  int64
+}
+
+// ArchivePage selects closed rooms, newest first. Before is a closing time.
+type ArchivePage struct {
+	CustomerId string
+	Before     *time.Time
+	Limit      int64
 }
@@ -0,0 +1,67 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: live.go
+
+// Package mock_room is a generated GoMock package.
+package mock_room
+
+import (
+	context "context"
+	reflect "reflect"
+	room "support-chat/internal/chat/room"
+	user "support-chat/internal/user"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLiveService is a mock of LiveService interface.
+type MockLiveService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLiveServiceMockRecorder
+}
+
+// MockLiveServiceMockRecorder is the mock recorder for MockLiveService.
+type MockLiveServiceMockRecorder struct {
+	mock *MockLiveService
+}
+
+// NewMockLiveService creates a new mock instance.
+func NewMockLiveService(ctrl *gomock.Controller) *MockLiveService {
+	mock := &MockLiveService{ctrl: ctrl}
+	mock.recorder = &MockLiveServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLiveService) EXPECT() *MockLiveServiceMockRecorder {
+	return m.recorder
+}
+
+// InviteToRoom mocks base method.
+func (m *MockLiveService) InviteToRoom(ctx context.Context, by *user.DTO, name, agentId, note string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InviteToRoom", ctx, by, name, agentId, note)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InviteToRoom indicates an expected call of InviteToRoom.
+func (mr *MockLiveServiceMockRecorder) InviteToRoom(ctx, by, name, agentId, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InviteToRoom", reflect.TypeOf((*MockLiveService)(nil).InviteToRoom), ctx, by, name, agentId, note)
+}
+
+// TransferRoom mocks base method.
+func (m *MockLiveService) TransferRoom(ctx context.Context, from *user.DTO, name, agentId, note string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferRoom", ctx, from, name, agentId, note)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferRoom indicates an expected call of TransferRoom.
+func (mr *MockLiveServiceMockRecorder) TransferRoom(ctx, from, name, agentId, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferRoom", reflect.TypeOf((*MockLiveService)(nil).TransferRoom), ctx, from, name, agentId, note)
+}
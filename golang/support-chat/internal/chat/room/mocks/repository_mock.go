@@ -6,11 +6,13 @@ package mock_room
 
 import (
 	context "context"
-	room "support-chat/internal/chat/room"
 	reflect "reflect"
+	room "support-chat/internal/chat/room"
 
 	gomock "github.com/golang/mock/gomock"
 	bson "go.mongodb.org/mongo-driver/bson"
+	mongo "go.mongodb.org/mongo-driver/mongo"
+	options "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MockRepository is a mock of Repository interface.
@@ -36,6 +38,79 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AggregateRatings mocks base method.
+func (m *MockRepository) AggregateRatings(ctx context.Context, pipeline mongo.Pipeline) ([]*room.RatingStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AggregateRatings", ctx, pipeline)
+	ret0, _ := ret[0].([]*room.RatingStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AggregateRatings indicates an expected call of AggregateRatings.
+func (mr *MockRepositoryMockRecorder) AggregateRatings(ctx, pipeline interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AggregateRatings", reflect.TypeOf((*MockRepository)(nil).AggregateRatings), ctx, pipeline)
+}
+
+// CountMessages mocks base method.
+func (m *MockRepository) CountMessages(ctx context.Context, filters bson.M) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountMessages", ctx, filters)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountMessages indicates an expected call of CountMessages.
+func (mr *MockRepositoryMockRecorder) CountMessages(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountMessages", reflect.TypeOf((*MockRepository)(nil).CountMessages), ctx, filters)
+}
+
+// CountRooms mocks base method.
+func (m *MockRepository) CountRooms(ctx context.Context, filters bson.M) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountRooms", ctx, filters)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountRooms indicates an expected call of CountRooms.
+func (mr *MockRepositoryMockRecorder) CountRooms(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountRooms", reflect.TypeOf((*MockRepository)(nil).CountRooms), ctx, filters)
+}
+
+// CreateMessage mocks base method.
+func (m *MockRepository) CreateMessage(ctx context.Context, message *room.RoomMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMessage", ctx, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateMessage indicates an expected call of CreateMessage.
+func (mr *MockRepositoryMockRecorder) CreateMessage(ctx, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMessage", reflect.TypeOf((*MockRepository)(nil).CreateMessage), ctx, message)
+}
+
+// CreateRating mocks base method.
+func (m *MockRepository) CreateRating(ctx context.Context, rating *room.Rating) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRating", ctx, rating)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRating indicates an expected call of CreateRating.
+func (mr *MockRepositoryMockRecorder) CreateRating(ctx, rating interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRating", reflect.TypeOf((*MockRepository)(nil).CreateRating), ctx, rating)
+}
+
 // CreateRoom mocks base method.
 func (m *MockRepository) CreateRoom(ctx context.Context, room *room.Model) (string, error) {
 	m.ctrl.T.Helper()
@@ -65,6 +140,66 @@ func (mr *MockRepositoryMockRecorder) DeleteRoom(ctx, name interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoom", reflect.TypeOf((*MockRepository)(nil).DeleteRoom), ctx, name)
 }
 
+// FindAndUpdateMessage mocks base method.
+func (m *MockRepository) FindAndUpdateMessage(ctx context.Context, filters, update bson.M) (*room.RoomMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAndUpdateMessage", ctx, filters, update)
+	ret0, _ := ret[0].(*room.RoomMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAndUpdateMessage indicates an expected call of FindAndUpdateMessage.
+func (mr *MockRepositoryMockRecorder) FindAndUpdateMessage(ctx, filters, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAndUpdateMessage", reflect.TypeOf((*MockRepository)(nil).FindAndUpdateMessage), ctx, filters, update)
+}
+
+// FindAndUpdateRoom mocks base method.
+func (m *MockRepository) FindAndUpdateRoom(ctx context.Context, filters, update bson.M, opts *options.FindOneAndUpdateOptions) (*room.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAndUpdateRoom", ctx, filters, update, opts)
+	ret0, _ := ret[0].(*room.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAndUpdateRoom indicates an expected call of FindAndUpdateRoom.
+func (mr *MockRepositoryMockRecorder) FindAndUpdateRoom(ctx, filters, update, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAndUpdateRoom", reflect.TypeOf((*MockRepository)(nil).FindAndUpdateRoom), ctx, filters, update, opts)
+}
+
+// GetMessage mocks base method.
+func (m *MockRepository) GetMessage(ctx context.Context, filters bson.M) (*room.RoomMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessage", ctx, filters)
+	ret0, _ := ret[0].(*room.RoomMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessage indicates an expected call of GetMessage.
+func (mr *MockRepositoryMockRecorder) GetMessage(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessage", reflect.TypeOf((*MockRepository)(nil).GetMessage), ctx, filters)
+}
+
+// GetMessages mocks base method.
+func (m *MockRepository) GetMessages(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*room.RoomMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessages", ctx, filters, opts)
+	ret0, _ := ret[0].([]*room.RoomMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMessages indicates an expected call of GetMessages.
+func (mr *MockRepositoryMockRecorder) GetMessages(ctx, filters, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessages", reflect.TypeOf((*MockRepository)(nil).GetMessages), ctx, filters, opts)
+}
+
 // GetRoom mocks base method.
 func (m *MockRepository) GetRoom(ctx context.Context, filters bson.M) (*room.Model, error) {
 	m.ctrl.T.Helper()
@@ -80,6 +215,21 @@ func (mr *MockRepositoryMockRecorder) GetRoom(ctx, filters interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoom", reflect.TypeOf((*MockRepository)(nil).GetRoom), ctx, filters)
 }
 
+// GetRooms mocks base method.
+func (m *MockRepository) GetRooms(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*room.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRooms", ctx, filters, opts)
+	ret0, _ := ret[0].([]*room.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRooms indicates an expected call of GetRooms.
+func (mr *MockRepositoryMockRecorder) GetRooms(ctx, filters, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRooms", reflect.TypeOf((*MockRepository)(nil).GetRooms), ctx, filters, opts)
+}
+
 // UpdateRoom mocks base method.
 func (m *MockRepository) UpdateRoom(ctx context.Context, model *room.Model) error {
 	m.ctrl.T.Helper()
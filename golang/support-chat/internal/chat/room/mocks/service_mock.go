@@ -6,9 +6,11 @@ package mock_room
 
 import (
 	context "context"
+	io "io"
+	reflect "reflect"
 	room "support-chat/internal/chat/room"
 	user "support-chat/internal/user"
-	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,6 +38,80 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// ActivateRoom mocks base method.
+func (m *MockService) ActivateRoom(ctx context.Context, name, agentId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActivateRoom", ctx, name, agentId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ActivateRoom indicates an expected call of ActivateRoom.
+func (mr *MockServiceMockRecorder) ActivateRoom(ctx, name, agentId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActivateRoom", reflect.TypeOf((*MockService)(nil).ActivateRoom), ctx, name, agentId)
+}
+
+// AddMessage mocks base method.
+func (m *MockService) AddMessage(ctx context.Context, roomName string, message *room.RoomMessage) (*room.RoomMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMessage", ctx, roomName, message)
+	ret0, _ := ret[0].(*room.RoomMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMessage indicates an expected call of AddMessage.
+func (mr *MockServiceMockRecorder) AddMessage(ctx, roomName, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMessage", reflect.TypeOf((*MockService)(nil).AddMessage), ctx, roomName, message)
+}
+
+// AssignNextRoom mocks base method.
+func (m *MockService) AssignNextRoom(ctx context.Context, agent *user.DTO) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignNextRoom", ctx, agent)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignNextRoom indicates an expected call of AssignNextRoom.
+func (mr *MockServiceMockRecorder) AssignNextRoom(ctx, agent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignNextRoom", reflect.TypeOf((*MockService)(nil).AssignNextRoom), ctx, agent)
+}
+
+// ClaimRoom mocks base method.
+func (m *MockService) ClaimRoom(ctx context.Context, name string, agent *user.DTO) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimRoom", ctx, name, agent)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimRoom indicates an expected call of ClaimRoom.
+func (mr *MockServiceMockRecorder) ClaimRoom(ctx, name, agent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimRoom", reflect.TypeOf((*MockService)(nil).ClaimRoom), ctx, name, agent)
+}
+
+// CloseRoom mocks base method.
+func (m *MockService) CloseRoom(ctx context.Context, name, closedBy, reason string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseRoom", ctx, name, closedBy, reason)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloseRoom indicates an expected call of CloseRoom.
+func (mr *MockServiceMockRecorder) CloseRoom(ctx, name, closedBy, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseRoom", reflect.TypeOf((*MockService)(nil).CloseRoom), ctx, name, closedBy, reason)
+}
+
 // CreateRoom mocks base method.
 func (m *MockService) CreateRoom(ctx context.Context, name string, user *user.DTO) (*room.Room, error) {
 	m.ctrl.T.Helper()
@@ -51,6 +127,21 @@ func (mr *MockServiceMockRecorder) CreateRoom(ctx, name, user interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRoom", reflect.TypeOf((*MockService)(nil).CreateRoom), ctx, name, user)
 }
 
+// DeleteMessage mocks base method.
+func (m *MockService) DeleteMessage(ctx context.Context, roomName, id string, u *user.DTO) (*room.RoomMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessage", ctx, roomName, id, u)
+	ret0, _ := ret[0].(*room.RoomMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMessage indicates an expected call of DeleteMessage.
+func (mr *MockServiceMockRecorder) DeleteMessage(ctx, roomName, id, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockService)(nil).DeleteMessage), ctx, roomName, id, u)
+}
+
 // DeleteRoom mocks base method.
 func (m *MockService) DeleteRoom(ctx context.Context, name string) error {
 	m.ctrl.T.Helper()
@@ -65,6 +156,109 @@ func (mr *MockServiceMockRecorder) DeleteRoom(ctx, name interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRoom", reflect.TypeOf((*MockService)(nil).DeleteRoom), ctx, name)
 }
 
+// EditMessage mocks base method.
+func (m *MockService) EditMessage(ctx context.Context, roomName, id string, editor *user.DTO, message room.EncryptedMessage) (*room.RoomMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EditMessage", ctx, roomName, id, editor, message)
+	ret0, _ := ret[0].(*room.RoomMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EditMessage indicates an expected call of EditMessage.
+func (mr *MockServiceMockRecorder) EditMessage(ctx, roomName, id, editor, message interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditMessage", reflect.TypeOf((*MockService)(nil).EditMessage), ctx, roomName, id, editor, message)
+}
+
+// EstimateWait mocks base method.
+func (m *MockService) EstimateWait(ctx context.Context, position int) time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EstimateWait", ctx, position)
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// EstimateWait indicates an expected call of EstimateWait.
+func (mr *MockServiceMockRecorder) EstimateWait(ctx, position interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimateWait", reflect.TypeOf((*MockService)(nil).EstimateWait), ctx, position)
+}
+
+// ExportTranscript mocks base method.
+func (m *MockService) ExportTranscript(ctx context.Context, w io.Writer, name string, viewer *user.DTO, format room.TranscriptFormat, supervised bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportTranscript", ctx, w, name, viewer, format, supervised)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportTranscript indicates an expected call of ExportTranscript.
+func (mr *MockServiceMockRecorder) ExportTranscript(ctx, w, name, viewer, format, supervised interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportTranscript", reflect.TypeOf((*MockService)(nil).ExportTranscript), ctx, w, name, viewer, format, supervised)
+}
+
+// GetArchivedRoom mocks base method.
+func (m *MockService) GetArchivedRoom(ctx context.Context, name string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchivedRoom", ctx, name)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArchivedRoom indicates an expected call of GetArchivedRoom.
+func (mr *MockServiceMockRecorder) GetArchivedRoom(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchivedRoom", reflect.TypeOf((*MockService)(nil).GetArchivedRoom), ctx, name)
+}
+
+// GetArchivedRooms mocks base method.
+func (m *MockService) GetArchivedRooms(ctx context.Context, page *room.ArchivePage) ([]*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchivedRooms", ctx, page)
+	ret0, _ := ret[0].([]*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArchivedRooms indicates an expected call of GetArchivedRooms.
+func (mr *MockServiceMockRecorder) GetArchivedRooms(ctx, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchivedRooms", reflect.TypeOf((*MockService)(nil).GetArchivedRooms), ctx, page)
+}
+
+// GetQueuePosition mocks base method.
+func (m *MockService) GetQueuePosition(ctx context.Context, name string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQueuePosition", ctx, name)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQueuePosition indicates an expected call of GetQueuePosition.
+func (mr *MockServiceMockRecorder) GetQueuePosition(ctx, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueuePosition", reflect.TypeOf((*MockService)(nil).GetQueuePosition), ctx, name)
+}
+
+// GetRatingStats mocks base method.
+func (m *MockService) GetRatingStats(ctx context.Context, query *room.RatingQuery) ([]*room.RatingStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRatingStats", ctx, query)
+	ret0, _ := ret[0].([]*room.RatingStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRatingStats indicates an expected call of GetRatingStats.
+func (mr *MockServiceMockRecorder) GetRatingStats(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRatingStats", reflect.TypeOf((*MockService)(nil).GetRatingStats), ctx, query)
+}
+
 // GetRoomByName mocks base method.
 func (m *MockService) GetRoomByName(ctx context.Context, name string) (*room.DTO, error) {
 	m.ctrl.T.Helper()
@@ -81,18 +275,210 @@ func (mr *MockServiceMockRecorder) GetRoomByName(ctx, name interface{}) *gomock.
 }
 
 // GetRoomWithFormatMessages mocks base method.
-func (m *MockService) GetRoomWithFormatMessages(ctx context.Context, name, userId string) ([]*room.FormatMessages, error) {
+func (m *MockService) GetRoomWithFormatMessages(ctx context.Context, name, userId string, page *room.Page) (*room.MessagesPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRoomWithFormatMessages", ctx, name, userId)
-	ret0, _ := ret[0].([]*room.FormatMessages)
+	ret := m.ctrl.Call(m, "GetRoomWithFormatMessages", ctx, name, userId, page)
+	ret0, _ := ret[0].(*room.MessagesPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetRoomWithFormatMessages indicates an expected call of GetRoomWithFormatMessages.
-func (mr *MockServiceMockRecorder) GetRoomWithFormatMessages(ctx, name, userId interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetRoomWithFormatMessages(ctx, name, userId, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoomWithFormatMessages", reflect.TypeOf((*MockService)(nil).GetRoomWithFormatMessages), ctx, name, userId, page)
+}
+
+// GetUnreadCount mocks base method.
+func (m *MockService) GetUnreadCount(ctx context.Context, name, userId string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUnreadCount", ctx, name, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUnreadCount indicates an expected call of GetUnreadCount.
+func (mr *MockServiceMockRecorder) GetUnreadCount(ctx, name, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUnreadCount", reflect.TypeOf((*MockService)(nil).GetUnreadCount), ctx, name, userId)
+}
+
+// GetWaitingRooms mocks base method.
+func (m *MockService) GetWaitingRooms(ctx context.Context) ([]*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWaitingRooms", ctx)
+	ret0, _ := ret[0].([]*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWaitingRooms indicates an expected call of GetWaitingRooms.
+func (mr *MockServiceMockRecorder) GetWaitingRooms(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWaitingRooms", reflect.TypeOf((*MockService)(nil).GetWaitingRooms), ctx)
+}
+
+// InviteToRoom mocks base method.
+func (m *MockService) InviteToRoom(ctx context.Context, name string, by *user.DTO, agentId string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InviteToRoom", ctx, name, by, agentId)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InviteToRoom indicates an expected call of InviteToRoom.
+func (mr *MockServiceMockRecorder) InviteToRoom(ctx, name, by, agentId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InviteToRoom", reflect.TypeOf((*MockService)(nil).InviteToRoom), ctx, name, by, agentId)
+}
+
+// LeaveRoom mocks base method.
+func (m *MockService) LeaveRoom(ctx context.Context, name string, u *user.DTO) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaveRoom", ctx, name, u)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LeaveRoom indicates an expected call of LeaveRoom.
+func (mr *MockServiceMockRecorder) LeaveRoom(ctx, name, u interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveRoom", reflect.TypeOf((*MockService)(nil).LeaveRoom), ctx, name, u)
+}
+
+// MarkDelivered mocks base method.
+func (m *MockService) MarkDelivered(ctx context.Context, name, userId, messageId string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDelivered", ctx, name, userId, messageId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkDelivered indicates an expected call of MarkDelivered.
+func (mr *MockServiceMockRecorder) MarkDelivered(ctx, name, userId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDelivered", reflect.TypeOf((*MockService)(nil).MarkDelivered), ctx, name, userId, messageId)
+}
+
+// MarkRead mocks base method.
+func (m *MockService) MarkRead(ctx context.Context, name, userId, messageId string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRead", ctx, name, userId, messageId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRead indicates an expected call of MarkRead.
+func (mr *MockServiceMockRecorder) MarkRead(ctx, name, userId, messageId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRead", reflect.TypeOf((*MockService)(nil).MarkRead), ctx, name, userId, messageId)
+}
+
+// ObserveRoom mocks base method.
+func (m *MockService) ObserveRoom(ctx context.Context, name string, supervisor *user.DTO) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ObserveRoom", ctx, name, supervisor)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ObserveRoom indicates an expected call of ObserveRoom.
+func (mr *MockServiceMockRecorder) ObserveRoom(ctx, name, supervisor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveRoom", reflect.TypeOf((*MockService)(nil).ObserveRoom), ctx, name, supervisor)
+}
+
+// PurgeArchive mocks base method.
+func (m *MockService) PurgeArchive(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeArchive", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeArchive indicates an expected call of PurgeArchive.
+func (mr *MockServiceMockRecorder) PurgeArchive(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeArchive", reflect.TypeOf((*MockService)(nil).PurgeArchive), ctx)
+}
+
+// RateRoom mocks base method.
+func (m *MockService) RateRoom(ctx context.Context, name string, customer *user.DTO, score int, comment string) (*room.RatingDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RateRoom", ctx, name, customer, score, comment)
+	ret0, _ := ret[0].(*room.RatingDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RateRoom indicates an expected call of RateRoom.
+func (mr *MockServiceMockRecorder) RateRoom(ctx, name, customer, score, comment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RateRoom", reflect.TypeOf((*MockService)(nil).RateRoom), ctx, name, customer, score, comment)
+}
+
+// RequeueExpiredClaims mocks base method.
+func (m *MockService) RequeueExpiredClaims(ctx context.Context) ([]*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueExpiredClaims", ctx)
+	ret0, _ := ret[0].([]*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequeueExpiredClaims indicates an expected call of RequeueExpiredClaims.
+func (mr *MockServiceMockRecorder) RequeueExpiredClaims(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueExpiredClaims", reflect.TypeOf((*MockService)(nil).RequeueExpiredClaims), ctx)
+}
+
+// RunArchivePurger mocks base method.
+func (m *MockService) RunArchivePurger(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunArchivePurger", ctx)
+}
+
+// RunArchivePurger indicates an expected call of RunArchivePurger.
+func (mr *MockServiceMockRecorder) RunArchivePurger(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunArchivePurger", reflect.TypeOf((*MockService)(nil).RunArchivePurger), ctx)
+}
+
+// TransferRoom mocks base method.
+func (m *MockService) TransferRoom(ctx context.Context, name string, from *user.DTO, toAgentId, note string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferRoom", ctx, name, from, toAgentId, note)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferRoom indicates an expected call of TransferRoom.
+func (mr *MockServiceMockRecorder) TransferRoom(ctx, name, from, toAgentId, note interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferRoom", reflect.TypeOf((*MockService)(nil).TransferRoom), ctx, name, from, toAgentId, note)
+}
+
+// UnobserveRoom mocks base method.
+func (m *MockService) UnobserveRoom(ctx context.Context, name, supervisorId string) (*room.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnobserveRoom", ctx, name, supervisorId)
+	ret0, _ := ret[0].(*room.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnobserveRoom indicates an expected call of UnobserveRoom.
+func (mr *MockServiceMockRecorder) UnobserveRoom(ctx, name, supervisorId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoomWithFormatMessages", reflect.TypeOf((*MockService)(nil).GetRoomWithFormatMessages), ctx, name, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnobserveRoom", reflect.TypeOf((*MockService)(nil).UnobserveRoom), ctx, name, supervisorId)
 }
 
 // UpdateRoom mocks base method.
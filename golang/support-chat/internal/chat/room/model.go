@@ -1,11 +1,142 @@
 package room
 
 import (
+	"bytes"
+	"sort"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type State string
+
+const (
+	StateWaiting State = "waiting"
+	StateClaimed State = "claimed"
+	StateActive  State = "active"
+	StateClosed  State = "closed"
+)
+
+const (
+	CloseReasonCustomerEnded = "customer_ended"
+	CloseReasonAgentsLeft    = "agents_left"
+)
+
+type Receipt string
+
+const (
+	ReceiptSent      Receipt = "sent"
+	ReceiptDelivered Receipt = "delivered"
+	ReceiptRead      Receipt = "read"
+)
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleAgent    Role = "agent"
+	// RoleSupervisor is a supervisor invited to help the agents of the room
+	RoleSupervisor Role = "supervisor"
+	// RoleObserver is a supervisor watching the room, the customer doesn't
+	// see observers on the roster
+	RoleObserver Role = "observer"
+)
+
 type Model struct {
-	ID       primitive.ObjectID `bson:"_id"`
-	Name     string             `bson:"name"`
-	Messages *[]*RoomMessage    `bson:"messages"`
+	ID             primitive.ObjectID      `bson:"_id"`
+	Name           string                  `bson:"name"`
+	CustomerId     string                  `bson:"customerId"`
+	AgentId        string                  `bson:"agentId"`
+	State          State                   `bson:"state"`
+	QueuedAt       *time.Time              `bson:"queuedAt"`
+	AssignedAt     *time.Time              `bson:"assignedAt"`
+	LeaseExpiresAt *time.Time              `bson:"leaseExpiresAt"`
+	ClosedAt       *time.Time              `bson:"closedAt"`
+	ClosedBy       string                  `bson:"closedBy"`
+	CloseReason    string                  `bson:"closeReason"`
+	Transfers      []*Transfer             `bson:"transfers"`
+	Participants   map[string]*Participant `bson:"participants"`
+	// DeliveredCursors and ReadCursors hold, per participant, the newest
+	// message that reached them and the newest one they have read
+	DeliveredCursors map[string]primitive.ObjectID `bson:"deliveredCursors"`
+	ReadCursors      map[string]primitive.ObjectID `bson:"readCursors"`
+}
+
+// Transfer records a hand-over of the room. An empty To means the room was
+// sent back to the queue. Note is internal and never shown to the customer.
+type Transfer struct {
+	From string    `bson:"from" json:"from"`
+	To   string    `bson:"to" json:"to,omitempty"`
+	Note string    `bson:"note" json:"note,omitempty"`
+	Time time.Time `bson:"time" json:"time"`
+}
+
+// Agent reports whether the participant serves the customer, the agents and
+// the supervisors invited to help them do.
+func (r Role) Agent() bool {
+	return r == RoleAgent || r == RoleSupervisor
+}
+
+// Participant is an entry of the room roster. The roster is keyed by user id,
+// so participants can be added and removed in a single update.
+type Participant struct {
+	UserId    string    `bson:"userId" json:"user_id"`
+	Role      Role      `bson:"role" json:"role"`
+	InvitedBy string    `bson:"invitedBy,omitempty" json:"invited_by,omitempty"`
+	JoinedAt  time.Time `bson:"joinedAt" json:"joined_at"`
+}
+
+// Agents returns the ids of every agent on the roster, longest present first.
+func (m *Model) Agents() []string {
+	var agents []*Participant
+	for _, p := range m.Participants {
+		if p.Role.Agent() {
+			agents = append(agents, p)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		return agents[i].JoinedAt.Before(agents[j].JoinedAt)
+	})
+
+	ids := make([]string, 0, len(agents))
+	for _, p := range agents {
+		ids = append(ids, p.UserId)
+	}
+
+	return ids
+}
+
+// Receipt tells how far the message got with the participants other than its
+// author. Whispers only count the agents, observers never count, so their
+// reading doesn't give them away to the customer.
+func (m *Model) Receipt(message *RoomMessage) Receipt {
+	receipt := ReceiptSent
+
+	for id, p := range m.Participants {
+		if id == message.Id || p.Role == RoleObserver || (message.AgentsOnly && !p.Role.Agent()) {
+			continue
+		}
+
+		if cursor, ok := m.ReadCursors[id]; ok && bytes.Compare(cursor[:], message.ID[:]) >= 0 {
+			return ReceiptRead
+		}
+		if cursor, ok := m.DeliveredCursors[id]; ok && bytes.Compare(cursor[:], message.ID[:]) >= 0 {
+			receipt = ReceiptDelivered
+		}
+	}
+
+	return receipt
+}
+
+// CustomerRoster returns the roster without the observers, as it is shown to
+// the customer.
+func CustomerRoster(participants []*Participant) []*Participant {
+	roster := make([]*Participant, 0, len(participants))
+	for _, p := range participants {
+		if p.Role != RoleObserver {
+			roster = append(roster, p)
+		}
+	}
+
+	return roster
 }
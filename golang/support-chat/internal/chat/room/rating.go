@@ -0,0 +1,72 @@
+package room
+
+import "time"
+
+const (
+	minRatingScore   = 1
+	maxRatingScore   = 5
+	maxRatingComment = 1000
+
+	// satisfiedScore is the lowest score counted as a satisfied customer
+	satisfiedScore = 4
+)
+
+// Rating groupings of the satisfaction stats
+const (
+	RatingGroupOverall = "overall"
+	RatingGroupAgent   = "agent"
+	RatingGroupDay     = "day"
+)
+
+// Rating is a document of the ratings collection. It is keyed by the room, so
+// every conversation can be rated once, and outlives the archived room.
+type Rating struct {
+	RoomName   string    `bson:"_id"`
+	CustomerId string    `bson:"customerId"`
+	AgentId    string    `bson:"agentId"`
+	Score      int       `bson:"score"`
+	Comment    string    `bson:"comment,omitempty"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+type RatingDTO struct {
+	RoomName   string    `json:"room_name"`
+	CustomerId string    `json:"customer_id"`
+	AgentId    string    `json:"agent_id,omitempty"`
+	Score      int       `json:"score"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type RateDTO struct {
+	Score   int    `json:"score"`
+	Comment string `json:"comment"`
+}
+
+// RatingQuery selects the ratings the stats are computed on.
+type RatingQuery struct {
+	GroupBy string
+	AgentId string
+	From    *time.Time
+	To      *time.Time
+}
+
+// RatingStats aggregates the ratings of a group. Key is the agent id or the
+// day, it is empty for the overall stats.
+type RatingStats struct {
+	Key       string  `json:"key,omitempty" bson:"_id"`
+	Count     int64   `json:"count" bson:"count"`
+	Average   float64 `json:"average" bson:"average"`
+	Satisfied int64   `json:"satisfied" bson:"satisfied"`
+}
+
+func MapRatingToDTO(r *Rating) *RatingDTO {
+	return &RatingDTO{
+		RoomName:   r.RoomName,
+		CustomerId: r.CustomerId,
+		AgentId:    r.AgentId,
+		Score:      r.Score,
+		Comment:    r.Comment,
+		CreatedAt:  r.CreatedAt,
+	}
+}
@@ -6,15 +6,26 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
 //go:generate mockgen -source=repository.go -destination=mocks/repository_mock.go
 type Repository interface {
 	GetRoom(ctx context.Context, filters bson.M) (*Model, error)
+	GetRooms(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*Model, error)
+	CountRooms(ctx context.Context, filters bson.M) (int64, error)
+	FindAndUpdateRoom(ctx context.Context, filters, update bson.M, opts *options.FindOneAndUpdateOptions) (*Model, error)
 	CreateRoom(ctx context.Context, room *Model) (string, error)
 	UpdateRoom(ctx context.Context, model *Model) error
 	DeleteRoom(ctx context.Context, name string) error
+	CreateMessage(ctx context.Context, message *RoomMessage) error
+	GetMessages(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*RoomMessage, error)
+	GetMessage(ctx context.Context, filters bson.M) (*RoomMessage, error)
+	CountMessages(ctx context.Context, filters bson.M) (int64, error)
+	FindAndUpdateMessage(ctx context.Context, filters, update bson.M) (*RoomMessage, error)
+	CreateRating(ctx context.Context, rating *Rating) error
+	AggregateRatings(ctx context.Context, pipeline mongo.Pipeline) ([]*RatingStats, error)
 }
 
 type repository struct {
@@ -53,6 +64,56 @@ func (r *repository) GetRoom(ctx context.Context, filters bson.M) (*Model, error
 	return &room, nil
 }
 
+func (r *repository) GetRooms(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*Model, error) {
+	var rooms []*Model
+
+	cursor, err := r.db.Database(r.dbName).Collection("rooms").Find(ctx, filters, opts)
+	if err != nil {
+		r.logger.Errorf("failed to get rooms: %v", err)
+		return nil, ErrFailedFindRooms
+	}
+
+	if err = cursor.All(ctx, &rooms); err != nil {
+		r.logger.Errorf("failed to get rooms: %v", err)
+		return nil, ErrFailedFindRooms
+	}
+
+	return rooms, nil
+}
+
+func (r *repository) CountRooms(ctx context.Context, filters bson.M) (int64, error) {
+	count, err := r.db.Database(r.dbName).Collection("rooms").CountDocuments(ctx, filters)
+	if err != nil {
+		r.logger.Errorf("failed to count rooms: %v", err)
+		return 0, ErrFailedFindRooms
+	}
+
+	return count, nil
+}
+
+// FindAndUpdateRoom atomically applies update to the first room matching filters
+// and returns the document as it is after the update.
+func (r *repository) FindAndUpdateRoom(ctx context.Context, filters, update bson.M, opts *options.FindOneAndUpdateOptions) (*Model, error) {
+	var room Model
+
+	if opts == nil {
+		opts = options.FindOneAndUpdate()
+	}
+	opts.SetReturnDocument(options.After)
+
+	err := r.db.Database(r.dbName).Collection("rooms").FindOneAndUpdate(ctx, filters, update, opts).Decode(&room)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+
+		r.logger.Errorf("failed to find and update room %v", err)
+		return nil, ErrFailedUpdateRoom
+	}
+
+	return &room, nil
+}
+
 func (r *repository) CreateRoom(ctx context.Context, room *Model) (string, error) {
 	//mod := mongo.IndexModel{
 	//	Keys:    bson.M{"email": 1}, // index in ascending order or -1 for descending order
@@ -98,5 +159,114 @@ func (r *repository) DeleteRoom(ctx context.Context, name string) error {
 		return ErrFailedDeleteRoom
 	}
 
+	_, err = r.db.Database(r.dbName).Collection("messages").DeleteMany(ctx, bson.M{"roomName": name})
+	if err != nil {
+		r.logger.Errorf("failed to delete room messages %v", err)
+		return ErrFailedDeleteRoom
+	}
+
+	return nil
+}
+
+func (r *repository) CreateMessage(ctx context.Context, message *RoomMessage) error {
+	_, err := r.db.Database(r.dbName).Collection("messages").InsertOne(ctx, message)
+	if err != nil {
+		r.logger.Errorf("failed to insert message to db: %v", err)
+		return ErrFailedSaveMessage
+	}
+
+	return nil
+}
+
+func (r *repository) GetMessages(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*RoomMessage, error) {
+	var messages []*RoomMessage
+
+	cursor, err := r.db.Database(r.dbName).Collection("messages").Find(ctx, filters, opts)
+	if err != nil {
+		r.logger.Errorf("failed to get messages: %v", err)
+		return nil, ErrFailedFindMessages
+	}
+
+	if err = cursor.All(ctx, &messages); err != nil {
+		r.logger.Errorf("failed to get messages: %v", err)
+		return nil, ErrFailedFindMessages
+	}
+
+	return messages, nil
+}
+
+func (r *repository) GetMessage(ctx context.Context, filters bson.M) (*RoomMessage, error) {
+	var message RoomMessage
+
+	if err := r.db.Database(r.dbName).Collection("messages").FindOne(ctx, filters).Decode(&message); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMessageNotFound
+		}
+
+		r.logger.Errorf("unable to find message due to internal error: %v", err)
+		return nil, ErrFailedFindMessages
+	}
+
+	return &message, nil
+}
+
+func (r *repository) CountMessages(ctx context.Context, filters bson.M) (int64, error) {
+	count, err := r.db.Database(r.dbName).Collection("messages").CountDocuments(ctx, filters)
+	if err != nil {
+		r.logger.Errorf("failed to count messages: %v", err)
+		return 0, ErrFailedFindMessages
+	}
+
+	return count, nil
+}
+
+// FindAndUpdateMessage atomically applies update to the message matching filters
+// and returns the document as it is after the update.
+func (r *repository) FindAndUpdateMessage(ctx context.Context, filters, update bson.M) (*RoomMessage, error) {
+	var message RoomMessage
+
+	err := r.db.Database(r.dbName).Collection("messages").
+		FindOneAndUpdate(ctx, filters, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMessageNotFound
+		}
+
+		r.logger.Errorf("failed to find and update message %v", err)
+		return nil, ErrFailedUpdateMessage
+	}
+
+	return &message, nil
+}
+
+func (r *repository) CreateRating(ctx context.Context, rating *Rating) error {
+	_, err := r.db.Database(r.dbName).Collection("ratings").InsertOne(ctx, rating)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAlreadyRated
+		}
+
+		r.logger.Errorf("failed to insert rating to db: %v", err)
+		return ErrFailedSaveRating
+	}
+
 	return nil
 }
+
+func (r *repository) AggregateRatings(ctx context.Context, pipeline mongo.Pipeline) ([]*RatingStats, error) {
+	var stats []*RatingStats
+
+	cursor, err := r.db.Database(r.dbName).Collection("ratings").Aggregate(ctx, pipeline)
+	if err != nil {
+		r.logger.Errorf("failed to aggregate ratings: %v", err)
+		return nil, ErrFailedFindRatings
+	}
+
+	if err = cursor.All(ctx, &stats); err != nil {
+		r.logger.Errorf("failed to aggregate ratings: %v", err)
+		return nil, ErrFailedFindRatings
+	}
+
+	return stats, nil
+}
@@ -4,16 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"github.com/go-redis/redis/v8"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
+	"sync"
 )
 
+// agentsChannelSuffix names the broker channel of the room that only agents
+// and observers listen to.
+const agentsChannelSuffix = ":agents"
+
 type Room struct {
 	ID        primitive.ObjectID `bson:"_id"`
 	Name      string             `bson:"name"`
-	Clients   map[*Client]bool
 	Broadcast chan *BroadcastMessage
+
+	// mu guards the clients and the observers, they are changed by the chat
+	// service while the subscriber of the room broadcasts to them
+	mu      sync.RWMutex
+	clients map[*Client]bool
+	// observers get every broadcast of the room, they are on the roster as
+	// observers but can't publish to the customer.
+	observers map[*Client]bool
+
+	// ctx ends when the room is closed, which stops its subscription and
+	// its publisher
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewRoom(name string) (*Room, error) {
@@ -21,54 +37,180 @@ func NewRoom(name string) (*Room, error) {
 		return nil, errors.New("[chat_room] invalid name")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Room{
 		ID:        primitive.NewObjectID(),
 		Name:      name,
-		Clients:   make(map[*Client]bool),
+		clients:   make(map[*Client]bool),
+		observers: make(map[*Client]bool),
 		Broadcast: make(chan *BroadcastMessage),
+		ctx:       ctx,
+		cancel:    cancel,
 	}, nil
 }
 
-func (r *Room) RunRoom(redis *redis.Client) {
-	go r.subscribeToRoomMessages(redis)
+// Send hands the message to the publisher of the room. Messages sent after
+// the room was closed are dropped instead of blocking the sender.
+func (r *Room) Send(message *BroadcastMessage) {
+	select {
+	case r.Broadcast <- message:
+	case <-r.ctx.Done():
+	}
+}
+
+// Close stops the subscription and the publisher of the room and drops its
+// observers. The clients are left to the caller, which tells them first.
+func (r *Room) Close() {
+	r.cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.observers = make(map[*Client]bool)
+}
+
+func (r *Room) AddClient(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[client] = true
+}
+
+func (r *Room) RemoveClient(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.clients, client)
+}
+
+func (r *Room) HasClient(client *Client) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.clients[client]
+}
+
+// Clients returns a snapshot of the clients, the room may change while the
+// caller works with it.
+func (r *Room) Clients() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return snapshot(r.clients)
+}
+
+func (r *Room) AddObserver(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.observers[client] = true
+}
+
+func (r *Room) RemoveObserver(client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.observers, client)
+}
+
+func (r *Room) HasObserver(client *Client) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.observers[client]
+}
+
+// Observes reports whether the user observes the room from any connection.
+func (r *Room) Observes(userId string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for client := range r.observers {
+		if client.Id == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Observers returns a snapshot of the observers.
+func (r *Room) Observers() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return snapshot(r.observers)
+}
+
+func snapshot(clients map[*Client]bool) []*Client {
+	list := make([]*Client, 0, len(clients))
+	for client := range clients {
+		list = append(list, client)
+	}
+
+	return list
+}
+
+// RunRoom publishes the broadcasts of the room until it is closed. The room is
+// subscribed before the first one is published, so its own clients get every
+// broadcast.
+func (r *Room) RunRoom(broker Broker) {
+	ch, err := broker.Subscribe(r.ctx, r.Name, r.Name+agentsChannelSuffix)
+	if err != nil {
+		log.Printf("failed to subscribe to room %v: %v", r.Name, err)
+	} else {
+		go r.forwardRoomMessages(ch)
+	}
 
 	for {
-		//select {
-		//case message := <-r.Broadcast:
-		//	j, err := json.Marshal(message.Message)
-		//	if err != nil {
-		//		log.Println(err)
-		//	}
-		//	r.publishRoomMessage(redis, j, message.RoomName)
-		//}
-		for message := range r.Broadcast {
+		select {
+		case message := <-r.Broadcast:
 			j, err := json.Marshal(message.Message)
 			if err != nil {
 				log.Printf("failed decode broadcast message %v", err)
 			}
-			r.publishRoomMessage(redis, j, message.RoomName)
+
+			channel := message.RoomName
+			if message.AgentsOnly {
+				channel += agentsChannelSuffix
+			}
+			r.publishRoomMessage(broker, j, channel)
+		case <-r.ctx.Done():
+			return
 		}
 	}
 }
 
-func (r *Room) broadcastToClientsInRoom(message []byte) {
-	for client := range r.Clients {
-		client.Send <- message
+func (r *Room) broadcastToClientsInRoom(message []byte, agentsOnly bool) {
+	for _, client := range r.Clients() {
+		if agentsOnly && client.Role != RoleAgent {
+			continue
+		}
+		client.Enqueue(message)
+	}
+
+	for _, client := range r.Observers() {
+		client.Enqueue(message)
 	}
 }
 
-func (r *Room) subscribeToRoomMessages(redis *redis.Client) {
-	pubsub := redis.Subscribe(context.Background(), r.Name)
-
-	ch := pubsub.Channel()
-
-	for msg := range ch {
-		r.broadcastToClientsInRoom([]byte(msg.Payload))
+func (r *Room) forwardRoomMessages(ch <-chan *BrokerMessage) {
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			r.broadcastToClientsInRoom(msg.Payload, msg.Channel != r.Name)
+		case <-r.ctx.Done():
+			return
+		}
 	}
 }
 
-func (r *Room) publishRoomMessage(redis *redis.Client, message []byte, roomName string) {
-	err := redis.Publish(context.Background(), roomName, message).Err()
+func (r *Room) publishRoomMessage(broker Broker, message []byte, roomName string) {
+	err := broker.Publish(context.Background(), roomName, message)
 
 	if err != nil {
 		log.Println(err)
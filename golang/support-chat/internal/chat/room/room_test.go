@@ -1,8 +1,11 @@
 package room_test
 
 import (
+	"context"
 	"support-chat/internal/chat/room"
+	mock_room "support-chat/internal/chat/room/mocks"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -45,3 +48,40 @@ func TestNewRoom(t *testing.T) {
 		})
 	}
 }
+
+func TestRoom_Close(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	broker := mock_room.NewMockBroker(controller)
+
+	subscribed := make(chan context.Context, 1)
+	broker.EXPECT().Subscribe(gomock.Any(), "room", "room:agents").
+		DoAndReturn(func(ctx context.Context, _ ...string) (<-chan *room.BrokerMessage, error) {
+			subscribed <- ctx
+			return make(chan *room.BrokerMessage), nil
+		})
+
+	r, _ := room.NewRoom("room")
+	r.AddObserver(&room.Client{Id: "supervisor"})
+
+	stopped := make(chan struct{})
+	go func() {
+		r.RunRoom(broker)
+		close(stopped)
+	}()
+	ctx := <-subscribed
+
+	r.Close()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("room still runs after close")
+	}
+	assert.NotNil(t, ctx.Err())
+	assert.Empty(t, r.Observers())
+
+	// nothing publishes the broadcast anymore, it must not block
+	r.Send(&room.BroadcastMessage{RoomName: "room"})
+}
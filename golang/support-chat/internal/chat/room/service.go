@@ -3,39 +3,111 @@ package room
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
 	"support-chat/internal/user"
+	"support-chat/pkg/rbac"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/service_mock.go
 type Service interface {
 	GetRoomByName(ctx context.Context, name string) (*DTO, error)
-	GetRoomWithFormatMessages(ctx context.Context, name, userId string) ([]*FormatMessages, error)
+	GetRoomWithFormatMessages(ctx context.Context, name, userId string, page *Page) (*MessagesPage, error)
+	GetUnreadCount(ctx context.Context, name, userId string) (int64, error)
+	MarkDelivered(ctx context.Context, name, userId, messageId string) (bool, error)
+	MarkRead(ctx context.Context, name, userId, messageId string) (bool, error)
+	AddMessage(ctx context.Context, roomName string, message *RoomMessage) (*RoomMessage, error)
+	EditMessage(ctx context.Context, roomName, id string, editor *user.DTO, message EncryptedMessage) (*RoomMessage, error)
+	DeleteMessage(ctx context.Context, roomName, id string, u *user.DTO) (*RoomMessage, error)
 	CreateRoom(ctx context.Context, name string, user *user.DTO) (*Room, error)
 	UpdateRoom(ctx context.Context, dto *DTO) error
 	DeleteRoom(ctx context.Context, name string) error
+	GetWaitingRooms(ctx context.Context) ([]*DTO, error)
+	GetQueuePosition(ctx context.Context, name string) (int, error)
+	EstimateWait(ctx context.Context, position int) time.Duration
+	AssignNextRoom(ctx context.Context, agent *user.DTO) (*DTO, error)
+	ClaimRoom(ctx context.Context, name string, agent *user.DTO) (*DTO, error)
+	ActivateRoom(ctx context.Context, name, agentId string) error
+	RequeueExpiredClaims(ctx context.Context) ([]*DTO, error)
+	TransferRoom(ctx context.Context, name string, from *user.DTO, toAgentId, note string) (*DTO, error)
+	InviteToRoom(ctx context.Context, name string, by *user.DTO, agentId string) (*DTO, error)
+	LeaveRoom(ctx context.Context, name string, u *user.DTO) (*DTO, error)
+	ObserveRoom(ctx context.Context, name string, supervisor *user.DTO) (*DTO, error)
+	UnobserveRoom(ctx context.Context, name, supervisorId string) (*DTO, error)
+	CloseRoom(ctx context.Context, name, closedBy, reason string) (*DTO, error)
+	GetArchivedRooms(ctx context.Context, page *ArchivePage) ([]*DTO, error)
+	GetArchivedRoom(ctx context.Context, name string) (*DTO, error)
+	PurgeArchive(ctx context.Context) (int, error)
+	RunArchivePurger(ctx context.Context)
+	ExportTranscript(ctx context.Context, w io.Writer, name string, viewer *user.DTO, format TranscriptFormat, supervised bool) error
+	RateRoom(ctx context.Context, name string, customer *user.DTO, score int, comment string) (*RatingDTO, error)
+	GetRatingStats(ctx context.Context, query *RatingQuery) ([]*RatingStats, error)
 }
 
+const (
+	defaultMessagesPage = 50
+	maxMessagesPage     = 200
+	defaultArchivePage  = 20
+	maxArchivePage      = 100
+
+	// archivePurgePeriod is how often closed rooms past the retention are purged
+	archivePurgePeriod = time.Hour
+)
+
 type service struct {
-	repository Repository
-	userSvc    user.Service
-	logger     *zap.SugaredLogger
+	repository       Repository
+	userSvc          user.Service
+	policy           *rbac.Policy
+	logger           *zap.SugaredLogger
+	defaultQueueWait time.Duration
+	claimLease       time.Duration
+	editWindow       time.Duration
+	archiveRetention time.Duration
 }
 
-func NewService(repository Repository, userSvc user.Service, logger *zap.SugaredLogger) (Service, error) {
+func NewService(repository Repository, userSvc user.Service, policy *rbac.Policy, logger *zap.SugaredLogger, defaultQueueWait, claimLease, editWindow, archiveRetention *int) (Service, error) {
 	if repository == nil {
 		return nil, errors.New("[chat_room_service] invalid repository")
 	}
 	if userSvc == nil {
 		return nil, errors.New("[chat_room_service] invalid user service")
 	}
+	if policy == nil {
+		return nil, errors.New("[chat_room_service] invalid policy")
+	}
 	if logger == nil {
 		return nil, errors.New("[chat_room_service] invalid logger")
 	}
+	if defaultQueueWait == nil {
+		return nil, errors.New("[chat_room_service] invalid default queue wait")
+	}
+	if claimLease == nil {
+		return nil, errors.New("[chat_room_service] invalid claim lease")
+	}
+	if editWindow == nil {
+		return nil, errors.New("[chat_room_service] invalid edit window")
+	}
+	if archiveRetention == nil {
+		return nil, errors.New("[chat_room_service] invalid archive retention")
+	}
 
-	return &service{repository: repository, userSvc: userSvc, logger: logger}, nil
+	return &service{
+		repository:       repository,
+		userSvc:          userSvc,
+		policy:           policy,
+		logger:           logger,
+		defaultQueueWait: time.Second * time.Duration(*defaultQueueWait),
+		claimLease:       time.Second * time.Duration(*claimLease),
+		editWindow:       time.Second * time.Duration(*editWindow),
+		archiveRetention: 24 * time.Hour * time.Duration(*archiveRetention),
+	}, nil
 }
 
 func (s *service) GetRoomByName(ctx context.Context, name string) (*DTO, error) {
@@ -48,33 +120,285 @@ func (s *service) GetRoomByName(ctx context.Context, name string) (*DTO, error)
 	return MapToDTO(room), nil
 }
 
-func (s *service) GetRoomWithFormatMessages(ctx context.Context, name, userId string) ([]*FormatMessages, error) {
+// GetRoomWithFormatMessages returns a page of the room history in the order
+// the messages were sent, with the receipt of every message and the number of
+// messages the user hasn't read yet. Whispers are left out for the customer.
+func (s *service) GetRoomWithFormatMessages(ctx context.Context, name, userId string, page *Page) (*MessagesPage, error) {
+	if page.Before != "" && page.After != "" {
+		return nil, ErrInvalidCursor
+	}
+
+	limit := page.Limit
+	if limit <= 0 || limit > maxMessagesPage {
+		limit = defaultMessagesPage
+	}
+
 	room, err := s.repository.GetRoom(ctx, bson.M{"name": name})
 	if err != nil {
 		s.logger.Errorf("failed to get room: %v", err)
 		return nil, err
 	}
 
+	filters := visibleMessages(room, userId)
+
+	// ids grow with time, so the latest page is read backwards and reversed
+	order := -1
+	switch {
+	case page.Before != "":
+		before, err := primitive.ObjectIDFromHex(page.Before)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		filters["_id"] = bson.M{"$lt": before}
+	case page.After != "":
+		after, err := primitive.ObjectIDFromHex(page.After)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		filters["_id"] = bson.M{"$gt": after}
+		order = 1
+	}
+
+	messages, err := s.repository.GetMessages(ctx, filters,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: order}}).SetLimit(limit))
+	if err != nil {
+		s.logger.Errorf("failed to get messages: %v", err)
+		return nil, err
+	}
+
+	if order < 0 {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
 	var msg []*FormatMessages
-	if room.Messages != nil {
-		for _, message := range *room.Messages {
-			if message.Id == userId {
-				msg = append(msg, &FormatMessages{
-					To:      message.Id,
-					Message: message.Message,
-					Time:    message.Time,
-				})
-			} else {
-				msg = append(msg, &FormatMessages{
-					From:    message.Id,
-					Message: message.Message,
-					Time:    message.Time,
-				})
-			}
+	for _, message := range messages {
+		formatted := &FormatMessages{
+			Id:         message.ID.Hex(),
+			Message:    message.Message,
+			Time:       message.Time,
+			AgentsOnly: message.AgentsOnly,
+			EditedAt:   message.EditedAt,
+			Deleted:    message.DeletedAt != nil,
+			Status:     room.Receipt(message),
+		}
+
+		if message.Id == userId {
+			formatted.To = message.Id
+		} else {
+			formatted.From = message.Id
+		}
+
+		msg = append(msg, formatted)
+	}
+
+	unread, err := s.countUnread(ctx, room, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MessagesPage{Messages: msg, Unread: unread}, nil
+}
+
+func (s *service) GetUnreadCount(ctx context.Context, name, userId string) (int64, error) {
+	room, err := s.repository.GetRoom(ctx, bson.M{"name": name})
+	if err != nil {
+		s.logger.Errorf("failed to get room: %v", err)
+		return 0, err
+	}
+
+	return s.countUnread(ctx, room, userId)
+}
+
+// MarkDelivered moves the delivered cursor of the user forward to the message,
+// which has to belong to the room. It reports false when the cursor was
+// already there.
+func (s *service) MarkDelivered(ctx context.Context, name, userId, messageId string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(messageId)
+	if err != nil {
+		return false, ErrInvalidId
+	}
+
+	if _, err = s.repository.GetMessage(ctx, bson.M{"_id": id, "roomName": name}); err != nil {
+		return false, err
+	}
+
+	return s.advanceCursor(ctx, "deliveredCursors", name, userId, id)
+}
+
+// MarkRead moves the read cursor of the user forward to the message, which has
+// to belong to the room.
+func (s *service) MarkRead(ctx context.Context, name, userId, messageId string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(messageId)
+	if err != nil {
+		return false, ErrInvalidId
+	}
+
+	if _, err = s.repository.GetMessage(ctx, bson.M{"_id": id, "roomName": name}); err != nil {
+		return false, err
+	}
+
+	return s.advanceCursor(ctx, "readCursors", name, userId, id)
+}
+
+// advanceCursor only ever moves a cursor forward, so receipts arriving out of
+// order can't move it back.
+func (s *service) advanceCursor(ctx context.Context, cursors, name, userId string, id primitive.ObjectID) (bool, error) {
+	field := cursors + "." + userId
+
+	_, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{
+			"name":                   name,
+			"participants." + userId: bson.M{"$exists": true},
+			"$or": bson.A{
+				bson.M{field: bson.M{"$exists": false}},
+				bson.M{field: bson.M{"$lt": id}},
+			},
+		},
+		bson.M{"$set": bson.M{field: id}},
+		nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return false, nil
+		}
+		s.logger.Errorf("failed to move %v: %v", cursors, err)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// countUnread counts the visible messages of others after the read cursor of the user.
+func (s *service) countUnread(ctx context.Context, room *Model, userId string) (int64, error) {
+	filters := visibleMessages(room, userId)
+	filters["id"] = bson.M{"$ne": userId}
+	filters["deletedAt"] = nil
+	if cursor, ok := room.ReadCursors[userId]; ok {
+		filters["_id"] = bson.M{"$gt": cursor}
+	}
+
+	unread, err := s.repository.CountMessages(ctx, filters)
+	if err != nil {
+		s.logger.Errorf("failed to count unread messages: %v", err)
+		return 0, err
+	}
+
+	return unread, nil
+}
+
+// tookPart reports whether the user is the customer of the room, the agent who
+// handled it or on its roster.
+func tookPart(room *Model, userId string) bool {
+	if userId == room.CustomerId || userId == room.AgentId {
+		return true
+	}
+	_, ok := room.Participants[userId]
+
+	return ok
+}
+
+// visibleMessages filters the messages of the room the user may see.
+func visibleMessages(room *Model, userId string) bson.M {
+	filters := bson.M{"roomName": room.Name}
+	if userId == room.CustomerId {
+		filters["agentsOnly"] = bson.M{"$ne": true}
+	}
+
+	return filters
+}
+
+// AddMessage stores the message in the room history and assigns its id.
+func (s *service) AddMessage(ctx context.Context, roomName string, message *RoomMessage) (*RoomMessage, error) {
+	message.ID = primitive.NewObjectID()
+	message.RoomName = roomName
+	if message.Time.IsZero() {
+		message.Time = time.Now()
+	}
+
+	if err := s.repository.CreateMessage(ctx, message); err != nil {
+		s.logger.Errorf("failed to save message: %v", err)
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// EditMessage replaces the content of a message. Only the author can edit,
+// and only within the edit window. The replaced content is kept as a revision.
+func (s *service) EditMessage(ctx context.Context, roomName, id string, editor *user.DTO, message EncryptedMessage) (*RoomMessage, error) {
+	current, err := s.getMessage(ctx, roomName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Id != editor.ID {
+		return nil, ErrNotAuthor
+	}
+	if time.Since(current.Time) > s.editWindow {
+		return nil, ErrEditWindowClosed
+	}
+
+	return s.reviseMessage(ctx, current, editor.ID, bson.M{"message": message, "editedAt": time.Now()})
+}
+
+// DeleteMessage clears the content of a message. Authors can delete within
+// the edit window, agents can redact any message of their rooms at any time.
+// The cleared content is kept as a revision.
+func (s *service) DeleteMessage(ctx context.Context, roomName, id string, u *user.DTO) (*RoomMessage, error) {
+	current, err := s.getMessage(ctx, roomName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.Support {
+		if current.Id != u.ID {
+			return nil, ErrNotAuthor
+		}
+		if time.Since(current.Time) > s.editWindow {
+			return nil, ErrEditWindowClosed
+		}
+	}
+
+	return s.reviseMessage(ctx, current, u.ID, bson.M{"message": EncryptedMessage{}, "deletedAt": time.Now(), "deletedBy": u.ID})
+}
+
+func (s *service) getMessage(ctx context.Context, roomName, id string) (*RoomMessage, error) {
+	messageId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidId
+	}
+
+	message, err := s.repository.GetMessage(ctx, bson.M{"_id": messageId, "roomName": roomName})
+	if err != nil {
+		return nil, err
+	}
+
+	if message.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+
+	return message, nil
+}
+
+// reviseMessage applies set to the message and pushes its current content to
+// the revisions. The update only matches while the message is unchanged, so
+// concurrent edits can't drop a revision.
+func (s *service) reviseMessage(ctx context.Context, current *RoomMessage, by string, set bson.M) (*RoomMessage, error) {
+	revision := &Revision{Message: current.Message, ReplacedAt: time.Now(), ReplacedBy: by}
+
+	message, err := s.repository.FindAndUpdateMessage(ctx,
+		bson.M{"_id": current.ID, "editedAt": current.EditedAt, "deletedAt": nil},
+		bson.M{"$set": set, "$push": bson.M{"revisions": revision}})
+	if err != nil {
+		if err == ErrMessageNotFound {
+			return nil, ErrFailedUpdateMessage
 		}
+		s.logger.Errorf("failed to revise message: %v", err)
+		return nil, err
 	}
 
-	return msg, nil
+	return message, nil
 }
 
 func (s *service) CreateRoom(ctx context.Context, roomName string, u *user.DTO) (*Room, error) {
@@ -84,10 +408,16 @@ func (s *service) CreateRoom(ctx context.Context, roomName string, u *user.DTO)
 		return nil, ErrFailedCreateRoom
 	}
 
+	queuedAt := time.Now()
 	m := &Model{
-		ID:       room.ID,
-		Name:     room.Name,
-		Messages: nil,
+		ID:         room.ID,
+		Name:       room.Name,
+		CustomerId: u.ID,
+		State:      StateWaiting,
+		QueuedAt:   &queuedAt,
+		Participants: map[string]*Participant{
+			u.ID: {UserId: u.ID, Role: RoleCustomer, JoinedAt: queuedAt},
+		},
 	}
 
 	_, err = s.repository.CreateRoom(ctx, m)
@@ -96,13 +426,7 @@ func (s *service) CreateRoom(ctx context.Context, roomName string, u *user.DTO)
 		return nil, err
 	}
 
-	userEntity, _ := user.MapToEntity(u)
-	userEntity.SetRoom(&roomName)
-	userEntity.SetFreeStatus(true)
-
-	userDto := user.MapToDTO(userEntity)
-
-	err = s.userSvc.UpdateUser(ctx, userDto)
+	_, err = s.userSvc.SetRoomName(ctx, u.ID, &roomName)
 	if err != nil {
 		s.logger.Errorf("failed to update user %v", err)
 		return nil, err
@@ -134,3 +458,782 @@ func (s *service) DeleteRoom(ctx context.Context, name string) error {
 	}
 	return nil
 }
+
+func (s *service) GetWaitingRooms(ctx context.Context) ([]*DTO, error) {
+	rooms, err := s.repository.GetRooms(ctx, bson.M{"state": StateWaiting},
+		options.Find().SetSort(bson.D{{Key: "queuedAt", Value: 1}}))
+	if err != nil {
+		s.logger.Errorf("failed to get waiting rooms: %v", err)
+		return nil, err
+	}
+
+	var dtos []*DTO
+	for _, room := range rooms {
+		dtos = append(dtos, MapToDTO(room))
+	}
+
+	return dtos, nil
+}
+
+func (s *service) GetQueuePosition(ctx context.Context, name string) (int, error) {
+	room, err := s.repository.GetRoom(ctx, bson.M{"name": name})
+	if err != nil {
+		s.logger.Errorf("failed to get room: %v", err)
+		return 0, err
+	}
+
+	if room.State != StateWaiting || room.QueuedAt == nil {
+		return 0, ErrNotInQueue
+	}
+
+	ahead, err := s.repository.CountRooms(ctx, bson.M{"state": StateWaiting, "queuedAt": bson.M{"$lt": room.QueuedAt}})
+	if err != nil {
+		s.logger.Errorf("failed to count waiting rooms: %v", err)
+		return 0, err
+	}
+
+	return int(ahead) + 1, nil
+}
+
+// EstimateWait multiplies the queue position by the average interval between
+// the latest assignments. Without enough history the configured default is used.
+func (s *service) EstimateWait(ctx context.Context, position int) time.Duration {
+	interval := s.defaultQueueWait
+
+	rooms, err := s.repository.GetRooms(ctx, bson.M{"assignedAt": bson.M{"$ne": nil}},
+		options.Find().SetSort(bson.D{{Key: "assignedAt", Value: -1}}).SetLimit(20))
+	if err != nil {
+		s.logger.Errorf("failed to get assigned rooms: %v", err)
+	}
+
+	if len(rooms) > 1 {
+		newest, oldest := rooms[0].AssignedAt, rooms[len(rooms)-1].AssignedAt
+		interval = newest.Sub(*oldest) / time.Duration(len(rooms)-1)
+	}
+
+	return interval * time.Duration(position)
+}
+
+// AssignNextRoom hands the longest waiting room to the agent. The room is
+// taken with a single find-and-modify so it can't be assigned twice.
+func (s *service) AssignNextRoom(ctx context.Context, agent *user.DTO) (*DTO, error) {
+	if !agent.Support {
+		return nil, ErrNotSupport
+	}
+	if !s.userSvc.HasCapacity(agent) {
+		return nil, user.ErrNoCapacity
+	}
+
+	assignedAt := time.Now()
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{"state": StateWaiting},
+		bson.M{"$set": bson.M{
+			"state":                    StateActive,
+			"agentId":                  agent.ID,
+			"assignedAt":               assignedAt,
+			"participants." + agent.ID: &Participant{UserId: agent.ID, Role: RoleAgent, JoinedAt: assignedAt},
+		}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "queuedAt", Value: 1}}))
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrQueueEmpty
+		}
+
+		s.logger.Errorf("failed to assign room: %v", err)
+		return nil, err
+	}
+
+	if err = s.bindParticipants(ctx, room, agent); err != nil {
+		// give the room back so the customer keeps their place
+		_, rErr := s.repository.FindAndUpdateRoom(ctx,
+			bson.M{"name": room.Name, "state": StateActive, "agentId": agent.ID},
+			bson.M{
+				"$set":   bson.M{"state": StateWaiting, "agentId": "", "assignedAt": nil},
+				"$unset": bson.M{"participants." + agent.ID: ""},
+			}, nil)
+		if rErr != nil {
+			s.logger.Errorf("failed to return room to the queue: %v", rErr)
+		}
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+// ClaimRoom reserves a waiting room for the agent until the lease expires.
+// The agent has to join the room over the websocket before that, otherwise
+// RequeueExpiredClaims puts the customer back in the queue.
+func (s *service) ClaimRoom(ctx context.Context, name string, agent *user.DTO) (*DTO, error) {
+	if !agent.Support {
+		return nil, ErrNotSupport
+	}
+	if !s.userSvc.HasCapacity(agent) {
+		return nil, user.ErrNoCapacity
+	}
+
+	now := time.Now()
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{"name": name, "state": StateWaiting},
+		bson.M{"$set": bson.M{
+			"state":                    StateClaimed,
+			"agentId":                  agent.ID,
+			"leaseExpiresAt":           now.Add(s.claimLease),
+			"participants." + agent.ID: &Participant{UserId: agent.ID, Role: RoleAgent, JoinedAt: now},
+		}}, nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrAlreadyClaimed
+		}
+
+		s.logger.Errorf("failed to claim room: %v", err)
+		return nil, err
+	}
+
+	if err = s.bindParticipants(ctx, room, agent); err != nil {
+		_, rErr := s.repository.FindAndUpdateRoom(ctx,
+			bson.M{"name": room.Name, "state": StateClaimed, "agentId": agent.ID},
+			bson.M{
+				"$set":   bson.M{"state": StateWaiting, "agentId": "", "leaseExpiresAt": nil},
+				"$unset": bson.M{"participants." + agent.ID: ""},
+			}, nil)
+		if rErr != nil {
+			s.logger.Errorf("failed to return room to the queue: %v", rErr)
+		}
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+func (s *service) ActivateRoom(ctx context.Context, name, agentId string) error {
+	_, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{"name": name, "state": StateClaimed, "agentId": agentId},
+		bson.M{"$set": bson.M{"state": StateActive, "assignedAt": time.Now(), "leaseExpiresAt": nil}},
+		nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RequeueExpiredClaims returns every room whose lease ran out to the queue.
+// The rooms keep their original queuedAt, so customers don't lose their place.
+func (s *service) RequeueExpiredClaims(ctx context.Context) ([]*DTO, error) {
+	expired, err := s.repository.GetRooms(ctx,
+		bson.M{"state": StateClaimed, "leaseExpiresAt": bson.M{"$lt": time.Now()}}, nil)
+	if err != nil {
+		s.logger.Errorf("failed to get expired claims: %v", err)
+		return nil, err
+	}
+
+	var requeued []*DTO
+	for _, claim := range expired {
+		// the agent id is part of the filter, so a claim renewed meanwhile is left alone
+		room, err := s.repository.FindAndUpdateRoom(ctx,
+			bson.M{"name": claim.Name, "state": StateClaimed, "agentId": claim.AgentId, "leaseExpiresAt": bson.M{"$lt": time.Now()}},
+			bson.M{
+				"$set":   bson.M{"state": StateWaiting, "agentId": "", "leaseExpiresAt": nil},
+				"$unset": bson.M{"participants." + claim.AgentId: ""},
+			}, nil)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+
+			s.logger.Errorf("failed to requeue room: %v", err)
+			return requeued, err
+		}
+
+		requeued = append(requeued, MapToDTO(room))
+		s.releaseParticipants(ctx, room)
+	}
+
+	return requeued, nil
+}
+
+// TransferRoom hands an active room from one agent to another, or back to the
+// queue when toAgentId is empty. The room keeps its original queuedAt, so a
+// customer sent back to the queue is served before later arrivals.
+func (s *service) TransferRoom(ctx context.Context, name string, from *user.DTO, toAgentId, note string) (*DTO, error) {
+	if !from.Support {
+		return nil, ErrNotSupport
+	}
+	if toAgentId == from.ID {
+		return nil, ErrInvalidUserId
+	}
+
+	current, err := s.repository.GetRoom(ctx, bson.M{"name": name})
+	if err != nil {
+		s.logger.Errorf("failed to get room: %v", err)
+		return nil, err
+	}
+
+	transfer := &Transfer{From: from.ID, To: toAgentId, Note: note, Time: time.Now()}
+	filters := bson.M{"name": name, "state": StateActive, "agentId": from.ID}
+
+	if toAgentId == "" {
+		// every agent leaves the roster, the next one comes from the queue
+		leaving := bson.M{}
+		for _, agentId := range current.Agents() {
+			leaving["participants."+agentId] = ""
+		}
+
+		update := bson.M{
+			"$set":  bson.M{"state": StateWaiting, "agentId": "", "assignedAt": nil},
+			"$push": bson.M{"transfers": transfer},
+		}
+		if len(leaving) > 0 {
+			update["$unset"] = leaving
+		}
+
+		room, err := s.repository.FindAndUpdateRoom(ctx, filters, update, nil)
+		if err != nil {
+			if err == ErrNotFound {
+				return nil, ErrNotParticipant
+			}
+			s.logger.Errorf("failed to transfer room: %v", err)
+			return nil, err
+		}
+
+		s.releaseParticipants(ctx, room)
+		return MapToDTO(room), nil
+	}
+
+	to, err := s.userSvc.GetUserById(ctx, toAgentId, false)
+	if err != nil {
+		s.logger.Errorf("failed to get agent: %v", err)
+		return nil, err
+	}
+	if !to.Support {
+		return nil, ErrNotSupport
+	}
+	if _, ok := current.Participants[to.ID]; ok {
+		return nil, ErrAlreadyInRoom
+	}
+
+	err = s.userSvc.AddRoom(ctx, to, name)
+	if err != nil {
+		s.logger.Errorf("failed to add room to agent: %v", err)
+		return nil, err
+	}
+
+	room, err := s.repository.FindAndUpdateRoom(ctx, filters,
+		bson.M{
+			"$set": bson.M{
+				"agentId":               to.ID,
+				"participants." + to.ID: &Participant{UserId: to.ID, Role: RoleAgent, JoinedAt: transfer.Time},
+			},
+			"$unset": bson.M{"participants." + from.ID: ""},
+			"$push":  bson.M{"transfers": transfer},
+		}, nil)
+	if err != nil {
+		if rErr := s.userSvc.RemoveRoom(ctx, to.ID, name); rErr != nil {
+			s.logger.Errorf("failed to remove room from agent: %v", rErr)
+		}
+		if err == ErrNotFound {
+			return nil, ErrNotParticipant
+		}
+		s.logger.Errorf("failed to transfer room: %v", err)
+		return nil, err
+	}
+
+	err = s.userSvc.RemoveRoom(ctx, from.ID, name)
+	if err != nil {
+		s.logger.Errorf("failed to remove room from agent: %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+// InviteToRoom adds a second agent or a supervisor to an active room. Only an
+// agent on the roster can invite, and the room counts towards the invited
+// agent's capacity like any other. The roster is updated first, so a failed
+// invitation only takes back the entry it added.
+func (s *service) InviteToRoom(ctx context.Context, name string, by *user.DTO, agentId string) (*DTO, error) {
+	if !by.Support {
+		return nil, ErrNotSupport
+	}
+
+	invited, err := s.userSvc.GetUserById(ctx, agentId, false)
+	if err != nil {
+		s.logger.Errorf("failed to get agent: %v", err)
+		return nil, err
+	}
+	if !invited.Support {
+		return nil, ErrNotSupport
+	}
+
+	role := RoleAgent
+	if s.policy.Can(invited.Role(), rbac.RoomsSupervise) {
+		role = RoleSupervisor
+	}
+	// mongo keeps milliseconds, the rollback finds the entry by its time
+	joinedAt := time.Now().Truncate(time.Millisecond)
+
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{
+			"name":                       name,
+			"state":                      StateActive,
+			"participants." + by.ID:      bson.M{"$exists": true},
+			"participants." + invited.ID: bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{
+			"participants." + invited.ID: &Participant{UserId: invited.ID, Role: role, InvitedBy: by.ID, JoinedAt: joinedAt},
+		}}, nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrNotParticipant
+		}
+		s.logger.Errorf("failed to invite agent: %v", err)
+		return nil, err
+	}
+
+	err = s.userSvc.AddRoom(ctx, invited, name)
+	if err != nil {
+		s.logger.Errorf("failed to add room to agent: %v", err)
+		_, rErr := s.repository.FindAndUpdateRoom(ctx,
+			bson.M{
+				"name": name,
+				"participants." + invited.ID + ".invitedBy": by.ID,
+				"participants." + invited.ID + ".joinedAt":  joinedAt,
+			},
+			bson.M{"$unset": bson.M{"participants." + invited.ID: ""}}, nil)
+		if rErr != nil {
+			s.logger.Errorf("failed to take back invitation: %v", rErr)
+		}
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+// LeaveRoom takes an agent off the roster. When the agent owned the room the
+// ownership passes to another agent still in it. The returned room has no
+// agents left when the caller should close it.
+func (s *service) LeaveRoom(ctx context.Context, name string, u *user.DTO) (*DTO, error) {
+	if !u.Support {
+		return nil, ErrNotSupport
+	}
+
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{"name": name, "state": bson.M{"$ne": StateClosed}, "participants." + u.ID: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"participants." + u.ID: ""}},
+		nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrNotParticipant
+		}
+		s.logger.Errorf("failed to leave room: %v", err)
+		return nil, err
+	}
+
+	if err = s.userSvc.RemoveRoom(ctx, u.ID, name); err != nil {
+		s.logger.Errorf("failed to remove room from agent: %v", err)
+		return nil, err
+	}
+
+	if agents := room.Agents(); room.AgentId == u.ID && len(agents) > 0 {
+		room, err = s.repository.FindAndUpdateRoom(ctx,
+			bson.M{"name": name, "agentId": u.ID},
+			bson.M{"$set": bson.M{"agentId": agents[0]}},
+			nil)
+		if err != nil {
+			s.logger.Errorf("failed to hand over room: %v", err)
+			return nil, err
+		}
+	}
+
+	return MapToDTO(room), nil
+}
+
+// ObserveRoom puts a supervisor on the roster as observer. Observers don't
+// take the room, it doesn't count towards their capacity.
+func (s *service) ObserveRoom(ctx context.Context, name string, supervisor *user.DTO) (*DTO, error) {
+	if !supervisor.Support || !s.policy.Can(supervisor.Role(), rbac.RoomsSupervise) {
+		return nil, ErrNotSupervisor
+	}
+
+	current, err := s.repository.GetRoom(ctx, bson.M{"name": name})
+	if err != nil {
+		s.logger.Errorf("failed to get room: %v", err)
+		return nil, err
+	}
+	if current.State == StateClosed {
+		return nil, ErrRoomClosed
+	}
+	if _, ok := current.Participants[supervisor.ID]; ok {
+		return nil, ErrAlreadyInRoom
+	}
+
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{
+			"name":                          name,
+			"state":                         bson.M{"$ne": StateClosed},
+			"participants." + supervisor.ID: bson.M{"$exists": false},
+		},
+		bson.M{"$set": bson.M{
+			"participants." + supervisor.ID: &Participant{UserId: supervisor.ID, Role: RoleObserver, JoinedAt: time.Now()},
+		}}, nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrAlreadyInRoom
+		}
+		s.logger.Errorf("failed to observe room: %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+// UnobserveRoom takes the observer off the roster again.
+func (s *service) UnobserveRoom(ctx context.Context, name, supervisorId string) (*DTO, error) {
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{"name": name, "participants." + supervisorId + ".role": RoleObserver},
+		bson.M{"$unset": bson.M{"participants." + supervisorId: ""}},
+		nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrNotParticipant
+		}
+		s.logger.Errorf("failed to unobserve room: %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+// CloseRoom frees every participant of the room and archives it. The
+// conversation stays readable for agents until the retention runs out.
+func (s *service) CloseRoom(ctx context.Context, name, closedBy, reason string) (*DTO, error) {
+	room, err := s.repository.FindAndUpdateRoom(ctx,
+		bson.M{"name": name, "state": bson.M{"$ne": StateClosed}},
+		bson.M{"$set": bson.M{
+			"state":          StateClosed,
+			"closedAt":       time.Now(),
+			"closedBy":       closedBy,
+			"closeReason":    reason,
+			"leaseExpiresAt": nil,
+		}}, nil)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrRoomClosed
+		}
+		s.logger.Errorf("failed to close room: %v", err)
+		return nil, err
+	}
+
+	s.releaseParticipants(ctx, room)
+
+	if _, err = s.userSvc.SetRoomName(ctx, room.CustomerId, nil); err != nil {
+		s.logger.Errorf("failed to update customer %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+func (s *service) GetArchivedRooms(ctx context.Context, page *ArchivePage) ([]*DTO, error) {
+	limit := page.Limit
+	if limit <= 0 || limit > maxArchivePage {
+		limit = defaultArchivePage
+	}
+
+	filters := bson.M{"state": StateClosed}
+	if page.CustomerId != "" {
+		filters["customerId"] = page.CustomerId
+	}
+	if page.Before != nil {
+		filters["closedAt"] = bson.M{"$lt": page.Before}
+	}
+
+	rooms, err := s.repository.GetRooms(ctx, filters,
+		options.Find().SetSort(bson.D{{Key: "closedAt", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		s.logger.Errorf("failed to get archived rooms: %v", err)
+		return nil, err
+	}
+
+	dtos := make([]*DTO, 0, len(rooms))
+	for _, room := range rooms {
+		dtos = append(dtos, MapToDTO(room))
+	}
+
+	return dtos, nil
+}
+
+func (s *service) GetArchivedRoom(ctx context.Context, name string) (*DTO, error) {
+	room, err := s.repository.GetRoom(ctx, bson.M{"name": name, "state": StateClosed})
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrRoomNotClosed
+		}
+		s.logger.Errorf("failed to get room: %v", err)
+		return nil, err
+	}
+
+	return MapToDTO(room), nil
+}
+
+// PurgeArchive deletes closed rooms and their messages once the retention has
+// passed. A retention of zero keeps the archive forever.
+func (s *service) PurgeArchive(ctx context.Context) (int, error) {
+	if s.archiveRetention <= 0 {
+		return 0, nil
+	}
+
+	expired, err := s.repository.GetRooms(ctx,
+		bson.M{"state": StateClosed, "closedAt": bson.M{"$lt": time.Now().Add(-s.archiveRetention)}}, nil)
+	if err != nil {
+		s.logger.Errorf("failed to get expired rooms: %v", err)
+		return 0, err
+	}
+
+	purged := 0
+	for _, room := range expired {
+		if err = s.DeleteRoom(ctx, room.Name); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (s *service) RunArchivePurger(ctx context.Context) {
+	ticker := time.NewTicker(archivePurgePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeArchive(ctx)
+			if err != nil {
+				s.logger.Errorf("failed to purge archive %v", err)
+			}
+			if purged > 0 {
+				s.logger.Infof("purged %v archived rooms", purged)
+			}
+		}
+	}
+}
+
+// ExportTranscript writes the whole history of the room to w in the given
+// format. Messages are read in batches and flushed as they are written, so
+// long conversations are streamed. Participants export their own rooms, the
+// customer without the whispers. A supervised export is of any room and needs
+// the rooms:supervise permission.
+func (s *service) ExportTranscript(ctx context.Context, w io.Writer, name string, viewer *user.DTO, format TranscriptFormat, supervised bool) error {
+	if supervised && (!viewer.Support || !s.policy.Can(viewer.Role(), rbac.RoomsSupervise)) {
+		return ErrNotSupervisor
+	}
+
+	room, err := s.repository.GetRoom(ctx, bson.M{"name": name})
+	if err != nil {
+		s.logger.Errorf("failed to get room: %v", err)
+		return err
+	}
+
+	if !supervised && !tookPart(room, viewer.ID) {
+		return ErrNotParticipant
+	}
+
+	transcript := newTranscriptWriter(format, w)
+	flusher, _ := w.(http.Flusher)
+
+	participants := MapToDTO(room).Participants
+	if !viewer.Support {
+		participants = CustomerRoster(participants)
+	}
+
+	err = transcript.Begin(&TranscriptHeader{
+		RoomName:     room.Name,
+		CustomerId:   room.CustomerId,
+		State:        room.State,
+		QueuedAt:     room.QueuedAt,
+		ClosedAt:     room.ClosedAt,
+		CloseReason:  room.CloseReason,
+		Participants: participants,
+		ExportedAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	filters := visibleMessages(room, viewer.ID)
+	for {
+		messages, err := s.repository.GetMessages(ctx, filters,
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(maxMessagesPage))
+		if err != nil {
+			s.logger.Errorf("failed to get messages: %v", err)
+			return err
+		}
+
+		for _, message := range messages {
+			if err = transcript.Message(mapToTranscriptMessage(room, message)); err != nil {
+				return err
+			}
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(messages) < maxMessagesPage {
+			break
+		}
+		filters["_id"] = bson.M{"$gt": messages[len(messages)-1].ID}
+	}
+
+	return transcript.End()
+}
+
+func mapToTranscriptMessage(room *Model, message *RoomMessage) *TranscriptMessage {
+	transcriptMessage := &TranscriptMessage{
+		Id:         message.ID.Hex(),
+		From:       message.Id,
+		Role:       RoleAgent,
+		Time:       message.Time,
+		EditedAt:   message.EditedAt,
+		Deleted:    message.DeletedAt != nil,
+		AgentsOnly: message.AgentsOnly,
+	}
+
+	if message.Id == room.CustomerId {
+		transcriptMessage.Role = RoleCustomer
+	} else if p, ok := room.Participants[message.Id]; ok {
+		// supervisors and observers still on the roster keep their role
+		transcriptMessage.Role = p.Role
+	}
+	if message.DeletedAt == nil {
+		envelope := message.Message
+		transcriptMessage.Message = &envelope
+	}
+
+	return transcriptMessage
+}
+
+// RateRoom stores the satisfaction score the customer gave a closed
+// conversation, attributed to the agent who owned the room.
+func (s *service) RateRoom(ctx context.Context, name string, customer *user.DTO, score int, comment string) (*RatingDTO, error) {
+	if score < minRatingScore || score > maxRatingScore {
+		return nil, ErrInvalidScore
+	}
+	if len(comment) > maxRatingComment {
+		return nil, ErrInvalidComment
+	}
+
+	room, err := s.repository.GetRoom(ctx, bson.M{"name": name, "state": StateClosed})
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, ErrRoomNotClosed
+		}
+		s.logger.Errorf("failed to get room: %v", err)
+		return nil, err
+	}
+
+	if room.CustomerId != customer.ID {
+		return nil, ErrNotParticipant
+	}
+
+	rating := &Rating{
+		RoomName:   room.Name,
+		CustomerId: room.CustomerId,
+		AgentId:    room.AgentId,
+		Score:      score,
+		Comment:    comment,
+		CreatedAt:  time.Now(),
+	}
+
+	if err = s.repository.CreateRating(ctx, rating); err != nil {
+		if err != ErrAlreadyRated {
+			s.logger.Errorf("failed to save rating: %v", err)
+		}
+		return nil, err
+	}
+
+	return MapRatingToDTO(rating), nil
+}
+
+// GetRatingStats aggregates the ratings overall, per agent or per day.
+func (s *service) GetRatingStats(ctx context.Context, query *RatingQuery) ([]*RatingStats, error) {
+	var key interface{}
+	switch query.GroupBy {
+	case "", RatingGroupOverall:
+		key = nil
+	case RatingGroupAgent:
+		key = "$agentId"
+	case RatingGroupDay:
+		key = bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$createdAt"}}
+	default:
+		return nil, ErrInvalidGroup
+	}
+
+	match := bson.M{}
+	if query.AgentId != "" {
+		match["agentId"] = query.AgentId
+	}
+	if query.From != nil || query.To != nil {
+		createdAt := bson.M{}
+		if query.From != nil {
+			createdAt["$gte"] = *query.From
+		}
+		if query.To != nil {
+			createdAt["$lt"] = *query.To
+		}
+		match["createdAt"] = createdAt
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     key,
+			"count":   bson.M{"$sum": 1},
+			"average": bson.M{"$avg": "$score"},
+			"satisfied": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$gte": bson.A{"$score", satisfiedScore}}, 1, 0},
+			}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	stats, err := s.repository.AggregateRatings(ctx, pipeline)
+	if err != nil {
+		s.logger.Errorf("failed to get rating stats: %v", err)
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// bindParticipants adds the room to the agent's active rooms.
+func (s *service) bindParticipants(ctx context.Context, room *Model, agent *user.DTO) error {
+	err := s.userSvc.AddRoom(ctx, agent, room.Name)
+	if err != nil {
+		s.logger.Errorf("failed to update agent %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// releaseParticipants undoes bindParticipants after a claim was dropped.
+func (s *service) releaseParticipants(ctx context.Context, room *Model) {
+	agents, err := s.userSvc.GetUsersByRoom(ctx, room.Name, false)
+	if err != nil {
+		s.logger.Errorf("failed to get agents of room %v", err)
+	}
+
+	for _, agent := range agents {
+		if !agent.Support {
+			continue
+		}
+
+		if err = s.userSvc.RemoveRoom(ctx, agent.ID, room.Name); err != nil {
+			s.logger.Errorf("failed to update agent %v", err)
+		}
+	}
+}
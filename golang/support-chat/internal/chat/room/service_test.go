@@ -1,43 +1,76 @@
 package room_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
 	"support-chat/internal/chat/room"
 	mock_room "support-chat/internal/chat/room/mocks"
 	"support-chat/internal/user"
 	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/logger"
+	"support-chat/pkg/rbac"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
+var policy, _ = rbac.NewPolicy(rbac.DefaultRoles)
+
 func TestNewService(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
 	tests := []struct {
-		name       string
-		repository room.Repository
-		userSvc    user.Service
-		logger     *zap.SugaredLogger
-		expect     func(*testing.T, room.Service, error)
+		name             string
+		repository       room.Repository
+		userSvc          user.Service
+		policy           *rbac.Policy
+		logger           *zap.SugaredLogger
+		defaultQueueWait *int
+		claimLease       *int
+		editWindow       *int
+		archiveRetention *int
+		expect           func(*testing.T, room.Service, error)
 	}{
 		{
-			name:       "should return service",
-			repository: mock_room.NewMockRepository(controller),
-			userSvc:    mock_user.NewMockService(controller),
-			logger:     &zap.SugaredLogger{},
+			name:             "should return service",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
 			expect: func(t *testing.T, s room.Service, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:       "should return invalid repository",
-			repository: nil,
-			userSvc:    mock_user.NewMockService(controller),
-			logger:     &zap.SugaredLogger{},
+			name:             "should return invalid repository",
+			repository:       nil,
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
 			expect: func(t *testing.T, s room.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -45,10 +78,15 @@ func TestNewService(t *testing.T) {
 			},
 		},
 		{
-			name:       "should return invalid user service",
-			repository: mock_room.NewMockRepository(controller),
-			userSvc:    nil,
-			logger:     &zap.SugaredLogger{},
+			name:             "should return invalid user service",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          nil,
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
 			expect: func(t *testing.T, s room.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -56,22 +94,1864 @@ func TestNewService(t *testing.T) {
 			},
 		},
 		{
-			name:       "should return invalid logger",
-			repository: mock_room.NewMockRepository(controller),
-			userSvc:    mock_user.NewMockService(controller),
-			logger:     nil,
+			name:             "should return invalid policy",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           nil,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
+			expect: func(t *testing.T, s room.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_service] invalid policy")
+			},
+		},
+		{
+			name:             "should return invalid logger",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           nil,
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
 			expect: func(t *testing.T, s room.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, "[chat_room_service] invalid logger")
 			},
 		},
+		{
+			name:             "should return invalid default queue wait",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: nil,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
+			expect: func(t *testing.T, s room.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_service] invalid default queue wait")
+			},
+		},
+		{
+			name:             "should return invalid claim lease",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       nil,
+			editWindow:       &editWindow,
+			archiveRetention: &archiveRetention,
+			expect: func(t *testing.T, s room.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_service] invalid claim lease")
+			},
+		},
+		{
+			name:             "should return invalid edit window",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       nil,
+			archiveRetention: &archiveRetention,
+			expect: func(t *testing.T, s room.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_service] invalid edit window")
+			},
+		},
+		{
+			name:             "should return invalid archive retention",
+			repository:       mock_room.NewMockRepository(controller),
+			userSvc:          mock_user.NewMockService(controller),
+			policy:           policy,
+			logger:           &zap.SugaredLogger{},
+			defaultQueueWait: &defaultQueueWait,
+			claimLease:       &claimLease,
+			editWindow:       &editWindow,
+			archiveRetention: nil,
+			expect: func(t *testing.T, s room.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_room_service] invalid archive retention")
+			},
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := room.NewService(tc.repository, tc.userSvc, tc.logger)
+			svc, err := room.NewService(tc.repository, tc.userSvc, tc.policy, tc.logger, tc.defaultQueueWait, tc.claimLease, tc.editWindow, tc.archiveRetention)
 			tc.expect(t, svc, err)
 		})
 	}
 }
+
+func TestService_GetRoomWithFormatMessages(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	first, cursor, last := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	model := &room.Model{
+		Name:       "room",
+		CustomerId: "customer",
+		AgentId:    "agent",
+		Participants: map[string]*room.Participant{
+			"customer": {UserId: "customer", Role: room.RoleCustomer},
+			"agent":    {UserId: "agent", Role: room.RoleAgent},
+		},
+		DeliveredCursors: map[string]primitive.ObjectID{"customer": last},
+		ReadCursors:      map[string]primitive.ObjectID{"customer": cursor, "agent": last},
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		userId string
+		page   *room.Page
+		setup  func(context.Context)
+		expect func(*testing.T, *room.MessagesPage, error)
+	}{
+		{
+			name:   "should return latest messages in order",
+			ctx:    context.Background(),
+			userId: "agent",
+			page:   &room.Page{},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx, bson.M{"roomName": "room"},
+					options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(50)).
+					Return([]*room.RoomMessage{
+						{ID: last, Id: "agent"},
+						{ID: first, Id: "agent"},
+					}, nil)
+				mockRepo.EXPECT().CountMessages(ctx, bson.M{"roomName": "room", "id": bson.M{"$ne": "agent"}, "deletedAt": nil, "_id": bson.M{"$gt": last}}).
+					Return(int64(0), nil)
+			},
+			expect: func(t *testing.T, page *room.MessagesPage, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, page.Messages, 2)
+				assert.Equal(t, "agent", page.Messages[0].To)
+				assert.Equal(t, room.ReceiptRead, page.Messages[0].Status)
+				assert.Equal(t, room.ReceiptDelivered, page.Messages[1].Status)
+				assert.Equal(t, int64(0), page.Unread)
+			},
+		},
+		{
+			name:   "should hide whispers from customer",
+			ctx:    context.Background(),
+			userId: "customer",
+			page:   &room.Page{Before: cursor.Hex(), Limit: 10},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx,
+					bson.M{"roomName": "room", "agentsOnly": bson.M{"$ne": true}, "_id": bson.M{"$lt": cursor}},
+					options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(10)).
+					Return(nil, nil)
+				mockRepo.EXPECT().CountMessages(ctx,
+					bson.M{"roomName": "room", "agentsOnly": bson.M{"$ne": true}, "id": bson.M{"$ne": "customer"}, "deletedAt": nil, "_id": bson.M{"$gt": cursor}}).
+					Return(int64(3), nil)
+			},
+			expect: func(t *testing.T, page *room.MessagesPage, err error) {
+				assert.Nil(t, err)
+				assert.Empty(t, page.Messages)
+				assert.Equal(t, int64(3), page.Unread)
+			},
+		},
+		{
+			name:   "should read forward after cursor",
+			ctx:    context.Background(),
+			userId: "agent",
+			page:   &room.Page{After: cursor.Hex(), Limit: 10},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx, bson.M{"roomName": "room", "_id": bson.M{"$gt": cursor}},
+					options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(10)).
+					Return(nil, nil)
+				mockRepo.EXPECT().CountMessages(ctx, gomock.Any()).Return(int64(0), nil)
+			},
+			expect: func(t *testing.T, page *room.MessagesPage, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:   "should return invalid cursor",
+			ctx:    context.Background(),
+			userId: "agent",
+			page:   &room.Page{Before: cursor.Hex(), After: cursor.Hex()},
+			setup:  func(ctx context.Context) {},
+			expect: func(t *testing.T, page *room.MessagesPage, err error) {
+				assert.Nil(t, page)
+				assert.Equal(t, room.ErrInvalidCursor, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			page, err := service.GetRoomWithFormatMessages(tc.ctx, "room", tc.userId, tc.page)
+			tc.expect(t, page, err)
+		})
+	}
+}
+
+func TestService_MarkDelivered(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	id := primitive.NewObjectID()
+	messageFilters := bson.M{"_id": id, "roomName": "room"}
+	cursorFilters := bson.M{
+		"name":                  "room",
+		"participants.customer": bson.M{"$exists": true},
+		"$or": bson.A{
+			bson.M{"deliveredCursors.customer": bson.M{"$exists": false}},
+			bson.M{"deliveredCursors.customer": bson.M{"$lt": id}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		messageId string
+		setup     func(context.Context)
+		expect    func(*testing.T, bool, error)
+	}{
+		{
+			name:      "should move delivered cursor",
+			ctx:       context.Background(),
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, messageFilters).Return(&room.RoomMessage{ID: id}, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, cursorFilters, bson.M{"$set": bson.M{"deliveredCursors.customer": id}}, nil).
+					Return(&room.Model{Name: "room"}, nil)
+			},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, moved)
+			},
+		},
+		{
+			name:      "should keep newer delivered cursor",
+			ctx:       context.Background(),
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, messageFilters).Return(&room.RoomMessage{ID: id}, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, cursorFilters, gomock.Any(), nil).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, moved)
+			},
+		},
+		{
+			name:      "should return message not found",
+			ctx:       context.Background(),
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, messageFilters).Return(nil, room.ErrMessageNotFound)
+			},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.False(t, moved)
+				assert.Equal(t, room.ErrMessageNotFound, err)
+			},
+		},
+		{
+			name:      "should return invalid id",
+			ctx:       context.Background(),
+			messageId: "invalid",
+			setup:     func(ctx context.Context) {},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.False(t, moved)
+				assert.Equal(t, room.ErrInvalidId, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			moved, err := service.MarkDelivered(tc.ctx, "room", "customer", tc.messageId)
+			tc.expect(t, moved, err)
+		})
+	}
+}
+
+func TestService_MarkRead(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	id := primitive.NewObjectID()
+	messageFilters := bson.M{"_id": id, "roomName": "room"}
+	cursorFilters := bson.M{
+		"name":                  "room",
+		"participants.customer": bson.M{"$exists": true},
+		"$or": bson.A{
+			bson.M{"readCursors.customer": bson.M{"$exists": false}},
+			bson.M{"readCursors.customer": bson.M{"$lt": id}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		messageId string
+		setup     func(context.Context)
+		expect    func(*testing.T, bool, error)
+	}{
+		{
+			name:      "should move read cursor",
+			ctx:       context.Background(),
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, messageFilters).Return(&room.RoomMessage{ID: id}, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, cursorFilters, bson.M{"$set": bson.M{"readCursors.customer": id}}, nil).
+					Return(&room.Model{Name: "room"}, nil)
+			},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, moved)
+			},
+		},
+		{
+			name:      "should keep newer read cursor",
+			ctx:       context.Background(),
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, messageFilters).Return(&room.RoomMessage{ID: id}, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, cursorFilters, gomock.Any(), nil).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, moved)
+			},
+		},
+		{
+			name:      "should return message not found",
+			ctx:       context.Background(),
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, messageFilters).Return(nil, room.ErrMessageNotFound)
+			},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.False(t, moved)
+				assert.Equal(t, room.ErrMessageNotFound, err)
+			},
+		},
+		{
+			name:      "should return invalid id",
+			ctx:       context.Background(),
+			messageId: "invalid",
+			setup:     func(ctx context.Context) {},
+			expect: func(t *testing.T, moved bool, err error) {
+				assert.False(t, moved)
+				assert.Equal(t, room.ErrInvalidId, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			moved, err := service.MarkRead(tc.ctx, "room", "customer", tc.messageId)
+			tc.expect(t, moved, err)
+		})
+	}
+}
+
+func TestService_GetQueuePosition(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	queuedAt := time.Now()
+	waitingRoom := &room.Model{Name: "waiting", State: room.StateWaiting, QueuedAt: &queuedAt}
+	activeRoom := &room.Model{Name: "active", State: room.StateActive, QueuedAt: &queuedAt}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		roomName string
+		setup    func(context.Context, string)
+		expect   func(*testing.T, int, error)
+	}{
+		{
+			name:     "should return queue position",
+			ctx:      context.Background(),
+			roomName: waitingRoom.Name,
+			setup: func(ctx context.Context, name string) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": name}).Return(waitingRoom, nil)
+				mockRepo.EXPECT().CountRooms(ctx, bson.M{"state": room.StateWaiting, "queuedAt": bson.M{"$lt": &queuedAt}}).Return(int64(2), nil)
+			},
+			expect: func(t *testing.T, position int, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 3, position)
+			},
+		},
+		{
+			name:     "should return room not in queue",
+			ctx:      context.Background(),
+			roomName: activeRoom.Name,
+			setup: func(ctx context.Context, name string) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": name}).Return(activeRoom, nil)
+			},
+			expect: func(t *testing.T, position int, err error) {
+				assert.Equal(t, 0, position)
+				assert.Equal(t, room.ErrNotInQueue, err)
+			},
+		},
+		{
+			name:     "should return not found",
+			ctx:      context.Background(),
+			roomName: "unknown",
+			setup: func(ctx context.Context, name string) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": name}).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, position int, err error) {
+				assert.Equal(t, 0, position)
+				assert.Equal(t, room.ErrNotFound, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx, tc.roomName)
+			position, err := service.GetQueuePosition(tc.ctx, tc.roomName)
+			tc.expect(t, position, err)
+		})
+	}
+}
+
+func TestService_ClaimRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	agentEntity, _ := user.NewUser("agent", "agent", "password", &salt)
+	agentEntity.Support = true
+	agent := user.MapToDTO(agentEntity)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	claimedRoom := &room.Model{Name: "room", CustomerId: customer.ID, AgentId: agent.ID, State: room.StateClaimed}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		roomName string
+		agent    *user.DTO
+		setup    func(context.Context, string)
+		expect   func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name:     "should claim room",
+			ctx:      context.Background(),
+			roomName: claimedRoom.Name,
+			agent:    agent,
+			setup: func(ctx context.Context, name string) {
+				mockUserSvc.EXPECT().HasCapacity(agent).Return(true)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, bson.M{"name": name, "state": room.StateWaiting}, gomock.Any(), nil).Return(claimedRoom, nil)
+				mockUserSvc.EXPECT().AddRoom(ctx, agent, name).Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, room.StateClaimed, dto.State)
+				assert.Equal(t, agent.ID, dto.AgentId)
+			},
+		},
+		{
+			name:     "should return already claimed",
+			ctx:      context.Background(),
+			roomName: claimedRoom.Name,
+			agent:    agent,
+			setup: func(ctx context.Context, name string) {
+				mockUserSvc.EXPECT().HasCapacity(agent).Return(true)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, bson.M{"name": name, "state": room.StateWaiting}, gomock.Any(), nil).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrAlreadyClaimed, err)
+			},
+		},
+		{
+			name:     "should return no capacity",
+			ctx:      context.Background(),
+			roomName: claimedRoom.Name,
+			agent:    agent,
+			setup: func(ctx context.Context, name string) {
+				mockUserSvc.EXPECT().HasCapacity(agent).Return(false)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, user.ErrNoCapacity, err)
+			},
+		},
+		{
+			name:     "should return not support",
+			ctx:      context.Background(),
+			roomName: claimedRoom.Name,
+			agent:    customer,
+			setup:    func(ctx context.Context, name string) {},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotSupport, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx, tc.roomName)
+			dto, err := service.ClaimRoom(tc.ctx, tc.roomName, tc.agent)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_AssignNextRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	agentEntity, _ := user.NewUser("agent", "agent", "password", &salt)
+	agentEntity.Support = true
+	agent := user.MapToDTO(agentEntity)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	assignedRoom := &room.Model{Name: "room", CustomerId: customer.ID, AgentId: agent.ID, State: room.StateActive}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		agent  *user.DTO
+		setup  func(context.Context)
+		expect func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name:  "should assign longest waiting room",
+			ctx:   context.Background(),
+			agent: agent,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().HasCapacity(agent).Return(true)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, bson.M{"state": room.StateWaiting}, gomock.Any(), gomock.Any()).Return(assignedRoom, nil)
+				mockUserSvc.EXPECT().AddRoom(ctx, agent, assignedRoom.Name).Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, room.StateActive, dto.State)
+				assert.Equal(t, agent.ID, dto.AgentId)
+			},
+		},
+		{
+			name:  "should return queue empty",
+			ctx:   context.Background(),
+			agent: agent,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().HasCapacity(agent).Return(true)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, bson.M{"state": room.StateWaiting}, gomock.Any(), gomock.Any()).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrQueueEmpty, err)
+			},
+		},
+		{
+			name:  "should return not support",
+			ctx:   context.Background(),
+			agent: customer,
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotSupport, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.AssignNextRoom(tc.ctx, tc.agent)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_TransferRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	fromEntity, _ := user.NewUser("from", "from", "password", &salt)
+	fromEntity.Support = true
+	from := user.MapToDTO(fromEntity)
+
+	toEntity, _ := user.NewUser("to", "to", "password", &salt)
+	toEntity.Support = true
+	to := user.MapToDTO(toEntity)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	activeFilters := bson.M{"name": "room", "state": room.StateActive, "agentId": from.ID}
+	transferredRoom := &room.Model{Name: "room", CustomerId: customer.ID, AgentId: to.ID, State: room.StateActive}
+	currentRoom := &room.Model{
+		Name:       "room",
+		CustomerId: customer.ID,
+		AgentId:    from.ID,
+		State:      room.StateActive,
+		Participants: map[string]*room.Participant{
+			customer.ID: {UserId: customer.ID, Role: room.RoleCustomer},
+			from.ID:     {UserId: from.ID, Role: room.RoleAgent},
+		},
+	}
+	escalatedRoom := &room.Model{
+		Name:       "room",
+		CustomerId: customer.ID,
+		AgentId:    from.ID,
+		State:      room.StateActive,
+		Participants: map[string]*room.Participant{
+			customer.ID: {UserId: customer.ID, Role: room.RoleCustomer},
+			from.ID:     {UserId: from.ID, Role: room.RoleAgent},
+			to.ID:       {UserId: to.ID, Role: room.RoleAgent, InvitedBy: from.ID},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		from    *user.DTO
+		toAgent string
+		setup   func(context.Context)
+		expect  func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name:    "should transfer room to agent",
+			ctx:     context.Background(),
+			from:    from,
+			toAgent: to.ID,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(currentRoom, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, to.ID, false).Return(to, nil)
+				mockUserSvc.EXPECT().AddRoom(ctx, to, "room").Return(nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, activeFilters, gomock.Any(), nil).Return(transferredRoom, nil)
+				mockUserSvc.EXPECT().RemoveRoom(ctx, from.ID, "room").Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, to.ID, dto.AgentId)
+			},
+		},
+		{
+			name:    "should give the slot back when room isn't transferable",
+			ctx:     context.Background(),
+			from:    from,
+			toAgent: to.ID,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(currentRoom, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, to.ID, false).Return(to, nil)
+				mockUserSvc.EXPECT().AddRoom(ctx, to, "room").Return(nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, activeFilters, gomock.Any(), nil).Return(nil, room.ErrNotFound)
+				mockUserSvc.EXPECT().RemoveRoom(ctx, to.ID, "room").Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotParticipant, err)
+			},
+		},
+		{
+			name:    "should reject agent already in room",
+			ctx:     context.Background(),
+			from:    from,
+			toAgent: to.ID,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(escalatedRoom, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, to.ID, false).Return(to, nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrAlreadyInRoom, err)
+			},
+		},
+		{
+			name:    "should return not support",
+			ctx:     context.Background(),
+			from:    customer,
+			toAgent: to.ID,
+			setup:   func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotSupport, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.TransferRoom(tc.ctx, "room", tc.from, tc.toAgent, "note")
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_InviteToRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	agentEntity, _ := user.NewUser("agent", "agent", "password", &salt)
+	agentEntity.Support = true
+	agent := user.MapToDTO(agentEntity)
+
+	supervisorEntity, _ := user.NewUser("supervisor", "supervisor", "password", &salt)
+	supervisorEntity.Support = true
+	supervisorEntity.Admin = true
+	supervisor := user.MapToDTO(supervisorEntity)
+
+	secondEntity, _ := user.NewUser("second", "second", "password", &salt)
+	secondEntity.Support = true
+	second := user.MapToDTO(secondEntity)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	escalatedRoom := &room.Model{
+		Name:       "room",
+		CustomerId: customer.ID,
+		AgentId:    agent.ID,
+		State:      room.StateActive,
+		Participants: map[string]*room.Participant{
+			customer.ID:   {UserId: customer.ID, Role: room.RoleCustomer},
+			agent.ID:      {UserId: agent.ID, Role: room.RoleAgent},
+			supervisor.ID: {UserId: supervisor.ID, Role: room.RoleSupervisor, InvitedBy: agent.ID},
+		},
+	}
+
+	invitedRole := func(t *testing.T, id string, role room.Role) func(context.Context, bson.M, bson.M, *options.FindOneAndUpdateOptions) (*room.Model, error) {
+		return func(_ context.Context, _, update bson.M, _ *options.FindOneAndUpdateOptions) (*room.Model, error) {
+			p := update["$set"].(bson.M)["participants."+id].(*room.Participant)
+			assert.Equal(t, role, p.Role)
+			return escalatedRoom, nil
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		by      *user.DTO
+		invited *user.DTO
+		setup   func(context.Context)
+		expect  func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name:    "should add supervisor to roster",
+			ctx:     context.Background(),
+			by:      agent,
+			invited: supervisor,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, supervisor.ID, false).Return(supervisor, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, gomock.Any(), gomock.Any(), nil).DoAndReturn(invitedRole(t, supervisor.ID, room.RoleSupervisor))
+				mockUserSvc.EXPECT().AddRoom(ctx, supervisor, "room").Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, agent.ID, dto.AgentId)
+				assert.Len(t, dto.Participants, 3)
+			},
+		},
+		{
+			name:    "should add second agent as agent",
+			ctx:     context.Background(),
+			by:      agent,
+			invited: second,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, second.ID, false).Return(second, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, gomock.Any(), gomock.Any(), nil).DoAndReturn(invitedRole(t, second.ID, room.RoleAgent))
+				mockUserSvc.EXPECT().AddRoom(ctx, second, "room").Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:    "should return not participant when inviter isn't in room",
+			ctx:     context.Background(),
+			by:      agent,
+			invited: supervisor,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, supervisor.ID, false).Return(supervisor, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, gomock.Any(), gomock.Any(), nil).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotParticipant, err)
+			},
+		},
+		{
+			name:    "should take back only the invitation when agent has no capacity",
+			ctx:     context.Background(),
+			by:      agent,
+			invited: supervisor,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, supervisor.ID, false).Return(supervisor, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, gomock.Any(), gomock.Any(), nil).Return(escalatedRoom, nil)
+				mockUserSvc.EXPECT().AddRoom(ctx, supervisor, "room").Return(user.ErrNoCapacity)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, gomock.Any(), bson.M{"$unset": bson.M{"participants." + supervisor.ID: ""}}, nil).
+					DoAndReturn(func(_ context.Context, filters, _ bson.M, _ *options.FindOneAndUpdateOptions) (*room.Model, error) {
+						assert.Equal(t, agent.ID, filters["participants."+supervisor.ID+".invitedBy"])
+						assert.Contains(t, filters, "participants."+supervisor.ID+".joinedAt")
+						return escalatedRoom, nil
+					})
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, user.ErrNoCapacity, err)
+			},
+		},
+		{
+			name:    "should return not support",
+			ctx:     context.Background(),
+			by:      agent,
+			invited: customer,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, customer.ID, false).Return(customer, nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotSupport, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.InviteToRoom(tc.ctx, "room", tc.by, tc.invited.ID)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_LeaveRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	agentEntity, _ := user.NewUser("agent", "agent", "password", &salt)
+	agentEntity.Support = true
+	agent := user.MapToDTO(agentEntity)
+
+	supervisorEntity, _ := user.NewUser("supervisor", "supervisor", "password", &salt)
+	supervisorEntity.Support = true
+	supervisor := user.MapToDTO(supervisorEntity)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	leaveFilters := bson.M{"name": "room", "state": bson.M{"$ne": room.StateClosed}, "participants." + agent.ID: bson.M{"$exists": true}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		u      *user.DTO
+		setup  func(context.Context)
+		expect func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name: "should hand room over to remaining agent",
+			ctx:  context.Background(),
+			u:    agent,
+			setup: func(ctx context.Context) {
+				left := &room.Model{
+					Name:       "room",
+					CustomerId: customer.ID,
+					AgentId:    agent.ID,
+					State:      room.StateActive,
+					Participants: map[string]*room.Participant{
+						customer.ID:   {UserId: customer.ID, Role: room.RoleCustomer},
+						supervisor.ID: {UserId: supervisor.ID, Role: room.RoleAgent, InvitedBy: agent.ID},
+					},
+				}
+				handedOver := *left
+				handedOver.AgentId = supervisor.ID
+
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, leaveFilters, gomock.Any(), nil).Return(left, nil)
+				mockUserSvc.EXPECT().RemoveRoom(ctx, agent.ID, "room").Return(nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, bson.M{"name": "room", "agentId": agent.ID},
+					bson.M{"$set": bson.M{"agentId": supervisor.ID}}, nil).Return(&handedOver, nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, supervisor.ID, dto.AgentId)
+			},
+		},
+		{
+			name: "should leave no agents behind",
+			ctx:  context.Background(),
+			u:    agent,
+			setup: func(ctx context.Context) {
+				left := &room.Model{
+					Name:       "room",
+					CustomerId: customer.ID,
+					AgentId:    agent.ID,
+					State:      room.StateActive,
+					Participants: map[string]*room.Participant{
+						customer.ID: {UserId: customer.ID, Role: room.RoleCustomer},
+					},
+				}
+
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, leaveFilters, gomock.Any(), nil).Return(left, nil)
+				mockUserSvc.EXPECT().RemoveRoom(ctx, agent.ID, "room").Return(nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, dto.Participants, 1)
+			},
+		},
+		{
+			name: "should return not participant",
+			ctx:  context.Background(),
+			u:    agent,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, leaveFilters, gomock.Any(), nil).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotParticipant, err)
+			},
+		},
+		{
+			name:  "should return not support",
+			ctx:   context.Background(),
+			u:     customer,
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotSupport, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.LeaveRoom(tc.ctx, "room", tc.u)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_ObserveRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	agentEntity, _ := user.NewUser("agent", "agent", "password", &salt)
+	agentEntity.Support = true
+	agent := user.MapToDTO(agentEntity)
+
+	supervisorEntity, _ := user.NewUser("supervisor", "supervisor", "password", &salt)
+	supervisorEntity.Support = true
+	supervisorEntity.Admin = true
+	supervisor := user.MapToDTO(supervisorEntity)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	active := &room.Model{
+		Name:       "room",
+		CustomerId: customer.ID,
+		AgentId:    agent.ID,
+		State:      room.StateActive,
+		Participants: map[string]*room.Participant{
+			customer.ID: {UserId: customer.ID, Role: room.RoleCustomer},
+			agent.ID:    {UserId: agent.ID, Role: room.RoleAgent},
+		},
+	}
+	observeFilters := bson.M{"name": "room", "state": bson.M{"$ne": room.StateClosed}, "participants." + supervisor.ID: bson.M{"$exists": false}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		u      *user.DTO
+		setup  func(context.Context)
+		expect func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name: "should add supervisor as observer",
+			ctx:  context.Background(),
+			u:    supervisor,
+			setup: func(ctx context.Context) {
+				observed := *active
+				observed.Participants = map[string]*room.Participant{
+					customer.ID:   active.Participants[customer.ID],
+					agent.ID:      active.Participants[agent.ID],
+					supervisor.ID: {UserId: supervisor.ID, Role: room.RoleObserver},
+				}
+
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(active, nil)
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, observeFilters, gomock.Any(), nil).
+					DoAndReturn(func(_ context.Context, _, update bson.M, _ *options.FindOneAndUpdateOptions) (*room.Model, error) {
+						p := update["$set"].(bson.M)["participants."+supervisor.ID].(*room.Participant)
+						assert.Equal(t, room.RoleObserver, p.Role)
+						return &observed, nil
+					})
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, dto.Participants, 3)
+				assert.Len(t, room.CustomerRoster(dto.Participants), 2)
+			},
+		},
+		{
+			name: "should return already in room",
+			ctx:  context.Background(),
+			u:    supervisor,
+			setup: func(ctx context.Context) {
+				invited := *active
+				invited.Participants = map[string]*room.Participant{
+					supervisor.ID: {UserId: supervisor.ID, Role: room.RoleAgent},
+				}
+
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(&invited, nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrAlreadyInRoom, err)
+			},
+		},
+		{
+			name: "should return room closed",
+			ctx:  context.Background(),
+			u:    supervisor,
+			setup: func(ctx context.Context) {
+				closed := *active
+				closed.State = room.StateClosed
+
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(&closed, nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrRoomClosed, err)
+			},
+		},
+		{
+			name:  "should return not supervisor",
+			ctx:   context.Background(),
+			u:     agent,
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrNotSupervisor, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.ObserveRoom(tc.ctx, "room", tc.u)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_EditMessage(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	authorEntity, _ := user.NewUser("author", "author", "password", &salt)
+	author := user.MapToDTO(authorEntity)
+
+	otherEntity, _ := user.NewUser("other", "other", "password", &salt)
+	other := user.MapToDTO(otherEntity)
+
+	id := primitive.NewObjectID()
+	edited := room.EncryptedMessage{Data: "edited"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		editor *user.DTO
+		setup  func(context.Context)
+		expect func(*testing.T, *room.RoomMessage, error)
+	}{
+		{
+			name:   "should keep revision of edited message",
+			ctx:    context.Background(),
+			editor: author,
+			setup: func(ctx context.Context) {
+				current := &room.RoomMessage{ID: id, RoomName: "room", Id: author.ID, Time: time.Now(), Message: room.EncryptedMessage{Data: "sent"}}
+				mockRepo.EXPECT().GetMessage(ctx, bson.M{"_id": id, "roomName": "room"}).Return(current, nil)
+				mockRepo.EXPECT().FindAndUpdateMessage(ctx, bson.M{"_id": id, "editedAt": current.EditedAt, "deletedAt": nil}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, update bson.M) (*room.RoomMessage, error) {
+						revision := update["$push"].(bson.M)["revisions"].(*room.Revision)
+						assert.Equal(t, "sent", revision.Message.Data)
+						assert.Equal(t, edited, update["$set"].(bson.M)["message"])
+						return &room.RoomMessage{ID: id, Id: author.ID, Message: edited, Revisions: []*room.Revision{revision}}, nil
+					})
+			},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, edited, message.Message)
+				assert.Len(t, message.Revisions, 1)
+			},
+		},
+		{
+			name:   "should return not author",
+			ctx:    context.Background(),
+			editor: other,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, bson.M{"_id": id, "roomName": "room"}).
+					Return(&room.RoomMessage{ID: id, Id: author.ID, Time: time.Now()}, nil)
+			},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, message)
+				assert.Equal(t, room.ErrNotAuthor, err)
+			},
+		},
+		{
+			name:   "should return edit window closed",
+			ctx:    context.Background(),
+			editor: author,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, bson.M{"_id": id, "roomName": "room"}).
+					Return(&room.RoomMessage{ID: id, Id: author.ID, Time: time.Now().Add(-time.Hour)}, nil)
+			},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, message)
+				assert.Equal(t, room.ErrEditWindowClosed, err)
+			},
+		},
+		{
+			name:   "should return message deleted",
+			ctx:    context.Background(),
+			editor: author,
+			setup: func(ctx context.Context) {
+				deletedAt := time.Now()
+				mockRepo.EXPECT().GetMessage(ctx, bson.M{"_id": id, "roomName": "room"}).
+					Return(&room.RoomMessage{ID: id, Id: author.ID, Time: time.Now(), DeletedAt: &deletedAt}, nil)
+			},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, message)
+				assert.Equal(t, room.ErrMessageDeleted, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			message, err := service.EditMessage(tc.ctx, "room", id.Hex(), tc.editor, edited)
+			tc.expect(t, message, err)
+		})
+	}
+}
+
+func TestService_DeleteMessage(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	customer := user.MapToDTO(customerEntity)
+
+	agentEntity, _ := user.NewUser("agent", "agent", "password", &salt)
+	agentEntity.Support = true
+	agent := user.MapToDTO(agentEntity)
+
+	id := primitive.NewObjectID()
+	old := &room.RoomMessage{ID: id, Id: customer.ID, Time: time.Now().Add(-time.Hour)}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		u         *user.DTO
+		messageId string
+		setup     func(context.Context)
+		expect    func(*testing.T, *room.RoomMessage, error)
+	}{
+		{
+			name:      "should let agent redact any message",
+			ctx:       context.Background(),
+			u:         agent,
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				deletedAt := time.Now()
+				mockRepo.EXPECT().GetMessage(ctx, bson.M{"_id": id, "roomName": "room"}).Return(old, nil)
+				mockRepo.EXPECT().FindAndUpdateMessage(ctx, gomock.Any(), gomock.Any()).
+					Return(&room.RoomMessage{ID: id, Id: customer.ID, DeletedAt: &deletedAt, DeletedBy: agent.ID}, nil)
+			},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, agent.ID, message.DeletedBy)
+			},
+		},
+		{
+			name:      "should return edit window closed for author",
+			ctx:       context.Background(),
+			u:         customer,
+			messageId: id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetMessage(ctx, bson.M{"_id": id, "roomName": "room"}).Return(old, nil)
+			},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, message)
+				assert.Equal(t, room.ErrEditWindowClosed, err)
+			},
+		},
+		{
+			name:      "should return invalid id",
+			ctx:       context.Background(),
+			u:         customer,
+			messageId: "invalid",
+			setup:     func(ctx context.Context) {},
+			expect: func(t *testing.T, message *room.RoomMessage, err error) {
+				assert.Nil(t, message)
+				assert.Equal(t, room.ErrInvalidId, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			message, err := service.DeleteMessage(tc.ctx, "room", tc.messageId, tc.u)
+			tc.expect(t, message, err)
+		})
+	}
+}
+
+func TestService_CloseRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+	salt := 10
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	customerEntity, _ := user.NewUser("customer", "customer", "password", &salt)
+	roomName := "room"
+	customerEntity.SetRoom(&roomName)
+	customer := user.MapToDTO(customerEntity)
+
+	closeFilters := bson.M{"name": "room", "state": bson.M{"$ne": room.StateClosed}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, *room.DTO, error)
+	}{
+		{
+			name: "should archive room and release agents",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				closedAt := time.Now()
+				closed := &room.Model{
+					Name:        "room",
+					CustomerId:  customer.ID,
+					State:       room.StateClosed,
+					ClosedAt:    &closedAt,
+					ClosedBy:    customer.ID,
+					CloseReason: room.CloseReasonCustomerEnded,
+				}
+
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, closeFilters, gomock.Any(), nil).Return(closed, nil)
+				mockUserSvc.EXPECT().GetUsersByRoom(ctx, "room", false).Return(nil, nil)
+				mockUserSvc.EXPECT().SetRoomName(ctx, customer.ID, nil).Return(customer, nil)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, room.StateClosed, dto.State)
+				assert.Equal(t, room.CloseReasonCustomerEnded, dto.CloseReason)
+			},
+		},
+		{
+			name: "should return room closed",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateRoom(ctx, closeFilters, gomock.Any(), nil).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *room.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, room.ErrRoomClosed, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.CloseRoom(tc.ctx, "room", customer.ID, room.CloseReasonCustomerEnded)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_PurgeArchive(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		retention int
+		setup     func(context.Context)
+		expect    func(*testing.T, int, error)
+	}{
+		{
+			name:      "should delete rooms past retention",
+			ctx:       context.Background(),
+			retention: 90,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRooms(ctx, gomock.Any(), nil).Return([]*room.Model{{Name: "old"}, {Name: "older"}}, nil)
+				mockRepo.EXPECT().DeleteRoom(ctx, "old").Return(nil)
+				mockRepo.EXPECT().DeleteRoom(ctx, "older").Return(nil)
+			},
+			expect: func(t *testing.T, purged int, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 2, purged)
+			},
+		},
+		{
+			name:      "should keep archive without retention",
+			ctx:       context.Background(),
+			retention: 0,
+			setup:     func(ctx context.Context) {},
+			expect: func(t *testing.T, purged int, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 0, purged)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &tc.retention)
+			tc.setup(tc.ctx)
+			purged, err := service.PurgeArchive(tc.ctx)
+			tc.expect(t, purged, err)
+		})
+	}
+}
+
+func TestService_ExportTranscript(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	model := &room.Model{
+		Name:         "room",
+		CustomerId:   "customer",
+		AgentId:      "agent",
+		State:        room.StateClosed,
+		Participants: map[string]*room.Participant{"invited": {UserId: "invited", Role: room.RoleAgent, InvitedBy: "agent"}},
+	}
+	sent := &room.RoomMessage{
+		ID:      primitive.NewObjectID(),
+		Id:      "customer",
+		Time:    time.Now(),
+		Message: room.EncryptedMessage{Data: "<cipher>", Salt: "salt", Iv: "iv"},
+	}
+	deletedAt := time.Now()
+	deleted := &room.RoomMessage{
+		ID:        primitive.NewObjectID(),
+		Id:        "agent",
+		Time:      time.Now(),
+		Message:   room.EncryptedMessage{Data: "secret", Salt: "salt", Iv: "iv"},
+		DeletedAt: &deletedAt,
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		viewer     *user.DTO
+		format     room.TranscriptFormat
+		supervised bool
+		setup      func(context.Context)
+		expect     func(*testing.T, string, error)
+	}{
+		{
+			name:   "should export json with ciphertext envelope",
+			ctx:    context.Background(),
+			viewer: &user.DTO{ID: "customer"},
+			format: room.TranscriptJSON,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx, bson.M{"roomName": "room", "agentsOnly": bson.M{"$ne": true}}, gomock.Any()).
+					Return([]*room.RoomMessage{sent, deleted}, nil)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Nil(t, err)
+
+				var transcript struct {
+					Room     room.TranscriptHeader     `json:"room"`
+					Messages []*room.TranscriptMessage `json:"messages"`
+				}
+				assert.Nil(t, json.Unmarshal([]byte(out), &transcript))
+				assert.Equal(t, "room", transcript.Room.RoomName)
+				assert.Len(t, transcript.Messages, 2)
+				assert.Equal(t, sent.ID.Hex(), transcript.Messages[0].Id)
+				assert.Equal(t, room.RoleCustomer, transcript.Messages[0].Role)
+				assert.Equal(t, &sent.Message, transcript.Messages[0].Message)
+				assert.True(t, transcript.Messages[1].Deleted)
+				assert.Nil(t, transcript.Messages[1].Message)
+			},
+		},
+		{
+			name:   "should escape html",
+			ctx:    context.Background(),
+			viewer: &user.DTO{ID: "agent", Support: true},
+			format: room.TranscriptHTML,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx, bson.M{"roomName": "room"}, gomock.Any()).
+					Return([]*room.RoomMessage{sent, deleted}, nil)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Nil(t, err)
+				assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+				assert.Contains(t, out, "&lt;cipher&gt;")
+				assert.NotContains(t, out, "secret")
+				assert.True(t, strings.HasSuffix(out, "</html>\n"))
+			},
+		},
+		{
+			name:   "should export room of invited agent",
+			ctx:    context.Background(),
+			viewer: &user.DTO{ID: "invited", Support: true},
+			format: room.TranscriptText,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx, bson.M{"roomName": "room"}, gomock.Any()).Return(nil, nil)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Nil(t, err)
+				assert.Contains(t, out, "room")
+			},
+		},
+		{
+			name:       "should export any room supervised",
+			ctx:        context.Background(),
+			viewer:     &user.DTO{ID: "admin", Support: true, Admin: true},
+			format:     room.TranscriptText,
+			supervised: true,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+				mockRepo.EXPECT().GetMessages(ctx, bson.M{"roomName": "room"}, gomock.Any()).Return(nil, nil)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Nil(t, err)
+				assert.Contains(t, out, "room")
+			},
+		},
+		{
+			name:   "should return not participant",
+			ctx:    context.Background(),
+			viewer: &user.DTO{ID: "other"},
+			format: room.TranscriptText,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Equal(t, room.ErrNotParticipant, err)
+				assert.Empty(t, out)
+			},
+		},
+		{
+			name:   "should return not participant for other agent",
+			ctx:    context.Background(),
+			viewer: &user.DTO{ID: "other", Support: true},
+			format: room.TranscriptText,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, bson.M{"name": "room"}).Return(model, nil)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Equal(t, room.ErrNotParticipant, err)
+				assert.Empty(t, out)
+			},
+		},
+		{
+			name:       "should return not supervisor",
+			ctx:        context.Background(),
+			viewer:     &user.DTO{ID: "other", Support: true},
+			format:     room.TranscriptText,
+			supervised: true,
+			setup:      func(ctx context.Context) {},
+			expect: func(t *testing.T, out string, err error) {
+				assert.Equal(t, room.ErrNotSupervisor, err)
+				assert.Empty(t, out)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			var out bytes.Buffer
+			err := service.ExportTranscript(tc.ctx, &out, "room", tc.viewer, tc.format, tc.supervised)
+			tc.expect(t, out.String(), err)
+		})
+	}
+}
+
+func TestService_RateRoom(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	customer := &user.DTO{ID: "customer"}
+	closedFilters := bson.M{"name": "room", "state": room.StateClosed}
+	closed := &room.Model{Name: "room", CustomerId: "customer", AgentId: "agent", State: room.StateClosed}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		customer *user.DTO
+		score    int
+		setup    func(context.Context)
+		expect   func(*testing.T, *room.RatingDTO, error)
+	}{
+		{
+			name:     "should rate the agent of the room",
+			ctx:      context.Background(),
+			customer: customer,
+			score:    5,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, closedFilters).Return(closed, nil)
+				mockRepo.EXPECT().CreateRating(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, rating *room.Rating) error {
+					assert.Equal(t, "room", rating.RoomName)
+					assert.Equal(t, "agent", rating.AgentId)
+					return nil
+				})
+			},
+			expect: func(t *testing.T, rating *room.RatingDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, rating.Score)
+				assert.Equal(t, "agent", rating.AgentId)
+			},
+		},
+		{
+			name:     "should return invalid score",
+			ctx:      context.Background(),
+			customer: customer,
+			score:    6,
+			setup:    func(ctx context.Context) {},
+			expect: func(t *testing.T, rating *room.RatingDTO, err error) {
+				assert.Nil(t, rating)
+				assert.Equal(t, room.ErrInvalidScore, err)
+			},
+		},
+		{
+			name:     "should return room not closed",
+			ctx:      context.Background(),
+			customer: customer,
+			score:    3,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, closedFilters).Return(nil, room.ErrNotFound)
+			},
+			expect: func(t *testing.T, rating *room.RatingDTO, err error) {
+				assert.Nil(t, rating)
+				assert.Equal(t, room.ErrRoomNotClosed, err)
+			},
+		},
+		{
+			name:     "should return not participant",
+			ctx:      context.Background(),
+			customer: &user.DTO{ID: "other"},
+			score:    3,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, closedFilters).Return(closed, nil)
+			},
+			expect: func(t *testing.T, rating *room.RatingDTO, err error) {
+				assert.Nil(t, rating)
+				assert.Equal(t, room.ErrNotParticipant, err)
+			},
+		},
+		{
+			name:     "should return already rated",
+			ctx:      context.Background(),
+			customer: customer,
+			score:    1,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetRoom(ctx, closedFilters).Return(closed, nil)
+				mockRepo.EXPECT().CreateRating(ctx, gomock.Any()).Return(room.ErrAlreadyRated)
+			},
+			expect: func(t *testing.T, rating *room.RatingDTO, err error) {
+				assert.Nil(t, rating)
+				assert.Equal(t, room.ErrAlreadyRated, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			rating, err := service.RateRoom(tc.ctx, "room", tc.customer, tc.score, "")
+			tc.expect(t, rating, err)
+		})
+	}
+}
+
+func TestService_GetRatingStats(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_room.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	defaultQueueWait := 120
+	claimLease := 60
+	editWindow := 900
+	archiveRetention := 90
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := room.NewService(mockRepo, mockUserSvc, policy, zapLogger, &defaultQueueWait, &claimLease, &editWindow, &archiveRetention)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		query  *room.RatingQuery
+		setup  func(context.Context)
+		expect func(*testing.T, []*room.RatingStats, error)
+	}{
+		{
+			name:  "should group by agent",
+			ctx:   context.Background(),
+			query: &room.RatingQuery{GroupBy: room.RatingGroupAgent},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().AggregateRatings(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, pipeline mongo.Pipeline) ([]*room.RatingStats, error) {
+					group := pipeline[1][0].Value.(bson.M)
+					assert.Equal(t, "$agentId", group["_id"])
+					return []*room.RatingStats{{Key: "agent", Count: 2, Average: 4.5, Satisfied: 2}}, nil
+				})
+			},
+			expect: func(t *testing.T, stats []*room.RatingStats, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, stats, 1)
+				assert.Equal(t, "agent", stats[0].Key)
+			},
+		},
+		{
+			name:  "should return invalid group",
+			ctx:   context.Background(),
+			query: &room.RatingQuery{GroupBy: "week"},
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, stats []*room.RatingStats, err error) {
+				assert.Nil(t, stats)
+				assert.Equal(t, room.ErrInvalidGroup, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			stats, err := service.GetRatingStats(tc.ctx, tc.query)
+			tc.expect(t, stats, err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+type TranscriptFormat string
+
+const (
+	TranscriptJSON TranscriptFormat = "json"
+	TranscriptText TranscriptFormat = "text"
+	TranscriptHTML TranscriptFormat = "html"
+)
+
+func ParseTranscriptFormat(format string) (TranscriptFormat, error) {
+	switch TranscriptFormat(format) {
+	case "", TranscriptJSON:
+		return TranscriptJSON, nil
+	case TranscriptText, TranscriptHTML:
+		return TranscriptFormat(format), nil
+	}
+
+	return "", ErrInvalidFormat
+}
+
+func (f TranscriptFormat) ContentType() string {
+	switch f {
+	case TranscriptText:
+		return "text/plain; charset=utf-8"
+	case TranscriptHTML:
+		return "text/html; charset=utf-8"
+	}
+
+	return "application/json"
+}
+
+func (f TranscriptFormat) Extension() string {
+	if f == TranscriptText {
+		return "txt"
+	}
+
+	return string(f)
+}
+
+// TranscriptHeader describes the conversation. Messages stay encrypted, so the
+// participants and ids are what a client needs to render them after decrypting.
+type TranscriptHeader struct {
+	RoomName     string         `json:"room_name"`
+	CustomerId   string         `json:"customer_id"`
+	State        State          `json:"state"`
+	QueuedAt     *time.Time     `json:"queued_at,omitempty"`
+	ClosedAt     *time.Time     `json:"closed_at,omitempty"`
+	CloseReason  string         `json:"close_reason,omitempty"`
+	Participants []*Participant `json:"participants"`
+	ExportedAt   time.Time      `json:"exported_at"`
+}
+
+// TranscriptMessage carries the ciphertext envelope of a message as it was
+// sent by the client. Deleted messages keep their place without content.
+type TranscriptMessage struct {
+	Id         string            `json:"id"`
+	From       string            `json:"from"`
+	Role       Role              `json:"role"`
+	Time       time.Time         `json:"time"`
+	EditedAt   *time.Time        `json:"edited_at,omitempty"`
+	Deleted    bool              `json:"deleted,omitempty"`
+	AgentsOnly bool              `json:"agents_only,omitempty"`
+	Message    *EncryptedMessage `json:"message,omitempty"`
+}
+
+// transcriptWriter renders a transcript piece by piece, so long conversations
+// never have to be held in memory.
+type transcriptWriter interface {
+	Begin(header *TranscriptHeader) error
+	Message(message *TranscriptMessage) error
+	End() error
+}
+
+func newTranscriptWriter(format TranscriptFormat, w io.Writer) transcriptWriter {
+	switch format {
+	case TranscriptText:
+		return &textTranscript{w: w}
+	case TranscriptHTML:
+		return &htmlTranscript{w: w}
+	}
+
+	return &jsonTranscript{w: w}
+}
+
+type jsonTranscript struct {
+	w     io.Writer
+	count int
+}
+
+func (t *jsonTranscript) Begin(header *TranscriptHeader) error {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(t.w, `{"room":%s,"messages":[`, h)
+	return err
+}
+
+func (t *jsonTranscript) Message(message *TranscriptMessage) error {
+	m, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if t.count > 0 {
+		if _, err = io.WriteString(t.w, ","); err != nil {
+			return err
+		}
+	}
+	t.count++
+
+	_, err = t.w.Write(m)
+	return err
+}
+
+func (t *jsonTranscript) End() error {
+	_, err := io.WriteString(t.w, "]}")
+	return err
+}
+
+type textTranscript struct {
+	w io.Writer
+}
+
+func (t *textTranscript) Begin(header *TranscriptHeader) error {
+	_, err := fmt.Fprintf(t.w, "Room: %s\nCustomer: %s\nState: %s\nExported: %s\n",
+		header.RoomName, header.CustomerId, header.State, header.ExportedAt.Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	if header.ClosedAt != nil {
+		_, err = fmt.Fprintf(t.w, "Closed: %s (%s)\n", header.ClosedAt.Format(time.RFC3339), header.CloseReason)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range header.Participants {
+		if _, err = fmt.Fprintf(t.w, "Participant: %s (%s)\n", p.UserId, p.Role); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(t.w, "\nMessages are encrypted, every line holds id, time, author and the data/salt/iv envelope.\n\n")
+	return err
+}
+
+func (t *textTranscript) Message(message *TranscriptMessage) error {
+	prefix := fmt.Sprintf("[%s] %s %s (%s)", message.Time.Format(time.RFC3339), message.Id, message.From, message.Role)
+	if message.AgentsOnly {
+		prefix += " [whisper]"
+	}
+	if message.EditedAt != nil {
+		prefix += " [edited]"
+	}
+
+	if message.Deleted {
+		_, err := fmt.Fprintf(t.w, "%s: <deleted>\n", prefix)
+		return err
+	}
+
+	_, err := fmt.Fprintf(t.w, "%s: data=%s salt=%s iv=%s\n",
+		prefix, message.Message.Data, message.Message.Salt, message.Message.Iv)
+	return err
+}
+
+func (t *textTranscript) End() error {
+	return nil
+}
+
+var (
+	htmlTranscriptBegin = template.Must(template.New("begin").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Transcript {{.RoomName}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+article { border-bottom: 1px solid #ddd; padding: .5em 0; }
+.meta { color: #666; font-size: .85em; }
+.ciphertext { font-family: monospace; word-break: break-all; }
+</style>
+</head>
+<body>
+<header>
+<h1>Transcript {{.RoomName}}</h1>
+<dl>
+<dt>Customer</dt><dd>{{.CustomerId}}</dd>
+<dt>State</dt><dd>{{.State}}</dd>
+{{if .ClosedAt}}<dt>Closed</dt><dd>{{.ClosedAt.Format "2006-01-02T15:04:05Z07:00"}} ({{.CloseReason}})</dd>{{end}}
+<dt>Exported</dt><dd>{{.ExportedAt.Format "2006-01-02T15:04:05Z07:00"}}</dd>
+<dt>Participants</dt><dd><ul>{{range .Participants}}<li data-user-id="{{.UserId}}" data-role="{{.Role}}">{{.UserId}} ({{.Role}})</li>{{end}}</ul></dd>
+</dl>
+<p>Messages are encrypted. Every message carries its data, salt and iv as data attributes so it can be decrypted locally.</p>
+</header>
+<main>
+`))
+	htmlTranscriptMessage = template.Must(template.New("message").Parse(`<article id="{{.Id}}" data-from="{{.From}}" data-role="{{.Role}}" data-time="{{.Time.Format "2006-01-02T15:04:05Z07:00"}}"{{if .AgentsOnly}} data-agents-only="true"{{end}}{{if .Message}} data-data="{{.Message.Data}}" data-salt="{{.Message.Salt}}" data-iv="{{.Message.Iv}}"{{end}}>
+<div class="meta">{{.Time.Format "2006-01-02 15:04:05"}} {{.From}} ({{.Role}}){{if .AgentsOnly}} whisper{{end}}{{if .EditedAt}} edited{{end}}</div>
+{{if .Deleted}}<p><em>deleted</em></p>{{else}}<p class="ciphertext">{{.Message.Data}}</p>{{end}}
+</article>
+`))
+)
+
+type htmlTranscript struct {
+	w io.Writer
+}
+
+func (t *htmlTranscript) Begin(header *TranscriptHeader) error {
+	return htmlTranscriptBegin.Execute(t.w, header)
+}
+
+func (t *htmlTranscript) Message(message *TranscriptMessage) error {
+	return htmlTranscriptMessage.Execute(t.w, message)
+}
+
+func (t *htmlTranscript) End() error {
+	_, err := io.WriteString(t.w, "</main>\n</body>\n</html>\n")
+	return err
+}
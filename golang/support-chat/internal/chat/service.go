@@ -7,10 +7,9 @@ import (
 	"log"
 	"support-chat/internal/chat/room"
 	"support-chat/internal/user"
-	"support-chat/pkg/jwt"
+	"sync"
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
@@ -19,28 +18,52 @@ import (
 //go:generate mockgen -source=service.go -destination=mocks/service_mock.go
 type Service interface {
 	Chat(ctx context.Context, ws *websocket.Conn) error
+	RunLeaseWatcher(ctx context.Context)
+	RunSessionWatcher(ctx context.Context)
+	TransferRoom(ctx context.Context, from *user.DTO, name, agentId, note string) (*room.DTO, error)
+	InviteToRoom(ctx context.Context, by *user.DTO, name, agentId, note string) (*room.DTO, error)
+	CloseSessions(userId string, sids ...string)
+}
+
+// leaseCheckPeriod is how often expired room claims are returned to the queue
+const leaseCheckPeriod = 5 * time.Second
+
+// sessionsChannel is the broker channel revoked sessions are announced on, so
+// every instance closes their connections
+const sessionsChannel = "sessions:closed"
+
+type closedSessions struct {
+	UserId string   `json:"user_id"`
+	Sids   []string `json:"sids"`
 }
 
 type service struct {
-	redisClient *redis.Client
-	clients     map[*room.Client]bool
-	rooms       map[*room.Room]bool
-	roomSvc     room.Service
-	jwtSvc      jwt.Service
-	userSvc     user.Service
-	logger      *zap.SugaredLogger
+	broker  room.Broker
+	roomSvc room.Service
+	userSvc user.Service
+	logger  *zap.SugaredLogger
+
+	// mu guards the clients, the rooms, the agents and the positions. It is
+	// never held while the database is asked or a client is written to.
+	mu      sync.Mutex
+	clients map[*room.Client]bool
+	rooms   map[string]*room.Room
+	// agents holds connected support clients in the order they get new conversations
+	agents []*room.Client
+	// positions remembers the last queue position sent to each waiting customer
+	positions map[*room.Client]int
+
+	// dispatchMu lets one dispatch run at a time, so agents are rotated in order
+	dispatchMu sync.Mutex
 }
 
-func NewService(redisClient *redis.Client, roomSvc room.Service, jwtSvc jwt.Service, userSvc user.Service, logger *zap.SugaredLogger) (Service, error) {
-	if redisClient == nil {
-		return nil, errors.New("[chat_service] invalid redis chat client")
+func NewService(broker room.Broker, roomSvc room.Service, userSvc user.Service, logger *zap.SugaredLogger) (Service, error) {
+	if broker == nil {
+		return nil, errors.New("[chat_service] invalid broker")
 	}
 	if roomSvc == nil {
 		return nil, errors.New("[chat_service] invalid room service")
 	}
-	if jwtSvc == nil {
-		return nil, errors.New("[chat_service] invalid jwt service")
-	}
 	if userSvc == nil {
 		return nil, errors.New("[chat_service] invalid user service")
 	}
@@ -48,33 +71,48 @@ func NewService(redisClient *redis.Client, roomSvc room.Service, jwtSvc jwt.Serv
 		return nil, errors.New("[chat_service] invalid logger")
 	}
 	return &service{
-		logger:      logger,
-		clients:     make(map[*room.Client]bool),
-		rooms:       make(map[*room.Room]bool),
-		roomSvc:     roomSvc,
-		jwtSvc:      jwtSvc,
-		userSvc:     userSvc,
-		redisClient: redisClient,
+		logger:    logger,
+		clients:   make(map[*room.Client]bool),
+		rooms:     make(map[string]*room.Room),
+		positions: make(map[*room.Client]int),
+		roomSvc:   roomSvc,
+		userSvc:   userSvc,
+		broker:    broker,
 	}, nil
 }
 
 func (s *service) Chat(ctx context.Context, ws *websocket.Conn) error {
-	userCtxValue := ctx.Value(contextKey("user"))
-	if userCtxValue == nil {
+	principal, ok := user.FromContext(ctx)
+	if !ok {
 		log.Println("Not authenticated")
 		return nil
 	}
 
-	u := userCtxValue.(user.DTO)
+	u := principal.User
 	c, err := room.NewClient(u.ID, ws)
 	if err != nil {
 		return err
 	}
+	c.SessionId = principal.Payload.Sid
+	if u.Support {
+		c.Role = room.RoleAgent
+	}
+	c.OnPresence = s.presenceChanged
+	c.OnDelivered = s.messageDelivered
 
 	go c.WritePump()
-	go c.ReadPump(s.messageHandler)
 	//s.cleanOldClient(&u)
 	s.registerClientAndCreateRoom(ctx, c, &u)
+	// reading starts once the client is registered, so a quick disconnect
+	// can't be forgotten before it was added
+	go func() {
+		c.ReadPump(s.messageHandler)
+		for _, roomName := range s.forgetClient(c) {
+			s.unobserveRoom(context.Background(), c.Id, roomName)
+		}
+	}()
+	s.presenceChanged(c, room.PresenceOnline)
+	s.dispatchQueue(context.Background())
 
 	return nil
 }
@@ -89,43 +127,45 @@ func (s *service) registerClientAndCreateRoom(ctx context.Context, client *room.
 			} else {
 				client.Room = r
 				//s.cleanOldClientInRoom(r, u)
-				r.Clients[client] = true
+				r.AddClient(client)
 			}
 		} else {
 			s.createRoomIfDoesntExist(ctx, client, u)
 		}
 	}
 
-	if u.Support && u.RoomName != nil {
-		r := s.findRoom(ctx, *u.RoomName)
-		if r == nil {
-			var emptyRoom string
-
-			uEntity, _ := user.MapToEntity(u)
-			uEntity.SetRoom(&emptyRoom)
-
-			err := s.userSvc.UpdateUser(ctx, user.MapToDTO(uEntity))
-			if err != nil {
-				msg, err := s.encodeMessage(room.MessageResponse{
-					Action:  "",
-					Message: nil,
-					From:    "",
-					Error:   "failed update user",
-				})
+	if u.Support {
+		for _, roomName := range u.Rooms {
+			r := s.findRoom(ctx, roomName)
+			if r == nil {
+				err := s.userSvc.RemoveRoom(ctx, u.ID, roomName)
 				if err != nil {
-					s.logger.Errorf("failed to encode message %v", err)
+					s.sendMessage(client, room.MessageResponse{
+						Action:  "",
+						Message: nil,
+						From:    "",
+						Error:   "failed update user",
+					})
+					return
 				}
-
-				client.Send <- msg
-				return
+				continue
 			}
-		} else {
+
 			//s.cleanOldClientInRoom(r, u)
-			r.Clients[client] = true
+			r.AddClient(client)
+
+			err := s.roomSvc.ActivateRoom(ctx, r.Name, u.ID)
+			if err != nil && err != room.ErrNotFound {
+				s.logger.Errorf("failed to activate room %v", err)
+			}
 		}
+
+		s.addAvailableAgent(client)
 	}
 
+	s.mu.Lock()
 	s.clients[client] = true
+	s.mu.Unlock()
 	//fmt.Println(len(s.clients))
 	//for r := range s.rooms {
 	//	fmt.Println(len(r.Clients))
@@ -151,13 +191,7 @@ func (s *service) registerClientAndCreateRoom(ctx context.Context, client *room.
 //}
 
 func (s *service) findRoom(ctx context.Context, roomName string) *room.Room {
-	var foundRoom *room.Room
-	for r := range s.rooms {
-		if r.Name == roomName {
-			foundRoom = r
-			break
-		}
-	}
+	foundRoom := s.liveRoom(roomName)
 
 	if foundRoom == nil {
 		foundRoom = s.runRoomFromRepository(ctx, roomName)
@@ -166,18 +200,41 @@ func (s *service) findRoom(ctx context.Context, roomName string) *room.Room {
 	return foundRoom
 }
 
+// liveRoom returns the room if it runs on this instance, without loading it.
+func (s *service) liveRoom(roomName string) *room.Room {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rooms[roomName]
+}
+
 func (s *service) runRoomFromRepository(ctx context.Context, roomName string) *room.Room {
 	var r *room.Room
 	dbRoom, _ := s.roomSvc.GetRoomByName(ctx, roomName)
-	if dbRoom != nil {
+	if dbRoom != nil && dbRoom.State != room.StateClosed {
 		r, _ = room.NewRoom(dbRoom.Name)
-		go r.RunRoom(s.redisClient)
-		s.rooms[r] = true
+		r = s.runRoom(r)
 	}
 
 	return r
 }
 
+// runRoom starts the room unless another connection started it while the
+// database was asked, and returns the running one.
+func (s *service) runRoom(r *room.Room) *room.Room {
+	s.mu.Lock()
+	if running, ok := s.rooms[r.Name]; ok {
+		s.mu.Unlock()
+		return running
+	}
+	s.rooms[r.Name] = r
+	s.mu.Unlock()
+
+	go r.RunRoom(s.broker)
+
+	return r
+}
+
 func (s *service) createRoomIfDoesntExist(ctx context.Context, client *room.Client, u *user.DTO) {
 	newRoomId, err := uuid.NewUUID()
 	if err != nil {
@@ -197,14 +254,618 @@ func (s *service) createRoomIfDoesntExist(ctx context.Context, client *room.Clie
 			s.logger.Errorf("failed to create room %v", err)
 		}
 
-		client.Send <- msg
+		client.Enqueue(msg)
 		return
 	}
 
 	client.Room = newRoom
-	newRoom.Clients[client] = true
-	go newRoom.RunRoom(s.redisClient)
-	s.rooms[newRoom] = true
+	newRoom.AddClient(client)
+	s.runRoom(newRoom)
+}
+
+func (s *service) addAvailableAgent(client *room.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.agents = append(s.agents, client)
+}
+
+// forgetClient drops the client and returns the rooms its user no longer
+// observes from any connection of this instance.
+func (s *service) forgetClient(client *room.Client) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.positions, client)
+	delete(s.clients, client)
+
+	var unobserved []string
+	for _, r := range s.rooms {
+		r.RemoveClient(client)
+		if !r.HasObserver(client) {
+			continue
+		}
+
+		r.RemoveObserver(client)
+		if !r.Observes(client.Id) {
+			unobserved = append(unobserved, r.Name)
+		}
+	}
+
+	for i, agent := range s.agents {
+		if agent == client {
+			s.agents = append(s.agents[:i], s.agents[i+1:]...)
+			break
+		}
+	}
+
+	return unobserved
+}
+
+// CloseSessions tells every instance to close the connections opened with
+// the sessions. When the broker can't be reached this instance closes its own.
+func (s *service) CloseSessions(userId string, sids ...string) {
+	payload, err := json.Marshal(&closedSessions{UserId: userId, Sids: sids})
+	if err == nil {
+		err = s.broker.Publish(context.Background(), sessionsChannel, payload)
+	}
+	if err != nil {
+		s.logger.Errorf("failed to publish closed sessions %v", err)
+		s.closeLocalSessions(userId, sids...)
+	}
+}
+
+// RunSessionWatcher closes the connections of the sessions revoked on any
+// instance until ctx is done.
+func (s *service) RunSessionWatcher(ctx context.Context) {
+	ch, err := s.broker.Subscribe(ctx, sessionsChannel)
+	if err != nil {
+		s.logger.Errorf("failed to subscribe to closed sessions %v", err)
+		return
+	}
+
+	for msg := range ch {
+		var closed closedSessions
+		if err = json.Unmarshal(msg.Payload, &closed); err != nil {
+			s.logger.Errorf("failed to decode closed sessions %v", err)
+			continue
+		}
+		s.closeLocalSessions(closed.UserId, closed.Sids...)
+	}
+}
+
+// closeLocalSessions closes the connections of this instance opened with the
+// sessions, the read pumps then forget the clients as for any other disconnect.
+func (s *service) closeLocalSessions(userId string, sids ...string) {
+	revoked := make(map[string]bool, len(sids))
+	for _, sid := range sids {
+		revoked[sid] = true
+	}
+
+	s.mu.Lock()
+	var closing []*room.Client
+	for client := range s.clients {
+		if client.Id == userId && revoked[client.SessionId] {
+			closing = append(closing, client)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, client := range closing {
+		if err := client.Connection.Close(); err != nil {
+			s.logger.Errorf("failed to close connection of %v: %v", client.Id, err)
+		}
+	}
+}
+
+// dispatchQueue assigns waiting rooms to agents with spare capacity in
+// arrival order and then tells every customer who is still waiting their
+// new queue position.
+func (s *service) dispatchQueue(ctx context.Context) {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	s.mu.Lock()
+	queue := append([]*room.Client(nil), s.agents...)
+	s.mu.Unlock()
+
+	var busy []*room.Client
+	for len(queue) > 0 {
+		agent := queue[0]
+
+		agentDTO, err := s.userSvc.GetUserById(ctx, agent.Id, false)
+		if err != nil {
+			s.logger.Errorf("failed to get agent %v", err)
+			queue = queue[1:]
+			busy = append(busy, agent)
+			continue
+		}
+
+		if !s.userSvc.HasCapacity(agentDTO) {
+			queue = queue[1:]
+			busy = append(busy, agent)
+			continue
+		}
+
+		assigned, err := s.roomSvc.AssignNextRoom(ctx, agentDTO)
+		if err == room.ErrQueueEmpty {
+			break
+		}
+		// one agent failing mustn't stop the queue for everyone else
+		if err != nil {
+			if err != user.ErrNoCapacity {
+				s.logger.Errorf("failed to assign room to agent %v: %v", agent.Id, err)
+			}
+			queue = queue[1:]
+			busy = append(busy, agent)
+			continue
+		}
+		// the agent goes to the back so conversations are spread evenly
+		queue = append(queue[1:], agent)
+
+		r := s.findRoom(ctx, assigned.Name)
+		if r != nil {
+			s.mu.Lock()
+			for _, client := range r.Clients() {
+				delete(s.positions, client)
+			}
+			s.mu.Unlock()
+
+			r.AddClient(agent)
+		}
+
+		s.sendMessage(agent, room.MessageResponse{
+			Action: "room-assigned",
+			Data: room.RoomAssigned{
+				RoomName:   assigned.Name,
+				CustomerId: assigned.CustomerId,
+			},
+		})
+	}
+	s.setAgentOrder(append(queue, busy...))
+
+	s.broadcastQueuePositions(ctx)
+}
+
+// setAgentOrder stores the order dispatchQueue left the agents in. Agents who
+// connected meanwhile keep their place behind them, the ones who left are
+// dropped.
+func (s *service) setAgentOrder(order []*room.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	connected := make(map[*room.Client]bool, len(s.agents))
+	for _, agent := range s.agents {
+		connected[agent] = true
+	}
+
+	agents := make([]*room.Client, 0, len(s.agents))
+	for _, agent := range order {
+		if connected[agent] {
+			agents = append(agents, agent)
+			delete(connected, agent)
+		}
+	}
+	for _, agent := range s.agents {
+		if connected[agent] {
+			agents = append(agents, agent)
+		}
+	}
+
+	s.agents = agents
+}
+
+// RunLeaseWatcher returns rooms whose claim lease expired to the queue and
+// keeps the queue moving until ctx is done.
+func (s *service) RunLeaseWatcher(ctx context.Context) {
+	ticker := time.NewTicker(leaseCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requeued, err := s.roomSvc.RequeueExpiredClaims(ctx)
+			if err != nil {
+				s.logger.Errorf("failed to requeue expired claims %v", err)
+			}
+
+			for _, r := range requeued {
+				s.logger.Infof("claim of room %v expired, room returned to the queue", r.Name)
+			}
+
+			s.dispatchQueue(ctx)
+		}
+	}
+}
+
+// TransferRoom moves the room to another agent, or back to the queue when
+// agentId is empty, and moves the connected agent clients along with it.
+func (s *service) TransferRoom(ctx context.Context, from *user.DTO, name, agentId, note string) (*room.DTO, error) {
+	transferred, err := s.roomSvc.TransferRoom(ctx, name, from, agentId, note)
+	if err != nil {
+		return nil, err
+	}
+
+	event := room.TransferEvent{
+		RoomName:   name,
+		CustomerId: transferred.CustomerId,
+		From:       from.ID,
+		To:         agentId,
+		Note:       note,
+	}
+
+	r := s.findRoom(ctx, name)
+	if r != nil {
+		for _, client := range r.Clients() {
+			if client.Id == from.ID {
+				r.RemoveClient(client)
+				s.sendMessage(client, room.MessageResponse{Action: "room-left", RoomName: name, Data: event})
+			}
+		}
+
+		if agentId != "" {
+			for _, client := range s.clientsOf(agentId) {
+				r.AddClient(client)
+				s.sendMessage(client, room.MessageResponse{Action: "room-joined", RoomName: name, Data: event})
+			}
+		}
+
+		text := "You have been transferred to another agent"
+		if agentId == "" {
+			text = "You have been returned to the queue, the next free agent will continue"
+		}
+
+		r.Send(&room.BroadcastMessage{
+			Action: "system",
+			Message: room.MessageResponse{
+				Action:   "system",
+				RoomName: name,
+				Data:     room.SystemMessage{Text: text},
+			},
+			RoomName: name,
+		})
+	}
+
+	if agentId == "" {
+		s.dispatchQueue(ctx)
+	}
+
+	return transferred, nil
+}
+
+// InviteToRoom brings another agent or a supervisor into the room next to the
+// agents already in it.
+func (s *service) InviteToRoom(ctx context.Context, by *user.DTO, name, agentId, note string) (*room.DTO, error) {
+	invited, err := s.roomSvc.InviteToRoom(ctx, name, by, agentId)
+	if err != nil {
+		return nil, err
+	}
+
+	r := s.findRoom(ctx, name)
+	if r != nil {
+		for _, client := range s.clientsOf(agentId) {
+			r.AddClient(client)
+			s.sendMessage(client, room.MessageResponse{
+				Action:   "room-joined",
+				RoomName: name,
+				Data: room.InviteEvent{
+					RoomName:   name,
+					CustomerId: invited.CustomerId,
+					InvitedBy:  by.ID,
+					Note:       note,
+				},
+			})
+		}
+
+		s.broadcastRoster(r, "participant-joined", invited)
+	}
+
+	return invited, nil
+}
+
+// leaveRoom takes the agent out of the room, the room is closed once the last
+// agent is gone.
+func (s *service) leaveRoom(ctx context.Context, u *user.DTO, roomName string) {
+	left, err := s.roomSvc.LeaveRoom(ctx, roomName, u)
+	if err != nil {
+		s.logger.Errorf("failed to leave room %v", err)
+		s.notifyUser(u.ID, room.MessageResponse{Action: "disconnect", RoomName: roomName, Error: err})
+		return
+	}
+
+	if r := s.liveRoom(roomName); r != nil {
+		for _, client := range r.Clients() {
+			if client.Id == u.ID {
+				r.RemoveClient(client)
+				s.sendMessage(client, room.MessageResponse{Action: "room-closed", RoomName: roomName})
+			}
+		}
+
+		if hasAgents(left) {
+			s.broadcastRoster(r, "participant-left", left)
+		}
+	}
+
+	if !hasAgents(left) {
+		s.closeRoom(ctx, roomName, u.ID, room.CloseReasonAgentsLeft)
+	}
+}
+
+// closeRoom ends the conversation for everyone in the room. Agents keep their
+// connection for the other conversations they handle, customers are disconnected.
+func (s *service) closeRoom(ctx context.Context, roomName, closedBy, reason string) {
+	closed, err := s.roomSvc.CloseRoom(ctx, roomName, closedBy, reason)
+	if err != nil {
+		s.logger.Errorf("failed to close room %v", err)
+		return
+	}
+
+	event := room.MessageResponse{
+		Action:   "room-closed",
+		RoomName: roomName,
+		Data:     room.RoomClosed{RoomName: roomName, ClosedBy: closed.ClosedBy, CloseReason: closed.CloseReason},
+	}
+
+	s.mu.Lock()
+	r := s.rooms[roomName]
+	delete(s.rooms, roomName)
+	s.mu.Unlock()
+
+	if r != nil {
+		// the customer stays connected to answer the rating prompt
+		for _, client := range r.Clients() {
+			r.RemoveClient(client)
+			s.sendMessage(client, event)
+			if client.Id == closed.CustomerId {
+				s.sendMessage(client, room.MessageResponse{
+					Action:   "rate-conversation",
+					RoomName: roomName,
+					Data:     room.RatingPrompt{RoomName: roomName, AgentId: closed.AgentId},
+				})
+			}
+		}
+
+		for _, client := range r.Observers() {
+			s.sendMessage(client, event)
+		}
+
+		r.Close()
+	}
+}
+
+// broadcastRoster tells the room about a roster change. The customer gets the
+// roster too, so the observers are left out.
+func (s *service) broadcastRoster(r *room.Room, action string, dto *room.DTO) {
+	r.Send(&room.BroadcastMessage{
+		Action: action,
+		Message: room.MessageResponse{
+			Action:   action,
+			RoomName: r.Name,
+			Data:     room.Roster{RoomName: r.Name, Participants: room.CustomerRoster(dto.Participants)},
+		},
+		RoomName: r.Name,
+	})
+}
+
+// broadcastObservers sends the whole roster, with the observers, to the agents.
+func (s *service) broadcastObservers(r *room.Room, action string, dto *room.DTO) {
+	r.Send(&room.BroadcastMessage{
+		Action: action,
+		Message: room.MessageResponse{
+			Action:   action,
+			RoomName: r.Name,
+			Data:     room.Roster{RoomName: r.Name, Participants: dto.Participants},
+		},
+		RoomName:   r.Name,
+		AgentsOnly: true,
+	})
+}
+
+// unobserveRoom takes the supervisor off the roster once they stopped
+// observing the room.
+func (s *service) unobserveRoom(ctx context.Context, id, roomName string) {
+	dto, err := s.roomSvc.UnobserveRoom(ctx, roomName, id)
+	if err != nil {
+		s.logger.Errorf("failed to unobserve room %v", err)
+		return
+	}
+
+	if r := s.liveRoom(roomName); r != nil {
+		s.broadcastObservers(r, "observer-left", dto)
+	}
+}
+
+func hasAgents(dto *room.DTO) bool {
+	for _, p := range dto.Participants {
+		if p.Role.Agent() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *service) broadcastQueuePositions(ctx context.Context) {
+	waiting, err := s.roomSvc.GetWaitingRooms(ctx)
+	if err != nil {
+		s.logger.Errorf("failed to get waiting rooms %v", err)
+		return
+	}
+
+	if len(waiting) == 0 {
+		return
+	}
+
+	waitPerPosition := s.roomSvc.EstimateWait(ctx, 1)
+
+	type update struct {
+		client *room.Client
+		msg    room.MessageResponse
+	}
+
+	var updates []update
+	s.mu.Lock()
+	for i, waitingRoom := range waiting {
+		position := i + 1
+
+		r := s.rooms[waitingRoom.Name]
+		if r == nil {
+			continue
+		}
+
+		for _, client := range r.Clients() {
+			if client.Id != waitingRoom.CustomerId || s.positions[client] == position {
+				continue
+			}
+			s.positions[client] = position
+
+			updates = append(updates, update{client: client, msg: room.MessageResponse{
+				Action: "queue-position",
+				Data: room.QueuePosition{
+					RoomName:      waitingRoom.Name,
+					Position:      position,
+					EstimatedWait: int64((waitPerPosition * time.Duration(position)).Seconds()),
+				},
+			}})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, u := range updates {
+		s.sendMessage(u.client, u.msg)
+	}
+}
+
+// presenceChanged tells the rooms of the client about its new presence. The
+// user stays online while another of their connections is.
+func (s *service) presenceChanged(client *room.Client, presence room.Presence) {
+	s.mu.Lock()
+	if presence != room.PresenceOnline {
+		for other := range s.clients {
+			if other != client && other.Id == client.Id && other.Presence() == room.PresenceOnline {
+				s.mu.Unlock()
+				return
+			}
+		}
+	}
+
+	var rooms []*room.Room
+	for _, r := range s.rooms {
+		if r.HasClient(client) {
+			rooms = append(rooms, r)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, r := range rooms {
+		r.Send(&room.BroadcastMessage{
+			Action: "presence",
+			Message: room.MessageResponse{
+				Action:   "presence",
+				From:     client.Id,
+				RoomName: r.Name,
+				Data:     room.PresenceEvent{UserId: client.Id, Status: presence},
+			},
+			RoomName: r.Name,
+		})
+	}
+}
+
+// messageDelivered moves the delivered cursor of the client's user once a chat
+// message of someone else was written to their socket.
+func (s *service) messageDelivered(client *room.Client, message []byte) {
+	var msg room.MessageResponse
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return
+	}
+
+	if msg.Id == "" || msg.From == client.Id || (msg.Action != "publish-room" && msg.Action != "whisper") {
+		return
+	}
+
+	// the write pump shouldn't wait for the database
+	go s.sendReceipt(context.Background(), msg.RoomName, client.Id, msg.Id, room.ReceiptDelivered)
+}
+
+// sendReceipt stores the receipt and tells the room about it when it moved a cursor.
+func (s *service) sendReceipt(ctx context.Context, roomName, userId, messageId string, receipt room.Receipt) {
+	var moved bool
+	var err error
+	if receipt == room.ReceiptRead {
+		moved, err = s.roomSvc.MarkRead(ctx, roomName, userId, messageId)
+	} else {
+		moved, err = s.roomSvc.MarkDelivered(ctx, roomName, userId, messageId)
+	}
+	if err != nil {
+		s.logger.Errorf("failed to store receipt %v", err)
+		s.notifyUser(userId, room.MessageResponse{Action: "receipt", RoomName: roomName, Error: err})
+		return
+	}
+	if !moved {
+		return
+	}
+
+	if r := s.liveRoom(roomName); r != nil {
+		r.Send(&room.BroadcastMessage{
+			Action: "receipt",
+			Message: room.MessageResponse{
+				Action:   "receipt",
+				From:     userId,
+				RoomName: roomName,
+				Data:     room.ReceiptEvent{UserId: userId, MessageId: messageId, Status: receipt},
+			},
+			RoomName: roomName,
+		})
+	}
+}
+
+// observing reports whether the user is on the roster of the room as observer.
+func (s *service) observing(ctx context.Context, id, roomName string) bool {
+	dto, err := s.roomSvc.GetRoomByName(ctx, roomName)
+	if err != nil || dto.State == room.StateClosed {
+		return false
+	}
+
+	for _, p := range dto.Participants {
+		if p.UserId == id && p.Role == room.RoleObserver {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notifyUser sends msg to every connection of the user on this instance.
+func (s *service) notifyUser(id string, msg room.MessageResponse) {
+	for _, client := range s.clientsOf(id) {
+		s.sendMessage(client, msg)
+	}
+}
+
+// clientsOf returns the connections of the user on this instance.
+func (s *service) clientsOf(id string) []*room.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var clients []*room.Client
+	for client := range s.clients {
+		if client.Id == id {
+			clients = append(clients, client)
+		}
+	}
+
+	return clients
+}
+
+func (s *service) sendMessage(client *room.Client, msg room.MessageResponse) {
+	encMsg, err := s.encodeMessage(msg)
+	if err != nil {
+		return
+	}
+
+	client.Enqueue(encMsg)
 }
 
 func (s *service) encodeMessage(msg room.MessageResponse) ([]byte, error) {
@@ -216,131 +877,275 @@ func (s *service) encodeMessage(msg room.MessageResponse) ([]byte, error) {
 	return encMsg, err
 }
 
-func (s *service) messageHandler(jsonMessage []byte) {
+// messageHandler acts on the message as the user the connection was opened
+// for. The user is loaded again for every message, the rooms and the roles
+// change while the connection is open.
+func (s *service) messageHandler(client *room.Client, jsonMessage []byte) {
 	var message room.Message
 	if err := json.Unmarshal(jsonMessage, &message); err != nil {
 		s.logger.Errorf("Error on unmarshal JSON message %s", err)
 		return
 	}
 
+	dbUser, err := s.userSvc.GetUserById(context.Background(), client.Id, false)
+	if err != nil {
+		s.logger.Errorf("failed to get user %v", err)
+		return
+	}
+	if dbUser.Disabled {
+		s.logger.Errorf("user %v is disabled", dbUser.ID)
+		return
+	}
+
 	switch message.Action {
 	case "publish-room":
-		uPayload, err := s.jwtSvc.ParseToken(message.Token, true)
-		if err != nil {
-			s.logger.Errorf("failed to parse token %v", err)
+		roomName, ok := targetRoom(&message, dbUser)
+		if !ok {
+			s.logger.Errorf("user %v is not a participant of room %v", dbUser.ID, message.RoomName)
+			return
+		}
+
+		r := s.liveRoom(roomName)
+		if r == nil {
+			return
 		}
 
-		dbUser, err := s.userSvc.GetUserById(context.Background(), uPayload.Id, false)
+		stored, err := s.roomSvc.AddMessage(context.Background(), roomName, &room.RoomMessage{
+			Id:      dbUser.ID,
+			Time:    time.Now(),
+			Message: message.Message,
+		})
 		if err != nil {
-			s.logger.Errorf("failed to get user %v", err)
+			r.Send(&room.BroadcastMessage{
+				Action: message.Action,
+				Message: room.MessageResponse{
+					Action:   "",
+					Message:  nil,
+					From:     "",
+					RoomName: roomName,
+					Error:    "failed update room",
+				},
+				RoomName: roomName,
+			})
+			return
 		}
 
-		dbRoom, err := s.roomSvc.GetRoomByName(context.Background(), *dbUser.RoomName)
+		r.Send(&room.BroadcastMessage{
+			Action: message.Action,
+			Message: room.MessageResponse{
+				Id:       stored.ID.Hex(),
+				Action:   message.Action,
+				Message:  &message.Message,
+				From:     dbUser.ID,
+				RoomName: roomName,
+				Error:    nil,
+			},
+			RoomName: roomName,
+		})
+	case "whisper":
+		// agents of the room and supervisors observing it may whisper
+		roomName, ok := targetRoom(&message, dbUser)
+		if !ok && s.observing(context.Background(), dbUser.ID, message.RoomName) {
+			roomName, ok = message.RoomName, true
+		}
+		if !dbUser.Support || !ok {
+			s.logger.Errorf("user %v can't whisper in room %v", dbUser.ID, message.RoomName)
+			return
+		}
+
+		stored, err := s.roomSvc.AddMessage(context.Background(), roomName, &room.RoomMessage{
+			Id:         dbUser.ID,
+			Time:       time.Now(),
+			Message:    message.Message,
+			AgentsOnly: true,
+		})
 		if err != nil {
-			s.logger.Errorf("failed to get room %v", err)
+			s.notifyUser(dbUser.ID, room.MessageResponse{Action: message.Action, RoomName: roomName, Error: err})
+			return
 		}
 
-		for r := range s.rooms {
-			if r.Name == *dbUser.RoomName {
-				var msg []*room.RoomMessage
+		r := s.findRoom(context.Background(), roomName)
+		if r == nil {
+			return
+		}
 
-				if dbRoom.Messages == nil {
-					msg = append(msg, &room.RoomMessage{
-						Id:      dbUser.ID,
-						Time:    time.Now(),
-						Message: message.Message,
-					})
-				} else {
-					msg = append(*dbRoom.Messages, &room.RoomMessage{
-						Id:      dbUser.ID,
-						Time:    time.Now(),
-						Message: message.Message,
-					})
-				}
-				dbRoom.Messages = &msg
+		r.Send(&room.BroadcastMessage{
+			Action: message.Action,
+			Message: room.MessageResponse{
+				Id:       stored.ID.Hex(),
+				Action:   message.Action,
+				Message:  &message.Message,
+				From:     dbUser.ID,
+				RoomName: roomName,
+			},
+			RoomName:   roomName,
+			AgentsOnly: true,
+		})
+	case "typing-start", "typing-stop":
+		roomName, ok := targetRoom(&message, dbUser)
+		if !ok {
+			return
+		}
 
-				////////
-				err := s.roomSvc.UpdateRoom(context.Background(), dbRoom)
-				if err != nil {
+		// typing is only fanned out, it never reaches the room store
+		r := s.liveRoom(roomName)
+		if r == nil {
+			return
+		}
 
-					r.Broadcast <- &room.BroadcastMessage{
-						Action: message.Action,
-						Message: room.MessageResponse{
-							Action:  "",
-							Message: nil,
-							From:    "",
-							Error:   "failed update room",
-						},
-						RoomName: *dbUser.RoomName,
-					}
-				}
+		r.Send(&room.BroadcastMessage{
+			Action: message.Action,
+			Message: room.MessageResponse{
+				Action:   message.Action,
+				From:     dbUser.ID,
+				RoomName: roomName,
+			},
+			RoomName: roomName,
+		})
+	case "mark-read":
+		roomName, ok := targetRoom(&message, dbUser)
+		if !ok {
+			s.logger.Errorf("user %v is not a participant of room %v", dbUser.ID, message.RoomName)
+			return
+		}
+
+		s.sendReceipt(context.Background(), roomName, dbUser.ID, message.MessageId, room.ReceiptRead)
+	case "edit-message", "delete-message":
+		roomName, ok := targetRoom(&message, dbUser)
+		if !ok {
+			s.logger.Errorf("user %v is not a participant of room %v", dbUser.ID, message.RoomName)
+			return
+		}
 
-				r.Broadcast <- &room.BroadcastMessage{
-					Action: message.Action,
-					Message: room.MessageResponse{
-						Action:  message.Action,
-						Message: &message.Message,
-						From:    dbUser.ID,
-						Error:   nil,
-					},
-					RoomName: *dbUser.RoomName,
+		var revised *room.RoomMessage
+		action := "message-edited"
+		if message.Action == "edit-message" {
+			revised, err = s.roomSvc.EditMessage(context.Background(), roomName, message.MessageId, dbUser, message.Message)
+		} else {
+			revised, err = s.roomSvc.DeleteMessage(context.Background(), roomName, message.MessageId, dbUser)
+			action = "message-deleted"
+		}
+		if err != nil {
+			s.notifyUser(dbUser.ID, room.MessageResponse{Action: message.Action, RoomName: roomName, Error: err})
+			return
+		}
+
+		r := s.findRoom(context.Background(), roomName)
+		if r == nil {
+			return
+		}
+
+		response := room.MessageResponse{
+			Id:       revised.ID.Hex(),
+			Action:   action,
+			From:     revised.Id,
+			RoomName: roomName,
+		}
+		if revised.DeletedAt == nil {
+			response.Message = &revised.Message
+		}
+
+		r.Send(&room.BroadcastMessage{
+			Action:     action,
+			Message:    response,
+			RoomName:   roomName,
+			AgentsOnly: revised.AgentsOnly,
+		})
+	case "observe", "unobserve":
+		if message.Action == "unobserve" {
+			if r := s.liveRoom(message.RoomName); r != nil {
+				for _, client := range s.clientsOf(dbUser.ID) {
+					r.RemoveObserver(client)
+					s.sendMessage(client, room.MessageResponse{Action: message.Action, RoomName: r.Name})
 				}
 			}
+			s.unobserveRoom(context.Background(), dbUser.ID, message.RoomName)
+			return
+		}
+
+		// only supervisors can observe, the room service checks the policy
+		observed, err := s.roomSvc.ObserveRoom(context.Background(), message.RoomName, dbUser)
+		if err != nil {
+			s.notifyUser(dbUser.ID, room.MessageResponse{Action: message.Action, RoomName: message.RoomName, Error: err})
+			return
+		}
+
+		r := s.findRoom(context.Background(), observed.Name)
+		if r == nil {
+			return
+		}
+
+		for _, client := range s.clientsOf(dbUser.ID) {
+			r.AddObserver(client)
+			s.sendMessage(client, room.MessageResponse{Action: message.Action, RoomName: r.Name})
 		}
+		s.broadcastObservers(r, "observer-joined", observed)
 	case "disconnect":
-		uPayload, err := s.jwtSvc.ParseToken(message.Token, true)
+		roomName, ok := targetRoom(&message, dbUser)
+		if !ok {
+			s.logger.Errorf("user %v is not a participant of room %v", dbUser.ID, message.RoomName)
+			return
+		}
+
+		// the room stays open for the customer while another agent is still in it
+		if dbUser.Support {
+			s.leaveRoom(context.Background(), dbUser, roomName)
+		} else {
+			reason := message.Reason
+			if reason == "" {
+				reason = room.CloseReasonCustomerEnded
+			}
+			s.closeRoom(context.Background(), roomName, dbUser.ID, reason)
+		}
+
+		// the agents of the closed room can take the next customer
+		s.dispatchQueue(context.Background())
+	case "rate-conversation":
+		rating, err := s.roomSvc.RateRoom(context.Background(), message.RoomName, dbUser, message.Score, message.Comment)
 		if err != nil {
-			s.logger.Errorf("failed to parse token %v", err)
+			s.logger.Errorf("failed to rate room %v", err)
+			s.notifyUser(dbUser.ID, room.MessageResponse{Action: message.Action, RoomName: message.RoomName, Error: err})
+			return
 		}
 
-		dbUser, err := s.userSvc.GetUserById(context.Background(), uPayload.Id, false)
+		s.notifyUser(dbUser.ID, room.MessageResponse{Action: message.Action, RoomName: message.RoomName, Data: rating})
+	case "transfer":
+		_, err = s.TransferRoom(context.Background(), dbUser, message.RoomName, message.AgentId, message.Note)
 		if err != nil {
-			s.logger.Errorf("failed to get user %v", err)
-		}
-
-		for r := range s.rooms {
-			if r.Name == *dbUser.RoomName {
-				for client := range r.Clients {
-					rUser, err := s.userSvc.GetUserById(context.Background(), client.Id, true)
-					if err != nil {
-						s.logger.Errorf("failed to get user %v", err)
-					}
-
-					userEntity, _ := user.MapToEntity(rUser)
-					userEntity.SetRoom(nil)
-					userEntity.SetFreeStatus(true)
-					err = s.userSvc.UpdateUser(context.Background(), user.MapToDTO(userEntity))
-					if err != nil {
-						msg, _ := s.encodeMessage(room.MessageResponse{
-							Action:  "",
-							Message: nil,
-							From:    "",
-							Error:   "failed update user",
-						})
-						client.Send <- msg
-						return
-					}
-
-					close(client.Send)
-					err = client.Connection.Close()
-					if err != nil {
-						s.logger.Errorf("failed close connection %v", err)
-					}
-
-					for serverClient := range s.clients {
-						if serverClient.Id == client.Id {
-							delete(s.clients, client)
-						}
-					}
-				}
+			s.logger.Errorf("failed to transfer room %v", err)
+			s.notifyUser(dbUser.ID, room.MessageResponse{
+				Action:   message.Action,
+				RoomName: message.RoomName,
+				Error:    err,
+			})
+		}
+	case "invite":
+		_, err = s.InviteToRoom(context.Background(), dbUser, message.RoomName, message.AgentId, message.Note)
+		if err != nil {
+			s.logger.Errorf("failed to invite agent %v", err)
+			s.notifyUser(dbUser.ID, room.MessageResponse{
+				Action:   message.Action,
+				RoomName: message.RoomName,
+				Error:    err,
+			})
+		}
+	}
+}
 
-				err := s.roomSvc.DeleteRoom(context.Background(), r.Name)
-				if err != nil {
-					s.logger.Errorf("failed ddelete room %v", err)
-				}
-				delete(s.rooms, r)
-				break
-			}
+// targetRoom resolves the room a message is meant for. Customers may leave it
+// empty and fall back to their own room, agents have to name one of theirs.
+func targetRoom(message *room.Message, u *user.DTO) (string, bool) {
+	if message.RoomName == "" {
+		if u.Support || u.RoomName == nil {
+			return "", false
 		}
+		return *u.RoomName, true
 	}
+
+	uEntity, err := user.MapToEntity(u)
+	if err != nil || !uEntity.HasRoom(message.RoomName) {
+		return "", false
+	}
+
+	return message.RoomName, true
 }
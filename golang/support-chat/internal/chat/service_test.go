@@ -1,66 +1,346 @@
 package chat_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"support-chat/internal/chat"
 	"support-chat/internal/chat/room"
 	mock_room "support-chat/internal/chat/room/mocks"
 	"support-chat/internal/user"
 	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/errors"
 	"support-chat/pkg/jwt"
-	mock_jwt "support-chat/pkg/jwt/mocks"
+	"support-chat/pkg/logger"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/go-redis/redis/v8"
+	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
+// readTimeout is how long a test waits for a message of the chat
+const readTimeout = 2 * time.Second
+
+// chatTest runs the chat service behind a websocket server the way the router
+// serves it. Its broker hands every published message to the subscribers of
+// the channel, as redis does.
+type chatTest struct {
+	roomSvc *mock_room.MockService
+	userSvc *mock_user.MockService
+	server  *httptest.Server
+
+	mu    sync.Mutex
+	users map[string]*user.DTO
+	rooms map[string]*room.DTO
+	subs  map[string][]chan *room.BrokerMessage
+	// registered gets a value once the service registered a new connection
+	registered chan struct{}
+}
+
+// newChatTest runs setup before the default expectations, gomock uses the
+// first expectation that matches a call, so the ones of the test win.
+func newChatTest(t *testing.T, controller *gomock.Controller, setup func(*chatTest)) *chatTest {
+	ct := &chatTest{
+		roomSvc:    mock_room.NewMockService(controller),
+		userSvc:    mock_user.NewMockService(controller),
+		users:      make(map[string]*user.DTO),
+		rooms:      make(map[string]*room.DTO),
+		subs:       make(map[string][]chan *room.BrokerMessage),
+		registered: make(chan struct{}, 1),
+	}
+
+	broker := mock_room.NewMockBroker(controller)
+	broker.EXPECT().Subscribe(gomock.Any(), gomock.Any()).DoAndReturn(ct.subscribe).AnyTimes()
+	broker.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(ct.publish).AnyTimes()
+
+	if setup != nil {
+		setup(ct)
+	}
+
+	ct.userSvc.EXPECT().GetUserById(gomock.Any(), gomock.Any(), false).
+		DoAndReturn(func(_ context.Context, id string, _ bool) (*user.DTO, error) {
+			u := ct.user(id)
+			if u == nil {
+				return nil, user.ErrNotFound
+			}
+			return u, nil
+		}).AnyTimes()
+	ct.userSvc.EXPECT().HasCapacity(gomock.Any()).Return(true).AnyTimes()
+	ct.roomSvc.EXPECT().GetRoomByName(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, name string) (*room.DTO, error) {
+			ct.mu.Lock()
+			defer ct.mu.Unlock()
+
+			dto, ok := ct.rooms[name]
+			if !ok {
+				return nil, room.ErrNotFound
+			}
+			return dto, nil
+		}).AnyTimes()
+	ct.roomSvc.EXPECT().ActivateRoom(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	ct.roomSvc.EXPECT().AssignNextRoom(gomock.Any(), gomock.Any()).Return(nil, room.ErrQueueEmpty).AnyTimes()
+	ct.roomSvc.EXPECT().GetWaitingRooms(gomock.Any()).Return(nil, nil).AnyTimes()
+	ct.roomSvc.EXPECT().MarkDelivered(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	ct.roomSvc.EXPECT().UnobserveRoom(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, name, _ string) (*room.DTO, error) {
+			return ct.roomSvc.GetRoomByName(ctx, name)
+		}).AnyTimes()
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	svc, _ := chat.NewService(broker, ct.roomSvc, ct.userSvc, zapLogger)
+	handler, _ := chat.NewHandler(svc)
+
+	router := chi.NewRouter()
+	router.Use(ct.authenticate)
+	handler.SetupRoutes(router)
+
+	ct.server = httptest.NewServer(router)
+	t.Cleanup(ct.server.Close)
+
+	return ct
+}
+
+// authenticate stands in for the ticket middleware, the user is picked by the
+// id query parameter.
+func (ct *chatTest) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		principal := &user.Principal{User: *ct.user(id), Payload: &jwt.Payload{Id: id, Sid: "sid"}}
+
+		// Chat returns once the client is registered
+		next.ServeHTTP(w, r.WithContext(user.WithPrincipal(r.Context(), principal)))
+		ct.registered <- struct{}{}
+	})
+}
+
+func (ct *chatTest) subscribe(_ context.Context, channels ...string) (<-chan *room.BrokerMessage, error) {
+	ch := make(chan *room.BrokerMessage, 256)
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for _, channel := range channels {
+		ct.subs[channel] = append(ct.subs[channel], ch)
+	}
+
+	return ch, nil
+}
+
+func (ct *chatTest) publish(_ context.Context, channel string, payload []byte) error {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	for _, ch := range ct.subs[channel] {
+		ch <- &room.BrokerMessage{Channel: channel, Payload: payload}
+	}
+
+	return nil
+}
+
+// setUser stores a copy of the user, the service gets the stored one.
+func (ct *chatTest) setUser(u *user.DTO) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	stored := *u
+	ct.users[u.ID] = &stored
+}
+
+func (ct *chatTest) user(id string) *user.DTO {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	stored, ok := ct.users[id]
+	if !ok {
+		return nil
+	}
+
+	u := *stored
+	return &u
+}
+
+func (ct *chatTest) setRoom(dto *room.DTO) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	ct.rooms[dto.Name] = dto
+}
+
+// connect opens a websocket as the user and waits until the service
+// registered it.
+func (ct *chatTest) connect(t *testing.T, u *user.DTO) *chatConn {
+	t.Helper()
+
+	ct.setUser(u)
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ct.server.URL, "http")+"/chat?id="+u.ID, nil)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = ws.Close() })
+
+	select {
+	case <-ct.registered:
+	case <-time.After(readTimeout):
+		t.Fatalf("connection of %v wasn't registered", u.ID)
+	}
+
+	return &chatConn{ws: ws}
+}
+
+// response is a room.MessageResponse as the client reads it.
+type response struct {
+	Id       string                 `json:"id"`
+	Action   string                 `json:"action"`
+	Message  *room.EncryptedMessage `json:"message"`
+	From     string                 `json:"from"`
+	RoomName string                 `json:"roomName"`
+	Data     json.RawMessage        `json:"data"`
+	Error    json.RawMessage        `json:"error"`
+}
+
+func (r *response) decode(t *testing.T, v interface{}) {
+	t.Helper()
+
+	assert.Nil(t, json.Unmarshal(r.Data, v))
+}
+
+// status is the status of the error the message carries.
+func (r *response) status() errors.Status {
+	var err errors.Error
+	_ = json.Unmarshal(r.Error, &err)
+
+	return err.Status
+}
+
+// chatConn is the client side of a connection. The write pump batches queued
+// messages into one frame, they are read one by one.
+type chatConn struct {
+	ws      *websocket.Conn
+	pending [][]byte
+}
+
+func (c *chatConn) send(t *testing.T, msg room.Message) {
+	t.Helper()
+
+	assert.Nil(t, c.ws.WriteJSON(msg))
+}
+
+// next reads until a message with the action arrives and returns it with the
+// messages that came before it.
+func (c *chatConn) next(t *testing.T, action string) (*response, []*response) {
+	t.Helper()
+
+	var skipped []*response
+	for {
+		for len(c.pending) == 0 {
+			_ = c.ws.SetReadDeadline(time.Now().Add(readTimeout))
+			_, frame, err := c.ws.ReadMessage()
+			if err != nil {
+				t.Fatalf("no %v message: %v", action, err)
+			}
+			c.pending = bytes.Split(frame, []byte{'\n'})
+		}
+
+		var msg response
+		err := json.Unmarshal(c.pending[0], &msg)
+		c.pending = c.pending[1:]
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+
+		if msg.Action == action {
+			return &msg, skipped
+		}
+		skipped = append(skipped, &msg)
+	}
+}
+
+// actions lists the actions of the messages.
+func actions(msgs []*response) []string {
+	list := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		list = append(list, msg.Action)
+	}
+
+	return list
+}
+
+func newCustomer(roomName string) *user.DTO {
+	return &user.DTO{ID: primitive.NewObjectID().Hex(), Verified: true, RoomName: &roomName}
+}
+
+func newAgent(rooms ...string) *user.DTO {
+	return &user.DTO{ID: primitive.NewObjectID().Hex(), Support: true, Verified: true, Rooms: rooms}
+}
+
+// newRoomDTO returns the room of the customer served by the agents.
+func newRoomDTO(name string, state room.State, customer *user.DTO, agents ...*user.DTO) *room.DTO {
+	dto := &room.DTO{
+		Name:         name,
+		CustomerId:   customer.ID,
+		State:        state,
+		Participants: []*room.Participant{{UserId: customer.ID, Role: room.RoleCustomer}},
+	}
+	for _, agent := range agents {
+		dto.AgentId = agent.ID
+		dto.Participants = append(dto.Participants, &room.Participant{UserId: agent.ID, Role: room.RoleAgent})
+	}
+
+	return dto
+}
+
 func TestNewService(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
 	tests := []struct {
-		name        string
-		redisClient *redis.Client
-		roomSvc     room.Service
-		jwtSvc      jwt.Service
-		userSvc     user.Service
-		logger      *zap.SugaredLogger
-		expect      func(*testing.T, chat.Service, error)
+		name    string
+		broker  room.Broker
+		roomSvc room.Service
+		userSvc user.Service
+		logger  *zap.SugaredLogger
+		expect  func(*testing.T, chat.Service, error)
 	}{
 		{
-			name:        "should return service",
-			redisClient: &redis.Client{},
-			roomSvc:     mock_room.NewMockService(controller),
-			jwtSvc:      mock_jwt.NewMockService(controller),
-			userSvc:     mock_user.NewMockService(controller),
-			logger:      &zap.SugaredLogger{},
+			name:    "should return service",
+			broker:  mock_room.NewMockBroker(controller),
+			roomSvc: mock_room.NewMockService(controller),
+			userSvc: mock_user.NewMockService(controller),
+			logger:  &zap.SugaredLogger{},
 			expect: func(t *testing.T, s chat.Service, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:        "should return invalid redis chat client",
-			redisClient: nil,
-			roomSvc:     mock_room.NewMockService(controller),
-			jwtSvc:      mock_jwt.NewMockService(controller),
-			userSvc:     mock_user.NewMockService(controller),
-			logger:      &zap.SugaredLogger{},
+			name:    "should return invalid broker",
+			broker:  nil,
+			roomSvc: mock_room.NewMockService(controller),
+			userSvc: mock_user.NewMockService(controller),
+			logger:  &zap.SugaredLogger{},
 			expect: func(t *testing.T, s chat.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
-				assert.EqualError(t, err, "[chat_service] invalid redis chat client")
+				assert.EqualError(t, err, "[chat_service] invalid broker")
 			},
 		},
 		{
-			name:        "should return invalid room service",
-			redisClient: &redis.Client{},
-			roomSvc:     nil,
-			jwtSvc:      mock_jwt.NewMockService(controller),
-			userSvc:     mock_user.NewMockService(controller),
-			logger:      &zap.SugaredLogger{},
+			name:    "should return invalid room service",
+			broker:  mock_room.NewMockBroker(controller),
+			roomSvc: nil,
+			userSvc: mock_user.NewMockService(controller),
+			logger:  &zap.SugaredLogger{},
 			expect: func(t *testing.T, s chat.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -68,25 +348,11 @@ func TestNewService(t *testing.T) {
 			},
 		},
 		{
-			name:        "should return invalid jwt service",
-			redisClient: &redis.Client{},
-			roomSvc:     mock_room.NewMockService(controller),
-			jwtSvc:      nil,
-			userSvc:     mock_user.NewMockService(controller),
-			logger:      &zap.SugaredLogger{},
-			expect: func(t *testing.T, s chat.Service, err error) {
-				assert.Nil(t, s)
-				assert.NotNil(t, err)
-				assert.EqualError(t, err, "[chat_service] invalid jwt service")
-			},
-		},
-		{
-			name:        "should return invalid user service",
-			redisClient: &redis.Client{},
-			roomSvc:     mock_room.NewMockService(controller),
-			jwtSvc:      mock_jwt.NewMockService(controller),
-			userSvc:     nil,
-			logger:      &zap.SugaredLogger{},
+			name:    "should return invalid user service",
+			broker:  mock_room.NewMockBroker(controller),
+			roomSvc: mock_room.NewMockService(controller),
+			userSvc: nil,
+			logger:  &zap.SugaredLogger{},
 			expect: func(t *testing.T, s chat.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -94,12 +360,11 @@ func TestNewService(t *testing.T) {
 			},
 		},
 		{
-			name:        "should return invalid logger",
-			redisClient: &redis.Client{},
-			roomSvc:     mock_room.NewMockService(controller),
-			jwtSvc:      mock_jwt.NewMockService(controller),
-			userSvc:     mock_user.NewMockService(controller),
-			logger:      nil,
+			name:    "should return invalid logger",
+			broker:  mock_room.NewMockBroker(controller),
+			roomSvc: mock_room.NewMockService(controller),
+			userSvc: mock_user.NewMockService(controller),
+			logger:  nil,
 			expect: func(t *testing.T, s chat.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -110,8 +375,883 @@ func TestNewService(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := chat.NewService(tc.redisClient, tc.roomSvc, tc.jwtSvc, tc.userSvc, tc.logger)
+			svc, err := chat.NewService(tc.broker, tc.roomSvc, tc.userSvc, tc.logger)
 			tc.expect(t, svc, err)
 		})
 	}
 }
+
+func TestService_DispatchQueue(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	busy := newAgent()
+	free := newAgent()
+
+	waiting := newRoomDTO(roomName, room.StateWaiting, customer)
+	assigned := newRoomDTO(roomName, room.StateClaimed, customer, free)
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should assign the waiting room to the agent who connects",
+			setup: func(ct *chatTest) {
+				ct.setRoom(waiting)
+				ct.roomSvc.EXPECT().GetWaitingRooms(gomock.Any()).Return([]*room.DTO{waiting}, nil)
+				ct.roomSvc.EXPECT().EstimateWait(gomock.Any(), 1).Return(30 * time.Second)
+				ct.roomSvc.EXPECT().AssignNextRoom(gomock.Any(), free).
+					DoAndReturn(func(_ context.Context, agent *user.DTO) (*room.DTO, error) {
+						agent.Rooms = []string{roomName}
+						ct.setUser(agent)
+						return assigned, nil
+					})
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				msg, _ := customerConn.next(t, "queue-position")
+				var position room.QueuePosition
+				msg.decode(t, &position)
+				assert.Equal(t, room.QueuePosition{RoomName: roomName, Position: 1, EstimatedWait: 30}, position)
+
+				agentConn := ct.connect(t, free)
+				msg, _ = agentConn.next(t, "room-assigned")
+				var roomAssigned room.RoomAssigned
+				msg.decode(t, &roomAssigned)
+				assert.Equal(t, room.RoomAssigned{RoomName: roomName, CustomerId: customer.ID}, roomAssigned)
+
+				// the agent joined the live room
+				agentConn.send(t, room.Message{Action: "typing-start", RoomName: roomName})
+				msg, _ = customerConn.next(t, "typing-start")
+				assert.Equal(t, free.ID, msg.From)
+			},
+		},
+		{
+			name: "should skip the agent without capacity",
+			setup: func(ct *chatTest) {
+				ct.setRoom(waiting)
+				ct.userSvc.EXPECT().HasCapacity(busy).Return(false).AnyTimes()
+				ct.roomSvc.EXPECT().GetWaitingRooms(gomock.Any()).Return([]*room.DTO{waiting}, nil).Times(2)
+				ct.roomSvc.EXPECT().EstimateWait(gomock.Any(), 1).Return(30 * time.Second).Times(2)
+				ct.roomSvc.EXPECT().AssignNextRoom(gomock.Any(), free).Return(assigned, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				customerConn.next(t, "queue-position")
+
+				ct.connect(t, busy)
+				freeConn := ct.connect(t, free)
+
+				msg, _ := freeConn.next(t, "room-assigned")
+				var roomAssigned room.RoomAssigned
+				msg.decode(t, &roomAssigned)
+				assert.Equal(t, room.RoomAssigned{RoomName: roomName, CustomerId: customer.ID}, roomAssigned)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_TransferRoom(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	from := newAgent(roomName)
+	to := newAgent()
+	note := "billing question"
+
+	active := newRoomDTO(roomName, room.StateActive, customer, from)
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should move the room to the other agent",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().TransferRoom(gomock.Any(), roomName, from, to.ID, note).
+					Return(newRoomDTO(roomName, room.StateActive, customer, to), nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				fromConn := ct.connect(t, from)
+				toConn := ct.connect(t, to)
+
+				fromConn.send(t, room.Message{Action: "transfer", RoomName: roomName, AgentId: to.ID, Note: note})
+
+				event := room.TransferEvent{RoomName: roomName, CustomerId: customer.ID, From: from.ID, To: to.ID, Note: note}
+
+				msg, _ := fromConn.next(t, "room-left")
+				var left room.TransferEvent
+				msg.decode(t, &left)
+				assert.Equal(t, event, left)
+
+				msg, _ = toConn.next(t, "room-joined")
+				var joined room.TransferEvent
+				msg.decode(t, &joined)
+				assert.Equal(t, event, joined)
+
+				msg, _ = customerConn.next(t, "system")
+				var system room.SystemMessage
+				msg.decode(t, &system)
+				assert.Equal(t, "You have been transferred to another agent", system.Text)
+
+				// the new agent gets the messages of the room from now on
+				toConn.next(t, "system")
+				customerConn.send(t, room.Message{Action: "typing-start"})
+				msg, _ = toConn.next(t, "typing-start")
+				assert.Equal(t, customer.ID, msg.From)
+			},
+		},
+		{
+			name: "should return the room to the queue",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().TransferRoom(gomock.Any(), roomName, from, "", note).
+					Return(newRoomDTO(roomName, room.StateWaiting, customer), nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				fromConn := ct.connect(t, from)
+
+				fromConn.send(t, room.Message{Action: "transfer", RoomName: roomName, Note: note})
+
+				msg, _ := fromConn.next(t, "room-left")
+				var left room.TransferEvent
+				msg.decode(t, &left)
+				assert.Equal(t, room.TransferEvent{RoomName: roomName, CustomerId: customer.ID, From: from.ID, Note: note}, left)
+
+				msg, _ = customerConn.next(t, "system")
+				var system room.SystemMessage
+				msg.decode(t, &system)
+				assert.Equal(t, "You have been returned to the queue, the next free agent will continue", system.Text)
+			},
+		},
+		{
+			name: "should tell the agent the transfer failed",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().TransferRoom(gomock.Any(), roomName, from, to.ID, note).Return(nil, room.ErrNotSupport)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				ct.connect(t, customer)
+				fromConn := ct.connect(t, from)
+				ct.connect(t, to)
+
+				fromConn.send(t, room.Message{Action: "transfer", RoomName: roomName, AgentId: to.ID, Note: note})
+
+				msg, skipped := fromConn.next(t, "transfer")
+				assert.Equal(t, room.StatusNotSupport, msg.status())
+				assert.NotContains(t, actions(skipped), "room-left")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_InviteToRoom(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	agent := newAgent(roomName)
+	second := newAgent()
+	other := newAgent(roomName)
+	supervisor := newAgent()
+	note := "needs a refund"
+
+	active := newRoomDTO(roomName, room.StateActive, customer, agent)
+	invited := newRoomDTO(roomName, room.StateActive, customer, agent, second)
+	observed := newRoomDTO(roomName, room.StateActive, customer, agent, second)
+	observed.Participants = append(observed.Participants, &room.Participant{UserId: supervisor.ID, Role: room.RoleObserver})
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should bring the agent into the room",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().InviteToRoom(gomock.Any(), roomName, agent, second.ID).Return(invited, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+				secondConn := ct.connect(t, second)
+
+				agentConn.send(t, room.Message{Action: "invite", RoomName: roomName, AgentId: second.ID, Note: note})
+
+				msg, _ := secondConn.next(t, "room-joined")
+				var joined room.InviteEvent
+				msg.decode(t, &joined)
+				assert.Equal(t, room.InviteEvent{RoomName: roomName, CustomerId: customer.ID, InvitedBy: agent.ID, Note: note}, joined)
+
+				for _, conn := range []*chatConn{customerConn, agentConn, secondConn} {
+					msg, _ = conn.next(t, "participant-joined")
+					var roster room.Roster
+					msg.decode(t, &roster)
+					assert.Equal(t, roomName, roster.RoomName)
+					assert.Len(t, roster.Participants, 3)
+				}
+			},
+		},
+		{
+			name: "should keep the observers off the roster of the customer",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().InviteToRoom(gomock.Any(), roomName, agent, second.ID).Return(observed, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+				ct.connect(t, second)
+
+				agentConn.send(t, room.Message{Action: "invite", RoomName: roomName, AgentId: second.ID})
+
+				msg, _ := customerConn.next(t, "participant-joined")
+				var roster room.Roster
+				msg.decode(t, &roster)
+				for _, p := range roster.Participants {
+					assert.NotEqual(t, room.RoleObserver, p.Role)
+				}
+				assert.Len(t, roster.Participants, 3)
+			},
+		},
+		{
+			name: "should tell the agent the invite failed",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().InviteToRoom(gomock.Any(), roomName, agent, second.ID).Return(nil, room.ErrAlreadyInRoom)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				agentConn.send(t, room.Message{Action: "invite", RoomName: roomName, AgentId: second.ID})
+
+				msg, _ := agentConn.next(t, "invite")
+				assert.Equal(t, room.StatusAlreadyInRoom, msg.status())
+			},
+		},
+		{
+			name: "should keep the room open while another agent is in it",
+			setup: func(ct *chatTest) {
+				ct.setRoom(newRoomDTO(roomName, room.StateActive, customer, agent, other))
+				ct.roomSvc.EXPECT().LeaveRoom(gomock.Any(), roomName, agent).
+					Return(newRoomDTO(roomName, room.StateActive, customer, other), nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+				otherConn := ct.connect(t, other)
+
+				agentConn.send(t, room.Message{Action: "disconnect", RoomName: roomName})
+
+				agentConn.next(t, "room-closed")
+
+				msg, skipped := customerConn.next(t, "participant-left")
+				var roster room.Roster
+				msg.decode(t, &roster)
+				assert.Len(t, roster.Participants, 2)
+				assert.NotContains(t, actions(skipped), "room-closed")
+
+				// the customer keeps talking to the other agent
+				customerConn.send(t, room.Message{Action: "typing-start"})
+				msg, _ = otherConn.next(t, "typing-start")
+				assert.Equal(t, customer.ID, msg.From)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_Whisper(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	agent := newAgent(roomName)
+	supervisor := newAgent()
+	supervisor.Admin = true
+	text := room.EncryptedMessage{Data: "data", Salt: "salt", Iv: "iv"}
+
+	active := newRoomDTO(roomName, room.StateActive, customer, agent)
+	observed := newRoomDTO(roomName, room.StateActive, customer, agent)
+	observed.Participants = append(observed.Participants, &room.Participant{UserId: supervisor.ID, Role: room.RoleObserver})
+
+	// whisper stores the message as agents only and returns it with an id
+	whisper := func(t *testing.T, from string) func(context.Context, string, *room.RoomMessage) (*room.RoomMessage, error) {
+		return func(_ context.Context, _ string, msg *room.RoomMessage) (*room.RoomMessage, error) {
+			assert.True(t, msg.AgentsOnly)
+			assert.Equal(t, from, msg.Id)
+			msg.ID = primitive.NewObjectID()
+			return msg, nil
+		}
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(*testing.T, *chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should deliver the whisper to the agents only",
+			setup: func(t *testing.T, ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().AddMessage(gomock.Any(), roomName, gomock.Any()).DoAndReturn(whisper(t, agent.ID))
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				agentConn.send(t, room.Message{Action: "whisper", RoomName: roomName, Message: text})
+
+				msg, _ := agentConn.next(t, "whisper")
+				assert.Equal(t, agent.ID, msg.From)
+				assert.Equal(t, &text, msg.Message)
+
+				agentConn.send(t, room.Message{Action: "typing-start", RoomName: roomName})
+				_, skipped := customerConn.next(t, "typing-start")
+				assert.NotContains(t, actions(skipped), "whisper")
+			},
+		},
+		{
+			name: "should drop the whisper of the customer",
+			setup: func(t *testing.T, ct *chatTest) {
+				ct.setRoom(active)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "whisper", RoomName: roomName, Message: text})
+				customerConn.send(t, room.Message{Action: "typing-start"})
+
+				_, skipped := agentConn.next(t, "typing-start")
+				assert.NotContains(t, actions(skipped), "whisper")
+			},
+		},
+		{
+			name: "should let the observing supervisor follow the room and whisper",
+			setup: func(t *testing.T, ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().ObserveRoom(gomock.Any(), roomName, supervisor).
+					DoAndReturn(func(context.Context, string, *user.DTO) (*room.DTO, error) {
+						ct.setRoom(observed)
+						return observed, nil
+					})
+				ct.roomSvc.EXPECT().AddMessage(gomock.Any(), roomName, gomock.Any()).DoAndReturn(whisper(t, supervisor.ID))
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+				supervisorConn := ct.connect(t, supervisor)
+
+				supervisorConn.send(t, room.Message{Action: "observe", RoomName: roomName})
+				supervisorConn.next(t, "observe")
+
+				msg, _ := agentConn.next(t, "observer-joined")
+				var roster room.Roster
+				msg.decode(t, &roster)
+				assert.Len(t, roster.Participants, 3)
+
+				customerConn.send(t, room.Message{Action: "typing-start"})
+				msg, _ = supervisorConn.next(t, "typing-start")
+				assert.Equal(t, customer.ID, msg.From)
+
+				supervisorConn.send(t, room.Message{Action: "whisper", RoomName: roomName, Message: text})
+				msg, _ = agentConn.next(t, "whisper")
+				assert.Equal(t, supervisor.ID, msg.From)
+
+				agentConn.send(t, room.Message{Action: "typing-stop", RoomName: roomName})
+				_, skipped := customerConn.next(t, "typing-stop")
+				assert.NotContains(t, actions(skipped), "observer-joined")
+				assert.NotContains(t, actions(skipped), "whisper")
+			},
+		},
+		{
+			name: "should refuse observing to everyone but supervisors",
+			setup: func(t *testing.T, ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().ObserveRoom(gomock.Any(), roomName, gomock.Any()).Return(nil, room.ErrNotSupervisor)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				ct.connect(t, customer)
+				otherConn := ct.connect(t, newAgent())
+
+				otherConn.send(t, room.Message{Action: "observe", RoomName: roomName})
+
+				msg, _ := otherConn.next(t, "observe")
+				assert.Equal(t, room.StatusNotSupervisor, msg.status())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, func(ct *chatTest) { tc.setup(t, ct) })
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_EditMessage(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	agent := newAgent(roomName)
+	messageId := primitive.NewObjectID()
+	text := room.EncryptedMessage{Data: "data", Salt: "salt", Iv: "iv"}
+	now := time.Now()
+
+	active := newRoomDTO(roomName, room.StateActive, customer, agent)
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should send the edited message to everyone in the room",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().EditMessage(gomock.Any(), roomName, messageId.Hex(), customer, text).
+					Return(&room.RoomMessage{ID: messageId, RoomName: roomName, Id: customer.ID, Message: text, EditedAt: &now}, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "edit-message", MessageId: messageId.Hex(), Message: text})
+
+				for _, conn := range []*chatConn{customerConn, agentConn} {
+					msg, _ := conn.next(t, "message-edited")
+					assert.Equal(t, messageId.Hex(), msg.Id)
+					assert.Equal(t, customer.ID, msg.From)
+					assert.Equal(t, &text, msg.Message)
+				}
+			},
+		},
+		{
+			name: "should send the deleted message without its text",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().DeleteMessage(gomock.Any(), roomName, messageId.Hex(), agent).
+					Return(&room.RoomMessage{ID: messageId, RoomName: roomName, Id: customer.ID, DeletedAt: &now, DeletedBy: agent.ID}, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				agentConn.send(t, room.Message{Action: "delete-message", RoomName: roomName, MessageId: messageId.Hex()})
+
+				for _, conn := range []*chatConn{customerConn, agentConn} {
+					msg, _ := conn.next(t, "message-deleted")
+					assert.Equal(t, messageId.Hex(), msg.Id)
+					assert.Nil(t, msg.Message)
+				}
+			},
+		},
+		{
+			name: "should send the edited whisper to the agents only",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().EditMessage(gomock.Any(), roomName, messageId.Hex(), agent, text).
+					Return(&room.RoomMessage{ID: messageId, RoomName: roomName, Id: agent.ID, Message: text, AgentsOnly: true, EditedAt: &now}, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				agentConn.send(t, room.Message{Action: "edit-message", RoomName: roomName, MessageId: messageId.Hex(), Message: text})
+				agentConn.next(t, "message-edited")
+
+				agentConn.send(t, room.Message{Action: "typing-start", RoomName: roomName})
+				_, skipped := customerConn.next(t, "typing-start")
+				assert.NotContains(t, actions(skipped), "message-edited")
+			},
+		},
+		{
+			name: "should tell the author the edit failed",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().EditMessage(gomock.Any(), roomName, messageId.Hex(), customer, text).Return(nil, room.ErrEditWindowClosed)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "edit-message", MessageId: messageId.Hex(), Message: text})
+
+				msg, _ := customerConn.next(t, "edit-message")
+				assert.Equal(t, room.StatusEditWindowClosed, msg.status())
+
+				customerConn.send(t, room.Message{Action: "typing-start"})
+				_, skipped := agentConn.next(t, "typing-start")
+				assert.NotContains(t, actions(skipped), "message-edited")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_TypingAndPresence(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	agent := newAgent(roomName)
+
+	active := newRoomDTO(roomName, room.StateActive, customer, agent)
+
+	// presence reads the presence events until the one of the user
+	presence := func(t *testing.T, conn *chatConn, id string) room.PresenceEvent {
+		for {
+			msg, _ := conn.next(t, "presence")
+			var event room.PresenceEvent
+			msg.decode(t, &event)
+			if event.UserId == id {
+				return event
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should fan out typing without storing it",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "typing-start"})
+				msg, _ := agentConn.next(t, "typing-start")
+				assert.Equal(t, customer.ID, msg.From)
+				assert.Equal(t, roomName, msg.RoomName)
+
+				customerConn.send(t, room.Message{Action: "typing-stop"})
+				msg, _ = agentConn.next(t, "typing-stop")
+				assert.Equal(t, customer.ID, msg.From)
+			},
+		},
+		{
+			name: "should drop typing in a room of someone else",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				ct.connect(t, agent)
+				strangerConn := ct.connect(t, newAgent())
+
+				strangerConn.send(t, room.Message{Action: "typing-start", RoomName: roomName})
+				customerConn.send(t, room.Message{Action: "typing-stop"})
+
+				_, skipped := customerConn.next(t, "typing-stop")
+				assert.NotContains(t, actions(skipped), "typing-start")
+			},
+		},
+		{
+			name: "should tell the room the agent is online",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				ct.connect(t, agent)
+
+				assert.Equal(t, room.PresenceEvent{UserId: agent.ID, Status: room.PresenceOnline}, presence(t, customerConn, agent.ID))
+			},
+		},
+		{
+			name: "should tell the room the agent went offline",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+				presence(t, customerConn, agent.ID)
+
+				assert.Nil(t, agentConn.ws.Close())
+
+				assert.Equal(t, room.PresenceEvent{UserId: agent.ID, Status: room.PresenceOffline}, presence(t, customerConn, agent.ID))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_Receipts(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	agent := newAgent(roomName)
+	messageId := primitive.NewObjectID()
+	text := room.EncryptedMessage{Data: "data", Salt: "salt", Iv: "iv"}
+
+	active := newRoomDTO(roomName, room.StateActive, customer, agent)
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should tell the room the customer read the message",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().MarkRead(gomock.Any(), roomName, customer.ID, messageId.Hex()).Return(true, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "mark-read", MessageId: messageId.Hex()})
+
+				msg, _ := agentConn.next(t, "receipt")
+				var receipt room.ReceiptEvent
+				msg.decode(t, &receipt)
+				assert.Equal(t, room.ReceiptEvent{UserId: customer.ID, MessageId: messageId.Hex(), Status: room.ReceiptRead}, receipt)
+			},
+		},
+		{
+			name: "should not tell the room when the cursor didn't move",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().MarkRead(gomock.Any(), roomName, customer.ID, messageId.Hex()).Return(false, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "mark-read", MessageId: messageId.Hex()})
+				customerConn.send(t, room.Message{Action: "typing-start"})
+
+				_, skipped := agentConn.next(t, "typing-start")
+				assert.NotContains(t, actions(skipped), "receipt")
+			},
+		},
+		{
+			name: "should tell the customer the receipt failed",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().MarkRead(gomock.Any(), roomName, customer.ID, messageId.Hex()).Return(false, room.ErrMessageNotFound)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "mark-read", MessageId: messageId.Hex()})
+
+				msg, _ := customerConn.next(t, "receipt")
+				assert.Equal(t, room.StatusMessageNotFound, msg.status())
+			},
+		},
+		{
+			name: "should tell the room the message reached the customer",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().AddMessage(gomock.Any(), roomName, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, msg *room.RoomMessage) (*room.RoomMessage, error) {
+						msg.ID = messageId
+						return msg, nil
+					})
+				ct.roomSvc.EXPECT().MarkDelivered(gomock.Any(), roomName, customer.ID, messageId.Hex()).Return(true, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				agentConn.send(t, room.Message{Action: "publish-room", RoomName: roomName, Message: text})
+
+				msg, _ := customerConn.next(t, "publish-room")
+				assert.Equal(t, messageId.Hex(), msg.Id)
+
+				msg, _ = agentConn.next(t, "receipt")
+				var receipt room.ReceiptEvent
+				msg.decode(t, &receipt)
+				assert.Equal(t, room.ReceiptEvent{UserId: customer.ID, MessageId: messageId.Hex(), Status: room.ReceiptDelivered}, receipt)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
+
+func TestService_CloseRoom(t *testing.T) {
+	roomName := "room"
+	customer := newCustomer(roomName)
+	agent := newAgent(roomName)
+
+	active := newRoomDTO(roomName, room.StateActive, customer, agent)
+
+	// closed returns the room as the customer or the agent left it
+	closed := func(closedBy, reason string) *room.DTO {
+		dto := newRoomDTO(roomName, room.StateClosed, customer, agent)
+		dto.ClosedBy = closedBy
+		dto.CloseReason = reason
+		return dto
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(*chatTest)
+		expect func(*testing.T, *chatTest)
+	}{
+		{
+			name: "should close the room the customer ended and ask for a rating",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().CloseRoom(gomock.Any(), roomName, customer.ID, room.CloseReasonCustomerEnded).
+					Return(closed(customer.ID, room.CloseReasonCustomerEnded), nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				customerConn.send(t, room.Message{Action: "disconnect"})
+
+				event := room.RoomClosed{RoomName: roomName, ClosedBy: customer.ID, CloseReason: room.CloseReasonCustomerEnded}
+
+				msg, _ := customerConn.next(t, "room-closed")
+				var roomClosed room.RoomClosed
+				msg.decode(t, &roomClosed)
+				assert.Equal(t, event, roomClosed)
+
+				msg, _ = customerConn.next(t, "rate-conversation")
+				var prompt room.RatingPrompt
+				msg.decode(t, &prompt)
+				assert.Equal(t, room.RatingPrompt{RoomName: roomName, AgentId: agent.ID}, prompt)
+
+				msg, _ = agentConn.next(t, "room-closed")
+				msg.decode(t, &roomClosed)
+				assert.Equal(t, event, roomClosed)
+			},
+		},
+		{
+			name: "should close the room once the last agent left",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().LeaveRoom(gomock.Any(), roomName, agent).Return(newRoomDTO(roomName, room.StateActive, customer), nil)
+				ct.roomSvc.EXPECT().CloseRoom(gomock.Any(), roomName, agent.ID, room.CloseReasonAgentsLeft).
+					Return(closed(agent.ID, room.CloseReasonAgentsLeft), nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+				agentConn := ct.connect(t, agent)
+
+				agentConn.send(t, room.Message{Action: "disconnect", RoomName: roomName})
+
+				msg, _ := customerConn.next(t, "room-closed")
+				var roomClosed room.RoomClosed
+				msg.decode(t, &roomClosed)
+				assert.Equal(t, room.RoomClosed{RoomName: roomName, ClosedBy: agent.ID, CloseReason: room.CloseReasonAgentsLeft}, roomClosed)
+
+				customerConn.next(t, "rate-conversation")
+			},
+		},
+		{
+			name: "should return the rating to the customer",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().RateRoom(gomock.Any(), roomName, customer, 5, "quick help").
+					Return(&room.RatingDTO{RoomName: roomName, CustomerId: customer.ID, AgentId: agent.ID, Score: 5, Comment: "quick help"}, nil)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+
+				customerConn.send(t, room.Message{Action: "rate-conversation", RoomName: roomName, Score: 5, Comment: "quick help"})
+
+				msg, _ := customerConn.next(t, "rate-conversation")
+				var rating room.RatingDTO
+				msg.decode(t, &rating)
+				assert.Equal(t, agent.ID, rating.AgentId)
+				assert.Equal(t, 5, rating.Score)
+			},
+		},
+		{
+			name: "should tell the customer the rating failed",
+			setup: func(ct *chatTest) {
+				ct.setRoom(active)
+				ct.roomSvc.EXPECT().RateRoom(gomock.Any(), roomName, customer, 5, "").Return(nil, room.ErrRoomNotClosed)
+			},
+			expect: func(t *testing.T, ct *chatTest) {
+				customerConn := ct.connect(t, customer)
+
+				customerConn.send(t, room.Message{Action: "rate-conversation", RoomName: roomName, Score: 5})
+
+				msg, _ := customerConn.next(t, "rate-conversation")
+				assert.Equal(t, room.StatusRoomNotClosed, msg.status())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			defer controller.Finish()
+
+			ct := newChatTest(t, controller, tc.setup)
+			tc.expect(t, ct)
+		})
+	}
+}
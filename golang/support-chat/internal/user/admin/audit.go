@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionCreateUser = "create_user"
+	ActionPromote    = "promote"
+	ActionDemote     = "demote"
+	ActionDisable    = "disable"
+	ActionEnable     = "enable"
+	// the settings actions target the role they apply to
+	ActionRequireMfa         = "require_mfa"
+	ActionDropMfaRequirement = "drop_mfa_requirement"
+)
+
+// ActorSSO is recorded as the actor of the role changes the identity provider
+// makes through the claims of its users.
+const ActorSSO = "sso"
+
+// Entry is a document of the audit log. Every change an admin makes to a user
+// is recorded with who made it and when.
+type Entry struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	ActorId   string             `bson:"actorId"`
+	Action    string             `bson:"action"`
+	TargetId  string             `bson:"targetId"`
+	Role      string             `bson:"role,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt"`
+}
+
+type EntryDTO struct {
+	ID        string    `json:"id"`
+	ActorId   string    `json:"actor_id"`
+	Action    string    `json:"action"`
+	TargetId  string    `json:"target_id"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func MapEntryToDTO(e *Entry) *EntryDTO {
+	return &EntryDTO{
+		ID:        e.ID.Hex(),
+		ActorId:   e.ActorId,
+		Action:    e.Action,
+		TargetId:  e.TargetId,
+		Role:      e.Role,
+		CreatedAt: e.CreatedAt,
+	}
+}
@@ -0,0 +1,24 @@
+package admin
+
+import "time"
+
+type CreateUserDTO struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Support  bool   `json:"support"`
+	Admin    bool   `json:"admin"`
+}
+
+type RoleDTO struct {
+	Role string `json:"role"`
+}
+
+// AuditQuery pages through the audit log from the newest entry, optionally
+// narrowed to an actor or a target.
+type AuditQuery struct {
+	ActorId  string
+	TargetId string
+	Before   *time.Time
+	Limit    int64
+}
@@ -0,0 +1,26 @@
+package admin
+
+import (
+	"support-chat/pkg/codes"
+	"support-chat/pkg/errors"
+)
+
+const (
+	StatusInvalidRole        errors.Status = "invalid_role"
+	StatusSelfChange         errors.Status = "admin_cant_change_self"
+	StatusUserHasRooms       errors.Status = "user_has_active_rooms"
+	StatusFailedSaveEntry    errors.Status = "failed_save_audit_entry"
+	StatusFailedFindEntries  errors.Status = "failed_find_audit_entries"
+	StatusFailedFindSettings errors.Status = "failed_find_settings"
+	StatusFailedSaveSettings errors.Status = "failed_save_settings"
+)
+
+var (
+	ErrInvalidRole        = errors.New(codes.BadRequest, StatusInvalidRole)
+	ErrSelfChange         = errors.New(codes.BadRequest, StatusSelfChange)
+	ErrUserHasRooms       = errors.New(codes.DuplicateError, StatusUserHasRooms)
+	ErrFailedSaveEntry    = errors.New(codes.BadRequest, StatusFailedSaveEntry)
+	ErrFailedFindEntries  = errors.New(codes.BadRequest, StatusFailedFindEntries)
+	ErrFailedFindSettings = errors.New(codes.InternalError, StatusFailedFindSettings)
+	ErrFailedSaveSettings = errors.New(codes.InternalError, StatusFailedSaveSettings)
+)
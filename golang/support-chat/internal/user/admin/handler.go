@@ -0,0 +1,304 @@
+package admin
+
+import (
+	"encoding/json"
+	gerrors "errors"
+	"net/http"
+	"strconv"
+	"support-chat/internal/user"
+	"support-chat/pkg/errors"
+	"support-chat/pkg/rbac"
+	"support-chat/pkg/respond"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	adminSvc    Service
+	permissions rbac.Middleware
+}
+
+func NewHandler(adminSvc Service, permissions rbac.Middleware) (*Handler, error) {
+	if adminSvc == nil {
+		return nil, gerrors.New("[user_admin_handler] invalid admin service")
+	}
+	if permissions == nil {
+		return nil, gerrors.New("[user_admin_handler] invalid permissions middleware")
+	}
+
+	return &Handler{adminSvc: adminSvc, permissions: permissions}, nil
+}
+
+func (h *Handler) SetupRoutes(router chi.Router) {
+	users := router.With(h.permissions.Require(rbac.UsersAdmin))
+	users.Get("/admin/users", h.SearchUsers)
+	users.Post("/admin/users", h.CreateUser)
+	users.Post("/admin/users/{id}/promote", h.Promote)
+	users.Post("/admin/users/{id}/demote", h.Demote)
+	users.Post("/admin/users/{id}/disable", h.Disable)
+	users.Post("/admin/users/{id}/enable", h.Enable)
+
+	users.Get("/admin/settings", h.GetSettings)
+	users.Patch("/admin/settings", h.UpdateSettings)
+
+	router.With(h.permissions.Require(rbac.AuditRead)).Get("/admin/audit", h.GetAuditLog)
+}
+
+// SearchUsers lists users matching the q query, optionally narrowed by the
+// support, admin and disabled flags.
+func (h *Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	query := &user.SearchQuery{Query: r.URL.Query().Get("q")}
+	var err error
+	if query.Support, err = boolParam(r, "support"); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+	if query.Admin, err = boolParam(r, "admin"); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+	if query.Disabled, err = boolParam(r, "disabled"); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+	if query.Skip, err = intParam(r, "skip"); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+	if query.Limit, err = intParam(r, "limit"); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	users, err := h.adminSvc.SearchUsers(r.Context(), &u, query)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, users)
+}
+
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	var dto CreateUserDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	created, err := h.adminSvc.CreateUser(r.Context(), &u, &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusCreated, created)
+}
+
+func (h *Handler) Promote(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	var dto RoleDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	promoted, err := h.adminSvc.Promote(r.Context(), &u, chi.URLParam(r, "id"), dto.Role)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, promoted)
+}
+
+func (h *Handler) Demote(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	var dto RoleDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	demoted, err := h.adminSvc.Demote(r.Context(), &u, chi.URLParam(r, "id"), dto.Role)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, demoted)
+}
+
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	disabled, err := h.adminSvc.Disable(r.Context(), &u, chi.URLParam(r, "id"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, disabled)
+}
+
+func (h *Handler) Enable(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	enabled, err := h.adminSvc.Enable(r.Context(), &u, chi.URLParam(r, "id"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, enabled)
+}
+
+// GetAuditLog returns the newest audit entries, optionally for an actor or a
+// target, before an RFC3339 time.
+func (h *Handler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	query := &AuditQuery{ActorId: r.URL.Query().Get("actor"), TargetId: r.URL.Query().Get("target")}
+	if before := r.URL.Query().Get("before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest("invalid before"))
+			return
+		}
+		query.Before = &t
+	}
+	var err error
+	if query.Limit, err = intParam(r, "limit"); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	entries, err := h.adminSvc.GetAuditLog(r.Context(), &u, query)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, entries)
+}
+
+func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	settings, err := h.adminSvc.GetSettings(r.Context(), &u)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, settings)
+}
+
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	u := principal.User
+
+	var dto SettingsDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, http.StatusBadRequest, errors.NewBadRequest(err.Error()))
+		return
+	}
+
+	settings, err := h.adminSvc.UpdateSettings(r.Context(), &u, &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, settings)
+}
+
+// boolParam reads an optional boolean from the query.
+func boolParam(r *http.Request, name string) (*bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, errors.NewBadRequest("invalid " + name)
+	}
+
+	return &b, nil
+}
+
+// intParam reads an optional number from the query, zero when it's missing.
+func intParam(r *http.Request, name string) (int64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.NewBadRequest("invalid " + name)
+	}
+
+	return n, nil
+}
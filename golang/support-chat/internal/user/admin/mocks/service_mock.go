@@ -0,0 +1,242 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package mock_admin is a generated GoMock package.
+package mock_admin
+
+import (
+	context "context"
+	reflect "reflect"
+	user "support-chat/internal/user"
+	admin "support-chat/internal/user/admin"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateUser mocks base method.
+func (m *MockService) CreateUser(ctx context.Context, actor *user.DTO, dto *admin.CreateUserDTO) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, actor, dto)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockServiceMockRecorder) CreateUser(ctx, actor, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), ctx, actor, dto)
+}
+
+// Demote mocks base method.
+func (m *MockService) Demote(ctx context.Context, actor *user.DTO, id, role string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Demote", ctx, actor, id, role)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Demote indicates an expected call of Demote.
+func (mr *MockServiceMockRecorder) Demote(ctx, actor, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Demote", reflect.TypeOf((*MockService)(nil).Demote), ctx, actor, id, role)
+}
+
+// Disable mocks base method.
+func (m *MockService) Disable(ctx context.Context, actor *user.DTO, id string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, actor, id)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockServiceMockRecorder) Disable(ctx, actor, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockService)(nil).Disable), ctx, actor, id)
+}
+
+// Enable mocks base method.
+func (m *MockService) Enable(ctx context.Context, actor *user.DTO, id string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", ctx, actor, id)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockServiceMockRecorder) Enable(ctx, actor, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockService)(nil).Enable), ctx, actor, id)
+}
+
+// GetAuditLog mocks base method.
+func (m *MockService) GetAuditLog(ctx context.Context, actor *user.DTO, query *admin.AuditQuery) ([]*admin.EntryDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditLog", ctx, actor, query)
+	ret0, _ := ret[0].([]*admin.EntryDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditLog indicates an expected call of GetAuditLog.
+func (mr *MockServiceMockRecorder) GetAuditLog(ctx, actor, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditLog", reflect.TypeOf((*MockService)(nil).GetAuditLog), ctx, actor, query)
+}
+
+// GetSettings mocks base method.
+func (m *MockService) GetSettings(ctx context.Context, actor *user.DTO) (*admin.SettingsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSettings", ctx, actor)
+	ret0, _ := ret[0].(*admin.SettingsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSettings indicates an expected call of GetSettings.
+func (mr *MockServiceMockRecorder) GetSettings(ctx, actor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettings", reflect.TypeOf((*MockService)(nil).GetSettings), ctx, actor)
+}
+
+// Promote mocks base method.
+func (m *MockService) Promote(ctx context.Context, actor *user.DTO, id, role string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Promote", ctx, actor, id, role)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Promote indicates an expected call of Promote.
+func (mr *MockServiceMockRecorder) Promote(ctx, actor, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Promote", reflect.TypeOf((*MockService)(nil).Promote), ctx, actor, id, role)
+}
+
+// SearchUsers mocks base method.
+func (m *MockService) SearchUsers(ctx context.Context, actor *user.DTO, query *user.SearchQuery) ([]*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, actor, query)
+	ret0, _ := ret[0].([]*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockServiceMockRecorder) SearchUsers(ctx, actor, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockService)(nil).SearchUsers), ctx, actor, query)
+}
+
+// SupportMfaRequired mocks base method.
+func (m *MockService) SupportMfaRequired(ctx context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportMfaRequired", ctx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SupportMfaRequired indicates an expected call of SupportMfaRequired.
+func (mr *MockServiceMockRecorder) SupportMfaRequired(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportMfaRequired", reflect.TypeOf((*MockService)(nil).SupportMfaRequired), ctx)
+}
+
+// SyncSupport mocks base method.
+func (m *MockService) SyncSupport(ctx context.Context, u *user.DTO, support bool) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncSupport", ctx, u, support)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncSupport indicates an expected call of SyncSupport.
+func (mr *MockServiceMockRecorder) SyncSupport(ctx, u, support interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncSupport", reflect.TypeOf((*MockService)(nil).SyncSupport), ctx, u, support)
+}
+
+// UpdateSettings mocks base method.
+func (m *MockService) UpdateSettings(ctx context.Context, actor *user.DTO, dto *admin.SettingsDTO) (*admin.SettingsDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSettings", ctx, actor, dto)
+	ret0, _ := ret[0].(*admin.SettingsDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSettings indicates an expected call of UpdateSettings.
+func (mr *MockServiceMockRecorder) UpdateSettings(ctx, actor, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSettings", reflect.TypeOf((*MockService)(nil).UpdateSettings), ctx, actor, dto)
+}
+
+// MockSessionCloser is a mock of SessionCloser interface.
+type MockSessionCloser struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionCloserMockRecorder
+}
+
+// MockSessionCloserMockRecorder is the mock recorder for MockSessionCloser.
+type MockSessionCloserMockRecorder struct {
+	mock *MockSessionCloser
+}
+
+// NewMockSessionCloser creates a new mock instance.
+func NewMockSessionCloser(ctrl *gomock.Controller) *MockSessionCloser {
+	mock := &MockSessionCloser{ctrl: ctrl}
+	mock.recorder = &MockSessionCloserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionCloser) EXPECT() *MockSessionCloserMockRecorder {
+	return m.recorder
+}
+
+// CloseSessions mocks base method.
+func (m *MockSessionCloser) CloseSessions(userId string, sids ...string) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{userId}
+	for _, a := range sids {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "CloseSessions", varargs...)
+}
+
+// CloseSessions indicates an expected call of CloseSessions.
+func (mr *MockSessionCloserMockRecorder) CloseSessions(userId interface{}, sids ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{userId}, sids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSessions", reflect.TypeOf((*MockSessionCloser)(nil).CloseSessions), varargs...)
+}
@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=repository.go -destination=mocks/repository_mock.go
+type Repository interface {
+	CreateEntry(ctx context.Context, entry *Entry) error
+	GetEntries(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*Entry, error)
+	GetSettings(ctx context.Context) (*Settings, error)
+	SaveSettings(ctx context.Context, settings *Settings) error
+}
+
+type repository struct {
+	db     *mongo.Client
+	dbName string
+	logger *zap.SugaredLogger
+}
+
+func NewRepository(db *mongo.Client, dbName string, logger *zap.SugaredLogger) (Repository, error) {
+	if db == nil {
+		return nil, errors.New("[user_admin_repository] invalid database")
+	}
+	if dbName == "" {
+		return nil, errors.New("[user_admin_repository] invalid database name")
+	}
+	if logger == nil {
+		return nil, errors.New("[user_admin_repository] invalid logger")
+	}
+
+	return &repository{db: db, dbName: dbName, logger: logger}, nil
+}
+
+func (r *repository) CreateEntry(ctx context.Context, entry *Entry) error {
+	_, err := r.db.Database(r.dbName).Collection("audit_log").InsertOne(ctx, entry)
+	if err != nil {
+		r.logger.Errorf("failed to insert audit entry to db: %v", err)
+		return ErrFailedSaveEntry
+	}
+
+	return nil
+}
+
+func (r *repository) GetEntries(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*Entry, error) {
+	var entries []*Entry
+
+	cursor, err := r.db.Database(r.dbName).Collection("audit_log").Find(ctx, filters, opts)
+	if err != nil {
+		r.logger.Errorf("failed to get audit entries: %v", err)
+		return nil, ErrFailedFindEntries
+	}
+
+	if err = cursor.All(ctx, &entries); err != nil {
+		r.logger.Errorf("failed to get audit entries: %v", err)
+		return nil, ErrFailedFindEntries
+	}
+
+	return entries, nil
+}
+
+// GetSettings returns the stored settings, or the defaults when none were
+// saved yet.
+func (r *repository) GetSettings(ctx context.Context) (*Settings, error) {
+	var settings Settings
+
+	err := r.db.Database(r.dbName).Collection("settings").FindOne(ctx, bson.M{"_id": settingsId}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return &Settings{ID: settingsId}, nil
+	}
+	if err != nil {
+		r.logger.Errorf("failed to get settings: %v", err)
+		return nil, ErrFailedFindSettings
+	}
+
+	return &settings, nil
+}
+
+func (r *repository) SaveSettings(ctx context.Context, settings *Settings) error {
+	settings.ID = settingsId
+	_, err := r.db.Database(r.dbName).Collection("settings").
+		ReplaceOne(ctx, bson.M{"_id": settingsId}, settings, options.Replace().SetUpsert(true))
+	if err != nil {
+		r.logger.Errorf("failed to save settings: %v", err)
+		return ErrFailedSaveSettings
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"support-chat/internal/user"
+	"support-chat/pkg/jwt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=service.go -destination=mocks/service_mock.go
+type Service interface {
+	SearchUsers(ctx context.Context, actor *user.DTO, query *user.SearchQuery) ([]*user.DTO, error)
+	CreateUser(ctx context.Context, actor *user.DTO, dto *CreateUserDTO) (*user.DTO, error)
+	Promote(ctx context.Context, actor *user.DTO, id, role string) (*user.DTO, error)
+	Demote(ctx context.Context, actor *user.DTO, id, role string) (*user.DTO, error)
+	Disable(ctx context.Context, actor *user.DTO, id string) (*user.DTO, error)
+	Enable(ctx context.Context, actor *user.DTO, id string) (*user.DTO, error)
+	GetAuditLog(ctx context.Context, actor *user.DTO, query *AuditQuery) ([]*EntryDTO, error)
+	GetSettings(ctx context.Context, actor *user.DTO) (*SettingsDTO, error)
+	UpdateSettings(ctx context.Context, actor *user.DTO, dto *SettingsDTO) (*SettingsDTO, error)
+	SupportMfaRequired(ctx context.Context) (bool, error)
+	SyncSupport(ctx context.Context, u *user.DTO, support bool) (*user.DTO, error)
+}
+
+// SessionCloser closes the live connections of the ended sessions.
+type SessionCloser interface {
+	CloseSessions(userId string, sids ...string)
+}
+
+const (
+	defaultAuditPage = 50
+	maxAuditPage     = 200
+)
+
+type service struct {
+	repository    Repository
+	userSvc       user.Service
+	jwtSvc        jwt.Service
+	sessionCloser SessionCloser
+	logger        *zap.SugaredLogger
+}
+
+func NewService(repository Repository, userSvc user.Service, jwtSvc jwt.Service, sessionCloser SessionCloser, logger *zap.SugaredLogger) (Service, error) {
+	if repository == nil {
+		return nil, errors.New("[user_admin_service] invalid repository")
+	}
+	if userSvc == nil {
+		return nil, errors.New("[user_admin_service] invalid user service")
+	}
+	if jwtSvc == nil {
+		return nil, errors.New("[user_admin_service] invalid jwt service")
+	}
+	if sessionCloser == nil {
+		return nil, errors.New("[user_admin_service] invalid session closer")
+	}
+	if logger == nil {
+		return nil, errors.New("[user_admin_service] invalid logger")
+	}
+
+	return &service{repository: repository, userSvc: userSvc, jwtSvc: jwtSvc, sessionCloser: sessionCloser, logger: logger}, nil
+}
+
+func (s *service) SearchUsers(ctx context.Context, actor *user.DTO, query *user.SearchQuery) ([]*user.DTO, error) {
+	return s.userSvc.SearchUsers(ctx, query)
+}
+
+// CreateUser registers a user on behalf of the admin, optionally with the
+// support and admin roles. Every step is recorded on its own. The admin vouches
+// for the address, so the user starts verified.
+func (s *service) CreateUser(ctx context.Context, actor *user.DTO, dto *CreateUserDTO) (*user.DTO, error) {
+	u, err := s.userSvc.CreateUser(ctx, dto.Email, dto.Name, dto.Password)
+	if err != nil {
+		s.logger.Errorf("failed to create user: %v", err)
+		return nil, err
+	}
+
+	if u, err = s.userSvc.SetVerified(ctx, u.ID, true); err != nil {
+		s.logger.Errorf("failed to verify user: %v", err)
+		return nil, err
+	}
+	u.Password = ""
+
+	if err = s.record(ctx, actor, ActionCreateUser, u.ID, ""); err != nil {
+		return nil, err
+	}
+
+	if dto.Support {
+		if u, err = s.Promote(ctx, actor, u.ID, jwt.RoleSupport); err != nil {
+			return nil, err
+		}
+	}
+	if dto.Admin {
+		if u, err = s.Promote(ctx, actor, u.ID, jwt.RoleAdmin); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// Promote grants the support or the admin role to the user.
+func (s *service) Promote(ctx context.Context, actor *user.DTO, id, role string) (*user.DTO, error) {
+	var u *user.DTO
+	var err error
+	switch role {
+	case jwt.RoleSupport:
+		u, err = s.userSvc.SetSupport(ctx, id, true)
+	case jwt.RoleAdmin:
+		u, err = s.userSvc.SetAdmin(ctx, id, true)
+	default:
+		return nil, ErrInvalidRole
+	}
+	if err != nil {
+		s.logger.Errorf("failed to promote user: %v", err)
+		return nil, err
+	}
+
+	if err = s.record(ctx, actor, ActionPromote, id, role); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// Demote takes the support or the admin role away. Agents have to finish
+// their conversations first, and admins can't demote themselves.
+func (s *service) Demote(ctx context.Context, actor *user.DTO, id, role string) (*user.DTO, error) {
+	var u *user.DTO
+	var err error
+	switch role {
+	case jwt.RoleSupport:
+		if err = s.checkNoRooms(ctx, id); err != nil {
+			return nil, err
+		}
+		u, err = s.userSvc.SetSupport(ctx, id, false)
+	case jwt.RoleAdmin:
+		if id == actor.ID {
+			return nil, ErrSelfChange
+		}
+		u, err = s.userSvc.SetAdmin(ctx, id, false)
+	default:
+		return nil, ErrInvalidRole
+	}
+	if err != nil {
+		s.logger.Errorf("failed to demote user: %v", err)
+		return nil, err
+	}
+
+	if err = s.record(ctx, actor, ActionDemote, id, role); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// SyncSupport gives a single sign-on user the support role the identity
+// provider grants them on every login. A change is recorded with ActorSSO, an
+// agent who lost the role still has to finish their conversations first.
+func (s *service) SyncSupport(ctx context.Context, u *user.DTO, support bool) (*user.DTO, error) {
+	if u.Support == support {
+		return u, nil
+	}
+
+	actor := &user.DTO{ID: ActorSSO}
+	if support {
+		return s.Promote(ctx, actor, u.ID, jwt.RoleSupport)
+	}
+
+	return s.Demote(ctx, actor, u.ID, jwt.RoleSupport)
+}
+
+// Disable blocks the user from logging in and ends their sessions, including
+// the connections they are chatting on.
+func (s *service) Disable(ctx context.Context, actor *user.DTO, id string) (*user.DTO, error) {
+	if id == actor.ID {
+		return nil, ErrSelfChange
+	}
+
+	u, err := s.userSvc.SetDisabled(ctx, id, true)
+	if err != nil {
+		s.logger.Errorf("failed to disable user: %v", err)
+		return nil, err
+	}
+
+	sessions, err := s.jwtSvc.GetSessions(ctx, id)
+	if err != nil {
+		s.logger.Errorf("failed to get sessions of disabled user: %v", err)
+		return nil, err
+	}
+
+	if err = s.jwtSvc.DeleteAllTokens(ctx, id); err != nil {
+		s.logger.Errorf("failed to delete tokens of disabled user: %v", err)
+		return nil, err
+	}
+
+	sids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sids = append(sids, session.Id)
+	}
+	s.sessionCloser.CloseSessions(id, sids...)
+
+	if err = s.record(ctx, actor, ActionDisable, id, ""); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (s *service) Enable(ctx context.Context, actor *user.DTO, id string) (*user.DTO, error) {
+	u, err := s.userSvc.SetDisabled(ctx, id, false)
+	if err != nil {
+		s.logger.Errorf("failed to enable user: %v", err)
+		return nil, err
+	}
+
+	if err = s.record(ctx, actor, ActionEnable, id, ""); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (s *service) GetAuditLog(ctx context.Context, actor *user.DTO, query *AuditQuery) ([]*EntryDTO, error) {
+	filters := bson.M{}
+	if query.ActorId != "" {
+		filters["actorId"] = query.ActorId
+	}
+	if query.TargetId != "" {
+		filters["targetId"] = query.TargetId
+	}
+	if query.Before != nil {
+		filters["createdAt"] = bson.M{"$lt": *query.Before}
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > maxAuditPage {
+		limit = defaultAuditPage
+	}
+
+	entries, err := s.repository.GetEntries(ctx, filters,
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		s.logger.Errorf("failed to get audit log: %v", err)
+		return nil, err
+	}
+
+	dtos := make([]*EntryDTO, 0, len(entries))
+	for _, entry := range entries {
+		dtos = append(dtos, MapEntryToDTO(entry))
+	}
+
+	return dtos, nil
+}
+
+func (s *service) checkNoRooms(ctx context.Context, id string) error {
+	target, err := s.userSvc.GetUserById(ctx, id, false)
+	if err != nil {
+		s.logger.Errorf("failed to get user: %v", err)
+		return err
+	}
+
+	if len(target.Rooms) > 0 {
+		return ErrUserHasRooms
+	}
+
+	return nil
+}
+
+func (s *service) GetSettings(ctx context.Context, actor *user.DTO) (*SettingsDTO, error) {
+	settings, err := s.repository.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return MapSettingsToDTO(settings), nil
+}
+
+// UpdateSettings replaces the settings and records what changed.
+func (s *service) UpdateSettings(ctx context.Context, actor *user.DTO, dto *SettingsDTO) (*SettingsDTO, error) {
+	settings, err := s.repository.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changed := settings.SupportMfaRequired != dto.SupportMfaRequired
+
+	settings.SupportMfaRequired = dto.SupportMfaRequired
+	settings.UpdatedAt = time.Now()
+	if err = s.repository.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	if changed {
+		action := ActionDropMfaRequirement
+		if settings.SupportMfaRequired {
+			action = ActionRequireMfa
+		}
+		if err = s.record(ctx, actor, action, "", jwt.RoleSupport); err != nil {
+			return nil, err
+		}
+	}
+
+	return MapSettingsToDTO(settings), nil
+}
+
+// SupportMfaRequired tells the login whether support users and admins need a
+// second factor.
+func (s *service) SupportMfaRequired(ctx context.Context) (bool, error) {
+	settings, err := s.repository.GetSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return settings.SupportMfaRequired, nil
+}
+
+// record writes the change to the audit log.
+func (s *service) record(ctx context.Context, actor *user.DTO, action, targetId, role string) error {
+	err := s.repository.CreateEntry(ctx, &Entry{
+		ID:        primitive.NewObjectID(),
+		ActorId:   actor.ID,
+		Action:    action,
+		TargetId:  targetId,
+		Role:      role,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Errorf("failed to record %v of %v by %v: %v", action, targetId, actor.ID, err)
+		return err
+	}
+
+	return nil
+}
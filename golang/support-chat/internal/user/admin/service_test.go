@@ -0,0 +1,485 @@
+package admin_test
+
+import (
+	"context"
+	"support-chat/internal/user"
+	"support-chat/internal/user/admin"
+	mock_admin "support-chat/internal/user/admin/mocks"
+	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/jwt"
+	mock_jwt "support-chat/pkg/jwt/mocks"
+	"support-chat/pkg/logger"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewService(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	tests := []struct {
+		name       string
+		repository admin.Repository
+		userSvc    user.Service
+		jwtSvc     jwt.Service
+		closer     admin.SessionCloser
+		logger     *zap.SugaredLogger
+		expect     func(*testing.T, admin.Service, error)
+	}{
+		{
+			name:       "should return service",
+			repository: mock_admin.NewMockRepository(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			jwtSvc:     mock_jwt.NewMockService(controller),
+			closer:     mock_admin.NewMockSessionCloser(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s admin.Service, err error) {
+				assert.NotNil(t, s)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:       "should return invalid repository",
+			repository: nil,
+			userSvc:    mock_user.NewMockService(controller),
+			jwtSvc:     mock_jwt.NewMockService(controller),
+			closer:     mock_admin.NewMockSessionCloser(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s admin.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_admin_service] invalid repository")
+			},
+		},
+		{
+			name:       "should return invalid user service",
+			repository: mock_admin.NewMockRepository(controller),
+			userSvc:    nil,
+			jwtSvc:     mock_jwt.NewMockService(controller),
+			closer:     mock_admin.NewMockSessionCloser(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s admin.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_admin_service] invalid user service")
+			},
+		},
+		{
+			name:       "should return invalid jwt service",
+			repository: mock_admin.NewMockRepository(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			jwtSvc:     nil,
+			closer:     mock_admin.NewMockSessionCloser(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s admin.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_admin_service] invalid jwt service")
+			},
+		},
+		{
+			name:       "should return invalid session closer",
+			repository: mock_admin.NewMockRepository(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			jwtSvc:     mock_jwt.NewMockService(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s admin.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_admin_service] invalid session closer")
+			},
+		},
+		{
+			name:       "should return invalid logger",
+			repository: mock_admin.NewMockRepository(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			jwtSvc:     mock_jwt.NewMockService(controller),
+			closer:     mock_admin.NewMockSessionCloser(controller),
+			logger:     nil,
+			expect: func(t *testing.T, s admin.Service, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_admin_service] invalid logger")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := admin.NewService(tc.repository, tc.userSvc, tc.jwtSvc, tc.closer, tc.logger)
+			tc.expect(t, s, err)
+		})
+	}
+}
+
+func TestService_Promote(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_admin.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := admin.NewService(mockRepo, mockUserSvc, mock_jwt.NewMockService(controller), mock_admin.NewMockSessionCloser(controller), zapLogger)
+
+	actor := &user.DTO{ID: "admin", Admin: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		actor  *user.DTO
+		role   string
+		setup  func(context.Context)
+		expect func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name:  "should promote to support and record it",
+			ctx:   context.Background(),
+			actor: actor,
+			role:  jwt.RoleSupport,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().SetSupport(ctx, "user", true).Return(&user.DTO{ID: "user", Support: true}, nil)
+				mockRepo.EXPECT().CreateEntry(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, entry *admin.Entry) error {
+					assert.Equal(t, "admin", entry.ActorId)
+					assert.Equal(t, admin.ActionPromote, entry.Action)
+					assert.Equal(t, "user", entry.TargetId)
+					assert.Equal(t, jwt.RoleSupport, entry.Role)
+					assert.False(t, entry.CreatedAt.IsZero())
+					return nil
+				})
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.True(t, u.Support)
+			},
+		},
+		{
+			name:  "should return invalid role",
+			ctx:   context.Background(),
+			actor: actor,
+			role:  "owner",
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, u)
+				assert.Equal(t, admin.ErrInvalidRole, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			u, err := service.Promote(tc.ctx, tc.actor, "user", tc.role)
+			tc.expect(t, u, err)
+		})
+	}
+}
+
+func TestService_Demote(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_admin.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := admin.NewService(mockRepo, mockUserSvc, mock_jwt.NewMockService(controller), mock_admin.NewMockSessionCloser(controller), zapLogger)
+
+	actor := &user.DTO{ID: "admin", Admin: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		id     string
+		role   string
+		setup  func(context.Context)
+		expect func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name: "should demote agent without rooms",
+			ctx:  context.Background(),
+			id:   "agent",
+			role: jwt.RoleSupport,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).Return(&user.DTO{ID: "agent", Support: true}, nil)
+				mockUserSvc.EXPECT().SetSupport(ctx, "agent", false).Return(&user.DTO{ID: "agent"}, nil)
+				mockRepo.EXPECT().CreateEntry(ctx, gomock.Any()).Return(nil)
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.False(t, u.Support)
+			},
+		},
+		{
+			name: "should return user has rooms",
+			ctx:  context.Background(),
+			id:   "agent",
+			role: jwt.RoleSupport,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).
+					Return(&user.DTO{ID: "agent", Support: true, Rooms: []string{"room"}}, nil)
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, u)
+				assert.Equal(t, admin.ErrUserHasRooms, err)
+			},
+		},
+		{
+			name:  "should return self change",
+			ctx:   context.Background(),
+			id:    "admin",
+			role:  jwt.RoleAdmin,
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, u)
+				assert.Equal(t, admin.ErrSelfChange, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			u, err := service.Demote(tc.ctx, actor, tc.id, tc.role)
+			tc.expect(t, u, err)
+		})
+	}
+}
+
+func TestService_SyncSupport(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_admin.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := admin.NewService(mockRepo, mockUserSvc, mock_jwt.NewMockService(controller), mock_admin.NewMockSessionCloser(controller), zapLogger)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		u       *user.DTO
+		support bool
+		setup   func(context.Context)
+		expect  func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name:    "should leave unchanged role alone",
+			ctx:     context.Background(),
+			u:       &user.DTO{ID: "agent", Support: true},
+			support: true,
+			setup:   func(ctx context.Context) {},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.True(t, u.Support)
+			},
+		},
+		{
+			name:    "should promote and record it as sso",
+			ctx:     context.Background(),
+			u:       &user.DTO{ID: "user"},
+			support: true,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().SetSupport(ctx, "user", true).Return(&user.DTO{ID: "user", Support: true}, nil)
+				mockRepo.EXPECT().CreateEntry(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, entry *admin.Entry) error {
+					assert.Equal(t, admin.ActorSSO, entry.ActorId)
+					assert.Equal(t, admin.ActionPromote, entry.Action)
+					assert.Equal(t, "user", entry.TargetId)
+					assert.Equal(t, jwt.RoleSupport, entry.Role)
+					return nil
+				})
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.True(t, u.Support)
+			},
+		},
+		{
+			name:    "should demote and record it as sso",
+			ctx:     context.Background(),
+			u:       &user.DTO{ID: "agent", Support: true},
+			support: false,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).Return(&user.DTO{ID: "agent", Support: true}, nil)
+				mockUserSvc.EXPECT().SetSupport(ctx, "agent", false).Return(&user.DTO{ID: "agent"}, nil)
+				mockRepo.EXPECT().CreateEntry(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, entry *admin.Entry) error {
+					assert.Equal(t, admin.ActorSSO, entry.ActorId)
+					assert.Equal(t, admin.ActionDemote, entry.Action)
+					return nil
+				})
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.False(t, u.Support)
+			},
+		},
+		{
+			name:    "should return user has rooms",
+			ctx:     context.Background(),
+			u:       &user.DTO{ID: "agent", Support: true},
+			support: false,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).
+					Return(&user.DTO{ID: "agent", Support: true, Rooms: []string{"room"}}, nil)
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, u)
+				assert.Equal(t, admin.ErrUserHasRooms, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			u, err := service.SyncSupport(tc.ctx, tc.u, tc.support)
+			tc.expect(t, u, err)
+		})
+	}
+}
+
+func TestService_Disable(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_admin.NewMockRepository(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockCloser := mock_admin.NewMockSessionCloser(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := admin.NewService(mockRepo, mockUserSvc, mockJwt, mockCloser, zapLogger)
+
+	actor := &user.DTO{ID: "admin", Admin: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		id     string
+		setup  func(context.Context)
+		expect func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name: "should disable user and end their sessions",
+			ctx:  context.Background(),
+			id:   "user",
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().SetDisabled(ctx, "user", true).Return(&user.DTO{ID: "user", Disabled: true}, nil)
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return([]*jwt.Session{{Id: "laptop"}, {Id: "phone"}}, nil)
+				mockJwt.EXPECT().DeleteAllTokens(ctx, "user").Return(nil)
+				mockCloser.EXPECT().CloseSessions("user", "laptop", "phone")
+				mockRepo.EXPECT().CreateEntry(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, entry *admin.Entry) error {
+					assert.Equal(t, admin.ActionDisable, entry.Action)
+					return nil
+				})
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.True(t, u.Disabled)
+			},
+		},
+		{
+			name: "should return failed get sessions",
+			ctx:  context.Background(),
+			id:   "user",
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().SetDisabled(ctx, "user", true).Return(&user.DTO{ID: "user", Disabled: true}, nil)
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(nil, jwt.ErrFailedGetSessions)
+			},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, u)
+				assert.Equal(t, jwt.ErrFailedGetSessions, err)
+			},
+		},
+		{
+			name:  "should return self change",
+			ctx:   context.Background(),
+			id:    "admin",
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, u *user.DTO, err error) {
+				assert.Nil(t, u)
+				assert.Equal(t, admin.ErrSelfChange, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			u, err := service.Disable(tc.ctx, actor, tc.id)
+			tc.expect(t, u, err)
+		})
+	}
+}
+
+func TestService_UpdateSettings(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_admin.NewMockRepository(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := admin.NewService(mockRepo, mock_user.NewMockService(controller), mock_jwt.NewMockService(controller), mock_admin.NewMockSessionCloser(controller), zapLogger)
+
+	actor := &user.DTO{ID: "admin", Admin: true}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		actor  *user.DTO
+		dto    *admin.SettingsDTO
+		setup  func(context.Context)
+		expect func(*testing.T, *admin.SettingsDTO, error)
+	}{
+		{
+			name:  "should require mfa and record it",
+			ctx:   context.Background(),
+			actor: actor,
+			dto:   &admin.SettingsDTO{SupportMfaRequired: true},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetSettings(ctx).Return(&admin.Settings{}, nil)
+				mockRepo.EXPECT().SaveSettings(ctx, gomock.Any()).Return(nil)
+				mockRepo.EXPECT().CreateEntry(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, e *admin.Entry) error {
+					assert.Equal(t, admin.ActionRequireMfa, e.Action)
+					assert.Equal(t, jwt.RoleSupport, e.Role)
+					return nil
+				})
+			},
+			expect: func(t *testing.T, s *admin.SettingsDTO, err error) {
+				assert.Nil(t, err)
+				assert.True(t, s.SupportMfaRequired)
+			},
+		},
+		{
+			name:  "should not record unchanged settings",
+			ctx:   context.Background(),
+			actor: actor,
+			dto:   &admin.SettingsDTO{SupportMfaRequired: true},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetSettings(ctx).Return(&admin.Settings{SupportMfaRequired: true}, nil)
+				mockRepo.EXPECT().SaveSettings(ctx, gomock.Any()).Return(nil)
+			},
+			expect: func(t *testing.T, s *admin.SettingsDTO, err error) {
+				assert.Nil(t, err)
+				assert.True(t, s.SupportMfaRequired)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			s, err := service.UpdateSettings(tc.ctx, tc.actor, tc.dto)
+			tc.expect(t, s, err)
+		})
+	}
+}
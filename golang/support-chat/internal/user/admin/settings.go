@@ -0,0 +1,23 @@
+package admin
+
+import "time"
+
+// settingsId is the id of the single settings document
+const settingsId = "settings"
+
+// Settings are the security settings admins change at runtime.
+type Settings struct {
+	ID string `bson:"_id"`
+	// SupportMfaRequired makes support users and admins log in with a second
+	// factor, the ones without one have to enroll on their next login.
+	SupportMfaRequired bool      `bson:"supportMfaRequired"`
+	UpdatedAt          time.Time `bson:"updatedAt"`
+}
+
+type SettingsDTO struct {
+	SupportMfaRequired bool `json:"support_mfa_required"`
+}
+
+func MapSettingsToDTO(s *Settings) *SettingsDTO {
+	return &SettingsDTO{SupportMfaRequired: s.SupportMfaRequired}
+}
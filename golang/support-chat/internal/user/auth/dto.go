@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"support-chat/pkg/errors"
+	"time"
 	"unicode"
 
 	"github.com/go-playground/validator/v10"
@@ -77,11 +78,76 @@ type RegistrationResponseDTO struct {
 type LoginDTO struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,password"`
+	// Device is an optional label of the session, like "Work laptop"
+	Device string `json:"device" validate:"max=64"`
 }
 
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type VerifyDTO struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// LoginResponseDTO carries either the token pair or, when the user needs a
+// second factor, the mfa token to send with the code to /mfa.
 type LoginResponseDTO struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	MfaToken     string `json:"mfa_token,omitempty"`
+	// MfaEnroll is set when the user has to enroll first, see /mfa/enroll
+	MfaEnroll bool `json:"mfa_enroll,omitempty"`
+	// RecoveryCodes are only returned by the login which confirmed the
+	// enrollment
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type SSOStartDTO struct {
+	// Url is the login page of the identity provider
+	Url string `json:"url"`
+	// State is set in the SSOStateCookie instead of being handed to the app
+	State string `json:"-"`
+}
+
+type SSOCallbackDTO struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+	// Device is an optional label of the session, like "Work laptop"
+	Device string `json:"device" validate:"max=64"`
+}
+
+type MfaDTO struct {
+	Token string `json:"mfa_token" validate:"required"`
+	// Code is a code of the authenticator app or a recovery code
+	Code string `json:"code" validate:"required"`
+}
+
+type MfaEnrollDTO struct {
+	Token string `json:"mfa_token" validate:"required"`
+}
+
+type TotpEnrollmentDTO struct {
+	Secret string `json:"secret"`
+	// Uri is the otpauth URI to show as a QR code
+	Uri string `json:"uri"`
+}
+
+type TotpCodeDTO struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 type RefreshDTO struct {
@@ -100,3 +166,18 @@ type CheckResponseDTO struct {
 	Role   string `json:"role"`
 	IsRoom bool   `json:"is_room"`
 }
+
+type TicketResponseDTO struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type SessionDTO struct {
+	Id         string    `json:"id"`
+	Device     string    `json:"device,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
@@ -6,9 +6,47 @@ import (
 )
 
 const (
-	StatusInvalidRequest errors.Status = "invalid_request"
+	StatusInvalidRequest        errors.Status = "invalid_request"
+	StatusToken                 errors.Status = "invalid_token"
+	StatusRequiredToken         errors.Status = "token_required"
+	StatusInvalidTicket         errors.Status = "invalid_ticket"
+	StatusRequiredTicket        errors.Status = "ticket_required"
+	StatusFailedCreateTicket    errors.Status = "failed_create_ticket"
+	StatusSessionNotFound       errors.Status = "session_not_found"
+	StatusNotAllowed            errors.Status = "not_allowed_to_manage_sessions"
+	StatusFailedCreateMailToken errors.Status = "failed_create_mail_token"
+	StatusInvalidMailToken      errors.Status = "invalid_or_expired_token"
+	StatusFailedSendMail        errors.Status = "failed_send_mail"
+	StatusMailCooldown          errors.Status = "mail_sent_recently"
+	StatusFailedCreateMfaToken  errors.Status = "failed_create_mfa_token"
+	StatusInvalidMfaToken       errors.Status = "invalid_or_expired_mfa_token"
+	StatusMfaRequired           errors.Status = "mfa_required"
+	StatusInvalidSSOState       errors.Status = "invalid_or_expired_sso_state"
+	StatusFailedSSO             errors.Status = "failed_sso"
+	StatusSSOEmail              errors.Status = "sso_email_not_verified"
+	StatusSSONotLinked          errors.Status = "sso_account_not_linked"
+	StatusSSOIdentityTaken      errors.Status = "sso_identity_linked_to_other_user"
 )
 
 var (
-	ErrInvalidRequest = errors.New(codes.BadRequest, StatusInvalidRequest)
+	ErrInvalidRequest        = errors.New(codes.BadRequest, StatusInvalidRequest)
+	ErrToken                 = errors.New(codes.Unauthorized, StatusToken)
+	ErrRequiredToken         = errors.New(codes.Unauthorized, StatusRequiredToken)
+	ErrInvalidTicket         = errors.New(codes.Unauthorized, StatusInvalidTicket)
+	ErrRequiredTicket        = errors.New(codes.Unauthorized, StatusRequiredTicket)
+	ErrFailedCreateTicket    = errors.New(codes.InternalError, StatusFailedCreateTicket)
+	ErrSessionNotFound       = errors.New(codes.NotFound, StatusSessionNotFound)
+	ErrNotAllowed            = errors.New(codes.Forbidden, StatusNotAllowed)
+	ErrFailedCreateMailToken = errors.New(codes.InternalError, StatusFailedCreateMailToken)
+	ErrInvalidMailToken      = errors.New(codes.BadRequest, StatusInvalidMailToken)
+	ErrFailedSendMail        = errors.New(codes.InternalError, StatusFailedSendMail)
+	ErrMailCooldown          = errors.New(codes.TooManyRequests, StatusMailCooldown)
+	ErrFailedCreateMfaToken  = errors.New(codes.InternalError, StatusFailedCreateMfaToken)
+	ErrInvalidMfaToken       = errors.New(codes.Unauthorized, StatusInvalidMfaToken)
+	ErrMfaRequired           = errors.New(codes.Forbidden, StatusMfaRequired)
+	ErrInvalidSSOState       = errors.New(codes.BadRequest, StatusInvalidSSOState)
+	ErrFailedSSO             = errors.New(codes.InternalError, StatusFailedSSO)
+	ErrSSOEmail              = errors.New(codes.Forbidden, StatusSSOEmail)
+	ErrSSONotLinked          = errors.New(codes.Forbidden, StatusSSONotLinked)
+	ErrSSOIdentityTaken      = errors.New(codes.DuplicateError, StatusSSOIdentityTaken)
 )
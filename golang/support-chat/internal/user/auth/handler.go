@@ -3,23 +3,35 @@ package auth
 import (
 	"encoding/json"
 	goErr "errors"
+	"net"
 	"net/http"
+	"support-chat/internal/user"
 	"support-chat/pkg/errors"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/rbac"
 	"support-chat/pkg/respond"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	authSvc Service
+	authSvc        Service
+	authMiddleware Middleware
+	permissions    rbac.Middleware
 }
 
-func NewHandler(authSvc Service) (*Handler, error) {
+func NewHandler(authSvc Service, authMiddleware Middleware, permissions rbac.Middleware) (*Handler, error) {
 	if authSvc == nil {
 		return nil, goErr.New("[chat_auth_handler] invalid auth service")
 	}
+	if authMiddleware == nil {
+		return nil, goErr.New("[chat_auth_handler] invalid auth middleware")
+	}
+	if permissions == nil {
+		return nil, goErr.New("[chat_auth_handler] invalid permissions middleware")
+	}
 
-	return &Handler{authSvc: authSvc}, nil
+	return &Handler{authSvc: authSvc, authMiddleware: authMiddleware, permissions: permissions}, nil
 }
 
 func (h *Handler) SetupRoutes(router chi.Router) {
@@ -28,6 +40,29 @@ func (h *Handler) SetupRoutes(router chi.Router) {
 	router.Post("/refresh", h.Refresh)
 	router.Post("/logout", h.Logout)
 	router.Post("/check", h.Check)
+	router.Post("/forgot-password", h.ForgotPassword)
+	router.Post("/reset-password", h.ResetPassword)
+	router.Post("/verify", h.Verify)
+	router.Post("/verify/resend", h.ResendVerification)
+	router.Post("/mfa", h.Mfa)
+	router.Post("/mfa/enroll", h.EnrollMfa)
+
+	router.Group(func(r chi.Router) {
+		r.Use(h.authMiddleware.JwtMiddleware)
+		r.With(h.permissions.Require(rbac.ChatConnect)).Post("/ws-ticket", h.CreateTicket)
+		r.Get("/sessions", h.GetSessions)
+		r.Delete("/sessions", h.RevokeOtherSessions)
+		r.Delete("/sessions/{id}", h.RevokeSession)
+		r.Post("/totp", h.EnrollTotp)
+		r.Post("/totp/confirm", h.ConfirmTotp)
+		r.Delete("/totp", h.DisableTotp)
+	})
+}
+
+// SetupWellKnownRoutes serves the public keys other services verify our tokens
+// with.
+func (h *Handler) SetupWellKnownRoutes(router chi.Router) {
+	router.Get("/.well-known/jwks.json", h.JWKS)
 }
 
 func (h *Handler) Registration(w http.ResponseWriter, r *http.Request) {
@@ -67,16 +102,60 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authSvc.Login(r.Context(), &dto)
+	tokens, err := h.authSvc.Login(r.Context(), &dto, &jwt.Device{
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	})
 	if err != nil {
 		respond.Respond(w, errors.HTTPCode(err), err)
 		return
 	}
 
-	respond.Respond(w, http.StatusOK, &LoginResponseDTO{
-		AccessToken:  *accessToken,
-		RefreshToken: *refreshToken,
-	})
+	respond.Respond(w, http.StatusOK, tokens)
+}
+
+func (h *Handler) Mfa(w http.ResponseWriter, r *http.Request) {
+	var dto MfaDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	tokens, err := h.authSvc.Mfa(r.Context(), &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, tokens)
+}
+
+func (h *Handler) EnrollMfa(w http.ResponseWriter, r *http.Request) {
+	var dto MfaEnrollDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	enrollment, err := h.authSvc.EnrollMfa(r.Context(), &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusCreated, enrollment)
 }
 
 func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -126,6 +205,92 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	respond.Respond(w, http.StatusOK, "OK")
 }
 
+// ForgotPassword answers the same whether the email has an account or not.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var dto ForgotPasswordDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	if err := h.authSvc.ForgotPassword(r.Context(), &dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusAccepted, "OK")
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var dto ResetPasswordDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	if err := h.authSvc.ResetPassword(r.Context(), &dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, "OK")
+}
+
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	var dto VerifyDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	if err := h.authSvc.Verify(r.Context(), &dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, "OK")
+}
+
+// ResendVerification answers the same whether the email has an account or not.
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var dto ResendVerificationDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	if err := h.authSvc.ResendVerification(r.Context(), &dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusAccepted, "OK")
+}
+
 func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
 	var dto CheckDTO
 
@@ -147,3 +312,157 @@ func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
 
 	respond.Respond(w, http.StatusOK, check)
 }
+
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	respond.Respond(w, http.StatusOK, h.authSvc.JWKS())
+}
+
+func (h *Handler) CreateTicket(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	ticket, err := h.authSvc.CreateTicket(r.Context(), principal)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusCreated, ticket)
+}
+
+// GetSessions lists the sessions of the caller, admins can pass the user query
+// to list the sessions of someone else.
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	sessions, err := h.authSvc.GetSessions(r.Context(), principal, r.URL.Query().Get("user"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, sessions)
+}
+
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	err := h.authSvc.RevokeSession(r.Context(), principal, r.URL.Query().Get("user"), chi.URLParam(r, "id"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, "OK")
+}
+
+// RevokeOtherSessions logs the caller out everywhere else.
+func (h *Handler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	err := h.authSvc.RevokeOtherSessions(r.Context(), principal, r.URL.Query().Get("user"))
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, "OK")
+}
+
+func (h *Handler) EnrollTotp(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	enrollment, err := h.authSvc.EnrollTotp(r.Context(), principal)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusCreated, enrollment)
+}
+
+func (h *Handler) ConfirmTotp(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	var dto TotpCodeDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	codes, err := h.authSvc.ConfirmTotp(r.Context(), principal, &dto)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, codes)
+}
+
+// DisableTotp takes a current code in the body, so a stolen access token
+// alone can't remove the second factor.
+func (h *Handler) DisableTotp(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	var dto TotpCodeDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	if err := h.authSvc.DisableTotp(r.Context(), principal, &dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, "OK")
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
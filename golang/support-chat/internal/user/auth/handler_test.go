@@ -3,6 +3,8 @@ package auth_test
 import (
 	"support-chat/internal/user/auth"
 	mock_auth "support-chat/internal/user/auth/mocks"
+	"support-chat/pkg/rbac"
+	mock_rbac "support-chat/pkg/rbac/mocks"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -14,32 +16,60 @@ func TestNewHandler(t *testing.T) {
 	defer controller.Finish()
 
 	tests := []struct {
-		name    string
-		authSvc auth.Service
-		expect  func(*testing.T, *auth.Handler, error)
+		name           string
+		authSvc        auth.Service
+		authMiddleware auth.Middleware
+		permissions    rbac.Middleware
+		expect         func(*testing.T, *auth.Handler, error)
 	}{
 		{
-			name:    "should return service",
-			authSvc: mock_auth.NewMockService(controller),
+			name:           "should return service",
+			authSvc:        mock_auth.NewMockService(controller),
+			authMiddleware: mock_auth.NewMockMiddleware(controller),
+			permissions:    mock_rbac.NewMockMiddleware(controller),
 			expect: func(t *testing.T, s *auth.Handler, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:    "should return invalid auth service",
-			authSvc: nil,
+			name:           "should return invalid auth service",
+			authSvc:        nil,
+			authMiddleware: mock_auth.NewMockMiddleware(controller),
+			permissions:    mock_rbac.NewMockMiddleware(controller),
 			expect: func(t *testing.T, s *auth.Handler, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, "[chat_auth_handler] invalid auth service")
 			},
 		},
+		{
+			name:           "should return invalid auth middleware",
+			authSvc:        mock_auth.NewMockService(controller),
+			authMiddleware: nil,
+			permissions:    mock_rbac.NewMockMiddleware(controller),
+			expect: func(t *testing.T, s *auth.Handler, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_auth_handler] invalid auth middleware")
+			},
+		},
+		{
+			name:           "should return invalid permissions middleware",
+			authSvc:        mock_auth.NewMockService(controller),
+			authMiddleware: mock_auth.NewMockMiddleware(controller),
+			permissions:    nil,
+			expect: func(t *testing.T, s *auth.Handler, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[chat_auth_handler] invalid permissions middleware")
+			},
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := auth.NewHandler(tc.authSvc)
+			svc, err := auth.NewHandler(tc.authSvc, tc.authMiddleware, tc.permissions)
 			tc.expect(t, svc, err)
 		})
 	}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	gerrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Purposes of the tokens sent by mail, a token only redeems for its purpose
+const (
+	PurposeResetPassword = "password-reset"
+	PurposeVerifyEmail   = "verify-email"
+)
+
+//go:generate mockgen -source=mail_token.go -destination=mocks/mail_token_mock.go
+type MailTokenService interface {
+	CreateToken(ctx context.Context, purpose, userId string, ttl time.Duration) (string, error)
+	ConsumeToken(ctx context.Context, purpose, token string) (string, error)
+	Throttle(ctx context.Context, purpose, key string, cooldown time.Duration) error
+}
+
+type mailTokenService struct {
+	redisClient *redis.Client
+}
+
+// NewMailTokenService keeps the single-use tokens sent by mail in the auth
+// redis. Only a hash of the token is stored.
+func NewMailTokenService(redisClient *redis.Client) (MailTokenService, error) {
+	if redisClient == nil {
+		return nil, gerrors.New("[user_auth_mail_token] invalid redis client")
+	}
+
+	return &mailTokenService{redisClient: redisClient}, nil
+}
+
+// CreateToken issues a token of the user which expires after ttl.
+func (s *mailTokenService) CreateToken(ctx context.Context, purpose, userId string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", ErrFailedCreateMailToken
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := s.redisClient.Set(ctx, mailTokenKey(purpose, token), userId, ttl).Err(); err != nil {
+		return "", ErrFailedCreateMailToken
+	}
+
+	return token, nil
+}
+
+// ConsumeToken redeems the token once and returns the id of its user.
+func (s *mailTokenService) ConsumeToken(ctx context.Context, purpose, token string) (string, error) {
+	userId, err := s.redisClient.GetDel(ctx, mailTokenKey(purpose, token)).Result()
+	if err != nil {
+		return "", ErrInvalidMailToken
+	}
+
+	return userId, nil
+}
+
+// Throttle allows one mail of the purpose per key and cooldown, it returns
+// ErrMailCooldown while the cooldown runs.
+func (s *mailTokenService) Throttle(ctx context.Context, purpose, key string, cooldown time.Duration) error {
+	ok, err := s.redisClient.SetNX(ctx, mailTokenKey(purpose+"-cooldown", key), 1, cooldown).Result()
+	if err != nil {
+		return ErrFailedCreateMailToken
+	}
+	if !ok {
+		return ErrMailCooldown
+	}
+
+	return nil
+}
+
+func mailTokenKey(purpose, token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("mail-token-%v-%v", purpose, hex.EncodeToString(hash[:]))
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	gerrors "errors"
+	"fmt"
+	"support-chat/pkg/jwt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// mfaTokenTTL is how long a login waits for the second factor
+	mfaTokenTTL = 5 * time.Minute
+	// mfaMaxAttempts is how many codes can be tried with one mfa token
+	mfaMaxAttempts = 5
+)
+
+// MfaPending is a login which passed the password check and waits for the
+// second factor.
+type MfaPending struct {
+	UserId string      `json:"user_id"`
+	Device *jwt.Device `json:"device"`
+}
+
+//go:generate mockgen -source=mfa_token.go -destination=mocks/mfa_token_mock.go
+type MfaTokenService interface {
+	CreateToken(ctx context.Context, pending *MfaPending) (string, error)
+	GetToken(ctx context.Context, token string) (*MfaPending, error)
+	Attempt(ctx context.Context, token string) (*MfaPending, error)
+	DeleteToken(ctx context.Context, token string) error
+}
+
+type mfaTokenService struct {
+	redisClient *redis.Client
+}
+
+// NewMfaTokenService keeps the mfa pending tokens in the auth redis. Only a
+// hash of the token is stored.
+func NewMfaTokenService(redisClient *redis.Client) (MfaTokenService, error) {
+	if redisClient == nil {
+		return nil, gerrors.New("[user_auth_mfa_token] invalid redis client")
+	}
+
+	return &mfaTokenService{redisClient: redisClient}, nil
+}
+
+func (s *mfaTokenService) CreateToken(ctx context.Context, pending *MfaPending) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", ErrFailedCreateMfaToken
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return "", ErrFailedCreateMfaToken
+	}
+
+	if err = s.redisClient.Set(ctx, mfaTokenKey(token), data, mfaTokenTTL).Err(); err != nil {
+		return "", ErrFailedCreateMfaToken
+	}
+
+	return token, nil
+}
+
+// GetToken returns the login of the token without counting an attempt.
+func (s *mfaTokenService) GetToken(ctx context.Context, token string) (*MfaPending, error) {
+	data, err := s.redisClient.Get(ctx, mfaTokenKey(token)).Bytes()
+	if err != nil {
+		return nil, ErrInvalidMfaToken
+	}
+
+	pending := new(MfaPending)
+	if err = json.Unmarshal(data, pending); err != nil {
+		return nil, ErrInvalidMfaToken
+	}
+
+	return pending, nil
+}
+
+// Attempt returns the login of the token and counts a code tried with it.
+// After mfaMaxAttempts the token is dropped and the user has to log in again.
+func (s *mfaTokenService) Attempt(ctx context.Context, token string) (*MfaPending, error) {
+	pending, err := s.GetToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts *redis.IntCmd
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		attempts = pipe.Incr(ctx, mfaAttemptsKey(token))
+		pipe.Expire(ctx, mfaAttemptsKey(token), mfaTokenTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, ErrInvalidMfaToken
+	}
+
+	if attempts.Val() > mfaMaxAttempts {
+		_ = s.DeleteToken(ctx, token)
+		return nil, ErrInvalidMfaToken
+	}
+
+	return pending, nil
+}
+
+func (s *mfaTokenService) DeleteToken(ctx context.Context, token string) error {
+	return s.redisClient.Del(ctx, mfaTokenKey(token), mfaAttemptsKey(token)).Err()
+}
+
+func mfaTokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("mfa-token-%v", hex.EncodeToString(hash[:]))
+}
+
+func mfaAttemptsKey(token string) string {
+	return mfaTokenKey(token) + "-attempts"
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	gerrors "errors"
+	"net/http"
+	"strings"
+	"support-chat/internal/user"
+	"support-chat/pkg/errors"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/rbac"
+	"support-chat/pkg/respond"
+
+	"go.uber.org/zap"
+)
+
+// AccessCookie is the cookie the access token is read from by CookieSource.
+const AccessCookie = "access_token"
+
+// TokenSource extracts the access token from the request. It returns an empty
+// token when the request doesn't carry one, and an error when it is malformed.
+type TokenSource func(r *http.Request) (string, error)
+
+// HeaderSource reads a bearer token from the Authorization header.
+func HeaderSource() TokenSource {
+	return func(r *http.Request) (string, error) {
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			return "", nil
+		}
+
+		parts := strings.Split(authorization, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			return "", ErrToken
+		}
+
+		return parts[1], nil
+	}
+}
+
+func CookieSource(name string) TokenSource {
+	return func(r *http.Request) (string, error) {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return "", nil
+		}
+
+		return cookie.Value, nil
+	}
+}
+
+//go:generate mockgen -source=middleware.go -destination=mocks/middleware_mock.go
+type Middleware interface {
+	JwtMiddleware(next http.Handler) http.Handler
+	TicketMiddleware(next http.Handler) http.Handler
+}
+
+type middleware struct {
+	jwtSvc    jwt.Service
+	userSvc   user.Service
+	ticketSvc TicketService
+	sources   []TokenSource
+	logger    *zap.SugaredLogger
+}
+
+// NewMiddleware authenticates the requests with the first token found in the
+// sources, in the given order.
+func NewMiddleware(jwtSvc jwt.Service, userSvc user.Service, ticketSvc TicketService, logger *zap.SugaredLogger, sources ...TokenSource) (Middleware, error) {
+	if jwtSvc == nil {
+		return nil, gerrors.New("[user_auth_middleware] invalid jwt service")
+	}
+	if userSvc == nil {
+		return nil, gerrors.New("[user_auth_middleware] invalid user service")
+	}
+	if ticketSvc == nil {
+		return nil, gerrors.New("[user_auth_middleware] invalid ticket service")
+	}
+	if logger == nil {
+		return nil, gerrors.New("[user_auth_middleware] invalid logger")
+	}
+	if len(sources) == 0 {
+		return nil, gerrors.New("[user_auth_middleware] invalid token sources")
+	}
+
+	return &middleware{jwtSvc: jwtSvc, userSvc: userSvc, ticketSvc: ticketSvc, sources: sources, logger: logger}, nil
+}
+
+func (m *middleware) JwtMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := m.token(r)
+		if err != nil {
+			m.logger.Error("invalid auth token")
+			respond.Respond(w, errors.HTTPCode(err), err)
+			return
+		}
+
+		if token == "" {
+			m.logger.Error("failed to get auth token")
+			respond.Respond(w, errors.HTTPCode(ErrRequiredToken), ErrRequiredToken)
+			return
+		}
+
+		payload, err := m.jwtSvc.ParseToken(token, true)
+		if err != nil {
+			m.logger.Errorf("failed to parse auth token: %v", err)
+			respond.Respond(w, errors.HTTPCode(err), err)
+			return
+		}
+
+		principal, err := m.authenticate(r.Context(), payload)
+		if err != nil {
+			respond.Respond(w, errors.HTTPCode(err), err)
+			return
+		}
+
+		next.ServeHTTP(w, withPrincipal(r, principal))
+	})
+}
+
+// TicketMiddleware authenticates the websocket handshake with the ticket query
+// parameter, the ticket is consumed whether the handshake succeeds or not.
+func (m *middleware) TicketMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			m.logger.Error("failed to get ticket")
+			respond.Respond(w, errors.HTTPCode(ErrRequiredTicket), ErrRequiredTicket)
+			return
+		}
+
+		payload, err := m.ticketSvc.ConsumeTicket(r.Context(), ticket)
+		if err != nil {
+			m.logger.Errorf("failed to consume ticket: %v", err)
+			respond.Respond(w, errors.HTTPCode(err), err)
+			return
+		}
+
+		principal, err := m.authenticate(r.Context(), payload)
+		if err != nil {
+			respond.Respond(w, errors.HTTPCode(err), err)
+			return
+		}
+
+		next.ServeHTTP(w, withPrincipal(r, principal))
+	})
+}
+
+// authenticate checks the session of the token is still alive and loads its
+// user.
+func (m *middleware) authenticate(ctx context.Context, payload *jwt.Payload) (*user.Principal, error) {
+	err := m.jwtSvc.VerifyToken(ctx, payload, true)
+	if err != nil {
+		m.logger.Errorf("failed to verify auth token: %v", err)
+		return nil, err
+	}
+
+	u, err := m.userSvc.GetUserById(ctx, payload.Id, false)
+	if err != nil {
+		m.logger.Errorf("failed to get user: %v", err)
+		return nil, err
+	}
+
+	if u.Disabled {
+		m.logger.Errorf("user %v is disabled", u.ID)
+		return nil, user.ErrUserDisabled
+	}
+
+	err = m.jwtSvc.ExtendExpire(ctx, payload)
+	if err != nil {
+		m.logger.Errorf("failed to extend expire token: %v", err)
+		return nil, err
+	}
+
+	return &user.Principal{User: *u, Payload: payload}, nil
+}
+
+func withPrincipal(r *http.Request, principal *user.Principal) *http.Request {
+	ctx := user.WithPrincipal(r.Context(), principal)
+	ctx = rbac.WithRole(ctx, principal.User.Role())
+	return r.WithContext(ctx)
+}
+
+func (m *middleware) token(r *http.Request) (string, error) {
+	for _, source := range m.sources {
+		token, err := source(r)
+		if err != nil || token != "" {
+			return token, err
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,287 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"support-chat/internal/user"
+	"support-chat/internal/user/auth"
+	mock_auth "support-chat/internal/user/auth/mocks"
+	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/jwt"
+	mock_jwt "support-chat/pkg/jwt/mocks"
+	"support-chat/pkg/rbac"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	tests := []struct {
+		name      string
+		jwtSvc    jwt.Service
+		userSvc   user.Service
+		ticketSvc auth.TicketService
+		logger    *zap.SugaredLogger
+		sources   []auth.TokenSource
+		expect    func(*testing.T, auth.Middleware, error)
+	}{
+		{
+			name:      "should return middleware",
+			jwtSvc:    mock_jwt.NewMockService(controller),
+			userSvc:   mock_user.NewMockService(controller),
+			ticketSvc: mock_auth.NewMockTicketService(controller),
+			logger:    &zap.SugaredLogger{},
+			sources:   []auth.TokenSource{auth.HeaderSource()},
+			expect: func(t *testing.T, m auth.Middleware, err error) {
+				assert.NotNil(t, m)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:      "should return invalid jwt service",
+			jwtSvc:    nil,
+			userSvc:   mock_user.NewMockService(controller),
+			ticketSvc: mock_auth.NewMockTicketService(controller),
+			logger:    &zap.SugaredLogger{},
+			sources:   []auth.TokenSource{auth.HeaderSource()},
+			expect: func(t *testing.T, m auth.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[user_auth_middleware] invalid jwt service")
+			},
+		},
+		{
+			name:      "should return invalid user service",
+			jwtSvc:    mock_jwt.NewMockService(controller),
+			userSvc:   nil,
+			ticketSvc: mock_auth.NewMockTicketService(controller),
+			logger:    &zap.SugaredLogger{},
+			sources:   []auth.TokenSource{auth.HeaderSource()},
+			expect: func(t *testing.T, m auth.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[user_auth_middleware] invalid user service")
+			},
+		},
+		{
+			name:      "should return invalid ticket service",
+			jwtSvc:    mock_jwt.NewMockService(controller),
+			userSvc:   mock_user.NewMockService(controller),
+			ticketSvc: nil,
+			logger:    &zap.SugaredLogger{},
+			sources:   []auth.TokenSource{auth.HeaderSource()},
+			expect: func(t *testing.T, m auth.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[user_auth_middleware] invalid ticket service")
+			},
+		},
+		{
+			name:      "should return invalid logger",
+			jwtSvc:    mock_jwt.NewMockService(controller),
+			userSvc:   mock_user.NewMockService(controller),
+			ticketSvc: mock_auth.NewMockTicketService(controller),
+			logger:    nil,
+			sources:   []auth.TokenSource{auth.HeaderSource()},
+			expect: func(t *testing.T, m auth.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[user_auth_middleware] invalid logger")
+			},
+		},
+		{
+			name:      "should return invalid token sources",
+			jwtSvc:    mock_jwt.NewMockService(controller),
+			userSvc:   mock_user.NewMockService(controller),
+			ticketSvc: mock_auth.NewMockTicketService(controller),
+			logger:    &zap.SugaredLogger{},
+			sources:   nil,
+			expect: func(t *testing.T, m auth.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[user_auth_middleware] invalid token sources")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := auth.NewMiddleware(tc.jwtSvc, tc.userSvc, tc.ticketSvc, tc.logger, tc.sources...)
+			tc.expect(t, m, err)
+		})
+	}
+}
+
+func TestMiddleware_JwtMiddleware(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+
+	m, _ := auth.NewMiddleware(mockJwt, mockUserSvc, mock_auth.NewMockTicketService(controller), zap.NewNop().Sugar(),
+		auth.HeaderSource(),
+		auth.CookieSource(auth.AccessCookie))
+
+	payload := &jwt.Payload{Id: "user", Role: jwt.RoleUser}
+	authenticate := func(token string) {
+		mockJwt.EXPECT().ParseToken(token, true).Return(payload, nil)
+		mockJwt.EXPECT().VerifyToken(gomock.Any(), payload, true).Return(nil)
+		mockUserSvc.EXPECT().GetUserById(gomock.Any(), "user", false).Return(&user.DTO{ID: "user", Verified: true}, nil)
+		mockJwt.EXPECT().ExtendExpire(gomock.Any(), payload).Return(nil)
+	}
+
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		setup      func()
+		wantStatus int
+	}{
+		{
+			name: "should authenticate with authorization header",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/?token=query", nil)
+				r.Header.Set("Authorization", "Bearer header")
+				return r
+			},
+			setup:      func() { authenticate("header") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should authenticate with cookie",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/?token=query", nil)
+				r.AddCookie(&http.Cookie{Name: auth.AccessCookie, Value: "cookie"})
+				return r
+			},
+			setup:      func() { authenticate("cookie") },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should not accept token in query",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/?token=query", nil)
+			},
+			setup:      func() {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "should return invalid token",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/?token=query", nil)
+				r.Header.Set("Authorization", "Basic header")
+				return r
+			},
+			setup:      func() {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "should return token required",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			setup:      func() {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "should return user disabled",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Authorization", "Bearer header")
+				return r
+			},
+			setup: func() {
+				mockJwt.EXPECT().ParseToken("header", true).Return(payload, nil)
+				mockJwt.EXPECT().VerifyToken(gomock.Any(), payload, true).Return(nil)
+				mockUserSvc.EXPECT().GetUserById(gomock.Any(), "user", false).Return(&user.DTO{ID: "user", Disabled: true}, nil)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup()
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok := user.FromContext(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, "user", principal.User.ID)
+				assert.Equal(t, payload, principal.Payload)
+
+				role, _ := rbac.RoleFromContext(r.Context())
+				assert.Equal(t, jwt.RoleUser, role)
+
+				w.WriteHeader(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+
+			m.JwtMiddleware(next).ServeHTTP(w, tc.request())
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestMiddleware_TicketMiddleware(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockTicket := mock_auth.NewMockTicketService(controller)
+
+	m, _ := auth.NewMiddleware(mockJwt, mockUserSvc, mockTicket, zap.NewNop().Sugar(), auth.HeaderSource())
+
+	payload := &jwt.Payload{Id: "user", Role: jwt.RoleUser}
+
+	tests := []struct {
+		name       string
+		target     string
+		setup      func()
+		wantStatus int
+	}{
+		{
+			name:   "should authenticate with ticket",
+			target: "/chat?ticket=ticket",
+			setup: func() {
+				mockTicket.EXPECT().ConsumeTicket(gomock.Any(), "ticket").Return(payload, nil)
+				mockJwt.EXPECT().VerifyToken(gomock.Any(), payload, true).Return(nil)
+				mockUserSvc.EXPECT().GetUserById(gomock.Any(), "user", false).Return(&user.DTO{ID: "user", Verified: true}, nil)
+				mockJwt.EXPECT().ExtendExpire(gomock.Any(), payload).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "should return invalid ticket",
+			target: "/chat?ticket=used",
+			setup: func() {
+				mockTicket.EXPECT().ConsumeTicket(gomock.Any(), "used").Return(nil, auth.ErrInvalidTicket)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "should not accept access token",
+			target:     "/chat?token=token",
+			setup:      func() {},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup()
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, ok := user.FromContext(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, "user", principal.User.ID)
+
+				w.WriteHeader(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+
+			m.TicketMiddleware(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mail_token.go
+
+// Package mock_auth is a generated GoMock package.
+package mock_auth
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockMailTokenService is a mock of MailTokenService interface.
+type MockMailTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailTokenServiceMockRecorder
+}
+
+// MockMailTokenServiceMockRecorder is the mock recorder for MockMailTokenService.
+type MockMailTokenServiceMockRecorder struct {
+	mock *MockMailTokenService
+}
+
+// NewMockMailTokenService creates a new mock instance.
+func NewMockMailTokenService(ctrl *gomock.Controller) *MockMailTokenService {
+	mock := &MockMailTokenService{ctrl: ctrl}
+	mock.recorder = &MockMailTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMailTokenService) EXPECT() *MockMailTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// ConsumeToken mocks base method.
+func (m *MockMailTokenService) ConsumeToken(ctx context.Context, purpose, token string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeToken", ctx, purpose, token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeToken indicates an expected call of ConsumeToken.
+func (mr *MockMailTokenServiceMockRecorder) ConsumeToken(ctx, purpose, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeToken", reflect.TypeOf((*MockMailTokenService)(nil).ConsumeToken), ctx, purpose, token)
+}
+
+// CreateToken mocks base method.
+func (m *MockMailTokenService) CreateToken(ctx context.Context, purpose, userId string, ttl time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, purpose, userId, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockMailTokenServiceMockRecorder) CreateToken(ctx, purpose, userId, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockMailTokenService)(nil).CreateToken), ctx, purpose, userId, ttl)
+}
+
+// Throttle mocks base method.
+func (m *MockMailTokenService) Throttle(ctx context.Context, purpose, key string, cooldown time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Throttle", ctx, purpose, key, cooldown)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Throttle indicates an expected call of Throttle.
+func (mr *MockMailTokenServiceMockRecorder) Throttle(ctx, purpose, key, cooldown interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Throttle", reflect.TypeOf((*MockMailTokenService)(nil).Throttle), ctx, purpose, key, cooldown)
+}
@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: mfa_token.go
+
+// Package mock_auth is a generated GoMock package.
+package mock_auth
+
+import (
+	context "context"
+	reflect "reflect"
+	auth "support-chat/internal/user/auth"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockMfaTokenService is a mock of MfaTokenService interface.
+type MockMfaTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMfaTokenServiceMockRecorder
+}
+
+// MockMfaTokenServiceMockRecorder is the mock recorder for MockMfaTokenService.
+type MockMfaTokenServiceMockRecorder struct {
+	mock *MockMfaTokenService
+}
+
+// NewMockMfaTokenService creates a new mock instance.
+func NewMockMfaTokenService(ctrl *gomock.Controller) *MockMfaTokenService {
+	mock := &MockMfaTokenService{ctrl: ctrl}
+	mock.recorder = &MockMfaTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMfaTokenService) EXPECT() *MockMfaTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// Attempt mocks base method.
+func (m *MockMfaTokenService) Attempt(ctx context.Context, token string) (*auth.MfaPending, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Attempt", ctx, token)
+	ret0, _ := ret[0].(*auth.MfaPending)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Attempt indicates an expected call of Attempt.
+func (mr *MockMfaTokenServiceMockRecorder) Attempt(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attempt", reflect.TypeOf((*MockMfaTokenService)(nil).Attempt), ctx, token)
+}
+
+// CreateToken mocks base method.
+func (m *MockMfaTokenService) CreateToken(ctx context.Context, pending *auth.MfaPending) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, pending)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockMfaTokenServiceMockRecorder) CreateToken(ctx, pending interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockMfaTokenService)(nil).CreateToken), ctx, pending)
+}
+
+// DeleteToken mocks base method.
+func (m *MockMfaTokenService) DeleteToken(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteToken indicates an expected call of DeleteToken.
+func (mr *MockMfaTokenServiceMockRecorder) DeleteToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteToken", reflect.TypeOf((*MockMfaTokenService)(nil).DeleteToken), ctx, token)
+}
+
+// GetToken mocks base method.
+func (m *MockMfaTokenService) GetToken(ctx context.Context, token string) (*auth.MfaPending, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetToken", ctx, token)
+	ret0, _ := ret[0].(*auth.MfaPending)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetToken indicates an expected call of GetToken.
+func (mr *MockMfaTokenServiceMockRecorder) GetToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetToken", reflect.TypeOf((*MockMfaTokenService)(nil).GetToken), ctx, token)
+}
@@ -1,8 +1,8 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: middleware.go
 
-// Package mock_user is a generated GoMock package.
-package mock_user
+// Package mock_auth is a generated GoMock package.
+package mock_auth
 
 import (
 	http "net/http"
@@ -47,3 +47,17 @@ func (mr *MockMiddlewareMockRecorder) JwtMiddleware(next interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JwtMiddleware", reflect.TypeOf((*MockMiddleware)(nil).JwtMiddleware), next)
 }
+
+// TicketMiddleware mocks base method.
+func (m *MockMiddleware) TicketMiddleware(next http.Handler) http.Handler {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TicketMiddleware", next)
+	ret0, _ := ret[0].(http.Handler)
+	return ret0
+}
+
+// TicketMiddleware indicates an expected call of TicketMiddleware.
+func (mr *MockMiddlewareMockRecorder) TicketMiddleware(next interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TicketMiddleware", reflect.TypeOf((*MockMiddleware)(nil).TicketMiddleware), next)
+}
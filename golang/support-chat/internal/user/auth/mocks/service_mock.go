@@ -6,8 +6,10 @@ package mock_auth
 
 import (
 	context "context"
-	auth "support-chat/internal/user/auth"
 	reflect "reflect"
+	user "support-chat/internal/user"
+	auth "support-chat/internal/user/auth"
+	jwt "support-chat/pkg/jwt"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -50,20 +52,151 @@ func (mr *MockServiceMockRecorder) Check(ctx, dto interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockService)(nil).Check), ctx, dto)
 }
 
+// CompleteLogin mocks base method.
+func (m *MockService) CompleteLogin(ctx context.Context, userDto *user.DTO, device *jwt.Device) (*auth.LoginResponseDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteLogin", ctx, userDto, device)
+	ret0, _ := ret[0].(*auth.LoginResponseDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteLogin indicates an expected call of CompleteLogin.
+func (mr *MockServiceMockRecorder) CompleteLogin(ctx, userDto, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteLogin", reflect.TypeOf((*MockService)(nil).CompleteLogin), ctx, userDto, device)
+}
+
+// ConfirmTotp mocks base method.
+func (m *MockService) ConfirmTotp(ctx context.Context, principal *user.Principal, dto *auth.TotpCodeDTO) (*auth.RecoveryCodesDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTotp", ctx, principal, dto)
+	ret0, _ := ret[0].(*auth.RecoveryCodesDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTotp indicates an expected call of ConfirmTotp.
+func (mr *MockServiceMockRecorder) ConfirmTotp(ctx, principal, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTotp", reflect.TypeOf((*MockService)(nil).ConfirmTotp), ctx, principal, dto)
+}
+
+// CreateTicket mocks base method.
+func (m *MockService) CreateTicket(ctx context.Context, principal *user.Principal) (*auth.TicketResponseDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTicket", ctx, principal)
+	ret0, _ := ret[0].(*auth.TicketResponseDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTicket indicates an expected call of CreateTicket.
+func (mr *MockServiceMockRecorder) CreateTicket(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTicket", reflect.TypeOf((*MockService)(nil).CreateTicket), ctx, principal)
+}
+
+// DisableTotp mocks base method.
+func (m *MockService) DisableTotp(ctx context.Context, principal *user.Principal, dto *auth.TotpCodeDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTotp", ctx, principal, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTotp indicates an expected call of DisableTotp.
+func (mr *MockServiceMockRecorder) DisableTotp(ctx, principal, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTotp", reflect.TypeOf((*MockService)(nil).DisableTotp), ctx, principal, dto)
+}
+
+// EnrollMfa mocks base method.
+func (m *MockService) EnrollMfa(ctx context.Context, dto *auth.MfaEnrollDTO) (*auth.TotpEnrollmentDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollMfa", ctx, dto)
+	ret0, _ := ret[0].(*auth.TotpEnrollmentDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollMfa indicates an expected call of EnrollMfa.
+func (mr *MockServiceMockRecorder) EnrollMfa(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollMfa", reflect.TypeOf((*MockService)(nil).EnrollMfa), ctx, dto)
+}
+
+// EnrollTotp mocks base method.
+func (m *MockService) EnrollTotp(ctx context.Context, principal *user.Principal) (*auth.TotpEnrollmentDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTotp", ctx, principal)
+	ret0, _ := ret[0].(*auth.TotpEnrollmentDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTotp indicates an expected call of EnrollTotp.
+func (mr *MockServiceMockRecorder) EnrollTotp(ctx, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTotp", reflect.TypeOf((*MockService)(nil).EnrollTotp), ctx, principal)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockService) ForgotPassword(ctx context.Context, dto *auth.ForgotPasswordDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockServiceMockRecorder) ForgotPassword(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockService)(nil).ForgotPassword), ctx, dto)
+}
+
+// GetSessions mocks base method.
+func (m *MockService) GetSessions(ctx context.Context, principal *user.Principal, userId string) ([]*auth.SessionDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, principal, userId)
+	ret0, _ := ret[0].([]*auth.SessionDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockServiceMockRecorder) GetSessions(ctx, principal, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockService)(nil).GetSessions), ctx, principal, userId)
+}
+
+// JWKS mocks base method.
+func (m *MockService) JWKS() *jwt.JWKS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(*jwt.JWKS)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockServiceMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockService)(nil).JWKS))
+}
+
 // Login mocks base method.
-func (m *MockService) Login(ctx context.Context, dto *auth.LoginDTO) (*string, *string, error) {
+func (m *MockService) Login(ctx context.Context, dto *auth.LoginDTO, device *jwt.Device) (*auth.LoginResponseDTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", ctx, dto)
-	ret0, _ := ret[0].(*string)
-	ret1, _ := ret[1].(*string)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "Login", ctx, dto, device)
+	ret0, _ := ret[0].(*auth.LoginResponseDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Login indicates an expected call of Login.
-func (mr *MockServiceMockRecorder) Login(ctx, dto interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) Login(ctx, dto, device interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockService)(nil).Login), ctx, dto)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockService)(nil).Login), ctx, dto, device)
 }
 
 // Logout mocks base method.
@@ -80,6 +213,21 @@ func (mr *MockServiceMockRecorder) Logout(ctx, dto interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockService)(nil).Logout), ctx, dto)
 }
 
+// Mfa mocks base method.
+func (m *MockService) Mfa(ctx context.Context, dto *auth.MfaDTO) (*auth.LoginResponseDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mfa", ctx, dto)
+	ret0, _ := ret[0].(*auth.LoginResponseDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Mfa indicates an expected call of Mfa.
+func (mr *MockServiceMockRecorder) Mfa(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mfa", reflect.TypeOf((*MockService)(nil).Mfa), ctx, dto)
+}
+
 // Refresh mocks base method.
 func (m *MockService) Refresh(ctx context.Context, dto *auth.RefreshDTO) (*string, *string, error) {
 	m.ctrl.T.Helper()
@@ -110,3 +258,151 @@ func (mr *MockServiceMockRecorder) Registration(ctx, dto interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Registration", reflect.TypeOf((*MockService)(nil).Registration), ctx, dto)
 }
+
+// ResendVerification mocks base method.
+func (m *MockService) ResendVerification(ctx context.Context, dto *auth.ResendVerificationDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockServiceMockRecorder) ResendVerification(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockService)(nil).ResendVerification), ctx, dto)
+}
+
+// ResetPassword mocks base method.
+func (m *MockService) ResetPassword(ctx context.Context, dto *auth.ResetPasswordDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockServiceMockRecorder) ResetPassword(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockService)(nil).ResetPassword), ctx, dto)
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockService) RevokeOtherSessions(ctx context.Context, principal *user.Principal, userId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", ctx, principal, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockServiceMockRecorder) RevokeOtherSessions(ctx, principal, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockService)(nil).RevokeOtherSessions), ctx, principal, userId)
+}
+
+// RevokeSession mocks base method.
+func (m *MockService) RevokeSession(ctx context.Context, principal *user.Principal, userId, sid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, principal, userId, sid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockServiceMockRecorder) RevokeSession(ctx, principal, userId, sid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockService)(nil).RevokeSession), ctx, principal, userId, sid)
+}
+
+// Verify mocks base method.
+func (m *MockService) Verify(ctx context.Context, dto *auth.VerifyDTO) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, dto)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockServiceMockRecorder) Verify(ctx, dto interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockService)(nil).Verify), ctx, dto)
+}
+
+// MockSessionCloser is a mock of SessionCloser interface.
+type MockSessionCloser struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionCloserMockRecorder
+}
+
+// MockSessionCloserMockRecorder is the mock recorder for MockSessionCloser.
+type MockSessionCloserMockRecorder struct {
+	mock *MockSessionCloser
+}
+
+// NewMockSessionCloser creates a new mock instance.
+func NewMockSessionCloser(ctrl *gomock.Controller) *MockSessionCloser {
+	mock := &MockSessionCloser{ctrl: ctrl}
+	mock.recorder = &MockSessionCloserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionCloser) EXPECT() *MockSessionCloserMockRecorder {
+	return m.recorder
+}
+
+// CloseSessions mocks base method.
+func (m *MockSessionCloser) CloseSessions(userId string, sids ...string) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{userId}
+	for _, a := range sids {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "CloseSessions", varargs...)
+}
+
+// CloseSessions indicates an expected call of CloseSessions.
+func (mr *MockSessionCloserMockRecorder) CloseSessions(userId interface{}, sids ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{userId}, sids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseSessions", reflect.TypeOf((*MockSessionCloser)(nil).CloseSessions), varargs...)
+}
+
+// MockMfaPolicy is a mock of MfaPolicy interface.
+type MockMfaPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockMfaPolicyMockRecorder
+}
+
+// MockMfaPolicyMockRecorder is the mock recorder for MockMfaPolicy.
+type MockMfaPolicyMockRecorder struct {
+	mock *MockMfaPolicy
+}
+
+// NewMockMfaPolicy creates a new mock instance.
+func NewMockMfaPolicy(ctrl *gomock.Controller) *MockMfaPolicy {
+	mock := &MockMfaPolicy{ctrl: ctrl}
+	mock.recorder = &MockMfaPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMfaPolicy) EXPECT() *MockMfaPolicyMockRecorder {
+	return m.recorder
+}
+
+// SupportMfaRequired mocks base method.
+func (m *MockMfaPolicy) SupportMfaRequired(ctx context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportMfaRequired", ctx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SupportMfaRequired indicates an expected call of SupportMfaRequired.
+func (mr *MockMfaPolicyMockRecorder) SupportMfaRequired(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportMfaRequired", reflect.TypeOf((*MockMfaPolicy)(nil).SupportMfaRequired), ctx)
+}
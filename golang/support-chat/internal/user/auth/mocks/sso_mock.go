@@ -0,0 +1,121 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: sso.go
+
+// Package mock_auth is a generated GoMock package.
+package mock_auth
+
+import (
+	context "context"
+	reflect "reflect"
+	user "support-chat/internal/user"
+	auth "support-chat/internal/user/auth"
+	jwt "support-chat/pkg/jwt"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSSOService is a mock of SSOService interface.
+type MockSSOService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSSOServiceMockRecorder
+}
+
+// MockSSOServiceMockRecorder is the mock recorder for MockSSOService.
+type MockSSOServiceMockRecorder struct {
+	mock *MockSSOService
+}
+
+// NewMockSSOService creates a new mock instance.
+func NewMockSSOService(ctrl *gomock.Controller) *MockSSOService {
+	mock := &MockSSOService{ctrl: ctrl}
+	mock.recorder = &MockSSOServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSSOService) EXPECT() *MockSSOServiceMockRecorder {
+	return m.recorder
+}
+
+// Callback mocks base method.
+func (m *MockSSOService) Callback(ctx context.Context, dto *auth.SSOCallbackDTO, device *jwt.Device) (*auth.LoginResponseDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Callback", ctx, dto, device)
+	ret0, _ := ret[0].(*auth.LoginResponseDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Callback indicates an expected call of Callback.
+func (mr *MockSSOServiceMockRecorder) Callback(ctx, dto, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Callback", reflect.TypeOf((*MockSSOService)(nil).Callback), ctx, dto, device)
+}
+
+// Link mocks base method.
+func (m *MockSSOService) Link(ctx context.Context, userId string) (*auth.SSOStartDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Link", ctx, userId)
+	ret0, _ := ret[0].(*auth.SSOStartDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Link indicates an expected call of Link.
+func (mr *MockSSOServiceMockRecorder) Link(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Link", reflect.TypeOf((*MockSSOService)(nil).Link), ctx, userId)
+}
+
+// Start mocks base method.
+func (m *MockSSOService) Start(ctx context.Context) (*auth.SSOStartDTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx)
+	ret0, _ := ret[0].(*auth.SSOStartDTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockSSOServiceMockRecorder) Start(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockSSOService)(nil).Start), ctx)
+}
+
+// MockSupportSync is a mock of SupportSync interface.
+type MockSupportSync struct {
+	ctrl     *gomock.Controller
+	recorder *MockSupportSyncMockRecorder
+}
+
+// MockSupportSyncMockRecorder is the mock recorder for MockSupportSync.
+type MockSupportSyncMockRecorder struct {
+	mock *MockSupportSync
+}
+
+// NewMockSupportSync creates a new mock instance.
+func NewMockSupportSync(ctrl *gomock.Controller) *MockSupportSync {
+	mock := &MockSupportSync{ctrl: ctrl}
+	mock.recorder = &MockSupportSyncMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSupportSync) EXPECT() *MockSupportSyncMockRecorder {
+	return m.recorder
+}
+
+// SyncSupport mocks base method.
+func (m *MockSupportSync) SyncSupport(ctx context.Context, u *user.DTO, support bool) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncSupport", ctx, u, support)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncSupport indicates an expected call of SyncSupport.
+func (mr *MockSupportSyncMockRecorder) SyncSupport(ctx, u, support interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncSupport", reflect.TypeOf((*MockSupportSync)(nil).SyncSupport), ctx, u, support)
+}
@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: sso_state.go
+
+// Package mock_auth is a generated GoMock package.
+package mock_auth
+
+import (
+	context "context"
+	reflect "reflect"
+	auth "support-chat/internal/user/auth"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSSOStateService is a mock of SSOStateService interface.
+type MockSSOStateService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSSOStateServiceMockRecorder
+}
+
+// MockSSOStateServiceMockRecorder is the mock recorder for MockSSOStateService.
+type MockSSOStateServiceMockRecorder struct {
+	mock *MockSSOStateService
+}
+
+// NewMockSSOStateService creates a new mock instance.
+func NewMockSSOStateService(ctrl *gomock.Controller) *MockSSOStateService {
+	mock := &MockSSOStateService{ctrl: ctrl}
+	mock.recorder = &MockSSOStateServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSSOStateService) EXPECT() *MockSSOStateServiceMockRecorder {
+	return m.recorder
+}
+
+// ConsumeState mocks base method.
+func (m *MockSSOStateService) ConsumeState(ctx context.Context, state string) (*auth.SSOPending, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeState", ctx, state)
+	ret0, _ := ret[0].(*auth.SSOPending)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeState indicates an expected call of ConsumeState.
+func (mr *MockSSOStateServiceMockRecorder) ConsumeState(ctx, state interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeState", reflect.TypeOf((*MockSSOStateService)(nil).ConsumeState), ctx, state)
+}
+
+// CreateState mocks base method.
+func (m *MockSSOStateService) CreateState(ctx context.Context, pending *auth.SSOPending) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateState", ctx, pending)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateState indicates an expected call of CreateState.
+func (mr *MockSSOStateServiceMockRecorder) CreateState(ctx, pending interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateState", reflect.TypeOf((*MockSSOStateService)(nil).CreateState), ctx, pending)
+}
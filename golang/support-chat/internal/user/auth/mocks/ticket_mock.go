@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ticket.go
+
+// Package mock_auth is a generated GoMock package.
+package mock_auth
+
+import (
+	context "context"
+	reflect "reflect"
+	jwt "support-chat/pkg/jwt"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTicketService is a mock of TicketService interface.
+type MockTicketService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTicketServiceMockRecorder
+}
+
+// MockTicketServiceMockRecorder is the mock recorder for MockTicketService.
+type MockTicketServiceMockRecorder struct {
+	mock *MockTicketService
+}
+
+// NewMockTicketService creates a new mock instance.
+func NewMockTicketService(ctrl *gomock.Controller) *MockTicketService {
+	mock := &MockTicketService{ctrl: ctrl}
+	mock.recorder = &MockTicketServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTicketService) EXPECT() *MockTicketServiceMockRecorder {
+	return m.recorder
+}
+
+// ConsumeTicket mocks base method.
+func (m *MockTicketService) ConsumeTicket(ctx context.Context, ticket string) (*jwt.Payload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeTicket", ctx, ticket)
+	ret0, _ := ret[0].(*jwt.Payload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeTicket indicates an expected call of ConsumeTicket.
+func (mr *MockTicketServiceMockRecorder) ConsumeTicket(ctx, ticket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeTicket", reflect.TypeOf((*MockTicketService)(nil).ConsumeTicket), ctx, ticket)
+}
+
+// CreateTicket mocks base method.
+func (m *MockTicketService) CreateTicket(ctx context.Context, payload *jwt.Payload) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTicket", ctx, payload)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTicket indicates an expected call of CreateTicket.
+func (mr *MockTicketServiceMockRecorder) CreateTicket(ctx, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTicket", reflect.TypeOf((*MockTicketService)(nil).CreateTicket), ctx, payload)
+}
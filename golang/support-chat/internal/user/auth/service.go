@@ -3,8 +3,15 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
+	"sort"
 	"support-chat/internal/user"
 	"support-chat/pkg/jwt"
+	"support-chat/pkg/mailer"
+	"support-chat/pkg/rbac"
+	"support-chat/pkg/totp"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -12,32 +19,138 @@ import (
 //go:generate mockgen -source=service.go -destination=mocks/service_mock.go
 type Service interface {
 	Registration(ctx context.Context, dto *RegistrationDTO) (*string, error)
-	Login(ctx context.Context, dto *LoginDTO) (*string, *string, error)
+	Login(ctx context.Context, dto *LoginDTO, device *jwt.Device) (*LoginResponseDTO, error)
+	CompleteLogin(ctx context.Context, userDto *user.DTO, device *jwt.Device) (*LoginResponseDTO, error)
+	Mfa(ctx context.Context, dto *MfaDTO) (*LoginResponseDTO, error)
+	EnrollMfa(ctx context.Context, dto *MfaEnrollDTO) (*TotpEnrollmentDTO, error)
 	Refresh(ctx context.Context, dto *RefreshDTO) (*string, *string, error)
 	Logout(ctx context.Context, dto *LogoutDTO) error
 	Check(ctx context.Context, dto *CheckDTO) (*CheckResponseDTO, error)
+	CreateTicket(ctx context.Context, principal *user.Principal) (*TicketResponseDTO, error)
+	GetSessions(ctx context.Context, principal *user.Principal, userId string) ([]*SessionDTO, error)
+	RevokeSession(ctx context.Context, principal *user.Principal, userId, sid string) error
+	RevokeOtherSessions(ctx context.Context, principal *user.Principal, userId string) error
+	JWKS() *jwt.JWKS
+	ForgotPassword(ctx context.Context, dto *ForgotPasswordDTO) error
+	ResetPassword(ctx context.Context, dto *ResetPasswordDTO) error
+	Verify(ctx context.Context, dto *VerifyDTO) error
+	ResendVerification(ctx context.Context, dto *ResendVerificationDTO) error
+	EnrollTotp(ctx context.Context, principal *user.Principal) (*TotpEnrollmentDTO, error)
+	ConfirmTotp(ctx context.Context, principal *user.Principal, dto *TotpCodeDTO) (*RecoveryCodesDTO, error)
+	DisableTotp(ctx context.Context, principal *user.Principal, dto *TotpCodeDTO) error
+}
+
+// SessionCloser closes the live connections of revoked sessions.
+type SessionCloser interface {
+	CloseSessions(userId string, sids ...string)
+}
+
+// MfaPolicy tells whether support users and admins have to log in with a
+// second factor.
+type MfaPolicy interface {
+	SupportMfaRequired(ctx context.Context) (bool, error)
 }
 
 type service struct {
-	userSvc user.Service
-	jwtSvc  jwt.Service
-	logger  *zap.SugaredLogger
+	userSvc        user.Service
+	jwtSvc         jwt.Service
+	ticketSvc      TicketService
+	mailTokenSvc   MailTokenService
+	mfaTokenSvc    MfaTokenService
+	mailer         mailer.Mailer
+	sessionCloser  SessionCloser
+	mfaPolicy      MfaPolicy
+	rbacPolicy     *rbac.Policy
+	appUrl         string
+	totpIssuer     string
+	resetTTL       time.Duration
+	verifyTTL      time.Duration
+	resendCooldown time.Duration
+	logger         *zap.SugaredLogger
 }
 
-func NewService(userSvc user.Service, jwtSvc jwt.Service, logger *zap.SugaredLogger) (Service, error) {
+func NewService(userSvc user.Service,
+	jwtSvc jwt.Service,
+	ticketSvc TicketService,
+	mailTokenSvc MailTokenService,
+	mfaTokenSvc MfaTokenService,
+	mailer mailer.Mailer,
+	sessionCloser SessionCloser,
+	mfaPolicy MfaPolicy,
+	rbacPolicy *rbac.Policy,
+	appUrl string,
+	totpIssuer string,
+	resetTTL *int,
+	verifyTTL *int,
+	resendCooldown *int,
+	logger *zap.SugaredLogger) (Service, error) {
 	if userSvc == nil {
 		return nil, errors.New("[user_auth_service] invalid user service")
 	}
 	if jwtSvc == nil {
 		return nil, errors.New("[user_auth_service] invalid jwt service")
 	}
+	if ticketSvc == nil {
+		return nil, errors.New("[user_auth_service] invalid ticket service")
+	}
+	if mailTokenSvc == nil {
+		return nil, errors.New("[user_auth_service] invalid mail token service")
+	}
+	if mfaTokenSvc == nil {
+		return nil, errors.New("[user_auth_service] invalid mfa token service")
+	}
+	if mailer == nil {
+		return nil, errors.New("[user_auth_service] invalid mailer")
+	}
+	if sessionCloser == nil {
+		return nil, errors.New("[user_auth_service] invalid session closer")
+	}
+	if mfaPolicy == nil {
+		return nil, errors.New("[user_auth_service] invalid mfa policy")
+	}
+	if rbacPolicy == nil {
+		return nil, errors.New("[user_auth_service] invalid rbac policy")
+	}
+	if appUrl == "" {
+		return nil, errors.New("[user_auth_service] invalid app url")
+	}
+	if totpIssuer == "" {
+		return nil, errors.New("[user_auth_service] invalid totp issuer")
+	}
+	if resetTTL == nil {
+		return nil, errors.New("[user_auth_service] invalid password reset ttl")
+	}
+	if verifyTTL == nil {
+		return nil, errors.New("[user_auth_service] invalid verification ttl")
+	}
+	if resendCooldown == nil {
+		return nil, errors.New("[user_auth_service] invalid verification cooldown")
+	}
 	if logger == nil {
 		return nil, errors.New("[user_auth_service] invalid logger")
 	}
 
-	return &service{userSvc: userSvc, logger: logger, jwtSvc: jwtSvc}, nil
+	return &service{
+		userSvc:        userSvc,
+		logger:         logger,
+		jwtSvc:         jwtSvc,
+		ticketSvc:      ticketSvc,
+		mailTokenSvc:   mailTokenSvc,
+		mfaTokenSvc:    mfaTokenSvc,
+		mailer:         mailer,
+		sessionCloser:  sessionCloser,
+		mfaPolicy:      mfaPolicy,
+		rbacPolicy:     rbacPolicy,
+		appUrl:         appUrl,
+		totpIssuer:     totpIssuer,
+		resetTTL:       time.Minute * time.Duration(*resetTTL),
+		verifyTTL:      time.Minute * time.Duration(*verifyTTL),
+		resendCooldown: time.Second * time.Duration(*resendCooldown),
+	}, nil
 }
 
+// Registration creates an unverified user and mails the verification link.
+// The account exists even if the mail fails, the link can be sent again.
 func (s *service) Registration(ctx context.Context, dto *RegistrationDTO) (*string, error) {
 	userDto, err := s.userSvc.CreateUser(ctx, dto.Email, dto.Name, dto.Password)
 	if err != nil {
@@ -45,35 +158,158 @@ func (s *service) Registration(ctx context.Context, dto *RegistrationDTO) (*stri
 		return nil, err
 	}
 
+	if err = s.sendVerification(ctx, userDto); err != nil {
+		s.logger.Errorf("failed to send verification of %v: %v", userDto.ID, err)
+	}
+
 	return &userDto.ID, nil
 }
 
-func (s *service) Login(ctx context.Context, dto *LoginDTO) (*string, *string, error) {
+// Login checks the password. Users with a second factor, or who have to
+// enroll one, get an mfa token instead of the token pair.
+func (s *service) Login(ctx context.Context, dto *LoginDTO, device *jwt.Device) (*LoginResponseDTO, error) {
 	userDto, err := s.userSvc.GetUserByEmail(ctx, dto.Email, true)
 	if err != nil {
 		s.logger.Errorf("failed to find user %v", err)
-		return nil, nil, err
+		return nil, err
 	}
 
 	userEntity, err := user.MapToEntity(userDto)
 	if err != nil {
 		s.logger.Errorf("failed to conver dto %v", err)
-		return nil, nil, err
+		return nil, err
 	}
 
 	cp, err := userEntity.CheckPassword(dto.Password)
 	if !cp {
 		s.logger.Errorf("failed to check password %v", err)
-		return nil, nil, err
+		return nil, err
+	}
+
+	device.Label = dto.Device
+
+	return s.CompleteLogin(ctx, userDto, device)
+}
+
+// CompleteLogin logs in a user whose credentials were checked, by the password
+// or by the identity provider. It returns the mfa token when a second factor
+// is needed.
+func (s *service) CompleteLogin(ctx context.Context, userDto *user.DTO, device *jwt.Device) (*LoginResponseDTO, error) {
+	if userDto.Disabled {
+		s.logger.Errorf("user %v is disabled", userDto.ID)
+		return nil, user.ErrUserDisabled
+	}
+
+	var err error
+	mfa := userDto.TotpEnabled
+	if !mfa {
+		if mfa, err = s.mfaEnforced(ctx, userDto); err != nil {
+			return nil, err
+		}
+	}
+	if mfa {
+		token, err := s.mfaTokenSvc.CreateToken(ctx, &MfaPending{UserId: userDto.ID, Device: device})
+		if err != nil {
+			s.logger.Errorf("failed to create mfa token %v", err)
+			return nil, err
+		}
+
+		return &LoginResponseDTO{MfaToken: token, MfaEnroll: !userDto.TotpEnabled}, nil
+	}
+
+	return s.createTokens(ctx, userDto, device)
+}
+
+// Mfa finishes a login with the second factor. For users who have to enroll,
+// the first code confirms the enrollment and the recovery codes are returned
+// with the tokens.
+func (s *service) Mfa(ctx context.Context, dto *MfaDTO) (*LoginResponseDTO, error) {
+	pending, err := s.mfaTokenSvc.Attempt(ctx, dto.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	userDto, err := s.userSvc.GetUserById(ctx, pending.UserId, false)
+	if err != nil {
+		s.logger.Errorf("failed to find user %v", err)
+		return nil, err
+	}
+
+	if userDto.Disabled {
+		s.logger.Errorf("user %v is disabled", userDto.ID)
+		return nil, user.ErrUserDisabled
 	}
 
-	accessToken, refreshToken, err := s.jwtSvc.CreateTokens(ctx, userDto.ID, userDto.Support)
+	var recoveryCodes []string
+	if userDto.TotpEnabled {
+		err = s.userSvc.CheckTotp(ctx, userDto.ID, dto.Code)
+	} else {
+		recoveryCodes, err = s.userSvc.ConfirmTotp(ctx, userDto.ID, dto.Code)
+	}
+	if err != nil {
+		s.logger.Errorf("failed second factor of %v: %v", userDto.ID, err)
+		return nil, err
+	}
+
+	if err = s.mfaTokenSvc.DeleteToken(ctx, dto.Token); err != nil {
+		s.logger.Errorf("failed to delete mfa token %v", err)
+		return nil, err
+	}
+
+	resp, err := s.createTokens(ctx, userDto, pending.Device)
+	if err != nil {
+		return nil, err
+	}
+	resp.RecoveryCodes = recoveryCodes
+
+	return resp, nil
+}
+
+// EnrollMfa starts the enrollment of a user who can't log in without a second
+// factor, the code is then sent to Mfa.
+func (s *service) EnrollMfa(ctx context.Context, dto *MfaEnrollDTO) (*TotpEnrollmentDTO, error) {
+	pending, err := s.mfaTokenSvc.GetToken(ctx, dto.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	userDto, err := s.userSvc.GetUserById(ctx, pending.UserId, false)
+	if err != nil {
+		s.logger.Errorf("failed to find user %v", err)
+		return nil, err
+	}
+
+	if userDto.TotpEnabled {
+		return nil, user.ErrTotpEnabled
+	}
+
+	return s.enrollTotp(ctx, userDto)
+}
+
+func (s *service) createTokens(ctx context.Context, userDto *user.DTO, device *jwt.Device) (*LoginResponseDTO, error) {
+	accessToken, refreshToken, err := s.jwtSvc.CreateTokens(ctx, userDto.ID, userDto.Role(), device)
 	if err != nil {
 		s.logger.Errorf("failed to create jwt token %v", err)
-		return nil, nil, err
+		return nil, err
 	}
 
-	return accessToken, refreshToken, nil
+	return &LoginResponseDTO{AccessToken: *accessToken, RefreshToken: *refreshToken}, nil
+}
+
+// mfaEnforced tells whether the policy makes the user log in with a second
+// factor.
+func (s *service) mfaEnforced(ctx context.Context, userDto *user.DTO) (bool, error) {
+	if !userDto.Support && !userDto.Admin {
+		return false, nil
+	}
+
+	required, err := s.mfaPolicy.SupportMfaRequired(ctx)
+	if err != nil {
+		s.logger.Errorf("failed to get mfa policy %v", err)
+		return false, err
+	}
+
+	return required, nil
 }
 
 func (s *service) Refresh(ctx context.Context, dto *RefreshDTO) (*string, *string, error) {
@@ -89,15 +325,29 @@ func (s *service) Refresh(ctx context.Context, dto *RefreshDTO) (*string, *strin
 		return nil, nil, err
 	}
 
-	err = s.jwtSvc.VerifyToken(ctx, payload, false)
-	if err != nil {
-		s.logger.Errorf("failed to verify token %v", err)
-		return nil, nil, err
+	if userDto.Disabled {
+		s.logger.Errorf("user %v is disabled", userDto.ID)
+		return nil, nil, user.ErrUserDisabled
+	}
+
+	// sessions started before 2FA was required end with their refresh token
+	if !userDto.TotpEnabled {
+		enforced, err := s.mfaEnforced(ctx, userDto)
+		if err != nil {
+			return nil, nil, err
+		}
+		if enforced {
+			return nil, nil, ErrMfaRequired
+		}
 	}
 
-	accessToken, refreshToken, err := s.jwtSvc.CreateTokens(ctx, payload.Id, userDto.Support)
+	accessToken, refreshToken, err := s.jwtSvc.RotateTokens(ctx, payload, userDto.Role())
 	if err != nil {
-		s.logger.Errorf("failed to create jwt token %v", err)
+		s.logger.Errorf("failed to rotate jwt token %v", err)
+		// the session was revoked, whoever holds it mustn't keep chatting
+		if err == jwt.ErrTokenReused {
+			s.sessionCloser.CloseSessions(payload.Id, payload.Sid)
+		}
 		return nil, nil, err
 	}
 
@@ -122,6 +372,7 @@ func (s *service) Logout(ctx context.Context, dto *LogoutDTO) error {
 		s.logger.Errorf("failed to delete tokens %v", err)
 		return err
 	}
+	s.sessionCloser.CloseSessions(payload.Id, payload.Sid)
 
 	return nil
 }
@@ -146,7 +397,7 @@ func (s *service) Check(ctx context.Context, dto *CheckDTO) (*CheckResponseDTO,
 	}
 
 	var isRoom bool
-	if u.RoomName == nil {
+	if u.RoomName == nil && len(u.Rooms) == 0 {
 		isRoom = false
 	} else {
 		isRoom = true
@@ -158,3 +409,307 @@ func (s *service) Check(ctx context.Context, dto *CheckDTO) (*CheckResponseDTO,
 		IsRoom: isRoom,
 	}, nil
 }
+
+// CreateTicket exchanges the access token of the principal for a websocket
+// ticket, so the token itself never ends up in a URL.
+func (s *service) CreateTicket(ctx context.Context, principal *user.Principal) (*TicketResponseDTO, error) {
+	ticket, err := s.ticketSvc.CreateTicket(ctx, principal.Payload)
+	if err != nil {
+		s.logger.Errorf("failed to create ticket %v", err)
+		return nil, err
+	}
+
+	return &TicketResponseDTO{Ticket: ticket, ExpiresIn: int(ticketTTL.Seconds())}, nil
+}
+
+// GetSessions lists the sessions of the user, the principal's own ones when
+// userId is empty. Only admins can see the sessions of others.
+func (s *service) GetSessions(ctx context.Context, principal *user.Principal, userId string) ([]*SessionDTO, error) {
+	userId, err := s.sessionsOwner(principal, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.jwtSvc.GetSessions(ctx, userId)
+	if err != nil {
+		s.logger.Errorf("failed to get sessions %v", err)
+		return nil, err
+	}
+
+	dtos := make([]*SessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		dtos = append(dtos, &SessionDTO{
+			Id:         session.Id,
+			Device:     session.Label,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    userId == principal.User.ID && session.Id == principal.Payload.Sid,
+		})
+	}
+	sort.Slice(dtos, func(i, j int) bool { return dtos[i].LastSeenAt.After(dtos[j].LastSeenAt) })
+
+	return dtos, nil
+}
+
+// RevokeSession logs the session out and closes its websockets.
+func (s *service) RevokeSession(ctx context.Context, principal *user.Principal, userId, sid string) error {
+	userId, err := s.sessionsOwner(principal, userId)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.jwtSvc.GetSessions(ctx, userId)
+	if err != nil {
+		s.logger.Errorf("failed to get sessions %v", err)
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Id == sid {
+			return s.revoke(ctx, userId, sid)
+		}
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions logs out everywhere but the current session. For the
+// sessions of another user every session is revoked.
+func (s *service) RevokeOtherSessions(ctx context.Context, principal *user.Principal, userId string) error {
+	userId, err := s.sessionsOwner(principal, userId)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := s.jwtSvc.GetSessions(ctx, userId)
+	if err != nil {
+		s.logger.Errorf("failed to get sessions %v", err)
+		return err
+	}
+
+	for _, session := range sessions {
+		if userId == principal.User.ID && session.Id == principal.Payload.Sid {
+			continue
+		}
+		if err = s.revoke(ctx, userId, session.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// JWKS returns the public keys of every key tokens can still be signed with.
+func (s *service) JWKS() *jwt.JWKS {
+	return s.jwtSvc.JWKS()
+}
+
+// ForgotPassword mails a reset link to the user. Unknown and disabled
+// accounts are skipped without an error, so the endpoint doesn't reveal which
+// addresses have an account.
+func (s *service) ForgotPassword(ctx context.Context, dto *ForgotPasswordDTO) error {
+	u, err := s.userSvc.GetUserByEmail(ctx, dto.Email, false)
+	if err == user.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		s.logger.Errorf("failed to find user %v", err)
+		return err
+	}
+	if u.Disabled {
+		return nil
+	}
+
+	token, err := s.mailTokenSvc.CreateToken(ctx, PurposeResetPassword, u.ID, s.resetTTL)
+	if err != nil {
+		s.logger.Errorf("failed to create reset token %v", err)
+		return err
+	}
+
+	err = s.mailer.Send(ctx, &mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %v,\n\n"+
+			"somebody asked to reset the password of your support chat account. Open the link below to choose a new\n"+
+			"password, it is valid for %v minutes:\n\n"+
+			"%v/reset-password?token=%v\n\n"+
+			"If it wasn't you, ignore this mail and your password stays the same.\n",
+			u.Name, s.resetTTL.Minutes(), s.appUrl, url.QueryEscape(token)),
+	})
+	if err != nil {
+		s.logger.Errorf("failed to send reset mail %v", err)
+		return ErrFailedSendMail
+	}
+
+	return nil
+}
+
+// ResetPassword redeems the reset token and ends every session of the user,
+// whoever knew the old password is logged out.
+func (s *service) ResetPassword(ctx context.Context, dto *ResetPasswordDTO) error {
+	userId, err := s.mailTokenSvc.ConsumeToken(ctx, PurposeResetPassword, dto.Token)
+	if err != nil {
+		return err
+	}
+
+	if err = s.userSvc.SetPassword(ctx, userId, dto.Password); err != nil {
+		s.logger.Errorf("failed to set password %v", err)
+		return err
+	}
+
+	return s.revokeAll(ctx, userId)
+}
+
+func (s *service) Verify(ctx context.Context, dto *VerifyDTO) error {
+	userId, err := s.mailTokenSvc.ConsumeToken(ctx, PurposeVerifyEmail, dto.Token)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.userSvc.SetVerified(ctx, userId, true); err != nil {
+		s.logger.Errorf("failed to verify user %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// ResendVerification mails a new verification link, at most once per
+// cooldown and address. Like ForgotPassword it doesn't reveal which addresses
+// have an account.
+func (s *service) ResendVerification(ctx context.Context, dto *ResendVerificationDTO) error {
+	if err := s.mailTokenSvc.Throttle(ctx, PurposeVerifyEmail, dto.Email, s.resendCooldown); err != nil {
+		return err
+	}
+
+	u, err := s.userSvc.GetUserByEmail(ctx, dto.Email, false)
+	if err == user.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		s.logger.Errorf("failed to find user %v", err)
+		return err
+	}
+	if u.Verified || u.Disabled {
+		return nil
+	}
+
+	return s.sendVerification(ctx, u)
+}
+
+// EnrollTotp starts the enrollment of the principal, ConfirmTotp enables it.
+func (s *service) EnrollTotp(ctx context.Context, principal *user.Principal) (*TotpEnrollmentDTO, error) {
+	return s.enrollTotp(ctx, &principal.User)
+}
+
+func (s *service) ConfirmTotp(ctx context.Context, principal *user.Principal, dto *TotpCodeDTO) (*RecoveryCodesDTO, error) {
+	codes, err := s.userSvc.ConfirmTotp(ctx, principal.User.ID, dto.Code)
+	if err != nil {
+		s.logger.Errorf("failed to confirm totp %v", err)
+		return nil, err
+	}
+
+	return &RecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+// DisableTotp removes the second factor after checking a code of it. Users
+// the policy requires it from can't remove it.
+func (s *service) DisableTotp(ctx context.Context, principal *user.Principal, dto *TotpCodeDTO) error {
+	enforced, err := s.mfaEnforced(ctx, &principal.User)
+	if err != nil {
+		return err
+	}
+	if enforced {
+		return ErrMfaRequired
+	}
+
+	if err = s.userSvc.CheckTotp(ctx, principal.User.ID, dto.Code); err != nil {
+		s.logger.Errorf("failed to check totp %v", err)
+		return err
+	}
+
+	if _, err = s.userSvc.DisableTotp(ctx, principal.User.ID); err != nil {
+		s.logger.Errorf("failed to disable totp %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) enrollTotp(ctx context.Context, userDto *user.DTO) (*TotpEnrollmentDTO, error) {
+	secret, err := s.userSvc.StartTotp(ctx, userDto.ID)
+	if err != nil {
+		s.logger.Errorf("failed to start totp %v", err)
+		return nil, err
+	}
+
+	return &TotpEnrollmentDTO{Secret: secret, Uri: totp.URI(s.totpIssuer, userDto.Email, secret)}, nil
+}
+
+func (s *service) sendVerification(ctx context.Context, u *user.DTO) error {
+	token, err := s.mailTokenSvc.CreateToken(ctx, PurposeVerifyEmail, u.ID, s.verifyTTL)
+	if err != nil {
+		s.logger.Errorf("failed to create verification token %v", err)
+		return err
+	}
+
+	err = s.mailer.Send(ctx, &mailer.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %v,\n\n"+
+			"welcome to the support chat. Open the link below to verify your email address, it is valid for %v\n"+
+			"hours:\n\n"+
+			"%v/verify?token=%v\n",
+			u.Name, s.verifyTTL.Hours(), s.appUrl, url.QueryEscape(token)),
+	})
+	if err != nil {
+		s.logger.Errorf("failed to send verification mail %v", err)
+		return ErrFailedSendMail
+	}
+
+	return nil
+}
+
+func (s *service) sessionsOwner(principal *user.Principal, userId string) (string, error) {
+	if userId == "" || userId == principal.User.ID {
+		return principal.User.ID, nil
+	}
+	if !s.rbacPolicy.Can(principal.User.Role(), rbac.UsersAdmin) {
+		return "", ErrNotAllowed
+	}
+
+	return userId, nil
+}
+
+func (s *service) revoke(ctx context.Context, userId, sid string) error {
+	if err := s.jwtSvc.DeleteTokens(ctx, &jwt.Payload{Id: userId, Sid: sid}); err != nil {
+		s.logger.Errorf("failed to revoke session %v of %v: %v", sid, userId, err)
+		return err
+	}
+	s.sessionCloser.CloseSessions(userId, sid)
+
+	return nil
+}
+
+func (s *service) revokeAll(ctx context.Context, userId string) error {
+	sessions, err := s.jwtSvc.GetSessions(ctx, userId)
+	if err != nil {
+		s.logger.Errorf("failed to get sessions %v", err)
+		return err
+	}
+
+	if err = s.jwtSvc.DeleteAllTokens(ctx, userId); err != nil {
+		s.logger.Errorf("failed to revoke sessions of %v: %v", userId, err)
+		return err
+	}
+
+	sids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sids = append(sids, session.Id)
+	}
+	s.sessionCloser.CloseSessions(userId, sids...)
+
+	return nil
+}
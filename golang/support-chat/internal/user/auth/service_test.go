@@ -2,12 +2,17 @@ package auth_test
 
 import (
 	"context"
+	"errors"
 	"support-chat/internal/user"
 	"support-chat/internal/user/auth"
+	mock_auth "support-chat/internal/user/auth/mocks"
 	mock_user "support-chat/internal/user/mocks"
 	"support-chat/pkg/jwt"
 	mock_jwt "support-chat/pkg/jwt/mocks"
 	"support-chat/pkg/logger"
+	"support-chat/pkg/mailer"
+	mock_mailer "support-chat/pkg/mailer/mocks"
+	"support-chat/pkg/rbac"
 
 	gjwt "github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
@@ -15,6 +20,15 @@ import (
 	"go.uber.org/zap"
 
 	"testing"
+	"time"
+)
+
+var (
+	resetTTL       = 30 // minutes
+	verifyTTL      = 60 // minutes
+	resendCooldown = 60 // seconds
+
+	rbacPolicy, _ = rbac.NewPolicy(rbac.DefaultRoles)
 )
 
 func TestNewService(t *testing.T) {
@@ -22,27 +36,63 @@ func TestNewService(t *testing.T) {
 	defer controller.Finish()
 
 	tests := []struct {
-		name    string
-		userSvc user.Service
-		logger  *zap.SugaredLogger
-		jwtSvc  jwt.Service
-		expect  func(*testing.T, auth.Service, error)
+		name           string
+		userSvc        user.Service
+		jwtSvc         jwt.Service
+		ticketSvc      auth.TicketService
+		mailTokenSvc   auth.MailTokenService
+		mfaTokenSvc    auth.MfaTokenService
+		mailer         mailer.Mailer
+		closer         auth.SessionCloser
+		mfaPolicy      auth.MfaPolicy
+		rbacPolicy     *rbac.Policy
+		appUrl         string
+		totpIssuer     string
+		resetTTL       *int
+		verifyTTL      *int
+		resendCooldown *int
+		logger         *zap.SugaredLogger
+		expect         func(*testing.T, auth.Service, error)
 	}{
 		{
-			name:    "should return service",
-			userSvc: mock_user.NewMockService(controller),
-			logger:  &zap.SugaredLogger{},
-			jwtSvc:  mock_jwt.NewMockService(controller),
+			name:           "should return service",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
 			expect: func(t *testing.T, service auth.Service, err error) {
 				assert.NotNil(t, service)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:    "should return invalid user service",
-			userSvc: nil,
-			logger:  &zap.SugaredLogger{},
-			jwtSvc:  mock_jwt.NewMockService(controller),
+			name:           "should return invalid user service",
+			userSvc:        nil,
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
 			expect: func(t *testing.T, service auth.Service, err error) {
 				assert.Nil(t, service)
 				assert.NotNil(t, err)
@@ -50,10 +100,22 @@ func TestNewService(t *testing.T) {
 			},
 		},
 		{
-			name:    "should return invalid jwt service",
-			userSvc: mock_user.NewMockService(controller),
-			jwtSvc:  nil,
-			logger:  &zap.SugaredLogger{},
+			name:           "should return invalid jwt service",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         nil,
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
 			expect: func(t *testing.T, service auth.Service, err error) {
 				assert.Nil(t, service)
 				assert.NotNil(t, err)
@@ -61,10 +123,298 @@ func TestNewService(t *testing.T) {
 			},
 		},
 		{
-			name:    "should return invalid logger",
-			userSvc: mock_user.NewMockService(controller),
-			jwtSvc:  mock_jwt.NewMockService(controller),
-			logger:  nil,
+			name:           "should return invalid ticket service",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      nil,
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid ticket service")
+			},
+		},
+		{
+			name:           "should return invalid mail token service",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   nil,
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid mail token service")
+			},
+		},
+		{
+			name:           "should return invalid mailer",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         nil,
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid mailer")
+			},
+		},
+		{
+			name:           "should return invalid session closer",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         nil,
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid session closer")
+			},
+		},
+		{
+			name:           "should return invalid app url",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid app url")
+			},
+		},
+		{
+			name:           "should return invalid password reset ttl",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       nil,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid password reset ttl")
+			},
+		},
+		{
+			name:           "should return invalid verification ttl",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      nil,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid verification ttl")
+			},
+		},
+		{
+			name:           "should return invalid verification cooldown",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: nil,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid verification cooldown")
+			},
+		},
+		{
+			name:           "should return invalid mfa token service",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    nil,
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid mfa token service")
+			},
+		},
+		{
+			name:           "should return invalid mfa policy",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      nil,
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid mfa policy")
+			},
+		},
+		{
+			name:           "should return invalid rbac policy",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     nil,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid rbac policy")
+			},
+		},
+		{
+			name:           "should return invalid totp issuer",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         &zap.SugaredLogger{},
+			expect: func(t *testing.T, service auth.Service, err error) {
+				assert.Nil(t, service)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_auth_service] invalid totp issuer")
+			},
+		},
+		{
+			name:           "should return invalid logger",
+			userSvc:        mock_user.NewMockService(controller),
+			jwtSvc:         mock_jwt.NewMockService(controller),
+			ticketSvc:      mock_auth.NewMockTicketService(controller),
+			mailTokenSvc:   mock_auth.NewMockMailTokenService(controller),
+			mfaTokenSvc:    mock_auth.NewMockMfaTokenService(controller),
+			mailer:         mock_mailer.NewMockMailer(controller),
+			closer:         mock_auth.NewMockSessionCloser(controller),
+			mfaPolicy:      mock_auth.NewMockMfaPolicy(controller),
+			rbacPolicy:     rbacPolicy,
+			appUrl:         "http://localhost",
+			totpIssuer:     "Support Chat",
+			resetTTL:       &resetTTL,
+			verifyTTL:      &verifyTTL,
+			resendCooldown: &resendCooldown,
+			logger:         nil,
 			expect: func(t *testing.T, service auth.Service, err error) {
 				assert.Nil(t, service)
 				assert.NotNil(t, err)
@@ -75,7 +425,7 @@ func TestNewService(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := auth.NewService(tc.userSvc, tc.jwtSvc, tc.logger)
+			svc, err := auth.NewService(tc.userSvc, tc.jwtSvc, tc.ticketSvc, tc.mailTokenSvc, tc.mfaTokenSvc, tc.mailer, tc.closer, tc.mfaPolicy, tc.rbacPolicy, tc.appUrl, tc.totpIssuer, tc.resetTTL, tc.verifyTTL, tc.resendCooldown, tc.logger)
 			tc.expect(t, svc, err)
 		})
 	}
@@ -87,13 +437,15 @@ func TestService_Registration(t *testing.T) {
 
 	mockUserSvc := mock_user.NewMockService(controller)
 	mockJwt := mock_jwt.NewMockService(controller)
+	mockMailToken := mock_auth.NewMockMailTokenService(controller)
+	mockMailer := mock_mailer.NewMockMailer(controller)
 
 	salt := 10
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := auth.NewService(mockUserSvc, mockJwt, zapLogger)
+	service, _ := auth.NewService(mockUserSvc, mockJwt, mock_auth.NewMockTicketService(controller), mockMailToken, mock_auth.NewMockMfaTokenService(controller), mockMailer, mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
 
 	userEntity, _ := user.NewUser("email", "name", "password", &salt)
 	userDto := user.MapToDTO(userEntity)
@@ -115,6 +467,12 @@ func TestService_Registration(t *testing.T) {
 			},
 			setup: func(ctx context.Context, dto *auth.RegistrationDTO) {
 				mockUserSvc.EXPECT().CreateUser(ctx, dto.Email, dto.Name, dto.Password).Return(userDto, nil)
+				mockMailToken.EXPECT().CreateToken(ctx, auth.PurposeVerifyEmail, userDto.ID, time.Hour).Return("token", nil)
+				mockMailer.EXPECT().Send(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg *mailer.Message) error {
+					assert.Equal(t, dto.Email, msg.To)
+					assert.Contains(t, msg.Body, "http://localhost/verify?token=token")
+					return nil
+				})
 			},
 			expect: func(t *testing.T, s *string, err error) {
 				assert.NotNil(t, s)
@@ -122,6 +480,24 @@ func TestService_Registration(t *testing.T) {
 				assert.Equal(t, userEntity.ID.Hex(), *s)
 			},
 		},
+		{
+			name: "should register when verification mail fails",
+			ctx:  context.Background(),
+			dto: &auth.RegistrationDTO{
+				Email:    "email",
+				Name:     "name",
+				Password: "password",
+			},
+			setup: func(ctx context.Context, dto *auth.RegistrationDTO) {
+				mockUserSvc.EXPECT().CreateUser(ctx, dto.Email, dto.Name, dto.Password).Return(userDto, nil)
+				mockMailToken.EXPECT().CreateToken(ctx, auth.PurposeVerifyEmail, userDto.ID, time.Hour).Return("token", nil)
+				mockMailer.EXPECT().Send(ctx, gomock.Any()).Return(errors.New("connection refused"))
+			},
+			expect: func(t *testing.T, s *string, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, userEntity.ID.Hex(), *s)
+			},
+		},
 		{
 			name: "should return failed to create user",
 			ctx:  context.Background(),
@@ -156,16 +532,25 @@ func TestService_Login(t *testing.T) {
 
 	mockUserSvc := mock_user.NewMockService(controller)
 	mockJwt := mock_jwt.NewMockService(controller)
+	mockMfaToken := mock_auth.NewMockMfaTokenService(controller)
+	mockPolicy := mock_auth.NewMockMfaPolicy(controller)
 
 	salt := 10
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := auth.NewService(mockUserSvc, mockJwt, zapLogger)
+	service, _ := auth.NewService(mockUserSvc, mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mockMfaToken, mock_mailer.NewMockMailer(controller), mock_auth.NewMockSessionCloser(controller), mockPolicy, rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
 
 	userEntity, _ := user.NewUser("email", "name", "password", &salt)
+	userEntity.Verified = true
 	userDto := user.MapToDTO(userEntity)
+	disabledDto := user.MapToDTO(userEntity)
+	disabledDto.Disabled = true
+	totpDto := user.MapToDTO(userEntity)
+	totpDto.TotpEnabled = true
+	supportDto := user.MapToDTO(userEntity)
+	supportDto.Support = true
 
 	tokenAccess := "tokenAccess"
 	tokenRefresh := "tokenRefresh"
@@ -177,7 +562,7 @@ func TestService_Login(t *testing.T) {
 		dto          *auth.LoginDTO
 		withPassword bool
 		setup        func(context.Context, *auth.LoginDTO, bool)
-		expect       func(*testing.T, *string, *string, error)
+		expect       func(*testing.T, *auth.LoginResponseDTO, error)
 	}{
 		{
 			name: "should return jwt token",
@@ -189,14 +574,51 @@ func TestService_Login(t *testing.T) {
 			withPassword: true,
 			setup: func(ctx context.Context, dto *auth.LoginDTO, withPassword bool) {
 				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, withPassword).Return(userDto, nil)
-				mockJwt.EXPECT().CreateTokens(ctx, userDto.ID, false).Return(&tokenAccess, &tokenRefresh, nil)
+				mockJwt.EXPECT().CreateTokens(ctx, userDto.ID, jwt.RoleUser, &jwt.Device{}).Return(&tokenAccess, &tokenRefresh, nil)
 			},
-			expect: func(t *testing.T, a *string, r *string, err error) {
-				assert.NotNil(t, a)
-				assert.NotNil(t, r)
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
 				assert.Nil(t, err)
-				assert.Equal(t, *a, tokenAccess)
-				assert.Equal(t, *r, tokenRefresh)
+				assert.Equal(t, tokenAccess, resp.AccessToken)
+				assert.Equal(t, tokenRefresh, resp.RefreshToken)
+				assert.Empty(t, resp.MfaToken)
+			},
+		},
+		{
+			name: "should return mfa token of user with totp",
+			ctx:  context.Background(),
+			dto: &auth.LoginDTO{
+				Email:    "email",
+				Password: "password",
+			},
+			withPassword: true,
+			setup: func(ctx context.Context, dto *auth.LoginDTO, withPassword bool) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, withPassword).Return(totpDto, nil)
+				mockMfaToken.EXPECT().CreateToken(ctx, &auth.MfaPending{UserId: totpDto.ID, Device: &jwt.Device{}}).Return("mfa", nil)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "mfa", resp.MfaToken)
+				assert.False(t, resp.MfaEnroll)
+				assert.Empty(t, resp.AccessToken)
+			},
+		},
+		{
+			name: "should require enrollment of support user",
+			ctx:  context.Background(),
+			dto: &auth.LoginDTO{
+				Email:    "email",
+				Password: "password",
+			},
+			withPassword: true,
+			setup: func(ctx context.Context, dto *auth.LoginDTO, withPassword bool) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, withPassword).Return(supportDto, nil)
+				mockPolicy.EXPECT().SupportMfaRequired(ctx).Return(true, nil)
+				mockMfaToken.EXPECT().CreateToken(ctx, gomock.Any()).Return("mfa", nil)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "mfa", resp.MfaToken)
+				assert.True(t, resp.MfaEnroll)
 			},
 		},
 		{
@@ -210,13 +632,28 @@ func TestService_Login(t *testing.T) {
 			setup: func(ctx context.Context, dto *auth.LoginDTO, withPassword bool) {
 				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, withPassword).Return(nil, user.ErrNotFound)
 			},
-			expect: func(t *testing.T, a *string, r *string, err error) {
-				assert.Empty(t, a)
-				assert.Empty(t, r)
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, resp)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, user.ErrNotFound.Error())
 			},
 		},
+		{
+			name: "should return user disabled",
+			ctx:  context.Background(),
+			dto: &auth.LoginDTO{
+				Email:    "email",
+				Password: "password",
+			},
+			withPassword: true,
+			setup: func(ctx context.Context, dto *auth.LoginDTO, withPassword bool) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, withPassword).Return(disabledDto, nil)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, resp)
+				assert.EqualError(t, err, user.ErrUserDisabled.Error())
+			},
+		},
 		{
 			name: "should return failed to create jwt token",
 			ctx:  context.Background(),
@@ -227,11 +664,10 @@ func TestService_Login(t *testing.T) {
 			withPassword: true,
 			setup: func(ctx context.Context, dto *auth.LoginDTO, withPassword bool) {
 				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, withPassword).Return(userDto, nil)
-				mockJwt.EXPECT().CreateTokens(ctx, userDto.ID, false).Return(&emptyStr, &emptyStr, jwt.ErrFailedCreateTokens)
+				mockJwt.EXPECT().CreateTokens(ctx, userDto.ID, jwt.RoleUser, &jwt.Device{}).Return(&emptyStr, &emptyStr, jwt.ErrFailedCreateTokens)
 			},
-			expect: func(t *testing.T, a *string, r *string, err error) {
-				assert.Empty(t, a)
-				assert.Empty(t, r)
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, resp)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, jwt.ErrFailedCreateTokens.Error())
 			},
@@ -241,8 +677,169 @@ func TestService_Login(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.setup(tc.ctx, tc.dto, tc.withPassword)
-			a, r, err := service.Login(tc.ctx, tc.dto)
-			tc.expect(t, a, r, err)
+			resp, err := service.Login(tc.ctx, tc.dto, &jwt.Device{})
+			tc.expect(t, resp, err)
+		})
+	}
+}
+
+func TestService_Mfa(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockMfaToken := mock_auth.NewMockMfaTokenService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mockUserSvc, mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mockMfaToken, mock_mailer.NewMockMailer(controller), mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	pending := &auth.MfaPending{UserId: "agent", Device: &jwt.Device{Label: "laptop"}}
+	enrolled := &user.DTO{ID: "agent", Support: true, TotpEnabled: true}
+	enrolling := &user.DTO{ID: "agent", Support: true}
+	dto := &auth.MfaDTO{Token: "mfa", Code: "123456"}
+	tokenAccess := "tokenAccess"
+	tokenRefresh := "tokenRefresh"
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, *auth.LoginResponseDTO, error)
+	}{
+		{
+			name: "should return tokens for valid code",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMfaToken.EXPECT().Attempt(ctx, dto.Token).Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).Return(enrolled, nil)
+				mockUserSvc.EXPECT().CheckTotp(ctx, "agent", dto.Code).Return(nil)
+				mockMfaToken.EXPECT().DeleteToken(ctx, dto.Token).Return(nil)
+				mockJwt.EXPECT().CreateTokens(ctx, "agent", jwt.RoleSupport, pending.Device).Return(&tokenAccess, &tokenRefresh, nil)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, tokenAccess, resp.AccessToken)
+				assert.Empty(t, resp.RecoveryCodes)
+			},
+		},
+		{
+			name: "should confirm enrollment and return recovery codes",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMfaToken.EXPECT().Attempt(ctx, dto.Token).Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).Return(enrolling, nil)
+				mockUserSvc.EXPECT().ConfirmTotp(ctx, "agent", dto.Code).Return([]string{"abcde-fghij"}, nil)
+				mockMfaToken.EXPECT().DeleteToken(ctx, dto.Token).Return(nil)
+				mockJwt.EXPECT().CreateTokens(ctx, "agent", jwt.RoleSupport, pending.Device).Return(&tokenAccess, &tokenRefresh, nil)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, tokenRefresh, resp.RefreshToken)
+				assert.Equal(t, []string{"abcde-fghij"}, resp.RecoveryCodes)
+			},
+		},
+		{
+			name: "should return invalid code",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMfaToken.EXPECT().Attempt(ctx, dto.Token).Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, "agent", false).Return(enrolled, nil)
+				mockUserSvc.EXPECT().CheckTotp(ctx, "agent", dto.Code).Return(user.ErrInvalidTotp)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, resp)
+				assert.Equal(t, user.ErrInvalidTotp, err)
+			},
+		},
+		{
+			name: "should return invalid mfa token",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMfaToken.EXPECT().Attempt(ctx, dto.Token).Return(nil, auth.ErrInvalidMfaToken)
+			},
+			expect: func(t *testing.T, resp *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, resp)
+				assert.Equal(t, auth.ErrInvalidMfaToken, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			resp, err := service.Mfa(tc.ctx, dto)
+			tc.expect(t, resp, err)
+		})
+	}
+}
+
+func TestService_DisableTotp(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockPolicy := mock_auth.NewMockMfaPolicy(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mockUserSvc, mock_jwt.NewMockService(controller), mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mock_auth.NewMockSessionCloser(controller), mockPolicy, rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	customer := &user.Principal{User: user.DTO{ID: "user", TotpEnabled: true}}
+	agent := &user.Principal{User: user.DTO{ID: "agent", Support: true, TotpEnabled: true}}
+	dto := &auth.TotpCodeDTO{Code: "123456"}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		principal *user.Principal
+		setup     func(context.Context)
+		expect    func(*testing.T, error)
+	}{
+		{
+			name:      "should disable totp",
+			ctx:       context.Background(),
+			principal: customer,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().CheckTotp(ctx, "user", dto.Code).Return(nil)
+				mockUserSvc.EXPECT().DisableTotp(ctx, "user").Return(&user.DTO{ID: "user"}, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:      "should return invalid code",
+			ctx:       context.Background(),
+			principal: customer,
+			setup: func(ctx context.Context) {
+				mockUserSvc.EXPECT().CheckTotp(ctx, "user", dto.Code).Return(user.ErrInvalidTotp)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrInvalidTotp, err)
+			},
+		},
+		{
+			name:      "should keep totp required by policy",
+			ctx:       context.Background(),
+			principal: agent,
+			setup: func(ctx context.Context) {
+				mockPolicy.EXPECT().SupportMfaRequired(ctx).Return(true, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrMfaRequired, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.DisableTotp(tc.ctx, tc.principal, dto)
+			tc.expect(t, err)
 		})
 	}
 }
@@ -253,16 +850,19 @@ func TestService_Refresh(t *testing.T) {
 
 	mockUserSvc := mock_user.NewMockService(controller)
 	mockJwt := mock_jwt.NewMockService(controller)
+	mockPolicy := mock_auth.NewMockMfaPolicy(controller)
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := auth.NewService(mockUserSvc, mockJwt, zapLogger)
+	mockCloser := mock_auth.NewMockSessionCloser(controller)
+	service, _ := auth.NewService(mockUserSvc, mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mockCloser, mockPolicy, rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
 
 	payload := jwt.Payload{
 		Id:             "id",
 		Role:           "role",
 		Uid:            "uid",
+		Sid:            "sid",
 		StandardClaims: gjwt.StandardClaims{},
 	}
 	tokenAccess := "tokenAccess"
@@ -290,8 +890,7 @@ func TestService_Refresh(t *testing.T) {
 			setup: func(ctx context.Context, dto *auth.RefreshDTO) {
 				mockJwt.EXPECT().ParseToken(dto.Token, false).Return(&payload, nil)
 				mockUserSvc.EXPECT().GetUserById(ctx, payload.Id, false).Return(userDto, nil)
-				mockJwt.EXPECT().VerifyToken(ctx, &payload, false).Return(nil)
-				mockJwt.EXPECT().CreateTokens(ctx, payload.Id, userDto.Support).Return(&tokenAccess, &tokenRefresh, nil)
+				mockJwt.EXPECT().RotateTokens(ctx, &payload, userDto.Role()).Return(&tokenAccess, &tokenRefresh, nil)
 			},
 			expect: func(t *testing.T, a *string, r *string, err error) {
 				assert.NotNil(t, a)
@@ -301,6 +900,22 @@ func TestService_Refresh(t *testing.T) {
 				assert.Equal(t, *r, tokenRefresh)
 			},
 		},
+		{
+			name: "should end agent session without required totp",
+			ctx:  context.Background(),
+			dto: &auth.RefreshDTO{
+				Token: "token",
+			},
+			setup: func(ctx context.Context, dto *auth.RefreshDTO) {
+				mockJwt.EXPECT().ParseToken(dto.Token, false).Return(&payload, nil)
+				mockUserSvc.EXPECT().GetUserById(ctx, payload.Id, false).Return(&user.DTO{ID: payload.Id, Support: true}, nil)
+				mockPolicy.EXPECT().SupportMfaRequired(ctx).Return(true, nil)
+			},
+			expect: func(t *testing.T, a *string, r *string, err error) {
+				assert.Nil(t, a)
+				assert.Equal(t, auth.ErrMfaRequired, err)
+			},
+		},
 		{
 			name: "should return failed parse token",
 			ctx:  context.Background(),
@@ -335,7 +950,7 @@ func TestService_Refresh(t *testing.T) {
 			},
 		},
 		{
-			name: "should return failed to verify token",
+			name: "should return refresh token reused",
 			ctx:  context.Background(),
 			dto: &auth.RefreshDTO{
 				Token: "token",
@@ -343,13 +958,14 @@ func TestService_Refresh(t *testing.T) {
 			setup: func(ctx context.Context, dto *auth.RefreshDTO) {
 				mockJwt.EXPECT().ParseToken(dto.Token, false).Return(&payload, nil)
 				mockUserSvc.EXPECT().GetUserById(ctx, payload.Id, false).Return(userDto, nil)
-				mockJwt.EXPECT().VerifyToken(ctx, &payload, false).Return(jwt.ErrNotFound)
+				mockJwt.EXPECT().RotateTokens(ctx, &payload, userDto.Role()).Return(nil, nil, jwt.ErrTokenReused)
+				mockCloser.EXPECT().CloseSessions(payload.Id, payload.Sid)
 			},
 			expect: func(t *testing.T, a *string, r *string, err error) {
 				assert.Empty(t, a)
 				assert.Empty(t, r)
 				assert.NotNil(t, err)
-				assert.EqualError(t, err, jwt.ErrNotFound.Error())
+				assert.EqualError(t, err, jwt.ErrTokenReused.Error())
 			},
 		},
 		{
@@ -361,8 +977,7 @@ func TestService_Refresh(t *testing.T) {
 			setup: func(ctx context.Context, dto *auth.RefreshDTO) {
 				mockJwt.EXPECT().ParseToken(dto.Token, false).Return(&payload, nil)
 				mockUserSvc.EXPECT().GetUserById(ctx, payload.Id, false).Return(userDto, nil)
-				mockJwt.EXPECT().VerifyToken(ctx, &payload, false).Return(nil)
-				mockJwt.EXPECT().CreateTokens(ctx, payload.Id, userDto.Support).Return(&emptyStr, &emptyStr, jwt.ErrFailedCreateTokens)
+				mockJwt.EXPECT().RotateTokens(ctx, &payload, userDto.Role()).Return(&emptyStr, &emptyStr, jwt.ErrFailedCreateTokens)
 			},
 			expect: func(t *testing.T, a *string, r *string, err error) {
 				assert.Empty(t, a)
@@ -388,16 +1003,18 @@ func TestService_Logout(t *testing.T) {
 
 	mockUserSvc := mock_user.NewMockService(controller)
 	mockJwt := mock_jwt.NewMockService(controller)
+	mockCloser := mock_auth.NewMockSessionCloser(controller)
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := auth.NewService(mockUserSvc, mockJwt, zapLogger)
+	service, _ := auth.NewService(mockUserSvc, mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mockCloser, mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
 
 	payload := jwt.Payload{
 		Id:             "id",
 		Role:           "role",
 		Uid:            "uid",
+		Sid:            "sid",
 		StandardClaims: gjwt.StandardClaims{},
 	}
 
@@ -418,6 +1035,7 @@ func TestService_Logout(t *testing.T) {
 				mockJwt.EXPECT().ParseToken(dto.Token, true).Return(&payload, nil)
 				mockJwt.EXPECT().VerifyToken(ctx, &payload, true).Return(nil)
 				mockJwt.EXPECT().DeleteTokens(ctx, &payload).Return(nil)
+				mockCloser.EXPECT().CloseSessions(payload.Id, payload.Sid)
 			},
 			expect: func(t *testing.T, err error) {
 				assert.Nil(t, err)
@@ -478,3 +1096,585 @@ func TestService_Logout(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CreateTicket(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockTicket := mock_auth.NewMockTicketService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mock_user.NewMockService(controller), mock_jwt.NewMockService(controller), mockTicket, mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	principal := &user.Principal{User: user.DTO{ID: "user"}, Payload: &jwt.Payload{Id: "user", Uid: "uid"}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, *auth.TicketResponseDTO, error)
+	}{
+		{
+			name: "should return ticket",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockTicket.EXPECT().CreateTicket(ctx, principal.Payload).Return("ticket", nil)
+			},
+			expect: func(t *testing.T, dto *auth.TicketResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, &auth.TicketResponseDTO{Ticket: "ticket", ExpiresIn: 30}, dto)
+			},
+		},
+		{
+			name: "should return failed create ticket",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockTicket.EXPECT().CreateTicket(ctx, principal.Payload).Return("", auth.ErrFailedCreateTicket)
+			},
+			expect: func(t *testing.T, dto *auth.TicketResponseDTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, auth.ErrFailedCreateTicket, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.CreateTicket(tc.ctx, principal)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_GetSessions(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockJwt := mock_jwt.NewMockService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mock_user.NewMockService(controller), mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	principal := &user.Principal{User: user.DTO{ID: "user"}, Payload: &jwt.Payload{Id: "user", Sid: "current"}}
+	admin := &user.Principal{User: user.DTO{ID: "admin", Admin: true}, Payload: &jwt.Payload{Id: "admin", Sid: "current"}}
+	now := time.Now()
+	sessions := []*jwt.Session{
+		{Id: "current", Device: jwt.Device{Label: "desktop"}, LastSeenAt: now.Add(-time.Hour)},
+		{Id: "other", Device: jwt.Device{Label: "phone"}, LastSeenAt: now},
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		principal *user.Principal
+		userId    string
+		setup     func(context.Context)
+		expect    func(*testing.T, []*auth.SessionDTO, error)
+	}{
+		{
+			name:      "should return own sessions, latest first",
+			ctx:       context.Background(),
+			principal: principal,
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+			},
+			expect: func(t *testing.T, dtos []*auth.SessionDTO, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, dtos, 2)
+				assert.Equal(t, "other", dtos[0].Id)
+				assert.False(t, dtos[0].Current)
+				assert.Equal(t, "current", dtos[1].Id)
+				assert.Equal(t, "desktop", dtos[1].Device)
+				assert.True(t, dtos[1].Current)
+			},
+		},
+		{
+			name:      "should return sessions of another user to admin",
+			ctx:       context.Background(),
+			principal: admin,
+			userId:    "user",
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+			},
+			expect: func(t *testing.T, dtos []*auth.SessionDTO, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, dtos, 2)
+				assert.False(t, dtos[0].Current)
+				assert.False(t, dtos[1].Current)
+			},
+		},
+		{
+			name:      "should return not allowed",
+			ctx:       context.Background(),
+			principal: principal,
+			userId:    "admin",
+			setup:     func(ctx context.Context) {},
+			expect: func(t *testing.T, dtos []*auth.SessionDTO, err error) {
+				assert.Nil(t, dtos)
+				assert.Equal(t, auth.ErrNotAllowed, err)
+			},
+		},
+		{
+			name:      "should return failed get sessions",
+			ctx:       context.Background(),
+			principal: principal,
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(nil, jwt.ErrFailedGetSessions)
+			},
+			expect: func(t *testing.T, dtos []*auth.SessionDTO, err error) {
+				assert.Nil(t, dtos)
+				assert.Equal(t, jwt.ErrFailedGetSessions, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dtos, err := service.GetSessions(tc.ctx, tc.principal, tc.userId)
+			tc.expect(t, dtos, err)
+		})
+	}
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockCloser := mock_auth.NewMockSessionCloser(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mock_user.NewMockService(controller), mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mockCloser, mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	principal := &user.Principal{User: user.DTO{ID: "user"}, Payload: &jwt.Payload{Id: "user", Sid: "current"}}
+	sessions := []*jwt.Session{{Id: "current"}, {Id: "other"}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		sid    string
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should revoke session and close its connections",
+			ctx:  context.Background(),
+			sid:  "other",
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+				mockJwt.EXPECT().DeleteTokens(ctx, &jwt.Payload{Id: "user", Sid: "other"}).Return(nil)
+				mockCloser.EXPECT().CloseSessions("user", "other")
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return session not found",
+			ctx:  context.Background(),
+			sid:  "unknown",
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrSessionNotFound, err)
+			},
+		},
+		{
+			name: "should return failed delete tokens",
+			ctx:  context.Background(),
+			sid:  "other",
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+				mockJwt.EXPECT().DeleteTokens(ctx, &jwt.Payload{Id: "user", Sid: "other"}).Return(jwt.ErrFailedDeleteToken)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, jwt.ErrFailedDeleteToken, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.RevokeSession(tc.ctx, principal, "", tc.sid)
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_RevokeOtherSessions(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockCloser := mock_auth.NewMockSessionCloser(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mock_user.NewMockService(controller), mockJwt, mock_auth.NewMockTicketService(controller), mock_auth.NewMockMailTokenService(controller), mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mockCloser, mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	principal := &user.Principal{User: user.DTO{ID: "user"}, Payload: &jwt.Payload{Id: "user", Sid: "current"}}
+	admin := &user.Principal{User: user.DTO{ID: "admin", Admin: true}, Payload: &jwt.Payload{Id: "admin", Sid: "current"}}
+	sessions := []*jwt.Session{{Id: "current"}, {Id: "other"}}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		principal *user.Principal
+		userId    string
+		setup     func(context.Context)
+		expect    func(*testing.T, error)
+	}{
+		{
+			name:      "should keep the current session",
+			ctx:       context.Background(),
+			principal: principal,
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+				mockJwt.EXPECT().DeleteTokens(ctx, &jwt.Payload{Id: "user", Sid: "other"}).Return(nil)
+				mockCloser.EXPECT().CloseSessions("user", "other")
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:      "should revoke every session of another user",
+			ctx:       context.Background(),
+			principal: admin,
+			userId:    "user",
+			setup: func(ctx context.Context) {
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return(sessions, nil)
+				mockJwt.EXPECT().DeleteTokens(ctx, &jwt.Payload{Id: "user", Sid: "current"}).Return(nil)
+				mockCloser.EXPECT().CloseSessions("user", "current")
+				mockJwt.EXPECT().DeleteTokens(ctx, &jwt.Payload{Id: "user", Sid: "other"}).Return(nil)
+				mockCloser.EXPECT().CloseSessions("user", "other")
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:      "should return not allowed",
+			ctx:       context.Background(),
+			principal: principal,
+			userId:    "admin",
+			setup:     func(ctx context.Context) {},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrNotAllowed, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.RevokeOtherSessions(tc.ctx, tc.principal, tc.userId)
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_ForgotPassword(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockMailToken := mock_auth.NewMockMailTokenService(controller)
+	mockMailer := mock_mailer.NewMockMailer(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mockUserSvc, mock_jwt.NewMockService(controller), mock_auth.NewMockTicketService(controller), mockMailToken, mock_auth.NewMockMfaTokenService(controller), mockMailer, mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	userDto := &user.DTO{ID: "user", Email: "user@example.com", Name: "User"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		dto    *auth.ForgotPasswordDTO
+		setup  func(context.Context, *auth.ForgotPasswordDTO)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should mail reset link",
+			ctx:  context.Background(),
+			dto:  &auth.ForgotPasswordDTO{Email: userDto.Email},
+			setup: func(ctx context.Context, dto *auth.ForgotPasswordDTO) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(userDto, nil)
+				mockMailToken.EXPECT().CreateToken(ctx, auth.PurposeResetPassword, userDto.ID, 30*time.Minute).Return("token", nil)
+				mockMailer.EXPECT().Send(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg *mailer.Message) error {
+					assert.Equal(t, userDto.Email, msg.To)
+					assert.Contains(t, msg.Body, "http://localhost/reset-password?token=token")
+					return nil
+				})
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should not reveal unknown email",
+			ctx:  context.Background(),
+			dto:  &auth.ForgotPasswordDTO{Email: "unknown@example.com"},
+			setup: func(ctx context.Context, dto *auth.ForgotPasswordDTO) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should skip disabled user",
+			ctx:  context.Background(),
+			dto:  &auth.ForgotPasswordDTO{Email: userDto.Email},
+			setup: func(ctx context.Context, dto *auth.ForgotPasswordDTO) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(&user.DTO{ID: "user", Disabled: true}, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return failed send mail",
+			ctx:  context.Background(),
+			dto:  &auth.ForgotPasswordDTO{Email: userDto.Email},
+			setup: func(ctx context.Context, dto *auth.ForgotPasswordDTO) {
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(userDto, nil)
+				mockMailToken.EXPECT().CreateToken(ctx, auth.PurposeResetPassword, userDto.ID, 30*time.Minute).Return("token", nil)
+				mockMailer.EXPECT().Send(ctx, gomock.Any()).Return(errors.New("connection refused"))
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrFailedSendMail, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx, tc.dto)
+			err := service.ForgotPassword(tc.ctx, tc.dto)
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockJwt := mock_jwt.NewMockService(controller)
+	mockMailToken := mock_auth.NewMockMailTokenService(controller)
+	mockCloser := mock_auth.NewMockSessionCloser(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mockUserSvc, mockJwt, mock_auth.NewMockTicketService(controller), mockMailToken, mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mockCloser, mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	dto := &auth.ResetPasswordDTO{Token: "token", Password: "Password1"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should set password and end every session",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().ConsumeToken(ctx, auth.PurposeResetPassword, dto.Token).Return("user", nil)
+				mockUserSvc.EXPECT().SetPassword(ctx, "user", dto.Password).Return(nil)
+				mockJwt.EXPECT().GetSessions(ctx, "user").Return([]*jwt.Session{{Id: "phone"}, {Id: "desktop"}}, nil)
+				mockJwt.EXPECT().DeleteAllTokens(ctx, "user").Return(nil)
+				mockCloser.EXPECT().CloseSessions("user", "phone", "desktop")
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return invalid token",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().ConsumeToken(ctx, auth.PurposeResetPassword, dto.Token).Return("", auth.ErrInvalidMailToken)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrInvalidMailToken, err)
+			},
+		},
+		{
+			name: "should return failed update user",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().ConsumeToken(ctx, auth.PurposeResetPassword, dto.Token).Return("user", nil)
+				mockUserSvc.EXPECT().SetPassword(ctx, "user", dto.Password).Return(user.ErrFailedUpdateUser)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrFailedUpdateUser, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.ResetPassword(tc.ctx, dto)
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_Verify(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockMailToken := mock_auth.NewMockMailTokenService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mockUserSvc, mock_jwt.NewMockService(controller), mock_auth.NewMockTicketService(controller), mockMailToken, mock_auth.NewMockMfaTokenService(controller), mock_mailer.NewMockMailer(controller), mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	dto := &auth.VerifyDTO{Token: "token"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should verify user",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().ConsumeToken(ctx, auth.PurposeVerifyEmail, dto.Token).Return("user", nil)
+				mockUserSvc.EXPECT().SetVerified(ctx, "user", true).Return(&user.DTO{ID: "user", Verified: true}, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return invalid token",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().ConsumeToken(ctx, auth.PurposeVerifyEmail, dto.Token).Return("", auth.ErrInvalidMailToken)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrInvalidMailToken, err)
+			},
+		},
+		{
+			name: "should return failed update user",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().ConsumeToken(ctx, auth.PurposeVerifyEmail, dto.Token).Return("user", nil)
+				mockUserSvc.EXPECT().SetVerified(ctx, "user", true).Return(nil, user.ErrFailedUpdateUser)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrFailedUpdateUser, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.Verify(tc.ctx, dto)
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_ResendVerification(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockMailToken := mock_auth.NewMockMailTokenService(controller)
+	mockMailer := mock_mailer.NewMockMailer(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := auth.NewService(mockUserSvc, mock_jwt.NewMockService(controller), mock_auth.NewMockTicketService(controller), mockMailToken, mock_auth.NewMockMfaTokenService(controller), mockMailer, mock_auth.NewMockSessionCloser(controller), mock_auth.NewMockMfaPolicy(controller), rbacPolicy, "http://localhost", "Support Chat", &resetTTL, &verifyTTL, &resendCooldown, zapLogger)
+
+	userDto := &user.DTO{ID: "user", Email: "user@example.com", Name: "User"}
+	dto := &auth.ResendVerificationDTO{Email: userDto.Email}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should mail verification link",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().Throttle(ctx, auth.PurposeVerifyEmail, dto.Email, time.Minute).Return(nil)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(userDto, nil)
+				mockMailToken.EXPECT().CreateToken(ctx, auth.PurposeVerifyEmail, userDto.ID, time.Hour).Return("token", nil)
+				mockMailer.EXPECT().Send(ctx, gomock.Any()).Return(nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return mail sent recently",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().Throttle(ctx, auth.PurposeVerifyEmail, dto.Email, time.Minute).Return(auth.ErrMailCooldown)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, auth.ErrMailCooldown, err)
+			},
+		},
+		{
+			name: "should not reveal unknown email",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().Throttle(ctx, auth.PurposeVerifyEmail, dto.Email, time.Minute).Return(nil)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should skip verified user",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockMailToken.EXPECT().Throttle(ctx, auth.PurposeVerifyEmail, dto.Email, time.Minute).Return(nil)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, dto.Email, false).Return(&user.DTO{ID: "user", Verified: true}, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.ResendVerification(tc.ctx, dto)
+			tc.expect(t, err)
+		})
+	}
+}
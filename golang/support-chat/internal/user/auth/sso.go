@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"support-chat/internal/user"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/oidc"
+
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=sso.go -destination=mocks/sso_mock.go
+type SSOService interface {
+	Start(ctx context.Context) (*SSOStartDTO, error)
+	Link(ctx context.Context, userId string) (*SSOStartDTO, error)
+	Callback(ctx context.Context, dto *SSOCallbackDTO, device *jwt.Device) (*LoginResponseDTO, error)
+}
+
+// SupportSync gives a single sign-on user the support role the provider grants
+// them and records the change.
+type SupportSync interface {
+	SyncSupport(ctx context.Context, u *user.DTO, support bool) (*user.DTO, error)
+}
+
+type ssoService struct {
+	authSvc      Service
+	userSvc      user.Service
+	oidcClient   oidc.Client
+	stateSvc     SSOStateService
+	supportSync  SupportSync
+	supportClaim string
+	supportValue string
+	logger       *zap.SugaredLogger
+}
+
+// NewSSOService logs users in with an OpenID Connect provider. Users are
+// matched by the issuer and subject of their ID token, unknown ones are created
+// on their first login. Every login gives them the support role when the
+// supportClaim of their ID token holds supportValue and takes it away when it
+// doesn't. An empty supportClaim leaves the roles to the admins.
+func NewSSOService(authSvc Service,
+	userSvc user.Service,
+	oidcClient oidc.Client,
+	stateSvc SSOStateService,
+	supportSync SupportSync,
+	supportClaim string,
+	supportValue string,
+	logger *zap.SugaredLogger) (SSOService, error) {
+	if authSvc == nil {
+		return nil, errors.New("[user_auth_sso_service] invalid auth service")
+	}
+	if userSvc == nil {
+		return nil, errors.New("[user_auth_sso_service] invalid user service")
+	}
+	if oidcClient == nil {
+		return nil, errors.New("[user_auth_sso_service] invalid oidc client")
+	}
+	if stateSvc == nil {
+		return nil, errors.New("[user_auth_sso_service] invalid sso state service")
+	}
+	if supportSync == nil {
+		return nil, errors.New("[user_auth_sso_service] invalid support sync")
+	}
+	if logger == nil {
+		return nil, errors.New("[user_auth_sso_service] invalid logger")
+	}
+
+	return &ssoService{
+		authSvc:      authSvc,
+		userSvc:      userSvc,
+		oidcClient:   oidcClient,
+		stateSvc:     stateSvc,
+		supportSync:  supportSync,
+		supportClaim: supportClaim,
+		supportValue: supportValue,
+		logger:       logger,
+	}, nil
+}
+
+// Start returns the login page of the provider. The nonce and the PKCE
+// verifier are kept under the state until the user comes back.
+func (s *ssoService) Start(ctx context.Context) (*SSOStartDTO, error) {
+	return s.start(ctx, &SSOPending{})
+}
+
+// Link returns the login page of the provider for a logged in user, the
+// account the user logs in with there is linked to theirs by the callback.
+// Existing accounts can only be used with single sign-on this way.
+func (s *ssoService) Link(ctx context.Context, userId string) (*SSOStartDTO, error) {
+	return s.start(ctx, &SSOPending{LinkUserId: userId})
+}
+
+func (s *ssoService) start(ctx context.Context, pending *SSOPending) (*SSOStartDTO, error) {
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return nil, ErrFailedSSO
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return nil, ErrFailedSSO
+	}
+
+	pending.Nonce, pending.Verifier = nonce, verifier
+
+	state, err := s.stateSvc.CreateState(ctx, pending)
+	if err != nil {
+		s.logger.Errorf("failed to create sso state %v", err)
+		return nil, err
+	}
+
+	return &SSOStartDTO{Url: s.oidcClient.AuthCodeURL(state, nonce, verifier), State: state}, nil
+}
+
+// Callback redeems the code the provider redirected back with and logs the
+// user of its ID token in. Accounts are matched by the identity of the token,
+// the email address only names new users, still only verified addresses are
+// accepted.
+func (s *ssoService) Callback(ctx context.Context, dto *SSOCallbackDTO, device *jwt.Device) (*LoginResponseDTO, error) {
+	pending, err := s.stateSvc.ConsumeState(ctx, dto.State)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.oidcClient.Exchange(ctx, dto.Code, pending.Verifier)
+	if err != nil {
+		s.logger.Errorf("failed to exchange sso code %v", err)
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token.Nonce), []byte(pending.Nonce)) != 1 {
+		s.logger.Errorf("sso nonce of %v doesn't match", token.Subject)
+		return nil, oidc.ErrIDToken
+	}
+
+	if token.Email == "" || token.EmailVerified == nil || !*token.EmailVerified {
+		s.logger.Errorf("sso user %v has no verified email", token.Subject)
+		return nil, ErrSSOEmail
+	}
+
+	var userDto *user.DTO
+	if pending.LinkUserId != "" {
+		userDto, err = s.link(ctx, pending.LinkUserId, token)
+	} else {
+		userDto, err = s.userSvc.GetUserByIdentity(ctx, token.Issuer, token.Subject)
+		if errors.Is(err, user.ErrNotFound) {
+			userDto, err = s.provision(ctx, token)
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("failed to find sso user %v", err)
+		return nil, err
+	}
+
+	if s.supportClaim != "" {
+		synced, err := s.supportSync.SyncSupport(ctx, userDto, s.isSupport(token))
+		if err != nil {
+			s.logger.Errorf("failed to sync support role of sso user %v: %v", userDto.ID, err)
+			return nil, err
+		}
+		userDto = synced
+	}
+
+	device.Label = dto.Device
+
+	return s.authSvc.CompleteLogin(ctx, userDto, device)
+}
+
+// link links the identity of the ID token to the user who started the login,
+// unless it already belongs to someone else.
+func (s *ssoService) link(ctx context.Context, userId string, token *oidc.IDToken) (*user.DTO, error) {
+	linked, err := s.userSvc.GetUserByIdentity(ctx, token.Issuer, token.Subject)
+	if err == nil {
+		if linked.ID != userId {
+			return nil, ErrSSOIdentityTaken
+		}
+		return linked, nil
+	}
+	if !errors.Is(err, user.ErrNotFound) {
+		return nil, err
+	}
+
+	userDto, err := s.userSvc.LinkIdentity(ctx, userId, token.Issuer, token.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("linked sso user %v to %v", token.Subject, userDto.ID)
+
+	return userDto, nil
+}
+
+// provision creates the user of the ID token. The random password is never
+// handed out, it can be replaced through the password reset. An account which
+// already has the email address isn't taken over, its owner has to link it.
+func (s *ssoService) provision(ctx context.Context, token *oidc.IDToken) (*user.DTO, error) {
+	_, err := s.userSvc.GetUserByEmail(ctx, token.Email, false)
+	if err == nil {
+		s.logger.Errorf("sso user %v has the email of an unlinked account", token.Subject)
+		return nil, ErrSSONotLinked
+	}
+	if !errors.Is(err, user.ErrNotFound) {
+		return nil, err
+	}
+
+	password, err := oidc.RandomString()
+	if err != nil {
+		return nil, ErrFailedSSO
+	}
+
+	name := token.Name
+	if name == "" {
+		name = strings.SplitN(token.Email, "@", 2)[0]
+	}
+
+	userDto, err := s.userSvc.CreateUser(ctx, token.Email, name, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if userDto, err = s.userSvc.SetVerified(ctx, userDto.ID, true); err != nil {
+		return nil, err
+	}
+
+	if userDto, err = s.userSvc.LinkIdentity(ctx, userDto.ID, token.Issuer, token.Subject); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("provisioned sso user %v", userDto.ID)
+
+	return userDto, nil
+}
+
+// isSupport reads the support claim, which is either a flag, a single value or
+// a list of values like groups or roles.
+func (s *ssoService) isSupport(token *oidc.IDToken) bool {
+	switch v := token.Claims[s.supportClaim].(type) {
+	case bool:
+		return v
+	case string:
+		return v == s.supportValue
+	case []interface{}:
+		for _, item := range v {
+			if item == s.supportValue {
+				return true
+			}
+		}
+	}
+
+	return false
+}
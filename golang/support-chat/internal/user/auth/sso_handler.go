@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	goErr "errors"
+	"net/http"
+	"support-chat/internal/user"
+	"support-chat/pkg/errors"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/respond"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SSOStateCookie binds a login to the browser which started it, the callback
+// only accepts the state of its cookie. Without it an attacker could have a
+// victim's browser complete a login the attacker started.
+const SSOStateCookie = "sso_state"
+
+// SSOHandler serves the login with the identity provider. The provider
+// redirects to the app, which posts the code and the state to /sso/callback.
+type SSOHandler struct {
+	ssoSvc         SSOService
+	authMiddleware Middleware
+}
+
+func NewSSOHandler(ssoSvc SSOService, authMiddleware Middleware) (*SSOHandler, error) {
+	if ssoSvc == nil {
+		return nil, goErr.New("[chat_auth_sso_handler] invalid sso service")
+	}
+	if authMiddleware == nil {
+		return nil, goErr.New("[chat_auth_sso_handler] invalid auth middleware")
+	}
+
+	return &SSOHandler{ssoSvc: ssoSvc, authMiddleware: authMiddleware}, nil
+}
+
+func (h *SSOHandler) SetupRoutes(router chi.Router) {
+	router.Get("/sso", h.Start)
+	router.Post("/sso/callback", h.Callback)
+	router.With(h.authMiddleware.JwtMiddleware).Post("/sso/link", h.Link)
+}
+
+func (h *SSOHandler) Start(w http.ResponseWriter, r *http.Request) {
+	start, err := h.ssoSvc.Start(r.Context())
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	setStateCookie(w, r, start.State, int(ssoStateTTL.Seconds()))
+	respond.Respond(w, http.StatusOK, start)
+}
+
+func (h *SSOHandler) Link(w http.ResponseWriter, r *http.Request) {
+	principal, ok := user.FromContext(r.Context())
+	if !ok {
+		respond.Respond(w, http.StatusUnauthorized, errors.NewInternal("Not authenticated"))
+		return
+	}
+
+	start, err := h.ssoSvc.Link(r.Context(), principal.User.ID)
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	setStateCookie(w, r, start.State, int(ssoStateTTL.Seconds()))
+	respond.Respond(w, http.StatusOK, start)
+}
+
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var dto SSOCallbackDTO
+
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), errors.NewInternal(err.Error()))
+		return
+	}
+
+	if err := Validate(dto); err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	cookie, err := r.Cookie(SSOStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(dto.State)) != 1 {
+		respond.Respond(w, errors.HTTPCode(ErrInvalidSSOState), ErrInvalidSSOState)
+		return
+	}
+	// the state is used up either way
+	setStateCookie(w, r, "", -1)
+
+	tokens, err := h.ssoSvc.Callback(r.Context(), &dto, &jwt.Device{
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+	})
+	if err != nil {
+		respond.Respond(w, errors.HTTPCode(err), err)
+		return
+	}
+
+	respond.Respond(w, http.StatusOK, tokens)
+}
+
+// setStateCookie keeps the state in a cookie scripts can't read, which only
+// comes along with requests of the site itself. A negative maxAge removes it.
+func setStateCookie(w http.ResponseWriter, r *http.Request, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SSOStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
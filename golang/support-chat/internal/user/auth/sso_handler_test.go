@@ -0,0 +1,148 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"support-chat/internal/user/auth"
+	mock_auth "support-chat/internal/user/auth/mocks"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSSOHandler(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	tests := []struct {
+		name           string
+		ssoSvc         auth.SSOService
+		authMiddleware auth.Middleware
+		expect         func(*testing.T, *auth.SSOHandler, error)
+	}{
+		{
+			name:           "should return handler",
+			ssoSvc:         mock_auth.NewMockSSOService(controller),
+			authMiddleware: mock_auth.NewMockMiddleware(controller),
+			expect: func(t *testing.T, h *auth.SSOHandler, err error) {
+				assert.NotNil(t, h)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:           "should return invalid sso service",
+			authMiddleware: mock_auth.NewMockMiddleware(controller),
+			expect: func(t *testing.T, h *auth.SSOHandler, err error) {
+				assert.Nil(t, h)
+				assert.EqualError(t, err, "[chat_auth_sso_handler] invalid sso service")
+			},
+		},
+		{
+			name:   "should return invalid auth middleware",
+			ssoSvc: mock_auth.NewMockSSOService(controller),
+			expect: func(t *testing.T, h *auth.SSOHandler, err error) {
+				assert.Nil(t, h)
+				assert.EqualError(t, err, "[chat_auth_sso_handler] invalid auth middleware")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := auth.NewSSOHandler(tc.ssoSvc, tc.authMiddleware)
+			tc.expect(t, h, err)
+		})
+	}
+}
+
+func TestSSOHandler_Start(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockSSO := mock_auth.NewMockSSOService(controller)
+	h, _ := auth.NewSSOHandler(mockSSO, mock_auth.NewMockMiddleware(controller))
+
+	mockSSO.EXPECT().Start(gomock.Any()).Return(&auth.SSOStartDTO{Url: "https://idp/authorize", State: "state"}, nil)
+
+	w := httptest.NewRecorder()
+	h.Start(w, httptest.NewRequest(http.MethodGet, "/sso", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]interface{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, map[string]interface{}{"url": "https://idp/authorize"}, body)
+
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, auth.SSOStateCookie, cookies[0].Name)
+		assert.Equal(t, "state", cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		assert.Positive(t, cookies[0].MaxAge)
+	}
+}
+
+func TestSSOHandler_Callback(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockSSO := mock_auth.NewMockSSOService(controller)
+	h, _ := auth.NewSSOHandler(mockSSO, mock_auth.NewMockMiddleware(controller))
+
+	tokens := &auth.LoginResponseDTO{AccessToken: "access", RefreshToken: "refresh"}
+
+	tests := []struct {
+		name       string
+		cookie     *http.Cookie
+		setup      func()
+		wantStatus int
+		wantClear  bool
+	}{
+		{
+			name:   "should log in with state of cookie",
+			cookie: &http.Cookie{Name: auth.SSOStateCookie, Value: "state"},
+			setup: func() {
+				mockSSO.EXPECT().Callback(gomock.Any(), &auth.SSOCallbackDTO{Code: "code", State: "state"}, gomock.Any()).
+					Return(tokens, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantClear:  true,
+		},
+		{
+			name:       "should reject other state",
+			cookie:     &http.Cookie{Name: auth.SSOStateCookie, Value: "other"},
+			setup:      func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "should reject missing cookie",
+			setup:      func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup()
+
+			r := httptest.NewRequest(http.MethodPost, "/sso/callback", strings.NewReader(`{"code":"code","state":"state"}`))
+			if tc.cookie != nil {
+				r.AddCookie(tc.cookie)
+			}
+			w := httptest.NewRecorder()
+			h.Callback(w, r)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+
+			cookies := w.Result().Cookies()
+			if tc.wantClear && assert.Len(t, cookies, 1) {
+				assert.Equal(t, auth.SSOStateCookie, cookies[0].Name)
+				assert.Negative(t, cookies[0].MaxAge)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	gerrors "errors"
+	"fmt"
+	"support-chat/pkg/oidc"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ssoStateTTL is how long a login may stay at the identity provider
+const ssoStateTTL = 10 * time.Minute
+
+// SSOPending is a login which was sent to the identity provider. The nonce
+// and the PKCE verifier never leave the server.
+type SSOPending struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkUserId is the user who links the account of the provider, empty
+	// for a plain login
+	LinkUserId string `json:"link_user_id,omitempty"`
+}
+
+//go:generate mockgen -source=sso_state.go -destination=mocks/sso_state_mock.go
+type SSOStateService interface {
+	CreateState(ctx context.Context, pending *SSOPending) (string, error)
+	ConsumeState(ctx context.Context, state string) (*SSOPending, error)
+}
+
+type ssoStateService struct {
+	redisClient *redis.Client
+}
+
+// NewSSOStateService keeps the logins waiting for the identity provider in the
+// auth redis. Only a hash of the state is stored.
+func NewSSOStateService(redisClient *redis.Client) (SSOStateService, error) {
+	if redisClient == nil {
+		return nil, gerrors.New("[user_auth_sso_state] invalid redis client")
+	}
+
+	return &ssoStateService{redisClient: redisClient}, nil
+}
+
+func (s *ssoStateService) CreateState(ctx context.Context, pending *SSOPending) (string, error) {
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", ErrFailedSSO
+	}
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return "", ErrFailedSSO
+	}
+
+	if err = s.redisClient.Set(ctx, ssoStateKey(state), data, ssoStateTTL).Err(); err != nil {
+		return "", ErrFailedSSO
+	}
+
+	return state, nil
+}
+
+// ConsumeState redeems the state once, so a callback can't be replayed.
+func (s *ssoStateService) ConsumeState(ctx context.Context, state string) (*SSOPending, error) {
+	data, err := s.redisClient.GetDel(ctx, ssoStateKey(state)).Bytes()
+	if err != nil {
+		return nil, ErrInvalidSSOState
+	}
+
+	pending := new(SSOPending)
+	if err = json.Unmarshal(data, pending); err != nil {
+		return nil, ErrInvalidSSOState
+	}
+
+	return pending, nil
+}
+
+func ssoStateKey(state string) string {
+	hash := sha256.Sum256([]byte(state))
+	return fmt.Sprintf("sso-state-%v", hex.EncodeToString(hash[:]))
+}
@@ -0,0 +1,482 @@
+package auth_test
+
+import (
+	"context"
+	"net/url"
+	"support-chat/internal/user"
+	"support-chat/internal/user/auth"
+	mock_auth "support-chat/internal/user/auth/mocks"
+	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/logger"
+	"support-chat/pkg/oidc"
+	mock_oidc "support-chat/pkg/oidc/mocks"
+	"support-chat/pkg/oidc/oidctest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewSSOService(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	tests := []struct {
+		name       string
+		authSvc    auth.Service
+		userSvc    user.Service
+		oidcClient oidc.Client
+		stateSvc   auth.SSOStateService
+		sync       auth.SupportSync
+		logger     *zap.SugaredLogger
+		expect     func(*testing.T, auth.SSOService, error)
+	}{
+		{
+			name:       "should return service",
+			authSvc:    mock_auth.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			oidcClient: mock_oidc.NewMockClient(controller),
+			stateSvc:   mock_auth.NewMockSSOStateService(controller),
+			sync:       mock_auth.NewMockSupportSync(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.NotNil(t, s)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:       "should return invalid auth service",
+			userSvc:    mock_user.NewMockService(controller),
+			oidcClient: mock_oidc.NewMockClient(controller),
+			stateSvc:   mock_auth.NewMockSSOStateService(controller),
+			sync:       mock_auth.NewMockSupportSync(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_auth_sso_service] invalid auth service")
+			},
+		},
+		{
+			name:       "should return invalid user service",
+			authSvc:    mock_auth.NewMockService(controller),
+			oidcClient: mock_oidc.NewMockClient(controller),
+			stateSvc:   mock_auth.NewMockSSOStateService(controller),
+			sync:       mock_auth.NewMockSupportSync(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_auth_sso_service] invalid user service")
+			},
+		},
+		{
+			name:     "should return invalid oidc client",
+			authSvc:  mock_auth.NewMockService(controller),
+			userSvc:  mock_user.NewMockService(controller),
+			stateSvc: mock_auth.NewMockSSOStateService(controller),
+			sync:     mock_auth.NewMockSupportSync(controller),
+			logger:   &zap.SugaredLogger{},
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_auth_sso_service] invalid oidc client")
+			},
+		},
+		{
+			name:       "should return invalid sso state service",
+			authSvc:    mock_auth.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			oidcClient: mock_oidc.NewMockClient(controller),
+			sync:       mock_auth.NewMockSupportSync(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_auth_sso_service] invalid sso state service")
+			},
+		},
+		{
+			name:       "should return invalid support sync",
+			authSvc:    mock_auth.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			oidcClient: mock_oidc.NewMockClient(controller),
+			stateSvc:   mock_auth.NewMockSSOStateService(controller),
+			logger:     &zap.SugaredLogger{},
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_auth_sso_service] invalid support sync")
+			},
+		},
+		{
+			name:       "should return invalid logger",
+			authSvc:    mock_auth.NewMockService(controller),
+			userSvc:    mock_user.NewMockService(controller),
+			oidcClient: mock_oidc.NewMockClient(controller),
+			stateSvc:   mock_auth.NewMockSSOStateService(controller),
+			sync:       mock_auth.NewMockSupportSync(controller),
+			expect: func(t *testing.T, s auth.SSOService, err error) {
+				assert.Nil(t, s)
+				assert.EqualError(t, err, "[user_auth_sso_service] invalid logger")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, err := auth.NewSSOService(tc.authSvc, tc.userSvc, tc.oidcClient, tc.stateSvc, tc.sync, "groups", "support", tc.logger)
+			tc.expect(t, svc, err)
+		})
+	}
+}
+
+func TestSSOService_Start(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockState := mock_auth.NewMockSSOStateService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	idp, err := oidctest.NewServer("support-chat", "")
+	assert.Nil(t, err)
+	defer idp.Close()
+
+	client, err := oidc.NewClient(context.Background(), idp.URL, "support-chat", "", "http://localhost/sso", []string{"openid"}, idp.Client())
+	assert.Nil(t, err)
+
+	service, _ := auth.NewSSOService(mock_auth.NewMockService(controller), mock_user.NewMockService(controller), client, mockState, mock_auth.NewMockSupportSync(controller), "groups", "support", zapLogger)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, *auth.SSOStartDTO, error)
+	}{
+		{
+			name: "should return login url",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().CreateState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, pending *auth.SSOPending) (string, error) {
+					assert.NotEmpty(t, pending.Nonce)
+					assert.NotEmpty(t, pending.Verifier)
+					assert.Empty(t, pending.LinkUserId)
+					return "state", nil
+				})
+			},
+			expect: func(t *testing.T, got *auth.SSOStartDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "state", got.State)
+
+				u, err := url.Parse(got.Url)
+				assert.Nil(t, err)
+				assert.Equal(t, "state", u.Query().Get("state"))
+				assert.NotEmpty(t, u.Query().Get("nonce"))
+				assert.NotEmpty(t, u.Query().Get("code_challenge"))
+			},
+		},
+		{
+			name: "should return failed sso",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().CreateState(ctx, gomock.Any()).Return("", auth.ErrFailedSSO)
+			},
+			expect: func(t *testing.T, got *auth.SSOStartDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrFailedSSO, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			got, err := service.Start(tc.ctx)
+			tc.expect(t, got, err)
+		})
+	}
+}
+
+func TestSSOService_Link(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockState := mock_auth.NewMockSSOStateService(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	idp, err := oidctest.NewServer("support-chat", "")
+	assert.Nil(t, err)
+	defer idp.Close()
+
+	client, err := oidc.NewClient(context.Background(), idp.URL, "support-chat", "", "http://localhost/sso", []string{"openid"}, idp.Client())
+	assert.Nil(t, err)
+
+	service, _ := auth.NewSSOService(mock_auth.NewMockService(controller), mock_user.NewMockService(controller), client, mockState, mock_auth.NewMockSupportSync(controller), "groups", "support", zapLogger)
+
+	ctx := context.Background()
+	mockState.EXPECT().CreateState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, pending *auth.SSOPending) (string, error) {
+		assert.Equal(t, "user", pending.LinkUserId)
+		assert.NotEmpty(t, pending.Nonce)
+		assert.NotEmpty(t, pending.Verifier)
+		return "state", nil
+	})
+
+	got, err := service.Link(ctx, "user")
+	assert.Nil(t, err)
+
+	u, err := url.Parse(got.Url)
+	assert.Nil(t, err)
+	assert.Equal(t, "state", u.Query().Get("state"))
+}
+
+func TestSSOService_Callback(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockAuthSvc := mock_auth.NewMockService(controller)
+	mockUserSvc := mock_user.NewMockService(controller)
+	mockState := mock_auth.NewMockSSOStateService(controller)
+	mockSync := mock_auth.NewMockSupportSync(controller)
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	idp, err := oidctest.NewServer("support-chat", "secret")
+	assert.Nil(t, err)
+	defer idp.Close()
+
+	client, err := oidc.NewClient(context.Background(), idp.URL, "support-chat", "secret", "http://localhost/sso", []string{"openid"}, idp.Client())
+	assert.Nil(t, err)
+
+	service, _ := auth.NewSSOService(mockAuthSvc, mockUserSvc, client, mockState, mockSync, "groups", "support", zapLogger)
+
+	pending := &auth.SSOPending{Nonce: "nonce", Verifier: "verifier"}
+	linking := &auth.SSOPending{Nonce: "nonce", Verifier: "verifier", LinkUserId: "user"}
+	tokens := &auth.LoginResponseDTO{AccessToken: "access", RefreshToken: "refresh"}
+	existing := &user.DTO{ID: "user", Email: "user@example.com", Name: "User", Verified: true}
+	created := &user.DTO{ID: "new", Email: "agent@example.com", Name: "Agent"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		claims map[string]interface{}
+		setup  func(context.Context)
+		expect func(*testing.T, *auth.LoginResponseDTO, error)
+	}{
+		{
+			name:   "should log existing user in and grant support",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email, "email_verified": true, "groups": []string{"support"}},
+			setup: func(ctx context.Context) {
+				agent := &user.DTO{ID: "user", Email: existing.Email, Name: existing.Name, Verified: true, Support: true}
+
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(existing, nil)
+				mockSync.EXPECT().SyncSupport(ctx, existing, true).Return(agent, nil)
+				mockAuthSvc.EXPECT().CompleteLogin(ctx, agent, &jwt.Device{Label: "laptop"}).Return(tokens, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, tokens, got)
+			},
+		},
+		{
+			name:   "should fail when support role can't be synced",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email, "email_verified": true, "groups": []string{"staff"}},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(existing, nil)
+				mockSync.EXPECT().SyncSupport(ctx, existing, false).Return(nil, user.ErrFailedUpdateUser)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, user.ErrFailedUpdateUser, err)
+			},
+		},
+		{
+			name:   "should refuse unlinked account with the email",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email, "email_verified": true},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, existing.Email, false).Return(existing, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrSSONotLinked, err)
+			},
+		},
+		{
+			name:   "should link identity to user",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": "other@example.com", "email_verified": true},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(linking, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().LinkIdentity(ctx, "user", idp.URL, "subject").Return(existing, nil)
+				mockSync.EXPECT().SyncSupport(ctx, existing, false).Return(existing, nil)
+				mockAuthSvc.EXPECT().CompleteLogin(ctx, existing, gomock.Any()).Return(tokens, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, tokens, got)
+			},
+		},
+		{
+			name:   "should refuse identity of other user",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email, "email_verified": true},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(linking, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(&user.DTO{ID: "other"}, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrSSOIdentityTaken, err)
+			},
+		},
+		{
+			name:   "should provision support user",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": created.Email, "email_verified": true, "name": created.Name, "groups": []string{"staff", "support"}},
+			setup: func(ctx context.Context) {
+				verified := &user.DTO{ID: "new", Email: created.Email, Name: created.Name, Verified: true}
+				support := &user.DTO{ID: "new", Email: created.Email, Name: created.Name, Verified: true, Support: true}
+
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, created.Email, false).Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().CreateUser(ctx, created.Email, created.Name, gomock.Any()).Return(created, nil)
+				mockUserSvc.EXPECT().SetVerified(ctx, "new", true).Return(verified, nil)
+				mockUserSvc.EXPECT().LinkIdentity(ctx, "new", idp.URL, "subject").Return(verified, nil)
+				mockSync.EXPECT().SyncSupport(ctx, verified, true).Return(support, nil)
+				mockAuthSvc.EXPECT().CompleteLogin(ctx, support, gomock.Any()).Return(tokens, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, tokens, got)
+			},
+		},
+		{
+			name:   "should provision plain user named after email",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": created.Email, "email_verified": true, "groups": "staff"},
+			setup: func(ctx context.Context) {
+				verified := &user.DTO{ID: "new", Email: created.Email, Name: "agent", Verified: true}
+
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, created.Email, false).Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().CreateUser(ctx, created.Email, "agent", gomock.Any()).Return(created, nil)
+				mockUserSvc.EXPECT().SetVerified(ctx, "new", true).Return(verified, nil)
+				mockUserSvc.EXPECT().LinkIdentity(ctx, "new", idp.URL, "subject").Return(verified, nil)
+				mockSync.EXPECT().SyncSupport(ctx, verified, false).Return(verified, nil)
+				mockAuthSvc.EXPECT().CompleteLogin(ctx, verified, gomock.Any()).Return(tokens, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, tokens, got)
+			},
+		},
+		{
+			name:   "should return failed create user",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": created.Email, "email_verified": true},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+				mockUserSvc.EXPECT().GetUserByIdentity(ctx, idp.URL, "subject").Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().GetUserByEmail(ctx, created.Email, false).Return(nil, user.ErrNotFound)
+				mockUserSvc.EXPECT().CreateUser(ctx, created.Email, "agent", gomock.Any()).Return(nil, user.ErrFailedCreateUser)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, user.ErrFailedCreateUser, err)
+			},
+		},
+		{
+			name:   "should return invalid sso state",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(nil, auth.ErrInvalidSSOState)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrInvalidSSOState, err)
+			},
+		},
+		{
+			name:   "should reject other nonce",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email, "nonce": "other"},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, oidc.ErrIDToken, err)
+			},
+		},
+		{
+			name:   "should reject other verifier",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(&auth.SSOPending{Nonce: "nonce", Verifier: "other"}, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, oidc.ErrExchange, err)
+			},
+		},
+		{
+			name:   "should reject unverified email",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email, "email_verified": false},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrSSOEmail, err)
+			},
+		},
+		{
+			name:   "should reject email without verified flag",
+			ctx:    context.Background(),
+			claims: map[string]interface{}{"email": existing.Email},
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrSSOEmail, err)
+			},
+		},
+		{
+			name: "should reject missing email",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockState.EXPECT().ConsumeState(ctx, "state").Return(pending, nil)
+			},
+			expect: func(t *testing.T, got *auth.LoginResponseDTO, err error) {
+				assert.Nil(t, got)
+				assert.Equal(t, auth.ErrSSOEmail, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, state, err := idp.Authorize(client.AuthCodeURL("state", pending.Nonce, pending.Verifier), tc.claims)
+			assert.Nil(t, err)
+
+			tc.setup(tc.ctx)
+			got, err := service.Callback(tc.ctx, &auth.SSOCallbackDTO{Code: code, State: state, Device: "laptop"}, &jwt.Device{})
+			tc.expect(t, got, err)
+		})
+	}
+}
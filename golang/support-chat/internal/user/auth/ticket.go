@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	gerrors "errors"
+	"fmt"
+	"support-chat/pkg/jwt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ticketTTL is how long a websocket ticket can be redeemed
+const ticketTTL = 30 * time.Second
+
+//go:generate mockgen -source=ticket.go -destination=mocks/ticket_mock.go
+type TicketService interface {
+	CreateTicket(ctx context.Context, payload *jwt.Payload) (string, error)
+	ConsumeTicket(ctx context.Context, ticket string) (*jwt.Payload, error)
+}
+
+type ticketService struct {
+	redisClient *redis.Client
+}
+
+// NewTicketService keeps the websocket tickets in the auth redis, next to the
+// tokens they were exchanged for.
+func NewTicketService(redisClient *redis.Client) (TicketService, error) {
+	if redisClient == nil {
+		return nil, gerrors.New("[user_auth_ticket] invalid redis client")
+	}
+
+	return &ticketService{redisClient: redisClient}, nil
+}
+
+// CreateTicket issues an opaque single-use ticket for the token payload.
+func (s *ticketService) CreateTicket(ctx context.Context, payload *jwt.Payload) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", ErrFailedCreateTicket
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", ErrFailedCreateTicket
+	}
+
+	if err = s.redisClient.Set(ctx, ticketKey(ticket), data, ticketTTL).Err(); err != nil {
+		return "", ErrFailedCreateTicket
+	}
+
+	return ticket, nil
+}
+
+// ConsumeTicket redeems the ticket, GETDEL makes sure it is only redeemed once.
+func (s *ticketService) ConsumeTicket(ctx context.Context, ticket string) (*jwt.Payload, error) {
+	data, err := s.redisClient.GetDel(ctx, ticketKey(ticket)).Bytes()
+	if err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	payload := new(jwt.Payload)
+	if err = json.Unmarshal(data, payload); err != nil {
+		return nil, ErrInvalidTicket
+	}
+
+	return payload, nil
+}
+
+func ticketKey(ticket string) string {
+	return fmt.Sprintf("ws-ticket-%v", ticket)
+}
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"support-chat/pkg/jwt"
 	"time"
 )
 
@@ -10,9 +11,42 @@ type DTO struct {
 	Name     string  `json:"name"`
 	Password string  `json:"password,omitempty"`
 	Support  bool    `json:"support,omitempty"`
+	Admin    bool    `json:"admin,omitempty"`
 	RoomName *string `bson:"roomName"`
-	Free     bool    `bson:"free"`
+	Disabled bool    `json:"disabled,omitempty"`
+	Verified bool    `json:"verified"`
+	// TotpEnabled is read only, MapToEntity ignores it
+	TotpEnabled bool     `json:"totp_enabled"`
+	Rooms       []string `json:"rooms,omitempty"`
+	Capacity    int      `json:"capacity,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// SearchQuery filters users by name or email and by their flags. Nil flags
+// match any value.
+type SearchQuery struct {
+	Query    string
+	Support  *bool
+	Admin    *bool
+	Disabled *bool
+	Skip     int64
+	Limit    int64
+}
+
+// Role is the role the tokens of the user are signed with. Support users and
+// admins keep their role without a verified email, they are vetted by an
+// admin.
+func (d *DTO) Role() string {
+	switch {
+	case d.Admin:
+		return jwt.RoleAdmin
+	case d.Support:
+		return jwt.RoleSupport
+	case !d.Verified:
+		return jwt.RoleUnverified
+	}
+
+	return jwt.RoleUser
+}
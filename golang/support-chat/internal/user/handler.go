@@ -4,26 +4,31 @@ import (
 	goErr "errors"
 	"net/http"
 	"support-chat/pkg/errors"
+	"support-chat/pkg/rbac"
 	"support-chat/pkg/respond"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Handler struct {
-	userSvc Service
+	userSvc     Service
+	permissions rbac.Middleware
 }
 
-func NewHandler(userSvc Service) (*Handler, error) {
+func NewHandler(userSvc Service, permissions rbac.Middleware) (*Handler, error) {
 	if userSvc == nil {
 		return nil, goErr.New("[user_handler] invalid user service")
 	}
+	if permissions == nil {
+		return nil, goErr.New("[user_handler] invalid permissions middleware")
+	}
 
-	return &Handler{userSvc: userSvc}, nil
+	return &Handler{userSvc: userSvc, permissions: permissions}, nil
 }
 
 func (h *Handler) SetupRoutes(router chi.Router) {
+	router = router.With(h.permissions.Require(rbac.UsersRead))
 	router.Get("/user/{id}", h.GetUserById)
-	router.Get("/free-user", h.GetFreeUser)
 }
 
 func (h *Handler) GetUserById(w http.ResponseWriter, r *http.Request) {
@@ -37,13 +42,3 @@ func (h *Handler) GetUserById(w http.ResponseWriter, r *http.Request) {
 
 	respond.Respond(w, http.StatusOK, user)
 }
-
-func (h *Handler) GetFreeUser(w http.ResponseWriter, r *http.Request) {
-	user, err := h.userSvc.GetFreeUser(r.Context())
-	if err != nil {
-		respond.Respond(w, errors.HTTPCode(err), err)
-		return
-	}
-
-	respond.Respond(w, http.StatusOK, user)
-}
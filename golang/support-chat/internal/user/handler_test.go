@@ -3,6 +3,8 @@ package user_test
 import (
 	"support-chat/internal/user"
 	mock_user "support-chat/internal/user/mocks"
+	"support-chat/pkg/rbac"
+	mock_rbac "support-chat/pkg/rbac/mocks"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -14,32 +16,45 @@ func TestNewHandler(t *testing.T) {
 	defer controller.Finish()
 
 	tests := []struct {
-		name    string
-		userSvc user.Service
-		expect  func(*testing.T, *user.Handler, error)
+		name        string
+		userSvc     user.Service
+		permissions rbac.Middleware
+		expect      func(*testing.T, *user.Handler, error)
 	}{
 		{
-			name:    "should return service",
-			userSvc: mock_user.NewMockService(controller),
+			name:        "should return service",
+			userSvc:     mock_user.NewMockService(controller),
+			permissions: mock_rbac.NewMockMiddleware(controller),
 			expect: func(t *testing.T, s *user.Handler, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:    "should return invalid user service",
-			userSvc: nil,
+			name:        "should return invalid user service",
+			userSvc:     nil,
+			permissions: mock_rbac.NewMockMiddleware(controller),
 			expect: func(t *testing.T, s *user.Handler, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, "[user_handler] invalid user service")
 			},
 		},
+		{
+			name:        "should return invalid permissions middleware",
+			userSvc:     mock_user.NewMockService(controller),
+			permissions: nil,
+			expect: func(t *testing.T, s *user.Handler, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_handler] invalid permissions middleware")
+			},
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := user.NewHandler(tc.userSvc)
+			svc, err := user.NewHandler(tc.userSvc, tc.permissions)
 			tc.expect(t, svc, err)
 		})
 	}
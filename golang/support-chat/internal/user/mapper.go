@@ -13,15 +13,20 @@ func MapToDTO(u *User) *DTO {
 	//}
 
 	return &DTO{
-		ID:        u.ID.Hex(),
-		Email:     u.Email,
-		Name:      u.Name,
-		Password:  u.Password,
-		Support:   u.Support,
-		RoomName:  u.RoomName,
-		Free:      u.Free,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:          u.ID.Hex(),
+		Email:       u.Email,
+		Name:        u.Name,
+		Password:    u.Password,
+		Support:     u.Support,
+		Admin:       u.Admin,
+		Disabled:    u.Disabled,
+		Verified:    u.Verified,
+		TotpEnabled: u.Totp != nil && u.Totp.Enabled,
+		RoomName:    u.RoomName,
+		Rooms:       u.Rooms,
+		Capacity:    u.Capacity,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
 	}
 }
 
@@ -37,8 +42,12 @@ func MapToEntity(dto *DTO) (*User, error) {
 		Name:      dto.Name,
 		Password:  dto.Password,
 		Support:   dto.Support,
+		Admin:     dto.Admin,
+		Disabled:  dto.Disabled,
+		Verified:  dto.Verified,
 		RoomName:  dto.RoomName,
-		Free:      dto.Free,
+		Rooms:     dto.Rooms,
+		Capacity:  dto.Capacity,
 		CreatedAt: dto.CreatedAt,
 		UpdatedAt: dto.UpdatedAt,
 	}, nil
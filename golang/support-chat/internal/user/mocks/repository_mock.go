@@ -6,12 +6,13 @@ package mock_user
 
 import (
 	context "context"
-	user "support-chat/internal/user"
-
 	reflect "reflect"
+	user "support-chat/internal/user"
 
 	gomock "github.com/golang/mock/gomock"
 	bson "go.mongodb.org/mongo-driver/bson"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+	options "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MockRepository is a mock of Repository interface.
@@ -37,6 +38,20 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddRoom mocks base method.
+func (m *MockRepository) AddRoom(ctx context.Context, id primitive.ObjectID, roomName string, capacity int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRoom", ctx, id, roomName, capacity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRoom indicates an expected call of AddRoom.
+func (mr *MockRepositoryMockRecorder) AddRoom(ctx, id, roomName, capacity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRoom", reflect.TypeOf((*MockRepository)(nil).AddRoom), ctx, id, roomName, capacity)
+}
+
 // CreateUser mocks base method.
 func (m *MockRepository) CreateUser(ctx context.Context, user *user.User) (string, error) {
 	m.ctrl.T.Helper()
@@ -52,6 +67,21 @@ func (mr *MockRepositoryMockRecorder) CreateUser(ctx, user interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockRepository)(nil).CreateUser), ctx, user)
 }
 
+// FindAndUpdateUser mocks base method.
+func (m *MockRepository) FindAndUpdateUser(ctx context.Context, filters, update bson.M) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAndUpdateUser", ctx, filters, update)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAndUpdateUser indicates an expected call of FindAndUpdateUser.
+func (mr *MockRepositoryMockRecorder) FindAndUpdateUser(ctx, filters, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAndUpdateUser", reflect.TypeOf((*MockRepository)(nil).FindAndUpdateUser), ctx, filters, update)
+}
+
 // GetUser mocks base method.
 func (m *MockRepository) GetUser(ctx context.Context, filters bson.M) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -68,18 +98,32 @@ func (mr *MockRepositoryMockRecorder) GetUser(ctx, filters interface{}) *gomock.
 }
 
 // GetUsers mocks base method.
-func (m *MockRepository) GetUsers(ctx context.Context, filters bson.M) ([]*user.User, error) {
+func (m *MockRepository) GetUsers(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUsers", ctx, filters)
+	ret := m.ctrl.Call(m, "GetUsers", ctx, filters, opts)
 	ret0, _ := ret[0].([]*user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUsers indicates an expected call of GetUsers.
-func (mr *MockRepositoryMockRecorder) GetUsers(ctx, filters interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetUsers(ctx, filters, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockRepository)(nil).GetUsers), ctx, filters, opts)
+}
+
+// RemoveRoom mocks base method.
+func (m *MockRepository) RemoveRoom(ctx context.Context, id primitive.ObjectID, roomName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRoom", ctx, id, roomName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRoom indicates an expected call of RemoveRoom.
+func (mr *MockRepositoryMockRecorder) RemoveRoom(ctx, id, roomName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockRepository)(nil).GetUsers), ctx, filters)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoom", reflect.TypeOf((*MockRepository)(nil).RemoveRoom), ctx, id, roomName)
 }
 
 // UpdateUser mocks base method.
@@ -95,3 +139,18 @@ func (mr *MockRepositoryMockRecorder) UpdateUser(ctx, user interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockRepository)(nil).UpdateUser), ctx, user)
 }
+
+// UpdateUsers mocks base method.
+func (m *MockRepository) UpdateUsers(ctx context.Context, filters, update bson.M) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUsers", ctx, filters, update)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUsers indicates an expected call of UpdateUsers.
+func (mr *MockRepositoryMockRecorder) UpdateUsers(ctx, filters, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsers", reflect.TypeOf((*MockRepository)(nil).UpdateUsers), ctx, filters, update)
+}
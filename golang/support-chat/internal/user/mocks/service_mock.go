@@ -6,8 +6,8 @@ package mock_user
 
 import (
 	context "context"
-	user "support-chat/internal/user"
 	reflect "reflect"
+	user "support-chat/internal/user"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +35,63 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// AddRoom mocks base method.
+func (m *MockService) AddRoom(ctx context.Context, userDTO *user.DTO, roomName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRoom", ctx, userDTO, roomName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRoom indicates an expected call of AddRoom.
+func (mr *MockServiceMockRecorder) AddRoom(ctx, userDTO, roomName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRoom", reflect.TypeOf((*MockService)(nil).AddRoom), ctx, userDTO, roomName)
+}
+
+// BackfillVerified mocks base method.
+func (m *MockService) BackfillVerified(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BackfillVerified", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BackfillVerified indicates an expected call of BackfillVerified.
+func (mr *MockServiceMockRecorder) BackfillVerified(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BackfillVerified", reflect.TypeOf((*MockService)(nil).BackfillVerified), ctx)
+}
+
+// CheckTotp mocks base method.
+func (m *MockService) CheckTotp(ctx context.Context, id, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckTotp", ctx, id, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckTotp indicates an expected call of CheckTotp.
+func (mr *MockServiceMockRecorder) CheckTotp(ctx, id, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckTotp", reflect.TypeOf((*MockService)(nil).CheckTotp), ctx, id, code)
+}
+
+// ConfirmTotp mocks base method.
+func (m *MockService) ConfirmTotp(ctx context.Context, id, code string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTotp", ctx, id, code)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmTotp indicates an expected call of ConfirmTotp.
+func (mr *MockServiceMockRecorder) ConfirmTotp(ctx, id, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTotp", reflect.TypeOf((*MockService)(nil).ConfirmTotp), ctx, id, code)
+}
+
 // CreateUser mocks base method.
 func (m *MockService) CreateUser(ctx context.Context, email, name, password string) (*user.DTO, error) {
 	m.ctrl.T.Helper()
@@ -50,19 +107,19 @@ func (mr *MockServiceMockRecorder) CreateUser(ctx, email, name, password interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), ctx, email, name, password)
 }
 
-// GetFreeUser mocks base method.
-func (m *MockService) GetFreeUser(ctx context.Context) (*user.DTO, error) {
+// DisableTotp mocks base method.
+func (m *MockService) DisableTotp(ctx context.Context, id string) (*user.DTO, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFreeUser", ctx)
+	ret := m.ctrl.Call(m, "DisableTotp", ctx, id)
 	ret0, _ := ret[0].(*user.DTO)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetFreeUser indicates an expected call of GetFreeUser.
-func (mr *MockServiceMockRecorder) GetFreeUser(ctx interface{}) *gomock.Call {
+// DisableTotp indicates an expected call of DisableTotp.
+func (mr *MockServiceMockRecorder) DisableTotp(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFreeUser", reflect.TypeOf((*MockService)(nil).GetFreeUser), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTotp", reflect.TypeOf((*MockService)(nil).DisableTotp), ctx, id)
 }
 
 // GetUserByEmail mocks base method.
@@ -95,6 +152,198 @@ func (mr *MockServiceMockRecorder) GetUserById(ctx, id, withPassword interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserById", reflect.TypeOf((*MockService)(nil).GetUserById), ctx, id, withPassword)
 }
 
+// GetUserByIdentity mocks base method.
+func (m *MockService) GetUserByIdentity(ctx context.Context, issuer, subject string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", ctx, issuer, subject)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockServiceMockRecorder) GetUserByIdentity(ctx, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockService)(nil).GetUserByIdentity), ctx, issuer, subject)
+}
+
+// GetUsersByRoom mocks base method.
+func (m *MockService) GetUsersByRoom(ctx context.Context, roomName string, withPassword bool) ([]*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByRoom", ctx, roomName, withPassword)
+	ret0, _ := ret[0].([]*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByRoom indicates an expected call of GetUsersByRoom.
+func (mr *MockServiceMockRecorder) GetUsersByRoom(ctx, roomName, withPassword interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByRoom", reflect.TypeOf((*MockService)(nil).GetUsersByRoom), ctx, roomName, withPassword)
+}
+
+// HasCapacity mocks base method.
+func (m *MockService) HasCapacity(userDTO *user.DTO) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasCapacity", userDTO)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// HasCapacity indicates an expected call of HasCapacity.
+func (mr *MockServiceMockRecorder) HasCapacity(userDTO interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasCapacity", reflect.TypeOf((*MockService)(nil).HasCapacity), userDTO)
+}
+
+// LinkIdentity mocks base method.
+func (m *MockService) LinkIdentity(ctx context.Context, id, issuer, subject string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkIdentity", ctx, id, issuer, subject)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkIdentity indicates an expected call of LinkIdentity.
+func (mr *MockServiceMockRecorder) LinkIdentity(ctx, id, issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkIdentity", reflect.TypeOf((*MockService)(nil).LinkIdentity), ctx, id, issuer, subject)
+}
+
+// RemoveRoom mocks base method.
+func (m *MockService) RemoveRoom(ctx context.Context, id, roomName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveRoom", ctx, id, roomName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveRoom indicates an expected call of RemoveRoom.
+func (mr *MockServiceMockRecorder) RemoveRoom(ctx, id, roomName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoom", reflect.TypeOf((*MockService)(nil).RemoveRoom), ctx, id, roomName)
+}
+
+// SearchUsers mocks base method.
+func (m *MockService) SearchUsers(ctx context.Context, query *user.SearchQuery) ([]*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, query)
+	ret0, _ := ret[0].([]*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockServiceMockRecorder) SearchUsers(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockService)(nil).SearchUsers), ctx, query)
+}
+
+// SetAdmin mocks base method.
+func (m *MockService) SetAdmin(ctx context.Context, id string, admin bool) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAdmin", ctx, id, admin)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetAdmin indicates an expected call of SetAdmin.
+func (mr *MockServiceMockRecorder) SetAdmin(ctx, id, admin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAdmin", reflect.TypeOf((*MockService)(nil).SetAdmin), ctx, id, admin)
+}
+
+// SetDisabled mocks base method.
+func (m *MockService) SetDisabled(ctx context.Context, id string, disabled bool) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDisabled", ctx, id, disabled)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetDisabled indicates an expected call of SetDisabled.
+func (mr *MockServiceMockRecorder) SetDisabled(ctx, id, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDisabled", reflect.TypeOf((*MockService)(nil).SetDisabled), ctx, id, disabled)
+}
+
+// SetPassword mocks base method.
+func (m *MockService) SetPassword(ctx context.Context, id, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPassword indicates an expected call of SetPassword.
+func (mr *MockServiceMockRecorder) SetPassword(ctx, id, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPassword", reflect.TypeOf((*MockService)(nil).SetPassword), ctx, id, password)
+}
+
+// SetRoomName mocks base method.
+func (m *MockService) SetRoomName(ctx context.Context, id string, roomName *string) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRoomName", ctx, id, roomName)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRoomName indicates an expected call of SetRoomName.
+func (mr *MockServiceMockRecorder) SetRoomName(ctx, id, roomName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRoomName", reflect.TypeOf((*MockService)(nil).SetRoomName), ctx, id, roomName)
+}
+
+// SetSupport mocks base method.
+func (m *MockService) SetSupport(ctx context.Context, id string, support bool) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSupport", ctx, id, support)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetSupport indicates an expected call of SetSupport.
+func (mr *MockServiceMockRecorder) SetSupport(ctx, id, support interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSupport", reflect.TypeOf((*MockService)(nil).SetSupport), ctx, id, support)
+}
+
+// SetVerified mocks base method.
+func (m *MockService) SetVerified(ctx context.Context, id string, verified bool) (*user.DTO, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVerified", ctx, id, verified)
+	ret0, _ := ret[0].(*user.DTO)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetVerified indicates an expected call of SetVerified.
+func (mr *MockServiceMockRecorder) SetVerified(ctx, id, verified interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVerified", reflect.TypeOf((*MockService)(nil).SetVerified), ctx, id, verified)
+}
+
+// StartTotp mocks base method.
+func (m *MockService) StartTotp(ctx context.Context, id string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartTotp", ctx, id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartTotp indicates an expected call of StartTotp.
+func (mr *MockServiceMockRecorder) StartTotp(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartTotp", reflect.TypeOf((*MockService)(nil).StartTotp), ctx, id)
+}
+
 // UpdateUser mocks base method.
 func (m *MockService) UpdateUser(ctx context.Context, userDTO *user.DTO) error {
 	m.ctrl.T.Helper()
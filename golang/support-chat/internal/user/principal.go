@@ -0,0 +1,24 @@
+package user
+
+import (
+	"context"
+	"support-chat/pkg/jwt"
+)
+
+// Principal is the authenticated caller of the request, the auth middleware
+// stores it in the context.
+type Principal struct {
+	User    DTO
+	Payload *jwt.Payload
+}
+
+type contextKey string
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey("principal"), p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey("principal")).(*Principal)
+	return p, ok
+}
@@ -8,14 +8,19 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
+	"time"
 )
 
 //go:generate mockgen -source=repository.go -destination=mocks/repository_mock.go
 type Repository interface {
 	GetUser(ctx context.Context, filters bson.M) (*User, error)
-	GetUsers(ctx context.Context, filters bson.M) ([]*User, error)
+	GetUsers(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*User, error)
+	FindAndUpdateUser(ctx context.Context, filters, update bson.M) (*User, error)
 	CreateUser(ctx context.Context, user *User) (string, error)
 	UpdateUser(ctx context.Context, user *User) error
+	UpdateUsers(ctx context.Context, filters, update bson.M) (int64, error)
+	AddRoom(ctx context.Context, id primitive.ObjectID, roomName string, capacity int) error
+	RemoveRoom(ctx context.Context, id primitive.ObjectID, roomName string) error
 }
 
 type repository struct {
@@ -54,10 +59,10 @@ func (r *repository) GetUser(ctx context.Context, filters bson.M) (*User, error)
 	return &user, nil
 }
 
-func (r *repository) GetUsers(ctx context.Context, filters bson.M) ([]*User, error) {
+func (r *repository) GetUsers(ctx context.Context, filters bson.M, opts *options.FindOptions) ([]*User, error) {
 	var users []*User
 
-	cursor, err := r.db.Database(r.dbName).Collection("users").Find(ctx, filters)
+	cursor, err := r.db.Database(r.dbName).Collection("users").Find(ctx, filters, opts)
 	if err != nil {
 		r.logger.Errorf("failed to get users: %v", err)
 		return nil, ErrFailedFindFreeUsers
@@ -77,13 +82,13 @@ func (r *repository) CreateUser(ctx context.Context, user *User) (string, error)
 		Options: options.Index().SetUnique(true),
 	}
 
-	_, err := r.db.Database(r.dbName).Collection("user").Indexes().CreateOne(ctx, mod)
+	_, err := r.db.Database(r.dbName).Collection("users").Indexes().CreateOne(ctx, mod)
 	if err != nil {
 		r.logger.Errorf("failed to create user index: %v", err)
 		return "", err
 	}
 
-	_, err = r.db.Database(r.dbName).Collection("user").InsertOne(ctx, user)
+	_, err = r.db.Database(r.dbName).Collection("users").InsertOne(ctx, user)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			r.logger.Errorf("failed to insert user data to db due to duplicate error: %v", err)
@@ -98,7 +103,7 @@ func (r *repository) CreateUser(ctx context.Context, user *User) (string, error)
 }
 
 func (r *repository) UpdateUser(ctx context.Context, user *User) error {
-	_, err := r.db.Database(r.dbName).Collection("user").UpdateOne(ctx, bson.M{"email": user.Email},
+	_, err := r.db.Database(r.dbName).Collection("users").UpdateOne(ctx, bson.M{"email": user.Email},
 		bson.D{primitive.E{Key: "$set", Value: user}})
 
 	if err != nil {
@@ -108,3 +113,69 @@ func (r *repository) UpdateUser(ctx context.Context, user *User) error {
 
 	return nil
 }
+
+// UpdateUsers applies update to every user matching filters and returns how
+// many were changed.
+func (r *repository) UpdateUsers(ctx context.Context, filters, update bson.M) (int64, error) {
+	res, err := r.db.Database(r.dbName).Collection("users").UpdateMany(ctx, filters, update)
+	if err != nil {
+		r.logger.Errorf("failed to update users %v", err)
+		return 0, ErrFailedUpdateUser
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// FindAndUpdateUser atomically applies update to the user matching filters and
+// returns the document as it is after the update.
+func (r *repository) FindAndUpdateUser(ctx context.Context, filters, update bson.M) (*User, error) {
+	var user User
+
+	err := r.db.Database(r.dbName).Collection("users").
+		FindOneAndUpdate(ctx, filters, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).
+		Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+
+		r.logger.Errorf("failed to find and update user %v", err)
+		return nil, ErrFailedUpdateUser
+	}
+
+	return &user, nil
+}
+
+// AddRoom adds the room to the user's active rooms only while the user has
+// fewer than capacity rooms, so concurrent assignments can't overfill an agent.
+func (r *repository) AddRoom(ctx context.Context, id primitive.ObjectID, roomName string, capacity int) error {
+	filters := bson.M{
+		"_id":   id,
+		"rooms": bson.M{"$ne": roomName},
+		"$expr": bson.M{"$lt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$rooms", bson.A{}}}}, capacity}},
+	}
+
+	res, err := r.db.Database(r.dbName).Collection("users").UpdateOne(ctx, filters,
+		bson.M{"$push": bson.M{"rooms": roomName}, "$set": bson.M{"updated_at": time.Now()}})
+	if err != nil {
+		r.logger.Errorf("failed to add room to user %v", err)
+		return ErrFailedUpdateUser
+	}
+
+	if res.MatchedCount == 0 {
+		return ErrNoCapacity
+	}
+
+	return nil
+}
+
+func (r *repository) RemoveRoom(ctx context.Context, id primitive.ObjectID, roomName string) error {
+	_, err := r.db.Database(r.dbName).Collection("users").UpdateOne(ctx, bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"rooms": roomName}, "$set": bson.M{"updated_at": time.Now()}})
+	if err != nil {
+		r.logger.Errorf("failed to remove room from user %v", err)
+		return ErrFailedUpdateUser
+	}
+
+	return nil
+}
@@ -3,27 +3,56 @@ package user
 import (
 	"context"
 	"errors"
+	"regexp"
+	"support-chat/pkg/totp"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/service_mock.go
 type Service interface {
 	GetUserById(ctx context.Context, id string, withPassword bool) (*DTO, error)
 	GetUserByEmail(ctx context.Context, email string, withPassword bool) (*DTO, error)
-	GetFreeUser(ctx context.Context) (*DTO, error)
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*DTO, error)
+	GetUsersByRoom(ctx context.Context, roomName string, withPassword bool) ([]*DTO, error)
 	CreateUser(ctx context.Context, email, name, password string) (*DTO, error)
 	UpdateUser(ctx context.Context, userDTO *DTO) error
+	AddRoom(ctx context.Context, userDTO *DTO, roomName string) error
+	RemoveRoom(ctx context.Context, id, roomName string) error
+	HasCapacity(userDTO *DTO) bool
+	SearchUsers(ctx context.Context, query *SearchQuery) ([]*DTO, error)
+	SetSupport(ctx context.Context, id string, support bool) (*DTO, error)
+	SetAdmin(ctx context.Context, id string, admin bool) (*DTO, error)
+	SetDisabled(ctx context.Context, id string, disabled bool) (*DTO, error)
+	SetPassword(ctx context.Context, id, password string) error
+	SetVerified(ctx context.Context, id string, verified bool) (*DTO, error)
+	BackfillVerified(ctx context.Context) error
+	SetRoomName(ctx context.Context, id string, roomName *string) (*DTO, error)
+	LinkIdentity(ctx context.Context, id, issuer, subject string) (*DTO, error)
+	StartTotp(ctx context.Context, id string) (string, error)
+	ConfirmTotp(ctx context.Context, id, code string) ([]string, error)
+	CheckTotp(ctx context.Context, id, code string) error
+	DisableTotp(ctx context.Context, id string) (*DTO, error)
 }
 
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
 type service struct {
-	repository Repository
-	logger     *zap.SugaredLogger
-	salt       int
+	repository      Repository
+	logger          *zap.SugaredLogger
+	salt            int
+	defaultCapacity int
 }
 
-func NewService(repository Repository, logger *zap.SugaredLogger, salt *int) (Service, error) {
+func NewService(repository Repository, logger *zap.SugaredLogger, salt, defaultCapacity *int) (Service, error) {
 	if repository == nil {
 		return nil, errors.New("[user_service] invalid repository")
 	}
@@ -33,8 +62,11 @@ func NewService(repository Repository, logger *zap.SugaredLogger, salt *int) (Se
 	if salt == nil {
 		return nil, errors.New("[user_service] invalid salt")
 	}
+	if defaultCapacity == nil {
+		return nil, errors.New("[user_service] invalid default capacity")
+	}
 
-	return &service{repository: repository, logger: logger, salt: *salt}, nil
+	return &service{repository: repository, logger: logger, salt: *salt, defaultCapacity: *defaultCapacity}, nil
 }
 
 func (s *service) GetUserById(ctx context.Context, id string, withPassword bool) (*DTO, error) {
@@ -70,53 +102,36 @@ func (s *service) GetUserByEmail(ctx context.Context, email string, withPassword
 	return MapToDTO(user), nil
 }
 
-func (s *service) GetFreeUser(ctx context.Context) (*DTO, error) {
-	users, err := s.repository.GetUsers(ctx, bson.M{"support": bson.M{"$eq": false}, "free": bson.M{"$eq": true}, "roomName": bson.M{"$ne": nil}})
+// GetUserByIdentity returns the user linked to the account of the identity
+// provider, without the password.
+func (s *service) GetUserByIdentity(ctx context.Context, issuer, subject string) (*DTO, error) {
+	user, err := s.repository.GetUser(ctx, bson.M{"identity.issuer": issuer, "identity.subject": subject})
 	if err != nil {
 		s.logger.Errorf("failed to get user: %v", err)
 		return nil, err
 	}
 
-	if len(users) == 0 {
-		return nil, ErrNoUsersYet
-	}
-
-	user := users[0]
-
-	userCtxValue := ctx.Value(contextKey("user"))
-	if userCtxValue == nil {
-		s.logger.Error("Not authenticated")
-		return nil, errors.New("not authenticated")
-	}
+	user.RemovePassword()
 
-	ctxUserDto := userCtxValue.(DTO)
+	return MapToDTO(user), nil
+}
 
-	ctxUserEntity, err := MapToEntity(&ctxUserDto)
+func (s *service) GetUsersByRoom(ctx context.Context, roomName string, withPassword bool) ([]*DTO, error) {
+	users, err := s.repository.GetUsers(ctx, bson.M{"$or": bson.A{bson.M{"roomName": roomName}, bson.M{"rooms": roomName}}}, nil)
 	if err != nil {
-		s.logger.Error(err)
+		s.logger.Errorf("failed to get users: %v", err)
 		return nil, err
 	}
 
-	ctxUserEntity.SetRoom(user.RoomName)
-
-	// update support
-	err = s.UpdateUser(ctx, MapToDTO(ctxUserEntity))
-	if err != nil {
-		s.logger.Error(err)
-		return nil, ErrFailedUpdateUser
+	var dtos []*DTO
+	for _, user := range users {
+		if !withPassword {
+			user.RemovePassword()
+		}
+		dtos = append(dtos, MapToDTO(user))
 	}
 
-	// update user
-	user.SetFreeStatus(false)
-	err = s.UpdateUser(ctx, MapToDTO(user))
-	if err != nil {
-		s.logger.Error(err)
-		return nil, ErrFailedUpdateUser
-	}
-
-	user.RemovePassword()
-
-	return MapToDTO(user), nil
+	return dtos, nil
 }
 
 func (s *service) CreateUser(ctx context.Context, email, name, password string) (*DTO, error) {
@@ -149,3 +164,337 @@ func (s *service) UpdateUser(ctx context.Context, userDTO *DTO) error {
 	}
 	return nil
 }
+
+func (s *service) AddRoom(ctx context.Context, userDTO *DTO, roomName string) error {
+	objId, err := primitive.ObjectIDFromHex(userDTO.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repository.AddRoom(ctx, objId, roomName, s.capacity(userDTO)); err != nil {
+		s.logger.Errorf("failed to add room to user: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *service) RemoveRoom(ctx context.Context, id, roomName string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repository.RemoveRoom(ctx, objId, roomName); err != nil {
+		s.logger.Errorf("failed to remove room from user: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *service) HasCapacity(userDTO *DTO) bool {
+	return len(userDTO.Rooms) < s.capacity(userDTO)
+}
+
+func (s *service) capacity(userDTO *DTO) int {
+	if userDTO.Capacity > 0 {
+		return userDTO.Capacity
+	}
+	return s.defaultCapacity
+}
+
+// SearchUsers lists users whose name or email contains the query, narrowed by
+// the flags set in it. Passwords are never returned.
+func (s *service) SearchUsers(ctx context.Context, query *SearchQuery) ([]*DTO, error) {
+	filters := bson.M{}
+	if query.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Query), Options: "i"}
+		filters["$or"] = bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}}
+	}
+	if query.Support != nil {
+		filters["support"] = *query.Support
+	}
+	if query.Admin != nil {
+		filters["admin"] = *query.Admin
+	}
+	if query.Disabled != nil {
+		filters["disabled"] = *query.Disabled
+	}
+
+	limit := query.Limit
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+
+	users, err := s.repository.GetUsers(ctx, filters,
+		options.Find().SetSort(bson.D{{Key: "email", Value: 1}}).SetSkip(query.Skip).SetLimit(limit))
+	if err != nil {
+		s.logger.Errorf("failed to search users: %v", err)
+		return nil, err
+	}
+
+	dtos := make([]*DTO, 0, len(users))
+	for _, user := range users {
+		user.RemovePassword()
+		dtos = append(dtos, MapToDTO(user))
+	}
+
+	return dtos, nil
+}
+
+func (s *service) SetSupport(ctx context.Context, id string, support bool) (*DTO, error) {
+	return s.setFlag(ctx, id, "support", support)
+}
+
+func (s *service) SetAdmin(ctx context.Context, id string, admin bool) (*DTO, error) {
+	return s.setFlag(ctx, id, "admin", admin)
+}
+
+func (s *service) SetDisabled(ctx context.Context, id string, disabled bool) (*DTO, error) {
+	return s.setFlag(ctx, id, "disabled", disabled)
+}
+
+func (s *service) SetVerified(ctx context.Context, id string, verified bool) (*DTO, error) {
+	return s.setFlag(ctx, id, "verified", verified)
+}
+
+// SetRoomName sets the room of the customer, nil once it is closed.
+func (s *service) SetRoomName(ctx context.Context, id string, roomName *string) (*DTO, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.FindAndUpdateUser(ctx, bson.M{"_id": objId},
+		bson.M{"$set": bson.M{"roomName": roomName, "updated_at": time.Now()}})
+	if err != nil {
+		s.logger.Errorf("failed to set user room: %v", err)
+		return nil, err
+	}
+
+	user.RemovePassword()
+
+	return MapToDTO(user), nil
+}
+
+// LinkIdentity links the account of the identity provider to the user. A user
+// only has one, linking another fails with ErrIdentityLinked.
+func (s *service) LinkIdentity(ctx context.Context, id, issuer, subject string) (*DTO, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.FindAndUpdateUser(ctx, bson.M{"_id": objId, "identity": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"identity": &Identity{Issuer: issuer, Subject: subject}, "updated_at": time.Now()}})
+	if err == ErrNotFound {
+		return nil, ErrIdentityLinked
+	}
+	if err != nil {
+		s.logger.Errorf("failed to link user identity: %v", err)
+		return nil, err
+	}
+
+	user.RemovePassword()
+
+	return MapToDTO(user), nil
+}
+
+// BackfillVerified marks the users registered before email verification
+// existed as verified, their documents have no verified field and would
+// otherwise get the unverified role. New users always store the field, so
+// running it again on every start changes nothing.
+func (s *service) BackfillVerified(ctx context.Context) error {
+	n, err := s.repository.UpdateUsers(ctx, bson.M{"verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"verified": true, "updated_at": time.Now()}})
+	if err != nil {
+		s.logger.Errorf("failed to backfill verified users: %v", err)
+		return err
+	}
+
+	if n > 0 {
+		s.logger.Infof("marked %d users registered before email verification as verified", n)
+	}
+
+	return nil
+}
+
+// SetPassword hashes the new password and replaces the stored one.
+func (s *service) SetPassword(ctx context.Context, id, password string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), s.salt)
+	if err != nil {
+		s.logger.Errorf("failed to hash password %v", err)
+		return ErrInvalidPassword
+	}
+
+	_, err = s.repository.FindAndUpdateUser(ctx, bson.M{"_id": objId},
+		bson.M{"$set": bson.M{"password": string(hashedPassword), "updated_at": time.Now()}})
+	if err != nil {
+		s.logger.Errorf("failed to set user password: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// StartTotp generates a new secret for the user, which only becomes the second
+// factor once ConfirmTotp accepted a code of it. Starting again replaces an
+// unconfirmed secret.
+func (s *service) StartTotp(ctx context.Context, id string) (string, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.logger.Errorf("failed to generate totp secret %v", err)
+		return "", ErrFailedUpdateUser
+	}
+
+	_, err = s.repository.FindAndUpdateUser(ctx, bson.M{"_id": objId, "totp.enabled": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"totp": &Totp{Secret: secret, RecoveryCodes: []string{}}, "updated_at": time.Now()}})
+	if err == ErrNotFound {
+		return "", ErrTotpEnabled
+	}
+	if err != nil {
+		s.logger.Errorf("failed to start totp: %v", err)
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// ConfirmTotp enables the second factor when code matches the started secret
+// and returns the recovery codes. They are only stored hashed, so this is the
+// one time the user sees them.
+func (s *service) ConfirmTotp(ctx context.Context, id, code string) ([]string, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.GetUser(ctx, bson.M{"_id": objId})
+	if err != nil {
+		s.logger.Errorf("failed to get user: %v", err)
+		return nil, err
+	}
+	if user.Totp == nil {
+		return nil, ErrTotpNotEnrolled
+	}
+	if user.Totp.Enabled {
+		return nil, ErrTotpEnabled
+	}
+
+	step, ok := totp.Match(user.Totp.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTotp
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		s.logger.Errorf("failed to generate recovery codes %v", err)
+		return nil, ErrFailedUpdateUser
+	}
+
+	// the secret is part of the filter, a restarted enrollment isn't confirmed
+	// by a code of the old secret
+	_, err = s.repository.FindAndUpdateUser(ctx,
+		bson.M{"_id": objId, "totp.secret": user.Totp.Secret, "totp.enabled": false},
+		bson.M{"$set": bson.M{
+			"totp.enabled":        true,
+			"totp.recovery_codes": hashes,
+			"totp.last_step":      step,
+			"updated_at":          time.Now(),
+		}})
+	if err == ErrNotFound {
+		return nil, ErrTotpNotEnrolled
+	}
+	if err != nil {
+		s.logger.Errorf("failed to confirm totp: %v", err)
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CheckTotp accepts a code of the authenticator app or one of the recovery
+// codes. Both can only be used once.
+func (s *service) CheckTotp(ctx context.Context, id, code string) error {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repository.GetUser(ctx, bson.M{"_id": objId})
+	if err != nil {
+		s.logger.Errorf("failed to get user: %v", err)
+		return err
+	}
+	if user.Totp == nil || !user.Totp.Enabled {
+		return ErrTotpNotEnrolled
+	}
+
+	var filters, update bson.M
+	if step, ok := totp.Match(user.Totp.Secret, code, time.Now()); ok {
+		filters = bson.M{"_id": objId, "totp.last_step": bson.M{"$lt": step}}
+		update = bson.M{"$set": bson.M{"totp.last_step": step}}
+	} else {
+		hash := hashRecoveryCode(code)
+		filters = bson.M{"_id": objId, "totp.recovery_codes": hash}
+		update = bson.M{"$pull": bson.M{"totp.recovery_codes": hash}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+
+	_, err = s.repository.FindAndUpdateUser(ctx, filters, update)
+	if err == ErrNotFound {
+		return ErrInvalidTotp
+	}
+	if err != nil {
+		s.logger.Errorf("failed to check totp: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) DisableTotp(ctx context.Context, id string) (*DTO, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.FindAndUpdateUser(ctx, bson.M{"_id": objId},
+		bson.M{"$unset": bson.M{"totp": ""}, "$set": bson.M{"updated_at": time.Now()}})
+	if err != nil {
+		s.logger.Errorf("failed to disable totp: %v", err)
+		return nil, err
+	}
+
+	user.RemovePassword()
+
+	return MapToDTO(user), nil
+}
+
+// setFlag updates a single flag of the user, so the rest of the document
+// isn't overwritten by a stale copy.
+func (s *service) setFlag(ctx context.Context, id, flag string, value bool) (*DTO, error) {
+	objId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repository.FindAndUpdateUser(ctx, bson.M{"_id": objId},
+		bson.M{"$set": bson.M{flag: value, "updated_at": time.Now()}})
+	if err != nil {
+		s.logger.Errorf("failed to set user %v: %v", flag, err)
+		return nil, err
+	}
+
+	user.RemovePassword()
+
+	return MapToDTO(user), nil
+}
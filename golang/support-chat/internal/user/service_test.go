@@ -5,6 +5,7 @@ import (
 	"support-chat/internal/user"
 	mock_user "support-chat/internal/user/mocks"
 	"support-chat/pkg/logger"
+	"support-chat/pkg/totp"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,7 @@ import (
 	"go.uber.org/zap"
 
 	"testing"
+	"time"
 )
 
 func TestNewService(t *testing.T) {
@@ -20,12 +22,14 @@ func TestNewService(t *testing.T) {
 	defer controller.Finish()
 
 	salt := 10
+	capacity := 3
 
 	tests := []struct {
 		name       string
 		repository user.Repository
 		logger     *zap.SugaredLogger
 		salt       *int
+		capacity   *int
 		expect     func(*testing.T, user.Service, error)
 	}{
 		{
@@ -33,6 +37,7 @@ func TestNewService(t *testing.T) {
 			repository: mock_user.NewMockRepository(controller),
 			logger:     &zap.SugaredLogger{},
 			salt:       &salt,
+			capacity:   &capacity,
 			expect: func(t *testing.T, s user.Service, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
@@ -43,6 +48,7 @@ func TestNewService(t *testing.T) {
 			repository: nil,
 			logger:     &zap.SugaredLogger{},
 			salt:       &salt,
+			capacity:   &capacity,
 			expect: func(t *testing.T, s user.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -54,6 +60,7 @@ func TestNewService(t *testing.T) {
 			repository: mock_user.NewMockRepository(controller),
 			logger:     nil,
 			salt:       &salt,
+			capacity:   &capacity,
 			expect: func(t *testing.T, s user.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -65,17 +72,30 @@ func TestNewService(t *testing.T) {
 			repository: mock_user.NewMockRepository(controller),
 			logger:     &zap.SugaredLogger{},
 			salt:       nil,
+			capacity:   &capacity,
 			expect: func(t *testing.T, s user.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, "[user_service] invalid salt")
 			},
 		},
+		{
+			name:       "should return invalid default capacity",
+			repository: mock_user.NewMockRepository(controller),
+			logger:     &zap.SugaredLogger{},
+			salt:       &salt,
+			capacity:   nil,
+			expect: func(t *testing.T, s user.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[user_service] invalid default capacity")
+			},
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := user.NewService(tc.repository, tc.logger, tc.salt)
+			svc, err := user.NewService(tc.repository, tc.logger, tc.salt, tc.capacity)
 			tc.expect(t, svc, err)
 		})
 	}
@@ -87,11 +107,12 @@ func TestService_GetUserById(t *testing.T) {
 
 	mockRepo := mock_user.NewMockRepository(controller)
 	salt := 10
+	capacity := 3
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := user.NewService(mockRepo, zapLogger, &salt)
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
 
 	userEntity, _ := user.NewUser("email", "name", "password", &salt)
 	userDTO := user.MapToDTO(userEntity)
@@ -150,11 +171,12 @@ func TestService_GetUserByEmail(t *testing.T) {
 
 	mockRepo := mock_user.NewMockRepository(controller)
 	salt := 10
+	capacity := 3
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := user.NewService(mockRepo, zapLogger, &salt)
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
 
 	userEntity, _ := user.NewUser("email", "name", "password", &salt)
 	userDTO := user.MapToDTO(userEntity)
@@ -211,11 +233,12 @@ func TestService_CreateUser(t *testing.T) {
 
 	mockRepo := mock_user.NewMockRepository(controller)
 	salt := 10
+	capacity := 3
 
 	newLogger, _ := logger.NewLogger("development")
 	zapLogger, _ := newLogger.SetupZapLogger()
 
-	service, _ := user.NewService(mockRepo, zapLogger, &salt)
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
 
 	userEntity, _ := user.NewUser("email", "name", "password", &salt)
 	userDTO := user.MapToDTO(userEntity)
@@ -271,3 +294,591 @@ func TestService_CreateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestService_HasCapacity(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 10
+	capacity := 2
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	tests := []struct {
+		name   string
+		dto    *user.DTO
+		expect bool
+	}{
+		{
+			name:   "should have capacity below default",
+			dto:    &user.DTO{Rooms: []string{"a"}},
+			expect: true,
+		},
+		{
+			name:   "should be full at default",
+			dto:    &user.DTO{Rooms: []string{"a", "b"}},
+			expect: false,
+		},
+		{
+			name:   "should use own capacity",
+			dto:    &user.DTO{Rooms: []string{"a", "b"}, Capacity: 5},
+			expect: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, service.HasCapacity(tc.dto))
+		})
+	}
+}
+
+func TestService_SearchUsers(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 10
+	capacity := 2
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	support := true
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		query  *user.SearchQuery
+		setup  func(context.Context)
+		expect func(*testing.T, []*user.DTO, error)
+	}{
+		{
+			name:  "should search by name or email without passwords",
+			ctx:   context.Background(),
+			query: &user.SearchQuery{Query: "a.b", Support: &support},
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUsers(ctx, gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, filters bson.M, _ interface{}) ([]*user.User, error) {
+					pattern := primitive.Regex{Pattern: `a\.b`, Options: "i"}
+					assert.Equal(t, bson.A{bson.M{"name": pattern}, bson.M{"email": pattern}}, filters["$or"])
+					assert.Equal(t, true, filters["support"])
+					assert.NotContains(t, filters, "admin")
+					return []*user.User{{ID: primitive.NewObjectID(), Email: "a.b@c", Password: "hash", Support: true}}, nil
+				})
+			},
+			expect: func(t *testing.T, users []*user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, users, 1)
+				assert.Empty(t, users[0].Password)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			users, err := service.SearchUsers(tc.ctx, tc.query)
+			tc.expect(t, users, err)
+		})
+	}
+}
+
+func TestService_SetPassword(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	id := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		id     string
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should store hashed password",
+			ctx:  context.Background(),
+			id:   id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, update bson.M) (*user.User, error) {
+						hashed := update["$set"].(bson.M)["password"].(string)
+						ok, _ := (&user.User{Password: hashed}).CheckPassword("Password1")
+						assert.True(t, ok)
+						return &user.User{ID: id}, nil
+					})
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return user not found",
+			ctx:  context.Background(),
+			id:   id.Hex(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id}, gomock.Any()).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrNotFound, err)
+			},
+		},
+		{
+			name:  "should return invalid id",
+			ctx:   context.Background(),
+			id:    "id",
+			setup: func(ctx context.Context) {},
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.SetPassword(tc.ctx, tc.id, "Password1")
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_SetRoomName(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	id := primitive.NewObjectID()
+	roomName := "room"
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		roomName *string
+		setup    func(context.Context)
+		expect   func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name:     "should only set room",
+			ctx:      context.Background(),
+			roomName: &roomName,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, update bson.M) (*user.User, error) {
+						set := update["$set"].(bson.M)
+						assert.Len(t, set, 2)
+						assert.Equal(t, &roomName, set["roomName"])
+						return &user.User{ID: id, RoomName: &roomName}, nil
+					})
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, &roomName, dto.RoomName)
+			},
+		},
+		{
+			name: "should return user not found",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id}, gomock.Any()).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, user.ErrNotFound, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.SetRoomName(tc.ctx, id.Hex(), tc.roomName)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_GetUserByIdentity(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	filters := bson.M{"identity.issuer": "https://idp.example.com", "identity.subject": "42"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name: "should return user without password",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				userEntity, _ := user.NewUser("email", "name", "password", &salt)
+				mockRepo.EXPECT().GetUser(ctx, filters).Return(userEntity, nil)
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "email", dto.Email)
+				assert.Empty(t, dto.Password)
+			},
+		},
+		{
+			name: "should return not found",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, filters).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, user.ErrNotFound, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.GetUserByIdentity(tc.ctx, "https://idp.example.com", "42")
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_LinkIdentity(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	id := primitive.NewObjectID()
+	filters := bson.M{"_id": id, "identity": bson.M{"$exists": false}}
+	identity := &user.Identity{Issuer: "https://idp.example.com", Subject: "42"}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, *user.DTO, error)
+	}{
+		{
+			name: "should link identity",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, filters, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, update bson.M) (*user.User, error) {
+						set := update["$set"].(bson.M)
+						assert.Equal(t, identity, set["identity"])
+						return &user.User{ID: id, Identity: identity}, nil
+					})
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, id.Hex(), dto.ID)
+			},
+		},
+		{
+			name: "should fail when user has an identity",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, filters, gomock.Any()).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, user.ErrIdentityLinked, err)
+			},
+		},
+		{
+			name: "should return update error",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, filters, gomock.Any()).Return(nil, user.ErrFailedUpdateUser)
+			},
+			expect: func(t *testing.T, dto *user.DTO, err error) {
+				assert.Nil(t, dto)
+				assert.Equal(t, user.ErrFailedUpdateUser, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			dto, err := service.LinkIdentity(tc.ctx, id.Hex(), identity.Issuer, identity.Subject)
+			tc.expect(t, dto, err)
+		})
+	}
+}
+
+func TestService_BackfillVerified(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	filters := bson.M{"verified": bson.M{"$exists": false}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should mark users without the field verified",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().UpdateUsers(ctx, filters, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, update bson.M) (int64, error) {
+						set := update["$set"].(bson.M)
+						assert.Equal(t, true, set["verified"])
+						return 2, nil
+					})
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return failed update user",
+			ctx:  context.Background(),
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().UpdateUsers(ctx, filters, gomock.Any()).Return(int64(0), user.ErrFailedUpdateUser)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrFailedUpdateUser, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.BackfillVerified(tc.ctx)
+			tc.expect(t, err)
+		})
+	}
+}
+
+func TestService_ConfirmTotp(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	id := primitive.NewObjectID()
+	secret, _ := totp.GenerateSecret()
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		code   string
+		setup  func(context.Context)
+		expect func(*testing.T, []string, error)
+	}{
+		{
+			name: "should enable totp and return recovery codes",
+			ctx:  context.Background(),
+			code: code,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(&user.User{ID: id, Totp: &user.Totp{Secret: secret}}, nil)
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id, "totp.secret": secret, "totp.enabled": false}, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _, update bson.M) (*user.User, error) {
+						set := update["$set"].(bson.M)
+						assert.Equal(t, true, set["totp.enabled"])
+						assert.Len(t, set["totp.recovery_codes"], 10)
+						return &user.User{ID: id}, nil
+					})
+			},
+			expect: func(t *testing.T, codes []string, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, codes, 10)
+			},
+		},
+		{
+			name: "should return invalid code",
+			ctx:  context.Background(),
+			code: "000000",
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(&user.User{ID: id, Totp: &user.Totp{Secret: secret}}, nil)
+			},
+			expect: func(t *testing.T, codes []string, err error) {
+				assert.Nil(t, codes)
+				assert.Equal(t, user.ErrInvalidTotp, err)
+			},
+		},
+		{
+			name: "should return not enrolled",
+			ctx:  context.Background(),
+			code: code,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(&user.User{ID: id}, nil)
+			},
+			expect: func(t *testing.T, codes []string, err error) {
+				assert.Equal(t, user.ErrTotpNotEnrolled, err)
+			},
+		},
+		{
+			name: "should return already enabled",
+			ctx:  context.Background(),
+			code: code,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(&user.User{ID: id, Totp: &user.Totp{Secret: secret, Enabled: true}}, nil)
+			},
+			expect: func(t *testing.T, codes []string, err error) {
+				assert.Equal(t, user.ErrTotpEnabled, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			codes, err := service.ConfirmTotp(tc.ctx, id.Hex(), tc.code)
+			tc.expect(t, codes, err)
+		})
+	}
+}
+
+func TestService_CheckTotp(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockRepo := mock_user.NewMockRepository(controller)
+	salt := 4
+	capacity := 3
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	service, _ := user.NewService(mockRepo, zapLogger, &salt, &capacity)
+
+	id := primitive.NewObjectID()
+	secret, _ := totp.GenerateSecret()
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(secret, step)
+	enabled := &user.User{ID: id, Totp: &user.Totp{Secret: secret, Enabled: true}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		code   string
+		setup  func(context.Context)
+		expect func(*testing.T, error)
+	}{
+		{
+			name: "should accept app code",
+			ctx:  context.Background(),
+			code: code,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(enabled, nil)
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id, "totp.last_step": bson.M{"$lt": step}}, gomock.Any()).Return(enabled, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should reject used app code",
+			ctx:  context.Background(),
+			code: code,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(enabled, nil)
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, bson.M{"_id": id, "totp.last_step": bson.M{"$lt": step}}, gomock.Any()).Return(nil, user.ErrNotFound)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrInvalidTotp, err)
+			},
+		},
+		{
+			name: "should use up recovery code",
+			ctx:  context.Background(),
+			code: "ABCDE-FGHIJ",
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(enabled, nil)
+				mockRepo.EXPECT().FindAndUpdateUser(ctx, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, filters, update bson.M) (*user.User, error) {
+						assert.Equal(t, filters["totp.recovery_codes"], update["$pull"].(bson.M)["totp.recovery_codes"])
+						return enabled, nil
+					})
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return not enrolled",
+			ctx:  context.Background(),
+			code: code,
+			setup: func(ctx context.Context) {
+				mockRepo.EXPECT().GetUser(ctx, bson.M{"_id": id}).Return(&user.User{ID: id, Totp: &user.Totp{Secret: secret}}, nil)
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Equal(t, user.ErrTotpNotEnrolled, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(tc.ctx)
+			err := service.CheckTotp(tc.ctx, id.Hex(), tc.code)
+			tc.expect(t, err)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+// recoveryCodes is how many recovery codes are handed out on enrollment
+const recoveryCodes = 10
+
+// Totp is the RFC 6238 second factor of a user.
+type Totp struct {
+	Secret  string `bson:"secret"`
+	Enabled bool   `bson:"enabled"`
+	// RecoveryCodes are the sha256 hashes of the unused recovery codes
+	RecoveryCodes []string `bson:"recovery_codes"`
+	// LastStep is the time step of the last accepted code, codes of it and
+	// older steps are rejected
+	LastStep int64 `bson:"last_step"`
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns the codes to show to the user and their hashes to
+// store. The codes carry 50 bits each, a plain hash is enough for them.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodes)
+	hashes := make([]string, 0, recoveryCodes)
+	for i := 0; i < recoveryCodes; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		code = code[:5] + "-" + code[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and dashes, so codes can be typed as they
+// are read.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -13,13 +13,35 @@ type User struct {
 	Name     string             `bson:"name"`
 	Password string             `bson:"password"`
 	Support  bool               `bson:"support"`
+	Admin    bool               `bson:"admin"`
 	RoomName *string            `bson:"roomName"`
-	Free     bool               `bson:"free"`
+	Disabled bool               `bson:"disabled"`
+	Verified bool               `bson:"verified"`
+
+	// Totp is the second factor, nil until the user starts enrolling. It is
+	// only changed by its own updates, so UpdateUser leaves it alone.
+	Totp *Totp `bson:"totp,omitempty"`
+
+	// Identity is the account of the identity provider the user logs in with,
+	// nil until one is linked. Like Totp only its own update changes it.
+	Identity *Identity `bson:"identity,omitempty"`
+
+	// Rooms and Capacity are only used by support users. A zero capacity
+	// means the service default applies.
+	Rooms    []string `bson:"rooms"`
+	Capacity int      `bson:"capacity"`
 
 	CreatedAt time.Time `bson:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at"`
 }
 
+// Identity is an account of an OpenID Connect provider, the subject is only
+// unique per issuer.
+type Identity struct {
+	Issuer  string `bson:"issuer"`
+	Subject string `bson:"subject"`
+}
+
 func NewUser(email, name, password string, salt *int) (*User, error) {
 	if email == "" {
 		return nil, errors.New("[user] invalid email")
@@ -46,7 +68,8 @@ func NewUser(email, name, password string, salt *int) (*User, error) {
 		Password:  string(hashedPassword),
 		Support:   false,
 		RoomName:  nil,
-		Free:      true,
+		Rooms:     []string{},
+		Capacity:  0,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}, nil
@@ -57,16 +80,43 @@ func (s *User) SetName(name string) {
 	s.UpdatedAt = time.Now()
 }
 
-func (s *User) SetFreeStatus(status bool) {
-	s.Free = status
+func (s *User) SetRoom(roomName *string) {
+	s.RoomName = roomName
 	s.UpdatedAt = time.Now()
 }
 
-func (s *User) SetRoom(roomName *string) {
-	s.RoomName = roomName
+func (s *User) AddRoom(roomName string) {
+	for _, r := range s.Rooms {
+		if r == roomName {
+			return
+		}
+	}
+	s.Rooms = append(s.Rooms, roomName)
 	s.UpdatedAt = time.Now()
 }
 
+func (s *User) RemoveRoom(roomName string) {
+	for i, r := range s.Rooms {
+		if r == roomName {
+			s.Rooms = append(s.Rooms[:i], s.Rooms[i+1:]...)
+			s.UpdatedAt = time.Now()
+			return
+		}
+	}
+}
+
+func (s *User) HasRoom(roomName string) bool {
+	if s.RoomName != nil && *s.RoomName == roomName {
+		return true
+	}
+	for _, r := range s.Rooms {
+		if r == roomName {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *User) SetPassword(password string) {
 	s.Password = password
 	s.UpdatedAt = time.Now()
@@ -13,6 +13,8 @@ const (
 	StatusFailedExtendToken    errors.Status = "failed_extend_token"
 	StatusFailedDeleteToken    errors.Status = "failed_delete_token"
 	StatusFailedCreateTokens   errors.Status = "failed_create_token"
+	StatusTokenReused          errors.Status = "refresh_token_reused"
+	StatusFailedGetSessions    errors.Status = "failed_get_sessions"
 )
 
 var (
@@ -23,4 +25,6 @@ var (
 	ErrFailedExtendToken    = errors.New(codes.Unauthorized, StatusFailedExtendToken)
 	ErrFailedDeleteToken    = errors.New(codes.Unauthorized, StatusFailedDeleteToken)
 	ErrFailedCreateTokens   = errors.New(codes.Unauthorized, StatusFailedCreateTokens)
+	ErrTokenReused          = errors.New(codes.Unauthorized, StatusTokenReused)
+	ErrFailedGetSessions    = errors.New(codes.InternalError, StatusFailedGetSessions)
 )
@@ -2,60 +2,80 @@ package jwt
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"time"
 )
 
+// Roles carried in the token
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+	// RoleUnverified is a user who hasn't verified the email address yet
+	RoleUnverified = "unverified"
+)
+
+// Token types carried in the typ claim, both are signed with the same keys
+const (
+	typeAccess  = "access"
+	typeRefresh = "refresh"
+)
+
 type Payload struct {
 	Id   string `json:"id"`
 	Role string `json:"role"`
-	Uid  string `json:"uid"`
+	// Uid is the id of the token, sent as the jti claim
+	Uid  string `json:"jti"`
+	Sid  string `json:"sid"`
+	Type string `json:"typ"`
 	jwt.StandardClaims
 }
 
-type CachedTokens struct {
-	AccessUid  string `json:"access"`
-	RefreshUid string `json:"refresh"`
-}
-
 //go:generate mockgen -source=jwt.go -destination=mocks/jwt_mock.go
 type Service interface {
-	CreateTokens(ctx context.Context, id string, support bool) (*string, *string, error)
+	CreateTokens(ctx context.Context, id, role string, device *Device) (*string, *string, error)
+	RotateTokens(ctx context.Context, payload *Payload, role string) (*string, *string, error)
 	ParseToken(token string, isAccess bool) (*Payload, error)
 	VerifyToken(ctx context.Context, payload *Payload, isAccess bool) error
 	DeleteTokens(ctx context.Context, payload *Payload) error
+	DeleteAllTokens(ctx context.Context, id string) error
+	GetSessions(ctx context.Context, id string) ([]*Session, error)
 	ExtendExpire(ctx context.Context, payload *Payload) error
+	JWKS() *JWKS
 }
 
 type service struct {
-	secretKeyAccess  string
-	expiryAccess     int
-	secretKeyRefresh string
-	expiryRefresh    int
-	autoLogout       int
-	redisClient      *redis.Client
+	keys          KeySet
+	issuer        string
+	audience      string
+	expiryAccess  int
+	expiryRefresh int
+	autoLogout    int
+	redisClient   *redis.Client
 }
 
-func NewJwtService(secretKeyAccess string,
+func NewJwtService(keys KeySet,
+	issuer string,
+	audience string,
 	expiryAccess *int,
-	secretKeyRefresh string,
 	expiryRefresh *int,
 	autoLogout *int,
 	redisClient *redis.Client) (Service, error) {
-	if secretKeyAccess == "" {
-		return nil, errors.New("[jwt] invalid jwt access secret key")
+	if keys == nil {
+		return nil, errors.New("[jwt] invalid key set")
+	}
+	if issuer == "" {
+		return nil, errors.New("[jwt] invalid jwt issuer")
+	}
+	if audience == "" {
+		return nil, errors.New("[jwt] invalid jwt audience")
 	}
 	if expiryAccess == nil {
 		return nil, errors.New("[jwt] invalid jwt expiry access")
 	}
-	if secretKeyRefresh == "" {
-		return nil, errors.New("[jwt] invalid jwt refresh secret key")
-	}
 	if expiryRefresh == nil {
 		return nil, errors.New("[jwt] invalid jwt expiry refresh")
 	}
@@ -66,85 +86,106 @@ func NewJwtService(secretKeyAccess string,
 		return nil, errors.New("[jwt] invalid redis client")
 	}
 	return &service{
-		secretKeyAccess:  secretKeyAccess,
-		expiryAccess:     *expiryAccess,
-		secretKeyRefresh: secretKeyRefresh,
-		expiryRefresh:    *expiryRefresh,
-		autoLogout:       *autoLogout,
-		redisClient:      redisClient}, nil
+		keys:          keys,
+		issuer:        issuer,
+		audience:      audience,
+		expiryAccess:  *expiryAccess,
+		expiryRefresh: *expiryRefresh,
+		autoLogout:    *autoLogout,
+		redisClient:   redisClient}, nil
 }
 
-func (s *service) CreateTokens(ctx context.Context, id string, support bool) (*string, *string, error) {
-	var role string
-	if support {
-		role = "support"
-	} else {
-		role = "user"
-	}
-
-	// sign access token
-	accessUid := uuid.New().String()
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, &Payload{
-		Id:   id,
-		Role: role,
-		Uid:  accessUid,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Minute * time.Duration(s.expiryAccess)).Unix(),
-		},
-	})
+// CreateTokens starts a new session, the sessions of the user on other
+// devices are kept.
+func (s *service) CreateTokens(ctx context.Context, id, role string, device *Device) (*string, *string, error) {
+	now := time.Now()
+	session := &Session{Id: uuid.New().String(), Device: *device, CreatedAt: now, LastSeenAt: now}
 
-	signedAccessToken, err := accessToken.SignedString([]byte(s.secretKeyAccess))
+	accessToken, refreshToken, err := s.signTokens(id, role, session)
 	if err != nil {
-		return nil, nil, ErrToken
+		return nil, nil, err
 	}
 
-	// sign refresh token
-	refreshUid := uuid.New().String()
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, &Payload{
-		Id:   id,
-		Role: role,
-		Uid:  refreshUid,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Minute * time.Duration(s.expiryRefresh)).Unix(),
-		},
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		s.saveSession(ctx, pipe, id, session)
+		return nil
 	})
-
-	signedRefreshToken, err := refreshToken.SignedString([]byte(s.secretKeyRefresh))
 	if err != nil {
-		return nil, nil, ErrToken
+		return nil, nil, ErrFailedCreateTokens
 	}
 
-	cacheJson, err := json.Marshal(CachedTokens{
-		AccessUid:  accessUid,
-		RefreshUid: refreshUid,
-	})
-	if err != nil {
-		return nil, nil, ErrFailedCreateCache
-	}
+	return accessToken, refreshToken, nil
+}
+
+// RotateTokens exchanges the refresh token for a new pair of the same session.
+// A refresh token which was already rotated means it leaked, so the whole
+// session is revoked.
+func (s *service) RotateTokens(ctx context.Context, payload *Payload, role string) (*string, *string, error) {
+	key := sessionKey(payload.Id, payload.Sid)
+
+	var accessToken, refreshToken *string
+	err := s.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		session, err := getSession(ctx, tx, payload.Id, payload.Sid)
+		if err != nil {
+			return err
+		}
+
+		if session.RefreshUid != payload.Uid {
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, key)
+				pipe.SRem(ctx, sessionsKey(payload.Id), payload.Sid)
+				return nil
+			})
+			if err != nil {
+				return ErrFailedDeleteToken
+			}
+			return ErrTokenReused
+		}
+
+		accessToken, refreshToken, err = s.signTokens(payload.Id, role, session)
+		if err != nil {
+			return err
+		}
+		session.LastSeenAt = time.Now()
 
-	err = s.redisClient.Set(ctx, fmt.Sprintf("token-%v", id), string(cacheJson), time.Minute*time.Duration(s.autoLogout)).Err()
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			s.saveSession(ctx, pipe, payload.Id, session)
+			return nil
+		})
+		if err != nil {
+			return ErrFailedCreateTokens
+		}
+		return nil
+	}, key)
+	if err == redis.TxFailedErr {
+		// another refresh with the same token won the race
+		return nil, nil, ErrNotFound
+	}
 	if err != nil {
-		return nil, nil, ErrFailedCreateTokens
+		return nil, nil, err
 	}
 
-	return &signedAccessToken, &signedRefreshToken, nil
+	return accessToken, refreshToken, nil
 }
 
+// ParseToken checks the signature with the key of the kid header and the
+// standard claims.
 func (s *service) ParseToken(token string, isAccess bool) (*Payload, error) {
-	var secret string
-	switch isAccess {
-	case true:
-		secret = s.secretKeyAccess
-	case false:
-		secret = s.secretKeyRefresh
+	tokenType := typeRefresh
+	if isAccess {
+		tokenType = typeAccess
 	}
 
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
+		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, ErrToken
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.keys.VerificationKey(kid)
 		if !ok {
 			return nil, ErrToken
 		}
-		return []byte(secret), nil
+		return &key.Private.PublicKey, nil
 	}
 
 	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
@@ -160,17 +201,19 @@ func (s *service) ParseToken(token string, isAccess bool) (*Payload, error) {
 		return nil, ErrToken
 	}
 
+	if !payload.VerifyIssuer(s.issuer, true) ||
+		!payload.VerifyAudience(s.audience, true) ||
+		payload.IssuedAt == 0 ||
+		payload.Uid == "" ||
+		payload.Type != tokenType {
+		return nil, ErrToken
+	}
+
 	return payload, nil
 }
 
 func (s *service) VerifyToken(ctx context.Context, payload *Payload, isAccess bool) error {
-	cacheJSON, err := s.redisClient.Get(ctx, fmt.Sprintf("token-%v", payload.Id)).Result()
-	if err != nil {
-		return ErrToken
-	}
-
-	cachedTokens := new(CachedTokens)
-	err = json.Unmarshal([]byte(cacheJSON), cachedTokens)
+	session, err := getSession(ctx, s.redisClient, payload.Id, payload.Sid)
 	if err != nil {
 		return err
 	}
@@ -178,32 +221,125 @@ func (s *service) VerifyToken(ctx context.Context, payload *Payload, isAccess bo
 	var tokenUid string
 	switch isAccess {
 	case true:
-		tokenUid = cachedTokens.AccessUid
+		tokenUid = session.AccessUid
 	case false:
-		tokenUid = cachedTokens.RefreshUid
+		tokenUid = session.RefreshUid
 	}
 
-	if err != nil || tokenUid != payload.Uid {
+	if tokenUid != payload.Uid {
 		return ErrNotFound
 	}
 
 	return nil
 }
 
+// DeleteTokens ends the session of the token.
 func (s *service) DeleteTokens(ctx context.Context, payload *Payload) error {
-	err := s.redisClient.Del(ctx, fmt.Sprintf("token-%v", payload.Id)).Err()
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(payload.Id, payload.Sid))
+		pipe.SRem(ctx, sessionsKey(payload.Id), payload.Sid)
+		return nil
+	})
+	if err != nil {
+		return ErrFailedDeleteToken
+	}
+
+	return nil
+}
+
+// DeleteAllTokens ends every session of the user.
+func (s *service) DeleteAllTokens(ctx context.Context, id string) error {
+	sids, err := s.redisClient.SMembers(ctx, sessionsKey(id)).Result()
 	if err != nil {
 		return ErrFailedDeleteToken
 	}
 
+	keys := []string{sessionsKey(id)}
+	for _, sid := range sids {
+		keys = append(keys, sessionKey(id, sid))
+	}
+
+	if err = s.redisClient.Del(ctx, keys...).Err(); err != nil {
+		return ErrFailedDeleteToken
+	}
+
 	return nil
 }
 
+// ExtendExpire keeps the session alive and records it was seen.
 func (s *service) ExtendExpire(ctx context.Context, payload *Payload) error {
-	err := s.redisClient.Expire(ctx, fmt.Sprintf("token-%v", payload.Id), time.Minute*time.Duration(s.autoLogout)).Err()
+	ttl := time.Minute * time.Duration(s.autoLogout)
+	key := sessionKey(payload.Id, payload.Sid)
+	_, err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, fieldLastSeenAt, time.Now().Unix())
+		pipe.Expire(ctx, key, ttl)
+		pipe.Expire(ctx, sessionsKey(payload.Id), ttl)
+		return nil
+	})
 	if err != nil {
 		return ErrFailedExtendToken
 	}
 
 	return nil
 }
+
+func (s *service) JWKS() *JWKS {
+	return s.keys.JWKS()
+}
+
+// signTokens signs a new token pair of the session and stores their uids on
+// it.
+func (s *service) signTokens(id, role string, session *Session) (*string, *string, error) {
+	key := s.keys.SigningKey()
+	now := time.Now()
+
+	session.AccessUid = uuid.New().String()
+	accessToken, err := sign(&Payload{
+		Id:   id,
+		Role: role,
+		Uid:  session.AccessUid,
+		Sid:  session.Id,
+		Type: typeAccess,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  s.audience,
+			Issuer:    s.issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Minute * time.Duration(s.expiryAccess)).Unix(),
+		},
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session.RefreshUid = uuid.New().String()
+	refreshToken, err := sign(&Payload{
+		Id:   id,
+		Role: role,
+		Uid:  session.RefreshUid,
+		Sid:  session.Id,
+		Type: typeRefresh,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  s.audience,
+			Issuer:    s.issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Minute * time.Duration(s.expiryRefresh)).Unix(),
+		},
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func sign(payload *Payload, key *Key) (*string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, payload)
+	token.Header["kid"] = key.Id
+
+	signed, err := token.SignedString(key.Private)
+	if err != nil {
+		return nil, ErrToken
+	}
+
+	return &signed, nil
+}
@@ -2,9 +2,12 @@ package jwt_test
 
 import (
 	"support-chat/pkg/jwt"
+	mock_jwt "support-chat/pkg/jwt/mocks"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	gjwt "github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -13,85 +16,106 @@ func TestNewJwtService(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
 
-	secretAccessKey := "key"
-	secretRefreahsKey := "key"
+	issuer := "issuer"
+	audience := "audience"
 	expiryAccess := 1  // minutes
 	expiryRefresh := 2 // minutes
 	autoLogout := 3    // minutes
 
 	tests := []struct {
-		name             string
-		secretKeyAccess  string
-		expiryAccess     *int
-		secretKeyRefresh string
-		expiryRefresh    *int
-		autoLogout       *int
-		redisClient      *redis.Client
-		expect           func(*testing.T, jwt.Service, error)
+		name          string
+		keys          jwt.KeySet
+		issuer        string
+		audience      string
+		expiryAccess  *int
+		expiryRefresh *int
+		autoLogout    *int
+		redisClient   *redis.Client
+		expect        func(*testing.T, jwt.Service, error)
 	}{
 		{
-			name:             "should return service",
-			secretKeyAccess:  secretAccessKey,
-			expiryAccess:     &expiryAccess,
-			secretKeyRefresh: secretRefreahsKey,
-			expiryRefresh:    &expiryRefresh,
-			autoLogout:       &autoLogout,
-			redisClient:      &redis.Client{},
+			name:          "should return service",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        issuer,
+			audience:      audience,
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    &autoLogout,
+			redisClient:   &redis.Client{},
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.NotNil(t, s)
 				assert.Nil(t, err)
 			},
 		},
 		{
-			name:             "should return invalid jwt access secret key",
-			secretKeyAccess:  "",
-			expiryAccess:     &expiryAccess,
-			secretKeyRefresh: secretRefreahsKey,
-			expiryRefresh:    &expiryRefresh,
-			autoLogout:       &autoLogout,
-			redisClient:      &redis.Client{},
+			name:          "should return invalid key set",
+			keys:          nil,
+			issuer:        issuer,
+			audience:      audience,
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    &autoLogout,
+			redisClient:   &redis.Client{},
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
-				assert.EqualError(t, err, "[jwt] invalid jwt access secret key")
+				assert.EqualError(t, err, "[jwt] invalid key set")
 			},
 		},
 		{
-			name:             "should return invalid jwt expiry access",
-			secretKeyAccess:  secretAccessKey,
-			expiryAccess:     nil,
-			secretKeyRefresh: secretRefreahsKey,
-			expiryRefresh:    &expiryRefresh,
-			autoLogout:       &autoLogout,
-			redisClient:      &redis.Client{},
+			name:          "should return invalid jwt issuer",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        "",
+			audience:      audience,
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    &autoLogout,
+			redisClient:   &redis.Client{},
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
-				assert.EqualError(t, err, "[jwt] invalid jwt expiry access")
+				assert.EqualError(t, err, "[jwt] invalid jwt issuer")
+			},
+		},
+		{
+			name:          "should return invalid jwt audience",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        issuer,
+			audience:      "",
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    &autoLogout,
+			redisClient:   &redis.Client{},
+			expect: func(t *testing.T, s jwt.Service, err error) {
+				assert.Nil(t, s)
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, "[jwt] invalid jwt audience")
 			},
 		},
 		{
-			name:             "should return invalid jwt refresh secret key",
-			secretKeyAccess:  secretAccessKey,
-			expiryAccess:     &expiryAccess,
-			secretKeyRefresh: "",
-			expiryRefresh:    &expiryRefresh,
-			autoLogout:       &autoLogout,
-			redisClient:      &redis.Client{},
+			name:          "should return invalid jwt expiry access",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        issuer,
+			audience:      audience,
+			expiryAccess:  nil,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    &autoLogout,
+			redisClient:   &redis.Client{},
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
-				assert.EqualError(t, err, "[jwt] invalid jwt refresh secret key")
+				assert.EqualError(t, err, "[jwt] invalid jwt expiry access")
 			},
 		},
 		{
-			name:             "should return invalid jwt expiry refresh",
-			secretKeyAccess:  secretAccessKey,
-			expiryAccess:     &expiryAccess,
-			secretKeyRefresh: secretRefreahsKey,
-			expiryRefresh:    nil,
-			autoLogout:       &autoLogout,
-			redisClient:      &redis.Client{},
+			name:          "should return invalid jwt expiry refresh",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        issuer,
+			audience:      audience,
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: nil,
+			autoLogout:    &autoLogout,
+			redisClient:   &redis.Client{},
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -99,13 +123,14 @@ func TestNewJwtService(t *testing.T) {
 			},
 		},
 		{
-			name:             "should return invalid jwt auto logout",
-			secretKeyAccess:  secretAccessKey,
-			expiryAccess:     &expiryAccess,
-			secretKeyRefresh: secretRefreahsKey,
-			expiryRefresh:    &expiryRefresh,
-			autoLogout:       nil,
-			redisClient:      &redis.Client{},
+			name:          "should return invalid jwt auto logout",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        issuer,
+			audience:      audience,
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    nil,
+			redisClient:   &redis.Client{},
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -113,13 +138,14 @@ func TestNewJwtService(t *testing.T) {
 			},
 		},
 		{
-			name:             "should return invalid redis client",
-			secretKeyAccess:  secretAccessKey,
-			expiryAccess:     &expiryAccess,
-			secretKeyRefresh: secretRefreahsKey,
-			expiryRefresh:    &expiryRefresh,
-			autoLogout:       &autoLogout,
-			redisClient:      nil,
+			name:          "should return invalid redis client",
+			keys:          mock_jwt.NewMockKeySet(controller),
+			issuer:        issuer,
+			audience:      audience,
+			expiryAccess:  &expiryAccess,
+			expiryRefresh: &expiryRefresh,
+			autoLogout:    &autoLogout,
+			redisClient:   nil,
 			expect: func(t *testing.T, s jwt.Service, err error) {
 				assert.Nil(t, s)
 				assert.NotNil(t, err)
@@ -130,9 +156,10 @@ func TestNewJwtService(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			svc, err := jwt.NewJwtService(tc.secretKeyAccess,
+			svc, err := jwt.NewJwtService(tc.keys,
+				tc.issuer,
+				tc.audience,
 				tc.expiryAccess,
-				tc.secretKeyRefresh,
 				tc.expiryRefresh,
 				tc.autoLogout,
 				tc.redisClient)
@@ -140,3 +167,170 @@ func TestNewJwtService(t *testing.T) {
 		})
 	}
 }
+
+func TestService_ParseToken(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockKeys := mock_jwt.NewMockKeySet(controller)
+	key := &jwt.Key{Id: "kid", Private: testKey(t)}
+	expiry := 1
+
+	service, _ := jwt.NewJwtService(mockKeys, "issuer", "audience", &expiry, &expiry, &expiry, &redis.Client{})
+
+	valid := func() *jwt.Payload {
+		return &jwt.Payload{
+			Id:   "user",
+			Role: jwt.RoleUser,
+			Uid:  "uid",
+			Sid:  "sid",
+			Type: "access",
+			StandardClaims: gjwt.StandardClaims{
+				Issuer:    "issuer",
+				Audience:  "audience",
+				IssuedAt:  time.Now().Unix(),
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			},
+		}
+	}
+	signed := func(method gjwt.SigningMethod, kid string, payload *jwt.Payload) string {
+		token := gjwt.NewWithClaims(method, payload)
+		token.Header["kid"] = kid
+		var signingKey interface{} = key.Private
+		if method == gjwt.SigningMethodHS256 {
+			signingKey = []byte("secret")
+		}
+		s, err := token.SignedString(signingKey)
+		assert.Nil(t, err)
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		token    func() string
+		isAccess bool
+		setup    func()
+		expect   func(*testing.T, *jwt.Payload, error)
+	}{
+		{
+			name:     "should return payload",
+			token:    func() string { return signed(gjwt.SigningMethodRS256, "kid", valid()) },
+			isAccess: true,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("kid").Return(key, true)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "user", payload.Id)
+				assert.Equal(t, "uid", payload.Uid)
+				assert.Equal(t, "sid", payload.Sid)
+			},
+		},
+		{
+			name:     "should reject unknown key",
+			token:    func() string { return signed(gjwt.SigningMethodRS256, "other", valid()) },
+			isAccess: true,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("other").Return(nil, false)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrTokenInvalidOrExpire, err)
+			},
+		},
+		{
+			name:     "should reject hmac token",
+			token:    func() string { return signed(gjwt.SigningMethodHS256, "kid", valid()) },
+			isAccess: true,
+			setup:    func() {},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrTokenInvalidOrExpire, err)
+			},
+		},
+		{
+			name: "should reject other audience",
+			token: func() string {
+				payload := valid()
+				payload.Audience = "other"
+				return signed(gjwt.SigningMethodRS256, "kid", payload)
+			},
+			isAccess: true,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("kid").Return(key, true)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrToken, err)
+			},
+		},
+		{
+			name: "should reject other issuer",
+			token: func() string {
+				payload := valid()
+				payload.Issuer = "other"
+				return signed(gjwt.SigningMethodRS256, "kid", payload)
+			},
+			isAccess: true,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("kid").Return(key, true)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrToken, err)
+			},
+		},
+		{
+			name: "should reject token without iat",
+			token: func() string {
+				payload := valid()
+				payload.IssuedAt = 0
+				return signed(gjwt.SigningMethodRS256, "kid", payload)
+			},
+			isAccess: true,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("kid").Return(key, true)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrToken, err)
+			},
+		},
+		{
+			name:     "should reject access token as refresh token",
+			token:    func() string { return signed(gjwt.SigningMethodRS256, "kid", valid()) },
+			isAccess: false,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("kid").Return(key, true)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrToken, err)
+			},
+		},
+		{
+			name: "should reject expired token",
+			token: func() string {
+				payload := valid()
+				payload.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+				return signed(gjwt.SigningMethodRS256, "kid", payload)
+			},
+			isAccess: true,
+			setup: func() {
+				mockKeys.EXPECT().VerificationKey("kid").Return(key, true)
+			},
+			expect: func(t *testing.T, payload *jwt.Payload, err error) {
+				assert.Nil(t, payload)
+				assert.Equal(t, jwt.ErrTokenInvalidOrExpire, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup()
+			payload, err := service.ParseToken(tc.token(), tc.isAccess)
+			tc.expect(t, payload, err)
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"support-chat/pkg/keyPair"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// keyReloadPeriod is how often the key folder is read again, so keys rotated
+// by another instance sharing the folder are picked up.
+const keyReloadPeriod = time.Minute
+
+// Key is a RSA key tokens are signed with, identified by the kid header.
+type Key struct {
+	Id        string
+	CreatedAt time.Time
+	Private   *rsa.PrivateKey
+}
+
+// JWK is the public part of a key as published in the JWKS.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeySet holds the signing keys. The newest key signs, older keys keep
+// verifying until every token they signed has expired, so a rotation doesn't
+// log anybody out.
+//
+//go:generate mockgen -source=keys.go -destination=mocks/keys_mock.go
+type KeySet interface {
+	SigningKey() *Key
+	VerificationKey(kid string) (*Key, bool)
+	JWKS() *JWKS
+	Rotate() error
+	RunRotation(ctx context.Context)
+}
+
+type keySet struct {
+	dir       string
+	rotation  time.Duration
+	retention time.Duration
+	generator keyPair.KeyPair
+	logger    *zap.SugaredLogger
+
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+// NewKeySet loads the keys of dir and generates a new one when the newest is
+// older than rotation hours. Keys are kept for rotation hours plus the
+// refresh token expiry.
+func NewKeySet(dir string, rotation *int, expiryRefresh *int, generator keyPair.KeyPair, logger *zap.SugaredLogger) (KeySet, error) {
+	if dir == "" {
+		return nil, errors.New("[jwt_keys] invalid keys folder")
+	}
+	if rotation == nil || *rotation <= 0 {
+		return nil, errors.New("[jwt_keys] invalid key rotation")
+	}
+	if expiryRefresh == nil {
+		return nil, errors.New("[jwt_keys] invalid jwt expiry refresh")
+	}
+	if generator == nil {
+		return nil, errors.New("[jwt_keys] invalid key generator")
+	}
+	if logger == nil {
+		return nil, errors.New("[jwt_keys] invalid logger")
+	}
+
+	rotationPeriod := time.Hour * time.Duration(*rotation)
+	k := &keySet{
+		dir:       dir,
+		rotation:  rotationPeriod,
+		retention: rotationPeriod + keyReloadPeriod + time.Minute*time.Duration(*expiryRefresh),
+		generator: generator,
+		logger:    logger,
+	}
+	if err := k.Rotate(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// SigningKey returns the newest key the other instances had time to load, so
+// they can verify what this one signs. A new key only signs right away when
+// there is no other.
+func (k *keySet) SigningKey() *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if time.Since(key.CreatedAt) >= keyReloadPeriod {
+			return key
+		}
+	}
+
+	return k.keys[0]
+}
+
+func (k *keySet) VerificationKey(kid string) (*Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.Id == kid {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (k *keySet) JWKS() *JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	jwks := &JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwks.Keys = append(jwks.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: key.Id,
+			N:   base64.RawURLEncoding.EncodeToString(key.Private.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.Private.E)).Bytes()),
+		})
+	}
+
+	return jwks
+}
+
+// Rotate reloads the keys, generates a new signing key when it is due and
+// removes the keys which can't have valid tokens anymore.
+func (k *keySet) Rotate() error {
+	keys, err := k.load()
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 || time.Since(keys[0].CreatedAt) >= k.rotation {
+		key, err := k.generate()
+		if err != nil {
+			return err
+		}
+		keys = append([]*Key{key}, keys...)
+		k.logger.Infof("generated signing key %v", key.Id)
+	}
+
+	active := []*Key{keys[0]}
+	for _, key := range keys[1:] {
+		if time.Since(key.CreatedAt) < k.retention {
+			active = append(active, key)
+			continue
+		}
+		if err = k.remove(key.Id); err != nil {
+			k.logger.Errorf("failed to remove expired key %v: %v", key.Id, err)
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = active
+	k.mu.Unlock()
+
+	return nil
+}
+
+// RunRotation rotates the keys until ctx is done.
+func (k *keySet) RunRotation(ctx context.Context) {
+	ticker := time.NewTicker(keyReloadPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Rotate(); err != nil {
+				k.logger.Errorf("failed to rotate keys %v", err)
+			}
+		}
+	}
+}
+
+// load reads the private keys of the folder, newest first.
+func (k *keySet) load() ([]*Key, error) {
+	entries, err := os.ReadDir(k.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*Key
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".rsa" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		pem, err := os.ReadFile(filepath.Join(k.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			k.logger.Errorf("skipping invalid key %v: %v", entry.Name(), err)
+			continue
+		}
+
+		keys = append(keys, &Key{
+			Id:        strings.TrimSuffix(entry.Name(), ".rsa"),
+			CreatedAt: info.ModTime(),
+			Private:   private,
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+
+	return keys, nil
+}
+
+// generate creates a key with the key pair generator and writes it to the
+// folder. The files are renamed into place, so other instances never read a
+// half written key.
+func (k *keySet) generate() (*Key, error) {
+	privPEM, pubPEM, err := k.generator.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	private, err := jwt.ParseRSAPrivateKeyFromPEM(privPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(k.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	kid := uuid.New().String()
+	if err = writeFile(filepath.Join(k.dir, kid+".rsa.pub"), pubPEM, 0644); err != nil {
+		return nil, err
+	}
+	if err = writeFile(filepath.Join(k.dir, kid+".rsa"), privPEM, 0600); err != nil {
+		return nil, err
+	}
+
+	return &Key{Id: kid, CreatedAt: time.Now(), Private: private}, nil
+}
+
+func (k *keySet) remove(kid string) error {
+	if err := os.Remove(filepath.Join(k.dir, kid+".rsa")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(filepath.Join(k.dir, kid+".rsa.pub")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
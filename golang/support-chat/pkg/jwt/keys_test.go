@@ -0,0 +1,212 @@
+package jwt_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/keyPair"
+	mock_keyPair "support-chat/pkg/keyPair/mocks"
+	"support-chat/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	return key
+}
+
+func testKeyPEM(key *rsa.PrivateKey) ([]byte, []byte, error) {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)}),
+		nil
+}
+
+// writeTestKey puts a key created age ago into dir.
+func writeTestKey(t *testing.T, dir, kid string, key *rsa.PrivateKey, age time.Duration) {
+	privPEM, _, _ := testKeyPEM(key)
+	path := filepath.Join(dir, kid+".rsa")
+	assert.Nil(t, os.WriteFile(path, privPEM, 0600))
+	created := time.Now().Add(-age)
+	assert.Nil(t, os.Chtimes(path, created, created))
+}
+
+func TestNewKeySet(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	rotation := 24      // hours
+	expiryRefresh := 60 // minutes
+	zero := 0
+
+	tests := []struct {
+		name          string
+		dir           string
+		rotation      *int
+		expiryRefresh *int
+		generator     keyPair.KeyPair
+		logger        *zap.SugaredLogger
+		expect        func(*testing.T, jwt.KeySet, error)
+	}{
+		{
+			name:          "should return invalid keys folder",
+			dir:           "",
+			rotation:      &rotation,
+			expiryRefresh: &expiryRefresh,
+			generator:     mock_keyPair.NewMockKeyPair(controller),
+			logger:        &zap.SugaredLogger{},
+			expect: func(t *testing.T, keys jwt.KeySet, err error) {
+				assert.Nil(t, keys)
+				assert.EqualError(t, err, "[jwt_keys] invalid keys folder")
+			},
+		},
+		{
+			name:          "should return invalid key rotation",
+			dir:           t.TempDir(),
+			rotation:      &zero,
+			expiryRefresh: &expiryRefresh,
+			generator:     mock_keyPair.NewMockKeyPair(controller),
+			logger:        &zap.SugaredLogger{},
+			expect: func(t *testing.T, keys jwt.KeySet, err error) {
+				assert.Nil(t, keys)
+				assert.EqualError(t, err, "[jwt_keys] invalid key rotation")
+			},
+		},
+		{
+			name:          "should return invalid jwt expiry refresh",
+			dir:           t.TempDir(),
+			rotation:      &rotation,
+			expiryRefresh: nil,
+			generator:     mock_keyPair.NewMockKeyPair(controller),
+			logger:        &zap.SugaredLogger{},
+			expect: func(t *testing.T, keys jwt.KeySet, err error) {
+				assert.Nil(t, keys)
+				assert.EqualError(t, err, "[jwt_keys] invalid jwt expiry refresh")
+			},
+		},
+		{
+			name:          "should return invalid key generator",
+			dir:           t.TempDir(),
+			rotation:      &rotation,
+			expiryRefresh: &expiryRefresh,
+			generator:     nil,
+			logger:        &zap.SugaredLogger{},
+			expect: func(t *testing.T, keys jwt.KeySet, err error) {
+				assert.Nil(t, keys)
+				assert.EqualError(t, err, "[jwt_keys] invalid key generator")
+			},
+		},
+		{
+			name:          "should return invalid logger",
+			dir:           t.TempDir(),
+			rotation:      &rotation,
+			expiryRefresh: &expiryRefresh,
+			generator:     mock_keyPair.NewMockKeyPair(controller),
+			logger:        nil,
+			expect: func(t *testing.T, keys jwt.KeySet, err error) {
+				assert.Nil(t, keys)
+				assert.EqualError(t, err, "[jwt_keys] invalid logger")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := jwt.NewKeySet(tc.dir, tc.rotation, tc.expiryRefresh, tc.generator, tc.logger)
+			tc.expect(t, keys, err)
+		})
+	}
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	newLogger, _ := logger.NewLogger("development")
+	zapLogger, _ := newLogger.SetupZapLogger()
+
+	rotation := 24      // hours
+	expiryRefresh := 60 // minutes
+	current, retired, expired := testKey(t), testKey(t), testKey(t)
+
+	tests := []struct {
+		name   string
+		setup  func(dir string, generator *mock_keyPair.MockKeyPair)
+		expect func(*testing.T, string, jwt.KeySet, error)
+	}{
+		{
+			name: "should generate the first key",
+			setup: func(dir string, generator *mock_keyPair.MockKeyPair) {
+				generator.EXPECT().GenerateKeyPair().Return(testKeyPEM(current))
+			},
+			expect: func(t *testing.T, dir string, keys jwt.KeySet, err error) {
+				assert.Nil(t, err)
+				jwks := keys.JWKS()
+				assert.Len(t, jwks.Keys, 1)
+				assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+				assert.Equal(t, jwks.Keys[0].Kid, keys.SigningKey().Id)
+				assert.FileExists(t, filepath.Join(dir, jwks.Keys[0].Kid+".rsa"))
+				assert.FileExists(t, filepath.Join(dir, jwks.Keys[0].Kid+".rsa.pub"))
+			},
+		},
+		{
+			name: "should keep the current key",
+			setup: func(dir string, generator *mock_keyPair.MockKeyPair) {
+				writeTestKey(t, dir, "current", current, time.Hour)
+			},
+			expect: func(t *testing.T, dir string, keys jwt.KeySet, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, keys.JWKS().Keys, 1)
+				assert.Equal(t, "current", keys.SigningKey().Id)
+			},
+		},
+		{
+			name: "should rotate and keep verifying with the retired key",
+			setup: func(dir string, generator *mock_keyPair.MockKeyPair) {
+				writeTestKey(t, dir, "retired", retired, 25*time.Hour)
+				generator.EXPECT().GenerateKeyPair().Return(testKeyPEM(current))
+			},
+			expect: func(t *testing.T, dir string, keys jwt.KeySet, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, keys.JWKS().Keys, 2)
+				_, ok := keys.VerificationKey("retired")
+				assert.True(t, ok)
+				// the new key signs once the other instances had time to load it
+				assert.Equal(t, "retired", keys.SigningKey().Id)
+			},
+		},
+		{
+			name: "should remove expired keys",
+			setup: func(dir string, generator *mock_keyPair.MockKeyPair) {
+				writeTestKey(t, dir, "current", current, time.Hour)
+				writeTestKey(t, dir, "expired", expired, 26*time.Hour)
+			},
+			expect: func(t *testing.T, dir string, keys jwt.KeySet, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, keys.JWKS().Keys, 1)
+				_, ok := keys.VerificationKey("expired")
+				assert.False(t, ok)
+				assert.NoFileExists(t, filepath.Join(dir, "expired.rsa"))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			generator := mock_keyPair.NewMockKeyPair(controller)
+			tc.setup(dir, generator)
+			keys, err := jwt.NewKeySet(dir, &rotation, &expiryRefresh, generator, zapLogger)
+			tc.expect(t, dir, keys, err)
+		})
+	}
+}
@@ -6,8 +6,8 @@ package mock_jwt
 
 import (
 	context "context"
-	jwt "support-chat/pkg/jwt"
 	reflect "reflect"
+	jwt "support-chat/pkg/jwt"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -36,9 +36,9 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // CreateTokens mocks base method.
-func (m *MockService) CreateTokens(ctx context.Context, id string, support bool) (*string, *string, error) {
+func (m *MockService) CreateTokens(ctx context.Context, id, role string, device *jwt.Device) (*string, *string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTokens", ctx, id, support)
+	ret := m.ctrl.Call(m, "CreateTokens", ctx, id, role, device)
 	ret0, _ := ret[0].(*string)
 	ret1, _ := ret[1].(*string)
 	ret2, _ := ret[2].(error)
@@ -46,9 +46,23 @@ func (m *MockService) CreateTokens(ctx context.Context, id string, support bool)
 }
 
 // CreateTokens indicates an expected call of CreateTokens.
-func (mr *MockServiceMockRecorder) CreateTokens(ctx, id, support interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateTokens(ctx, id, role, device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTokens", reflect.TypeOf((*MockService)(nil).CreateTokens), ctx, id, role, device)
+}
+
+// DeleteAllTokens mocks base method.
+func (m *MockService) DeleteAllTokens(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAllTokens", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAllTokens indicates an expected call of DeleteAllTokens.
+func (mr *MockServiceMockRecorder) DeleteAllTokens(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTokens", reflect.TypeOf((*MockService)(nil).CreateTokens), ctx, id, support)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllTokens", reflect.TypeOf((*MockService)(nil).DeleteAllTokens), ctx, id)
 }
 
 // DeleteTokens mocks base method.
@@ -79,6 +93,35 @@ func (mr *MockServiceMockRecorder) ExtendExpire(ctx, payload interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendExpire", reflect.TypeOf((*MockService)(nil).ExtendExpire), ctx, payload)
 }
 
+// GetSessions mocks base method.
+func (m *MockService) GetSessions(ctx context.Context, id string) ([]*jwt.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, id)
+	ret0, _ := ret[0].([]*jwt.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockServiceMockRecorder) GetSessions(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockService)(nil).GetSessions), ctx, id)
+}
+
+// JWKS mocks base method.
+func (m *MockService) JWKS() *jwt.JWKS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(*jwt.JWKS)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockServiceMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockService)(nil).JWKS))
+}
+
 // ParseToken mocks base method.
 func (m *MockService) ParseToken(token string, isAccess bool) (*jwt.Payload, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +137,22 @@ func (mr *MockServiceMockRecorder) ParseToken(token, isAccess interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseToken", reflect.TypeOf((*MockService)(nil).ParseToken), token, isAccess)
 }
 
+// RotateTokens mocks base method.
+func (m *MockService) RotateTokens(ctx context.Context, payload *jwt.Payload, role string) (*string, *string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateTokens", ctx, payload, role)
+	ret0, _ := ret[0].(*string)
+	ret1, _ := ret[1].(*string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RotateTokens indicates an expected call of RotateTokens.
+func (mr *MockServiceMockRecorder) RotateTokens(ctx, payload, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateTokens", reflect.TypeOf((*MockService)(nil).RotateTokens), ctx, payload, role)
+}
+
 // VerifyToken mocks base method.
 func (m *MockService) VerifyToken(ctx context.Context, payload *jwt.Payload, isAccess bool) error {
 	m.ctrl.T.Helper()
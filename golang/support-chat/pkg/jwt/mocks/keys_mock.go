@@ -0,0 +1,105 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: keys.go
+
+// Package mock_jwt is a generated GoMock package.
+package mock_jwt
+
+import (
+	context "context"
+	reflect "reflect"
+	jwt "support-chat/pkg/jwt"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockKeySet is a mock of KeySet interface.
+type MockKeySet struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeySetMockRecorder
+}
+
+// MockKeySetMockRecorder is the mock recorder for MockKeySet.
+type MockKeySetMockRecorder struct {
+	mock *MockKeySet
+}
+
+// NewMockKeySet creates a new mock instance.
+func NewMockKeySet(ctrl *gomock.Controller) *MockKeySet {
+	mock := &MockKeySet{ctrl: ctrl}
+	mock.recorder = &MockKeySetMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeySet) EXPECT() *MockKeySetMockRecorder {
+	return m.recorder
+}
+
+// JWKS mocks base method.
+func (m *MockKeySet) JWKS() *jwt.JWKS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(*jwt.JWKS)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MockKeySetMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockKeySet)(nil).JWKS))
+}
+
+// Rotate mocks base method.
+func (m *MockKeySet) Rotate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockKeySetMockRecorder) Rotate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockKeySet)(nil).Rotate))
+}
+
+// RunRotation mocks base method.
+func (m *MockKeySet) RunRotation(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunRotation", ctx)
+}
+
+// RunRotation indicates an expected call of RunRotation.
+func (mr *MockKeySetMockRecorder) RunRotation(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunRotation", reflect.TypeOf((*MockKeySet)(nil).RunRotation), ctx)
+}
+
+// SigningKey mocks base method.
+func (m *MockKeySet) SigningKey() *jwt.Key {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SigningKey")
+	ret0, _ := ret[0].(*jwt.Key)
+	return ret0
+}
+
+// SigningKey indicates an expected call of SigningKey.
+func (mr *MockKeySetMockRecorder) SigningKey() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SigningKey", reflect.TypeOf((*MockKeySet)(nil).SigningKey))
+}
+
+// VerificationKey mocks base method.
+func (m *MockKeySet) VerificationKey(kid string) (*jwt.Key, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerificationKey", kid)
+	ret0, _ := ret[0].(*jwt.Key)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// VerificationKey indicates an expected call of VerificationKey.
+func (mr *MockKeySetMockRecorder) VerificationKey(kid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerificationKey", reflect.TypeOf((*MockKeySet)(nil).VerificationKey), kid)
+}
@@ -0,0 +1,136 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Fields of the session hash
+const (
+	fieldAccess     = "access"
+	fieldRefresh    = "refresh"
+	fieldDevice     = "device"
+	fieldUserAgent  = "userAgent"
+	fieldIp         = "ip"
+	fieldCreatedAt  = "createdAt"
+	fieldLastSeenAt = "lastSeenAt"
+)
+
+// Device describes where the user logged in from.
+type Device struct {
+	Label     string
+	UserAgent string
+	IP        string
+}
+
+// Session is the state of one logged in device, stored as a hash so the last
+// seen time can be written without racing a rotation. Every rotation replaces
+// both uids, tokens carrying older ones are no longer accepted.
+type Session struct {
+	Id         string
+	AccessUid  string
+	RefreshUid string
+	Device
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+func (s *service) GetSessions(ctx context.Context, id string) ([]*Session, error) {
+	sids, err := s.redisClient.SMembers(ctx, sessionsKey(id)).Result()
+	if err != nil {
+		return nil, ErrFailedGetSessions
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(sids))
+	_, err = s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, sid := range sids {
+			cmds[i] = pipe.HGetAll(ctx, sessionKey(id, sid))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ErrFailedGetSessions
+	}
+
+	var sessions []*Session
+	var expired []interface{}
+	for i, cmd := range cmds {
+		session, err := mapToSession(sids[i], cmd.Val())
+		if err != nil {
+			expired = append(expired, sids[i])
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	// the sessions of the set expire on their own
+	if len(expired) > 0 {
+		s.redisClient.SRem(ctx, sessionsKey(id), expired...)
+	}
+
+	return sessions, nil
+}
+
+// saveSession stores the session and adds it to the sessions of the user. Both
+// expire after autoLogout minutes without activity.
+func (s *service) saveSession(ctx context.Context, pipe redis.Pipeliner, id string, session *Session) {
+	ttl := time.Minute * time.Duration(s.autoLogout)
+	key := sessionKey(id, session.Id)
+
+	pipe.HSet(ctx, key, map[string]interface{}{
+		fieldAccess:     session.AccessUid,
+		fieldRefresh:    session.RefreshUid,
+		fieldDevice:     session.Label,
+		fieldUserAgent:  session.UserAgent,
+		fieldIp:         session.IP,
+		fieldCreatedAt:  session.CreatedAt.Unix(),
+		fieldLastSeenAt: session.LastSeenAt.Unix(),
+	})
+	pipe.Expire(ctx, key, ttl)
+	pipe.SAdd(ctx, sessionsKey(id), session.Id)
+	pipe.Expire(ctx, sessionsKey(id), ttl)
+}
+
+func getSession(ctx context.Context, client redis.Cmdable, id, sid string) (*Session, error) {
+	fields, err := client.HGetAll(ctx, sessionKey(id, sid)).Result()
+	if err != nil {
+		return nil, ErrToken
+	}
+
+	return mapToSession(sid, fields)
+}
+
+func mapToSession(sid string, fields map[string]string) (*Session, error) {
+	// a session touched after it was deleted has no uids
+	if fields[fieldAccess] == "" || fields[fieldRefresh] == "" {
+		return nil, ErrToken
+	}
+
+	createdAt, _ := strconv.ParseInt(fields[fieldCreatedAt], 10, 64)
+	lastSeenAt, _ := strconv.ParseInt(fields[fieldLastSeenAt], 10, 64)
+
+	return &Session{
+		Id:         sid,
+		AccessUid:  fields[fieldAccess],
+		RefreshUid: fields[fieldRefresh],
+		Device: Device{
+			Label:     fields[fieldDevice],
+			UserAgent: fields[fieldUserAgent],
+			IP:        fields[fieldIp],
+		},
+		CreatedAt:  time.Unix(createdAt, 0),
+		LastSeenAt: time.Unix(lastSeenAt, 0),
+	}, nil
+}
+
+func sessionKey(id, sid string) string {
+	return fmt.Sprintf("session-%v-%v", id, sid)
+}
+
+func sessionsKey(id string) string {
+	return fmt.Sprintf("sessions-%v", id)
+}
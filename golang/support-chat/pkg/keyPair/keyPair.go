@@ -12,6 +12,7 @@ import (
 	"os"
 )
 
+//go:generate mockgen -source=keyPair.go -destination=mocks/keyPair_mock.go
 type KeyPair interface {
 	GenerateKeyPair() ([]byte, []byte, error)
 	WriteKeysToKeysFolder(roomName string, privKey, pubKey []byte) error
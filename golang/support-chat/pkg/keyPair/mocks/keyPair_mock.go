@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: keyPair.go
+
+// Package mock_keyPair is a generated GoMock package.
+package mock_keyPair
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockKeyPair is a mock of KeyPair interface.
+type MockKeyPair struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyPairMockRecorder
+}
+
+// MockKeyPairMockRecorder is the mock recorder for MockKeyPair.
+type MockKeyPairMockRecorder struct {
+	mock *MockKeyPair
+}
+
+// NewMockKeyPair creates a new mock instance.
+func NewMockKeyPair(ctrl *gomock.Controller) *MockKeyPair {
+	mock := &MockKeyPair{ctrl: ctrl}
+	mock.recorder = &MockKeyPairMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyPair) EXPECT() *MockKeyPairMockRecorder {
+	return m.recorder
+}
+
+// GenerateKeyPair mocks base method.
+func (m *MockKeyPair) GenerateKeyPair() ([]byte, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateKeyPair")
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GenerateKeyPair indicates an expected call of GenerateKeyPair.
+func (mr *MockKeyPairMockRecorder) GenerateKeyPair() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateKeyPair", reflect.TypeOf((*MockKeyPair)(nil).GenerateKeyPair))
+}
+
+// WriteKeysToKeysFolder mocks base method.
+func (m *MockKeyPair) WriteKeysToKeysFolder(roomName string, privKey, pubKey []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteKeysToKeysFolder", roomName, privKey, pubKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteKeysToKeysFolder indicates an expected call of WriteKeysToKeysFolder.
+func (mr *MockKeyPairMockRecorder) WriteKeysToKeysFolder(roomName, privKey, pubKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteKeysToKeysFolder", reflect.TypeOf((*MockKeyPair)(nil).WriteKeysToKeysFolder), roomName, privKey, pubKey)
+}
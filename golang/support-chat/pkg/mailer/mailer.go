@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Senders selectable in the config
+const (
+	SenderSMTP   = "smtp"
+	SenderOutbox = "outbox"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+//go:generate mockgen -source=mailer.go -destination=mocks/mailer_mock.go
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// format renders the message as a plain text mail.
+func format(from string, msg *Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %v\r\n", from)
+	fmt.Fprintf(&b, "To: %v\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %v\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
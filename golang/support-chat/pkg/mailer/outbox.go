@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type outboxMailer struct {
+	dir  string
+	from string
+}
+
+// NewOutboxMailer writes every mail as an .eml file into dir instead of
+// sending it, for local development and tests.
+func NewOutboxMailer(dir, from string) (Mailer, error) {
+	if dir == "" {
+		return nil, errors.New("[mailer_outbox] invalid folder")
+	}
+	if from == "" {
+		return nil, errors.New("[mailer_outbox] invalid from")
+	}
+
+	return &outboxMailer{dir: dir, from: from}, nil
+}
+
+func (m *outboxMailer) Send(_ context.Context, msg *Message) error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%v-%v.eml", time.Now().Format("20060102T150405.000000000"), msg.To)
+	return os.WriteFile(filepath.Join(m.dir, filepath.Base(name)), format(m.from, msg), 0644)
+}
@@ -0,0 +1,74 @@
+package mailer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"support-chat/pkg/mailer"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOutboxMailer(t *testing.T) {
+	tests := []struct {
+		name   string
+		dir    string
+		from   string
+		expect func(*testing.T, mailer.Mailer, error)
+	}{
+		{
+			name: "should return mailer",
+			dir:  t.TempDir(),
+			from: "support@example.com",
+			expect: func(t *testing.T, m mailer.Mailer, err error) {
+				assert.NotNil(t, m)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "should return invalid folder",
+			dir:  "",
+			from: "support@example.com",
+			expect: func(t *testing.T, m mailer.Mailer, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[mailer_outbox] invalid folder")
+			},
+		},
+		{
+			name: "should return invalid from",
+			dir:  t.TempDir(),
+			from: "",
+			expect: func(t *testing.T, m mailer.Mailer, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[mailer_outbox] invalid from")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := mailer.NewOutboxMailer(tc.dir, tc.from)
+			tc.expect(t, m, err)
+		})
+	}
+}
+
+func TestOutboxMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	m, _ := mailer.NewOutboxMailer(dir, "support@example.com")
+
+	err := m.Send(context.Background(), &mailer.Message{To: "user@example.com", Subject: "Hello", Body: "line\nline"})
+	assert.Nil(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "From: support@example.com\r\n")
+	assert.Contains(t, string(data), "To: user@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: Hello\r\n")
+	assert.Contains(t, string(data), "\r\n\r\nline\r\nline")
+}
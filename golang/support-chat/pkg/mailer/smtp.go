@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+)
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer sends through the SMTP server, authenticating only when a
+// username is set.
+func NewSMTPMailer(host, port, username, password, from string) (Mailer, error) {
+	if host == "" {
+		return nil, errors.New("[mailer_smtp] invalid host")
+	}
+	if port == "" {
+		return nil, errors.New("[mailer_smtp] invalid port")
+	}
+	if from == "" {
+		return nil, errors.New("[mailer_smtp] invalid from")
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &smtpMailer{addr: net.JoinHostPort(host, port), auth: auth, from: from}, nil
+}
+
+func (m *smtpMailer) Send(_ context.Context, msg *Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg))
+}
@@ -0,0 +1,16 @@
+package oidc
+
+import (
+	"support-chat/pkg/codes"
+	"support-chat/pkg/errors"
+)
+
+const (
+	StatusExchange errors.Status = "failed_exchange_code"
+	StatusIDToken  errors.Status = "invalid_id_token"
+)
+
+var (
+	ErrExchange = errors.New(codes.Unauthorized, StatusExchange)
+	ErrIDToken  = errors.New(codes.Unauthorized, StatusIDToken)
+)
@@ -0,0 +1,282 @@
+// Package oidc is a relying party of the OpenID Connect authorization code
+// flow with PKCE. The provider is configured through its discovery document.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Provider is the part of the discovery document the flow needs.
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// IDToken holds the validated claims of an ID token. Claims has all of them,
+// for the ones which depend on the provider.
+type IDToken struct {
+	// Issuer and Subject identify the account at the provider, the email
+	// address may change
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified *bool
+	Name          string
+	Nonce         string
+	Claims        map[string]interface{}
+}
+
+//go:generate mockgen -source=oidc.go -destination=mocks/oidc_mock.go
+type Client interface {
+	AuthCodeURL(state, nonce, verifier string) string
+	Exchange(ctx context.Context, code, verifier string) (*IDToken, error)
+}
+
+type client struct {
+	provider     *Provider
+	clientId     string
+	clientSecret string
+	redirectUrl  string
+	scopes       []string
+	httpClient   *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+}
+
+// NewClient reads the discovery document of issuer. The client secret is
+// optional, public clients only rely on PKCE.
+func NewClient(ctx context.Context, issuer, clientId, clientSecret, redirectUrl string, scopes []string, httpClient *http.Client) (Client, error) {
+	if issuer == "" {
+		return nil, errors.New("[oidc] invalid issuer")
+	}
+	if clientId == "" {
+		return nil, errors.New("[oidc] invalid client id")
+	}
+	if redirectUrl == "" {
+		return nil, errors.New("[oidc] invalid redirect url")
+	}
+	if httpClient == nil {
+		return nil, errors.New("[oidc] invalid http client")
+	}
+
+	c := &client{
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		redirectUrl:  redirectUrl,
+		scopes:       scopes,
+		httpClient:   httpClient,
+	}
+
+	provider := new(Provider)
+	if err := c.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, fmt.Errorf("[oidc] failed to discover %v: %w", issuer, err)
+	}
+	// the issuer of the document has to be the configured one, otherwise
+	// tokens of another issuer would be accepted
+	if provider.Issuer != issuer {
+		return nil, fmt.Errorf("[oidc] discovered issuer %v doesn't match %v", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JwksUri == "" {
+		return nil, fmt.Errorf("[oidc] incomplete discovery document of %v", issuer)
+	}
+	c.provider = provider
+
+	return c, nil
+}
+
+// AuthCodeURL returns the URL of the provider the user logs in at. state and
+// nonce are checked when the user comes back, verifier is sent with the code.
+func (c *client) AuthCodeURL(state, nonce, verifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", c.clientId)
+	v.Set("redirect_uri", c.redirectUrl)
+	v.Set("scope", strings.Join(c.scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", Challenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(c.provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return c.provider.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange redeems the code at the token endpoint and validates the ID token
+// of the response. The nonce is left to the caller.
+func (c *client) Exchange(ctx context.Context, code, verifier string) (*IDToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.redirectUrl)
+	form.Set("client_id", c.clientId)
+	form.Set("code_verifier", verifier)
+	if c.clientSecret != "" {
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, ErrExchange
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrExchange
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&tokens); err != nil || tokens.IDToken == "" {
+		return nil, ErrExchange
+	}
+
+	return c.verify(ctx, tokens.IDToken)
+}
+
+// verify checks the signature, issuer, audience and expiry of the ID token.
+func (c *client) verify(ctx context.Context, raw string) (*IDToken, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, ErrIDToken
+	}
+
+	if !claims.VerifyIssuer(c.provider.Issuer, true) || !claims.VerifyAudience(c.clientId, true) {
+		return nil, ErrIDToken
+	}
+	// jwt only checks exp when it is set, an ID token must have it
+	if _, ok := claims["exp"]; !ok {
+		return nil, ErrIDToken
+	}
+
+	token := &IDToken{Issuer: c.provider.Issuer, Claims: claims}
+	token.Subject, _ = claims["sub"].(string)
+	token.Email, _ = claims["email"].(string)
+	token.Name, _ = claims["name"].(string)
+	token.Nonce, _ = claims["nonce"].(string)
+	if verified, ok := claims["email_verified"].(bool); ok {
+		token.EmailVerified = &verified
+	}
+	if token.Subject == "" {
+		return nil, ErrIDToken
+	}
+
+	return token, nil
+}
+
+// key returns the signing key of kid. The keys are fetched again when kid is
+// unknown, so a key rotation of the provider is picked up.
+func (c *client) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	c.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if err := c.loadKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok = c.keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown key %v", kid)
+	}
+
+	return key, nil
+}
+
+func (c *client) loadKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.getJSON(ctx, c.provider.JwksUri, &jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *client) getJSON(ctx context.Context, url string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %v of %v", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,183 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"support-chat/pkg/oidc"
+	"support-chat/pkg/oidc/oidctest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	clientId    = "support-chat"
+	redirectUrl = "http://localhost:3000/sso/callback"
+)
+
+func TestNewClient(t *testing.T) {
+	idp, err := oidctest.NewServer(clientId, "")
+	assert.Nil(t, err)
+	defer idp.Close()
+
+	tests := []struct {
+		name       string
+		issuer     string
+		clientId   string
+		redirect   string
+		httpClient *http.Client
+		wantErr    bool
+	}{
+		{name: "should discover provider", issuer: idp.URL, clientId: clientId, redirect: redirectUrl, httpClient: idp.Client()},
+		{name: "should fail with invalid issuer", clientId: clientId, redirect: redirectUrl, httpClient: idp.Client(), wantErr: true},
+		{name: "should fail with invalid client id", issuer: idp.URL, redirect: redirectUrl, httpClient: idp.Client(), wantErr: true},
+		{name: "should fail with invalid redirect url", issuer: idp.URL, clientId: clientId, httpClient: idp.Client(), wantErr: true},
+		{name: "should fail with invalid http client", issuer: idp.URL, clientId: clientId, redirect: redirectUrl, wantErr: true},
+		{name: "should fail when issuer doesn't match", issuer: idp.URL + "/", clientId: clientId, redirect: redirectUrl, httpClient: idp.Client(), wantErr: true},
+		{name: "should fail without discovery document", issuer: idp.URL + "/tenant", clientId: clientId, redirect: redirectUrl, httpClient: idp.Client(), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := oidc.NewClient(context.Background(), tc.issuer, tc.clientId, "", tc.redirect, []string{"openid"}, tc.httpClient)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.Nil(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestClient_AuthCodeURL(t *testing.T) {
+	idp, err := oidctest.NewServer(clientId, "")
+	assert.Nil(t, err)
+	defer idp.Close()
+
+	c, err := oidc.NewClient(context.Background(), idp.URL, clientId, "", redirectUrl, []string{"openid", "email"}, idp.Client())
+	assert.Nil(t, err)
+
+	u, err := url.Parse(c.AuthCodeURL("state", "nonce", "verifier"))
+	assert.Nil(t, err)
+	assert.Equal(t, idp.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+
+	q := u.Query()
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, clientId, q.Get("client_id"))
+	assert.Equal(t, redirectUrl, q.Get("redirect_uri"))
+	assert.Equal(t, "openid email", q.Get("scope"))
+	assert.Equal(t, "state", q.Get("state"))
+	assert.Equal(t, "nonce", q.Get("nonce"))
+	assert.Equal(t, oidc.Challenge("verifier"), q.Get("code_challenge"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+}
+
+func TestClient_Exchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		claims   map[string]interface{}
+		verifier string
+		code     string
+		rotate   bool
+		want     *oidc.IDToken
+		wantErr  error
+	}{
+		{
+			name:   "should return validated id token",
+			claims: map[string]interface{}{"sub": "42", "email": "user@example.com", "email_verified": true, "name": "User"},
+			want:   &oidc.IDToken{Subject: "42", Email: "user@example.com", EmailVerified: boolPtr(true), Name: "User", Nonce: "nonce"},
+		},
+		{
+			name:   "should authenticate confidential client",
+			secret: "secret",
+			want:   &oidc.IDToken{Subject: "subject", Nonce: "nonce"},
+		},
+		{
+			name:   "should fetch rotated key",
+			rotate: true,
+			want:   &oidc.IDToken{Subject: "subject", Nonce: "nonce"},
+		},
+		{
+			name:     "should fail with wrong verifier",
+			verifier: "other",
+			wantErr:  oidc.ErrExchange,
+		},
+		{
+			name:    "should fail with unknown code",
+			code:    "unknown",
+			wantErr: oidc.ErrExchange,
+		},
+		{
+			name:    "should fail with other audience",
+			claims:  map[string]interface{}{"aud": "other"},
+			wantErr: oidc.ErrIDToken,
+		},
+		{
+			name:    "should fail with other issuer",
+			claims:  map[string]interface{}{"iss": "https://other.example.com"},
+			wantErr: oidc.ErrIDToken,
+		},
+		{
+			name:    "should fail with expired token",
+			claims:  map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()},
+			wantErr: oidc.ErrIDToken,
+		},
+		{
+			name:    "should fail without subject",
+			claims:  map[string]interface{}{"sub": ""},
+			wantErr: oidc.ErrIDToken,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			idp, err := oidctest.NewServer(clientId, tc.secret)
+			assert.Nil(t, err)
+			defer idp.Close()
+
+			ctx := context.Background()
+			c, err := oidc.NewClient(ctx, idp.URL, clientId, tc.secret, redirectUrl, []string{"openid"}, idp.Client())
+			assert.Nil(t, err)
+
+			verifier, err := oidc.RandomString()
+			assert.Nil(t, err)
+
+			if tc.rotate {
+				code, _, err := idp.Authorize(c.AuthCodeURL("state", "nonce", verifier), nil)
+				assert.Nil(t, err)
+				_, err = c.Exchange(ctx, code, verifier)
+				assert.Nil(t, err)
+				assert.Nil(t, idp.RotateKey())
+			}
+
+			code, state, err := idp.Authorize(c.AuthCodeURL("state", "nonce", verifier), tc.claims)
+			assert.Nil(t, err)
+			assert.Equal(t, "state", state)
+
+			if tc.verifier != "" {
+				verifier = tc.verifier
+			}
+			if tc.code != "" {
+				code = tc.code
+			}
+
+			got, err := c.Exchange(ctx, code, verifier)
+			assert.Equal(t, tc.wantErr, err)
+			if tc.want != nil {
+				assert.NotNil(t, got)
+				assert.Equal(t, idp.URL, got.Issuer)
+				got.Issuer, got.Claims = "", nil
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,214 @@
+// Package oidctest runs a local OpenID Connect provider for tests. It
+// implements discovery, the JWKS and the token endpoint of the authorization
+// code flow with PKCE, the login itself is done by Authorize.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Server is the mock provider. The issuer is its URL.
+type Server struct {
+	*httptest.Server
+	ClientId     string
+	ClientSecret string
+
+	mu    sync.Mutex
+	key   *rsa.PrivateKey
+	kid   string
+	codes map[string]*grant
+}
+
+type grant struct {
+	redirectUri string
+	challenge   string
+	claims      jwt.MapClaims
+}
+
+// NewServer starts a provider which knows a single client. An empty secret
+// makes it a public client.
+func NewServer(clientId, clientSecret string) (*Server, error) {
+	s := &Server{ClientId: clientId, ClientSecret: clientSecret, codes: map[string]*grant{}}
+	if err := s.RotateKey(); err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+
+	return s, nil
+}
+
+// RotateKey replaces the signing key, tokens are then signed with a kid the
+// clients haven't seen yet.
+func (s *Server) RotateKey() error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	kid, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.key, s.kid = key, kid
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Authorize logs a user in at the authorization URL the client built. It
+// returns the code and the state the provider would redirect back with. The
+// claims are added to the ID token, sub defaults to "subject".
+func (s *Server) Authorize(authUrl string, claims map[string]interface{}) (string, string, error) {
+	u, err := url.Parse(authUrl)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+
+	if q.Get("response_type") != "code" || q.Get("client_id") != s.ClientId {
+		return "", "", errors.New("invalid authorization request")
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		return "", "", errors.New("missing pkce challenge")
+	}
+
+	idClaims := jwt.MapClaims{"sub": "subject", "nonce": q.Get("nonce")}
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+
+	code, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	s.codes[code] = &grant{redirectUri: q.Get("redirect_uri"), challenge: q.Get("code_challenge"), claims: idClaims}
+	s.mu.Unlock()
+
+	return code, q.Get("state"), nil
+}
+
+// SignIDToken signs claims with the current key, for tests of tokens the
+// token endpoint wouldn't issue.
+func (s *Server) SignIDToken(claims jwt.MapClaims) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+
+	return token.SignedString(s.key)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	pub := s.key.PublicKey
+	kid := s.kid
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if r.PostForm.Get("client_id") != s.ClientId || r.PostForm.Get("client_secret") != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	// codes are single use, a failed redemption burns them too
+	s.mu.Lock()
+	g, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || g.redirectUri != r.PostForm.Get("redirect_uri") || g.challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{"iss": s.URL, "aud": s.ClientId, "iat": now.Unix(), "exp": now.Add(time.Minute).Unix()}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+
+	idToken, err := s.SignIDToken(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,16 @@
+package rbac
+
+import (
+	"support-chat/pkg/codes"
+	"support-chat/pkg/errors"
+)
+
+const (
+	StatusNotAuthenticated errors.Status = "not_authenticated"
+	StatusPermissionDenied errors.Status = "permission_denied"
+)
+
+var (
+	ErrNotAuthenticated = errors.New(codes.Unauthorized, StatusNotAuthenticated)
+	ErrPermissionDenied = errors.New(codes.Forbidden, StatusPermissionDenied)
+)
@@ -0,0 +1,68 @@
+package rbac
+
+import (
+	"context"
+	gerrors "errors"
+	"net/http"
+	"support-chat/pkg/errors"
+	"support-chat/pkg/respond"
+
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=middleware.go -destination=mocks/middleware_mock.go
+type Middleware interface {
+	Require(permission Permission) func(next http.Handler) http.Handler
+}
+
+type middleware struct {
+	policy *Policy
+	logger *zap.SugaredLogger
+}
+
+func NewMiddleware(policy *Policy, logger *zap.SugaredLogger) (Middleware, error) {
+	if policy == nil {
+		return nil, gerrors.New("[rbac_middleware] invalid policy")
+	}
+	if logger == nil {
+		return nil, gerrors.New("[rbac_middleware] invalid logger")
+	}
+
+	return &middleware{policy: policy, logger: logger}, nil
+}
+
+type contextKey string
+
+// WithRole stores the role of the authenticated user, the auth middleware
+// calls it before the permissions are checked.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, contextKey("role"), role)
+}
+
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(contextKey("role")).(string)
+	return role, ok
+}
+
+// Require lets the request through when the role of the user is granted the
+// permission.
+func (m *middleware) Require(permission Permission) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := RoleFromContext(r.Context())
+			if !ok {
+				m.logger.Error("no role in request context")
+				respond.Respond(w, errors.HTTPCode(ErrNotAuthenticated), ErrNotAuthenticated)
+				return
+			}
+
+			if !m.policy.Can(role, permission) {
+				m.logger.Errorf("role %v doesn't have permission %v", role, permission)
+				respond.Respond(w, errors.HTTPCode(ErrPermissionDenied), ErrPermissionDenied)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
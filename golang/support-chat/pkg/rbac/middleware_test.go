@@ -0,0 +1,103 @@
+package rbac_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/rbac"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewMiddleware(t *testing.T) {
+	policy, _ := rbac.LoadPolicy("")
+
+	tests := []struct {
+		name   string
+		policy *rbac.Policy
+		logger *zap.SugaredLogger
+		expect func(*testing.T, rbac.Middleware, error)
+	}{
+		{
+			name:   "should return middleware",
+			policy: policy,
+			logger: &zap.SugaredLogger{},
+			expect: func(t *testing.T, m rbac.Middleware, err error) {
+				assert.NotNil(t, m)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:   "should return invalid policy",
+			policy: nil,
+			logger: &zap.SugaredLogger{},
+			expect: func(t *testing.T, m rbac.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[rbac_middleware] invalid policy")
+			},
+		},
+		{
+			name:   "should return invalid logger",
+			policy: policy,
+			logger: nil,
+			expect: func(t *testing.T, m rbac.Middleware, err error) {
+				assert.Nil(t, m)
+				assert.EqualError(t, err, "[rbac_middleware] invalid logger")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := rbac.NewMiddleware(tc.policy, tc.logger)
+			tc.expect(t, m, err)
+		})
+	}
+}
+
+func TestMiddleware_Require(t *testing.T) {
+	policy, _ := rbac.LoadPolicy("")
+	m, _ := rbac.NewMiddleware(policy, zap.NewNop().Sugar())
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		permission rbac.Permission
+		wantStatus int
+	}{
+		{
+			name:       "should let granted role through",
+			ctx:        rbac.WithRole(context.Background(), jwt.RoleSupport),
+			permission: rbac.RoomsQueue,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should return permission denied",
+			ctx:        rbac.WithRole(context.Background(), jwt.RoleUser),
+			permission: rbac.RoomsQueue,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "should return not authenticated",
+			ctx:        context.Background(),
+			permission: rbac.RoomsRead,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(tc.ctx)
+
+			m.Require(tc.permission)(next).ServeHTTP(w, r)
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
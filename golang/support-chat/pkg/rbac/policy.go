@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"support-chat/pkg/jwt"
+)
+
+type Permission string
+
+// Permissions checked on the routes
+const (
+	ChatConnect   Permission = "chat:connect"
+	RoomsRead     Permission = "rooms:read"
+	RoomsRate     Permission = "rooms:rate"
+	RoomsQueue    Permission = "rooms:queue"
+	RoomsTransfer Permission = "rooms:transfer"
+	RoomsArchive  Permission = "rooms:archive"
+	// RoomsSupervise lets supervisors observe conversations and whisper in them
+	RoomsSupervise Permission = "rooms:supervise"
+	RatingsRead    Permission = "ratings:read"
+	CannedRead     Permission = "canned:read"
+	CannedWrite    Permission = "canned:write"
+	UsersRead      Permission = "users:read"
+	UsersAdmin     Permission = "users:admin"
+	AuditRead      Permission = "audit:read"
+
+	// All grants every permission, including the ones added later
+	All Permission = "*"
+)
+
+var permissions = map[Permission]bool{
+	ChatConnect:    true,
+	RoomsRead:      true,
+	RoomsRate:      true,
+	RoomsQueue:     true,
+	RoomsTransfer:  true,
+	RoomsArchive:   true,
+	RoomsSupervise: true,
+	RatingsRead:    true,
+	CannedRead:     true,
+	CannedWrite:    true,
+	UsersRead:      true,
+	UsersAdmin:     true,
+	AuditRead:      true,
+	All:            true,
+}
+
+var supportPermissions = []Permission{
+	ChatConnect, RoomsRead, RoomsQueue, RoomsTransfer, RoomsArchive, RatingsRead, CannedRead, CannedWrite, UsersRead,
+}
+
+// DefaultRoles is the mapping used when no policy file is configured.
+var DefaultRoles = map[string][]Permission{
+	jwt.RoleUnverified: {ChatConnect},
+	jwt.RoleUser:       {ChatConnect, RoomsRead, RoomsRate},
+	jwt.RoleSupport:    supportPermissions,
+	jwt.RoleAdmin:      append([]Permission{UsersAdmin, AuditRead, RoomsSupervise}, supportPermissions...),
+}
+
+// Policy maps the roles to the permissions they are granted.
+type Policy struct {
+	roles map[string]map[Permission]bool
+}
+
+func NewPolicy(roles map[string][]Permission) (*Policy, error) {
+	p := &Policy{roles: make(map[string]map[Permission]bool, len(roles))}
+	for role, granted := range roles {
+		p.roles[role] = make(map[Permission]bool, len(granted))
+		for _, permission := range granted {
+			if !permissions[permission] {
+				return nil, fmt.Errorf("[rbac] unknown permission %q of role %q", permission, role)
+			}
+			p.roles[role][permission] = true
+		}
+	}
+
+	return p, nil
+}
+
+// LoadPolicy reads the role mapping from a JSON file of role to permission
+// list. The file replaces the default roles, it falls back to them when the
+// path is empty.
+func LoadPolicy(path string) (*Policy, error) {
+	if path == "" {
+		return NewPolicy(DefaultRoles)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[rbac] failed to read policy: %w", err)
+	}
+
+	var roles map[string][]Permission
+	if err = json.Unmarshal(data, &roles); err != nil {
+		return nil, fmt.Errorf("[rbac] failed to parse policy: %w", err)
+	}
+
+	return NewPolicy(roles)
+}
+
+func (p *Policy) Can(role string, permission Permission) bool {
+	granted := p.roles[role]
+	return granted[All] || granted[permission]
+}
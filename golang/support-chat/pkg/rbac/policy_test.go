@@ -0,0 +1,100 @@
+package rbac_test
+
+import (
+	"os"
+	"path/filepath"
+	"support-chat/pkg/jwt"
+	"support-chat/pkg/rbac"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Can(t *testing.T) {
+	policy, err := rbac.NewPolicy(map[string][]rbac.Permission{
+		jwt.RoleUser:  {rbac.RoomsRead},
+		jwt.RoleAdmin: {rbac.All},
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name       string
+		role       string
+		permission rbac.Permission
+		want       bool
+	}{
+		{name: "should grant listed permission", role: jwt.RoleUser, permission: rbac.RoomsRead, want: true},
+		{name: "should deny missing permission", role: jwt.RoleUser, permission: rbac.UsersAdmin, want: false},
+		{name: "should grant everything on wildcard", role: jwt.RoleAdmin, permission: rbac.AuditRead, want: true},
+		{name: "should deny unknown role", role: "guest", permission: rbac.RoomsRead, want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, policy.Can(tc.role, tc.permission))
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		expect func(*testing.T, *rbac.Policy, error)
+	}{
+		{
+			name: "should return default policy",
+			path: "",
+			expect: func(t *testing.T, p *rbac.Policy, err error) {
+				assert.Nil(t, err)
+				assert.True(t, p.Can(jwt.RoleAdmin, rbac.UsersAdmin))
+				assert.True(t, p.Can(jwt.RoleAdmin, rbac.RoomsQueue))
+				assert.False(t, p.Can(jwt.RoleSupport, rbac.UsersAdmin))
+				assert.True(t, p.Can(jwt.RoleAdmin, rbac.RoomsSupervise))
+				assert.False(t, p.Can(jwt.RoleSupport, rbac.RoomsSupervise))
+				assert.False(t, p.Can(jwt.RoleUser, rbac.RoomsQueue))
+				assert.True(t, p.Can(jwt.RoleUnverified, rbac.ChatConnect))
+				assert.False(t, p.Can(jwt.RoleUnverified, rbac.RoomsRead))
+			},
+		},
+		{
+			name: "should replace default roles",
+			path: write("policy.json", `{"user": ["rooms:read", "rooms:queue"]}`),
+			expect: func(t *testing.T, p *rbac.Policy, err error) {
+				assert.Nil(t, err)
+				assert.True(t, p.Can(jwt.RoleUser, rbac.RoomsQueue))
+				assert.False(t, p.Can(jwt.RoleAdmin, rbac.UsersAdmin))
+			},
+		},
+		{
+			name: "should return unknown permission",
+			path: write("unknown.json", `{"user": ["rooms:delete"]}`),
+			expect: func(t *testing.T, p *rbac.Policy, err error) {
+				assert.Nil(t, p)
+				assert.EqualError(t, err, `[rbac] unknown permission "rooms:delete" of role "user"`)
+			},
+		},
+		{
+			name: "should return missing file",
+			path: filepath.Join(dir, "missing.json"),
+			expect: func(t *testing.T, p *rbac.Policy, err error) {
+				assert.Nil(t, p)
+				assert.NotNil(t, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := rbac.LoadPolicy(tc.path)
+			tc.expect(t, p, err)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"support-chat/pkg/totp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "should match vector 59", unix: 59, want: "287082"},
+		{name: "should match vector 1111111109", unix: 1111111109, want: "081804"},
+		{name: "should match vector 1234567890", unix: 1234567890, want: "005924"},
+		{name: "should match vector 20000000000", unix: 20000000000, want: "353130"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := totp.Code(rfcSecret, totp.Step(time.Unix(tc.unix, 0)))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, code)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := totp.Step(now)
+	previous, _ := totp.Code(rfcSecret, step-1)
+	stale, _ := totp.Code(rfcSecret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "should match current code", code: "081804", wantStep: step, wantOk: true},
+		{name: "should allow one step of drift", code: previous, wantStep: step - 1, wantOk: true},
+		{name: "should reject stale code", code: stale, wantOk: false},
+		{name: "should reject malformed code", code: "81804", wantOk: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := totp.Match(rfcSecret, tc.code, now)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.wantStep, got)
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+
+	uri := totp.URI("Support Chat", "agent@example.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Support%20Chat:agent@example.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=Support+Chat")
+}